@@ -0,0 +1 @@
+var b = 2;
\ No newline at end of file
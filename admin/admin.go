@@ -0,0 +1,138 @@
+// Package admin provides an admin sub application with runtime controls for baa.
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-baa/baa"
+)
+
+// Options admin config
+type Options struct {
+	// Prefix of admin routes, default is /_admin
+	Prefix string
+	// Auth authenticates admin requests, it should break the chain when denied.
+	// Username and Password are used as basic auth when Auth is nil, one of them is required.
+	Auth     baa.HandlerFunc
+	Username string
+	Password string
+	// SetLogLevel switches log level of the logger used by the application, the log level
+	// endpoint is disabled when nil, baa.Logger has no level.
+	SetLogLevel func(level string) error
+}
+
+// Mount registers admin routes of b under prefix on b self,
+// admin routes are served in maintenance mode.
+//
+// Example:
+// 		admin.Mount(b, admin.Options{Username: "admin", Password: os.Getenv("ADMIN_PASSWORD")})
+func Mount(b *baa.Baa, opt Options) {
+	register(b, b, opt)
+}
+
+// New create an admin application controls b, it's used to serve admin on a separate port.
+//
+// Example:
+// 		go admin.New(b, opt).Run("127.0.0.1:9090")
+func New(b *baa.Baa, opt Options) *baa.Baa {
+	app := baa.New()
+	app.SetDI("logger", b.Logger())
+	register(app, b, opt)
+	return app
+}
+
+// register registers admin routes on app controls b
+func register(app, b *baa.Baa, opt Options) {
+	auth := opt.Auth
+	if auth == nil {
+		if opt.Username == "" || opt.Password == "" {
+			panic("admin.Options Auth or Username and Password is required")
+		}
+		auth = basicAuth(opt.Username, opt.Password)
+	}
+	prefix := opt.Prefix
+	if prefix == "" {
+		prefix = "/_admin"
+	}
+
+	g := app.NewGroup(prefix, auth)
+	exempt := func(n baa.MetaRouteNode) {
+		n.SetMeta(baa.RouteMetaMaintenanceExempt, true)
+	}
+	exempt(g.Get("/routes", func(c *baa.Context) {
+		c.JSON(http.StatusOK, map[string]interface{}{
+			"routes": b.Router().Routes(),
+			"named":  b.Router().NamedRoutes(),
+		})
+	}))
+	exempt(g.Get("/stats", func(c *baa.Context) {
+		c.JSON(http.StatusOK, b.Stats())
+	}))
+	exempt(g.Get("/info", b.InfoHandler()))
+	exempt(g.Get("/maintenance", func(c *baa.Context) {
+		c.JSON(http.StatusOK, map[string]bool{"enabled": b.Maintenance()})
+	}))
+	exempt(g.Route("/maintenance", "POST,PUT", func(c *baa.Context) {
+		on, err := strconv.ParseBool(c.Query("enabled"))
+		if err != nil {
+			c.Error(baa.NewHTTPError(http.StatusBadRequest, "invalid enabled"))
+			return
+		}
+		b.SetMaintenance(on)
+		b.Logger().Printf("admin: maintenance mode %v", on)
+		c.JSON(http.StatusOK, map[string]bool{"enabled": on})
+	}))
+	exempt(g.Post("/cache/flush", func(c *baa.Context) {
+		f, ok := b.Cache().(interface {
+			Flush() error
+		})
+		if !ok {
+			c.Error(baa.NewHTTPError(http.StatusNotImplemented, "cache does not support flush"))
+			return
+		}
+		if err := f.Flush(); err != nil {
+			c.Error(err)
+			return
+		}
+		b.Logger().Println("admin: cache flushed")
+		c.JSON(http.StatusOK, map[string]bool{"flushed": true})
+	}))
+	exempt(g.Post("/reload", func(c *baa.Context) {
+		if err := b.Reload(); err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, map[string]bool{"reloaded": true})
+	}))
+	if opt.SetLogLevel != nil {
+		exempt(g.Route("/loglevel", "POST,PUT", func(c *baa.Context) {
+			level := strings.TrimSpace(c.Query("level"))
+			if level == "" {
+				c.Error(baa.NewHTTPError(http.StatusBadRequest, "level is required"))
+				return
+			}
+			if err := opt.SetLogLevel(level); err != nil {
+				c.Error(baa.NewHTTPError(http.StatusBadRequest).WithError(err))
+				return
+			}
+			c.JSON(http.StatusOK, map[string]string{"level": level})
+		}))
+	}
+}
+
+// basicAuth returns a handler checks basic auth credentials
+func basicAuth(username, password string) baa.HandlerFunc {
+	return func(c *baa.Context) {
+		u, p, ok := c.Req.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(u), []byte(username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(p), []byte(password)) != 1 {
+			c.Resp.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			c.Error(baa.NewHTTPError(http.StatusUnauthorized))
+			return
+		}
+		c.Next()
+	}
+}
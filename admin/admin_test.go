@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdmin1(t *testing.T) {
+	Convey("admin runtime controls", t, func() {
+		app := baa.New()
+		app.Get("/hello", func(c *baa.Context) {
+			c.String(http.StatusOK, "hello")
+		})
+		level := "info"
+		Mount(app, Options{Username: "admin", Password: "secret", SetLogLevel: func(v string) error {
+			if v != "debug" && v != "info" {
+				return errors.New("unknown level")
+			}
+			level = v
+			return nil
+		}})
+
+		request := func(method, path string, auth bool) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest(method, path, nil)
+			if auth {
+				req.SetBasicAuth("admin", "secret")
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			return w
+		}
+
+		So(request("GET", "/_admin/stats", false).Code, ShouldEqual, http.StatusUnauthorized)
+		w := request("GET", "/_admin/routes", true)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldContainSubstring, "/hello")
+		So(request("GET", "/_admin/stats", true).Code, ShouldEqual, http.StatusOK)
+		So(request("GET", "/_admin/info", true).Code, ShouldEqual, http.StatusOK)
+
+		So(request("POST", "/_admin/maintenance?enabled=x", true).Code, ShouldEqual, http.StatusBadRequest)
+		So(request("POST", "/_admin/maintenance?enabled=true", true).Code, ShouldEqual, http.StatusOK)
+		So(app.Maintenance(), ShouldBeTrue)
+		So(request("GET", "/hello", false).Code, ShouldEqual, http.StatusServiceUnavailable)
+		w = request("GET", "/_admin/maintenance", true)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldContainSubstring, "true")
+		So(request("PUT", "/_admin/maintenance?enabled=false", true).Code, ShouldEqual, http.StatusOK)
+		So(request("GET", "/hello", false).Code, ShouldEqual, http.StatusOK)
+
+		app.Cache().Set("k", "v", time.Minute)
+		So(request("POST", "/_admin/cache/flush", true).Code, ShouldEqual, http.StatusOK)
+		_, ok := app.Cache().Get("k")
+		So(ok, ShouldBeFalse)
+
+		So(request("POST", "/_admin/loglevel?level=debug", true).Code, ShouldEqual, http.StatusOK)
+		So(level, ShouldEqual, "debug")
+		So(request("POST", "/_admin/loglevel?level=trace", true).Code, ShouldEqual, http.StatusBadRequest)
+		So(request("POST", "/_admin/loglevel", true).Code, ShouldEqual, http.StatusBadRequest)
+
+		reloaded := false
+		app.OnReload(func() error {
+			reloaded = true
+			return nil
+		})
+		So(request("POST", "/_admin/reload", true).Code, ShouldEqual, http.StatusOK)
+		So(reloaded, ShouldBeTrue)
+	})
+	Convey("separate admin app", t, func() {
+		app := baa.New()
+		admin := New(app, Options{Prefix: "/ops", Auth: func(c *baa.Context) {
+			if c.Req.Header.Get("X-Token") != "t" {
+				c.Error(baa.NewHTTPError(http.StatusForbidden))
+				return
+			}
+			c.Next()
+		}})
+		req, _ := http.NewRequest("POST", "/ops/maintenance?enabled=1", nil)
+		req.Header.Set("X-Token", "t")
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(app.Maintenance(), ShouldBeTrue)
+
+		req, _ = http.NewRequest("GET", "/ops/stats", nil)
+		w = httptest.NewRecorder()
+		admin.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusForbidden)
+		So(strings.TrimSpace(w.Body.String()), ShouldEqual, "Forbidden")
+
+		So(func() { New(app, Options{}) }, ShouldPanic)
+	})
+}
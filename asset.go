@@ -0,0 +1,251 @@
+package baa
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// assetCacheControl cache header for fingerprinted assets, content never changes
+const assetCacheControl = "public, max-age=31536000, immutable"
+
+// AssetManifest maps logical asset names to fingerprinted file names
+// eg: css/app.css -> css/app.3f2a1b9c.css
+type AssetManifest struct {
+	files     map[string]string  // logical name -> hashed name
+	origins   map[string]string  // hashed name -> logical name
+	integrity map[string]string  // logical name -> subresource integrity
+	bundles   map[string]*bundle // logical and hashed name -> built bundle
+	mu        sync.RWMutex
+}
+
+// NewAssetManifest create an empty asset manifest
+func NewAssetManifest() *AssetManifest {
+	m := new(AssetManifest)
+	m.files = make(map[string]string)
+	m.origins = make(map[string]string)
+	m.integrity = make(map[string]string)
+	m.bundles = make(map[string]*bundle)
+	return m
+}
+
+// GenerateAssetManifest walks the dir and fingerprints every file by content hash
+func GenerateAssetManifest(dir string) (*AssetManifest, error) {
+	m := NewAssetManifest()
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		sum, sri, err := hashFile(file)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		m.Set(name, fingerprint(name, sum[:8]))
+		m.SetIntegrity(name, sri)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadAssetManifest loads asset manifest from a JSON file, eg: {"app.css": "app.3f2a1b9c.css"}
+func LoadAssetManifest(file string) (*AssetManifest, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	if err := Unmarshal(data, &files); err != nil {
+		return nil, err
+	}
+	m := NewAssetManifest()
+	for k, v := range files {
+		m.Set(k, v)
+	}
+	return m, nil
+}
+
+// Set maps logical name to hashed name
+func (m *AssetManifest) Set(name, hashed string) {
+	name = strings.TrimPrefix(name, "/")
+	hashed = strings.TrimPrefix(hashed, "/")
+	m.mu.Lock()
+	m.files[name] = hashed
+	m.origins[hashed] = name
+	m.mu.Unlock()
+}
+
+// Lookup returns the hashed name of logical name, returns name self when not found
+func (m *AssetManifest) Lookup(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if v, ok := m.files[name]; ok {
+		return v
+	}
+	return name
+}
+
+// Origin returns the logical name of hashed name
+func (m *AssetManifest) Origin(hashed string) (string, bool) {
+	m.mu.RLock()
+	name, ok := m.origins[strings.TrimPrefix(hashed, "/")]
+	m.mu.RUnlock()
+	return name, ok
+}
+
+// SetIntegrity sets the subresource integrity of logical name, eg: sha384-oqVuAfXRKap7fdgcCY5uykM6+R9GqQ8K/uxy9rx7HNQlGYl1kPzQho1wx4JwY8wC
+func (m *AssetManifest) SetIntegrity(name, integrity string) {
+	m.mu.Lock()
+	m.integrity[strings.TrimPrefix(name, "/")] = integrity
+	m.mu.Unlock()
+}
+
+// Integrity returns the subresource integrity of logical name
+func (m *AssetManifest) Integrity(name string) (string, bool) {
+	m.mu.RLock()
+	v, ok := m.integrity[strings.TrimPrefix(name, "/")]
+	m.mu.RUnlock()
+	return v, ok
+}
+
+// Files returns a copy of logical name to hashed name map
+func (m *AssetManifest) Files() map[string]string {
+	m.mu.RLock()
+	files := make(map[string]string, len(m.files))
+	for k, v := range m.files {
+		files[k] = v
+	}
+	m.mu.RUnlock()
+	return files
+}
+
+// Save writes manifest to a JSON file
+func (m *AssetManifest) Save(file string) error {
+	data, err := MarshalIndent(m.Files(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// Assets serves files of dir under prefix with fingerprinted names, and registers
+// template func asset returns the fingerprinted url of a logical name,
+// template func sri returns the subresource integrity of the served file,
+// template func bundle returns urls of a bundle added by m.AddBundle.
+// The manifest is generated from dir when m is nil.
+// Fingerprinted files are served with immutable cache headers.
+//
+// Example:
+// 		b.Assets("/assets", "./public", nil)
+// 		<link rel="stylesheet" href="{{ asset "css/app.css" }}">
+// 		<script src="{{ asset "js/app.js" }}" integrity="{{ sri "js/app.js" }}" crossorigin="anonymous"></script>
+func (b *Baa) Assets(prefix, dir string, m *AssetManifest) *AssetManifest {
+	if prefix == "" {
+		panic("baa.Assets prefix can not be empty")
+	}
+	if dir == "" {
+		panic("baa.Assets dir can not be empty")
+	}
+	if m == nil {
+		var err error
+		if m, err = GenerateAssetManifest(dir); err != nil {
+			panic("baa.Assets generate manifest error: " + err.Error())
+		}
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	dir = strings.TrimSuffix(dir, "/")
+
+	b.AddTemplateFunc("asset", func(name string) string {
+		return prefix + "/" + m.Lookup(name)
+	})
+	b.AddTemplateFunc("sri", func(name string) (string, error) {
+		if v, ok := m.Integrity(name); ok {
+			return v, nil
+		}
+		// manifest loaded from file has no integrity, hash the file would be served
+		file, _ := assetFile(dir, m, m.Lookup(name))
+		_, sri, err := hashFile(file)
+		if err != nil {
+			return "", err
+		}
+		m.SetIntegrity(name, sri)
+		return sri, nil
+	})
+	b.AddTemplateFunc("bundle", func(name string) ([]string, error) {
+		return m.bundleURLs(prefix, name, b.Debug())
+	})
+	b.Get(prefix+"/*", func(c *Context) {
+		name := path.Clean("/" + c.Param(""))[1:]
+		if m.serveBundle(name, c) {
+			return
+		}
+		file, hashed := assetFile(dir, m, name)
+		if hashed {
+			c.Resp.Header().Set("Cache-Control", assetCacheControl)
+		}
+		if err := serveFile(file, c); err != nil {
+			c.Resp.Header().Del("Cache-Control")
+			if os.IsNotExist(err) {
+				c.NotFound()
+				return
+			}
+			http.Error(c.Resp, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		}
+	})
+	return m
+}
+
+// assetFile returns the file path of name in dir, and whether name is a fingerprinted name
+func assetFile(dir string, m *AssetManifest, name string) (string, bool) {
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	origin, ok := m.Origin(name)
+	if !ok {
+		return file, false
+	}
+	// fingerprinted file may not exists when manifest generated at startup
+	if _, err := os.Stat(file); err != nil {
+		file = filepath.Join(dir, filepath.FromSlash(origin))
+	}
+	return file, true
+}
+
+// hashFile returns hex encoded sha256 and sha384 subresource integrity of file content
+func hashFile(file string) (string, string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	h2 := sha512.New384()
+	if _, err := io.Copy(io.MultiWriter(h, h2), f); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), "sha384-" + base64.StdEncoding.EncodeToString(h2.Sum(nil)), nil
+}
+
+// fingerprint inserts hash into file name before extension
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return name[:len(name)-len(ext)] + "." + hash + ext
+}
@@ -0,0 +1,128 @@
+package baa
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AssetBundle declares files concatenated into a bundle served in production,
+// files are served individually in debug mode.
+type AssetBundle struct {
+	// Name logical name of the bundle, eg: js/app.js
+	Name string
+	// Files logical names of files in the asset directory, in order
+	Files []string
+	// Minify minifies the concatenated content, eg: an adapter of a minifier
+	// package, the content is not minified when it's nil.
+	Minify func(name string, content []byte) ([]byte, error)
+}
+
+// bundle is a built asset bundle
+type bundle struct {
+	name    string
+	hashed  string
+	files   []string
+	content []byte
+	modtime time.Time
+}
+
+// AddBundle builds bundle from files in dir, the content is concatenated with
+// new lines, minified and fingerprinted. The bundle is served from memory by
+// b.Assets, and template func bundle returns its url in production or urls of
+// its files in debug mode. It's intended to be called at startup.
+//
+// Example:
+// 		m := b.Assets("/assets", "./public", nil)
+// 		err := m.AddBundle("./public", baa.AssetBundle{
+// 			Name:  "js/app.js",
+// 			Files: []string{"js/vendor.js", "js/main.js"},
+// 		})
+// 		{{ range bundle "js/app.js" }}<script src="{{ . }}"></script>{{ end }}
+func (m *AssetManifest) AddBundle(dir string, b AssetBundle) error {
+	name := strings.TrimPrefix(b.Name, "/")
+	if name == "" {
+		return errors.New("baa: asset bundle name can not be empty")
+	}
+	if len(b.Files) == 0 {
+		return errors.New("baa: asset bundle " + name + " has no files")
+	}
+	buf := new(bytes.Buffer)
+	files := make([]string, len(b.Files))
+	for i, f := range b.Files {
+		files[i] = strings.TrimPrefix(path.Clean("/"+f), "/")
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(files[i])))
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	content := buf.Bytes()
+	if b.Minify != nil {
+		var err error
+		if content, err = b.Minify(name, content); err != nil {
+			return err
+		}
+	}
+	sum := sha256.Sum256(content)
+	sri := sha512.Sum384(content)
+	bd := &bundle{
+		name:    name,
+		hashed:  fingerprint(name, hex.EncodeToString(sum[:])[:8]),
+		files:   files,
+		content: content,
+		modtime: time.Now(),
+	}
+	m.Set(name, bd.hashed)
+	m.SetIntegrity(name, "sha384-"+base64.StdEncoding.EncodeToString(sri[:]))
+	m.mu.Lock()
+	m.bundles[name] = bd
+	m.bundles[bd.hashed] = bd
+	m.mu.Unlock()
+	return nil
+}
+
+// bundleURLs returns the url of bundle name, urls of its files in debug mode
+func (m *AssetManifest) bundleURLs(prefix, name string, debug bool) ([]string, error) {
+	m.mu.RLock()
+	bd := m.bundles[strings.TrimPrefix(name, "/")]
+	m.mu.RUnlock()
+	if bd == nil {
+		return nil, errors.New("baa: asset bundle " + name + " not found")
+	}
+	if !debug {
+		return []string{prefix + "/" + bd.hashed}, nil
+	}
+	urls := make([]string, len(bd.files))
+	for i, f := range bd.files {
+		urls[i] = prefix + "/" + m.Lookup(f)
+	}
+	return urls, nil
+}
+
+// serveBundle serves the bundle of logical or hashed name, returns false when
+// name is not a bundle.
+func (m *AssetManifest) serveBundle(name string, c *Context) bool {
+	m.mu.RLock()
+	bd := m.bundles[name]
+	m.mu.RUnlock()
+	if bd == nil {
+		return false
+	}
+	if name == bd.hashed {
+		c.Resp.Header().Set("Cache-Control", assetCacheControl)
+	}
+	c.ServeContent(bd.name, bd.modtime, bytes.NewReader(bd.content))
+	return true
+}
@@ -0,0 +1,165 @@
+package baa
+
+import (
+	"bytes"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAssets1(t *testing.T) {
+	Convey("asset fingerprinting", t, func() {
+		b2 := New()
+		m := b2.Assets("/assets/", "_fixture", nil)
+		hashed := m.Lookup("img/baa.jpg")
+		So(hashed, ShouldStartWith, "img/baa.")
+		So(hashed, ShouldEndWith, ".jpg")
+		So(hashed, ShouldNotEqual, "img/baa.jpg")
+		So(m.Lookup("notfound.js"), ShouldEqual, "notfound.js")
+		b2.Get("/page", func(c *Context) {
+			c.HTML(200, "_fixture/asset.html")
+		})
+
+		Convey("template func", func() {
+			req, _ := http.NewRequest("GET", "/page", nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "<img src=\"/assets/"+hashed+"\">\n")
+		})
+		Convey("sri template func", func() {
+			_, sri, _ := hashFile("_fixture/img/baa.jpg")
+			So(sri, ShouldStartWith, "sha384-")
+			v, ok := m.Integrity("img/baa.jpg")
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, sri)
+			b2.Get("/sri", func(c *Context) {
+				c.HTML(200, "_fixture/sri.html")
+			})
+			req, _ := http.NewRequest("GET", "/sri", nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(html.UnescapeString(w.Body.String()), ShouldEqual, "<script src=\"/assets/"+hashed+"\" integrity=\""+sri+"\"></script>\n")
+		})
+		Convey("serve fingerprinted file", func() {
+			req, _ := http.NewRequest("GET", "/assets/"+hashed, nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Cache-Control"), ShouldEqual, assetCacheControl)
+		})
+		Convey("serve logical file", func() {
+			req, _ := http.NewRequest("GET", "/assets/img/baa.jpg", nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Cache-Control"), ShouldEqual, "")
+		})
+		Convey("serve not found and dir", func() {
+			req, _ := http.NewRequest("GET", "/assets/notfound.js", nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+
+			req, _ = http.NewRequest("GET", "/assets/img", nil)
+			w = httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+		})
+	})
+
+	Convey("asset manifest file", t, func() {
+		dir, err := ioutil.TempDir("", "baa-assets")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "app.1234.js"), []byte("var a;"), 0644)
+
+		m := NewAssetManifest()
+		m.Set("/app.js", "/app.1234.js")
+		file := filepath.Join(dir, "manifest.json")
+		So(m.Save(file), ShouldBeNil)
+		m2, err := LoadAssetManifest(file)
+		So(err, ShouldBeNil)
+		So(m2.Files(), ShouldResemble, map[string]string{"app.js": "app.1234.js"})
+
+		_, err = LoadAssetManifest(filepath.Join(dir, "notfound.json"))
+		So(err, ShouldNotBeNil)
+		_, err = LoadAssetManifest(filepath.Join(dir, "app.1234.js"))
+		So(err, ShouldNotBeNil)
+		_, err = GenerateAssetManifest(filepath.Join(dir, "notfound"))
+		So(err, ShouldNotBeNil)
+
+		b2 := New()
+		b2.Assets("/static", dir, m2)
+		req, _ := http.NewRequest("GET", "/static/app.1234.js", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(strings.TrimSpace(w.Body.String()), ShouldEqual, "var a;")
+		So(w.Header().Get("Cache-Control"), ShouldEqual, assetCacheControl)
+
+		// integrity of loaded manifest is computed from the served file
+		_, ok := m2.Integrity("app.js")
+		So(ok, ShouldBeFalse)
+		b2.Get("/sri", func(c *Context) {
+			c.HTML(200, "_fixture/sri.html")
+		})
+		m2.Set("img/baa.jpg", "app.1234.js")
+		req, _ = http.NewRequest("GET", "/sri", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		_, sri, _ := hashFile(filepath.Join(dir, "app.1234.js"))
+		So(html.UnescapeString(w.Body.String()), ShouldContainSubstring, "integrity=\""+sri+"\"")
+
+		So(func() { b2.Assets("", dir, m2) }, ShouldPanic)
+		So(func() { b2.Assets("/s", "", m2) }, ShouldPanic)
+		So(func() { b2.Assets("/s", filepath.Join(dir, "notfound"), nil) }, ShouldPanic)
+	})
+}
+
+func TestAssetBundle1(t *testing.T) {
+	Convey("asset bundles", t, func() {
+		b2 := New()
+		m := b2.Assets("/assets", "_fixture/bundle", nil)
+		So(m.AddBundle("_fixture/bundle", AssetBundle{
+			Name:  "js/app.js",
+			Files: []string{"a.js", "/b.js"},
+			Minify: func(name string, content []byte) ([]byte, error) {
+				return bytes.Replace(content, []byte(" "), nil, -1), nil
+			},
+		}), ShouldBeNil)
+		So(m.AddBundle("_fixture/bundle", AssetBundle{Name: "none.js", Files: []string{"none.js"}}), ShouldNotBeNil)
+		So(m.AddBundle("_fixture/bundle", AssetBundle{Name: "empty.js"}), ShouldNotBeNil)
+		hashed := m.Lookup("js/app.js")
+		So(hashed, ShouldStartWith, "js/app.")
+		b2.Get("/page", func(c *Context) {
+			c.HTML(200, "_fixture/bundle.html")
+		})
+
+		b2.SetDebug(false)
+		So(serveTo(b2, "/page").Body.String(), ShouldEqual, `<script src="/assets/`+hashed+`"></script>`+"\n")
+		b2.SetDebug(true)
+		So(serveTo(b2, "/page").Body.String(), ShouldEqual, `<script src="/assets/`+m.Lookup("a.js")+`"></script>`+
+			`<script src="/assets/`+m.Lookup("b.js")+`"></script>`+"\n")
+
+		w := serveTo(b2, "/assets/"+hashed)
+		So(w.Body.String(), ShouldEqual, "vara=1;\nvarb=2;\n")
+		So(w.Header().Get("Cache-Control"), ShouldEqual, assetCacheControl)
+		w = serveTo(b2, "/assets/js/app.js")
+		So(w.Body.String(), ShouldEqual, "vara=1;\nvarb=2;\n")
+		So(w.Header().Get("Cache-Control"), ShouldBeEmpty)
+		So(serveTo(b2, "/assets/"+m.Lookup("a.js")).Body.String(), ShouldEqual, "var a = 1;\n")
+		sri, ok := m.Integrity("js/app.js")
+		So(ok, ShouldBeTrue)
+		So(sri, ShouldStartWith, "sha384-")
+	})
+}
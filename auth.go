@@ -0,0 +1,161 @@
+package baa
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// authUserKey is the context store key of current user
+	authUserKey = "baa.authUser"
+	// authSessionKey is the session key of logged in user id
+	authSessionKey = "baa.authID"
+)
+
+// ErrAuthNotConfigured is returned by Login when SetAuth or SetSessions is not called
+var ErrAuthNotConfigured = errors.New("baa: auth is not configured, call SetAuth and SetSessions first")
+
+// AuthUser is a user can log in
+type AuthUser interface {
+	// AuthID returns the stable id of user stored at login
+	AuthID() string
+}
+
+// currentUser is the current user cached in context, user is nil when not logged in
+type currentUser struct {
+	user AuthUser
+}
+
+// UserProvider loads the user of id stored at login, it returns nil user without
+// error when the user no longer exists.
+type UserProvider interface {
+	UserByID(id string) (AuthUser, error)
+}
+
+// UserProviderFunc is an adapter allows functions to be used as UserProvider
+type UserProviderFunc func(id string) (AuthUser, error)
+
+// UserByID calls f(id)
+func (f UserProviderFunc) UserByID(id string) (AuthUser, error) {
+	return f(id)
+}
+
+// AuthOptions config of login, logout and authentication
+type AuthOptions struct {
+	// Provider loads the current user, required
+	Provider UserProvider
+	// LoginURL unauthenticated web requests are redirected to with query next,
+	// default /login
+	LoginURL string
+	// IsAPI returns if the request is an API request answered 401 instead of
+	// redirect, default is requests do not accept text/html
+	IsAPI func(c *Context) bool
+}
+
+// SetAuth configures Login, Logout, c.User and RequireLogin, the login is kept
+// in the session so it lasts as long as the session and is revoked by Logout.
+// Sessions must be enabled by SetSessions, before or after SetAuth. Logins kept
+// by CookieSessionStore can not be revoked before expiry, use a server side store
+// when Logout must end copied sessions.
+//
+// Example:
+// 		b.SetSessions(baa.SessionOptions{})
+// 		b.SetAuth(baa.AuthOptions{Provider: baa.UserProviderFunc(users.Find)})
+// 		b.Post("/login", func(c *baa.Context) {
+// 			user, err := users.Check(c.Posts()["name"], c.Posts()["password"])
+// 			...
+// 			baa.Login(c, user)
+// 		})
+// 		b.Group("/account", func() { ... }, baa.RequireLogin())
+func (b *Baa) SetAuth(opt AuthOptions) {
+	if f, ok := opt.Provider.(UserProviderFunc); opt.Provider == nil || (ok && f == nil) {
+		panic("baa.SetAuth provider can not be nil")
+	}
+	if opt.LoginURL == "" {
+		opt.LoginURL = "/login"
+	}
+	if opt.IsAPI == nil {
+		opt.IsAPI = func(c *Context) bool {
+			return !strings.Contains(c.Req.Header.Get("Accept"), "text/html")
+		}
+	}
+	b.auth = &opt
+}
+
+// Login logs user in, the user is the current user of following requests until
+// Logout or the session expires. The session is renewed against session fixation.
+func Login(c *Context, user AuthUser) error {
+	if c.baa.auth == nil || c.baa.sessions == nil {
+		return ErrAuthNotConfigured
+	}
+	if user == nil {
+		return errors.New("baa: login user can not be nil")
+	}
+	s := c.Session()
+	s.Renew()
+	s.Set(authSessionKey, user.AuthID())
+	c.Set(authUserKey, currentUser{user})
+	return nil
+}
+
+// Logout logs the current user out, the session is destroyed.
+func Logout(c *Context) {
+	if c.baa.auth == nil || c.baa.sessions == nil {
+		return
+	}
+	c.Session().Destroy()
+	c.Set(authUserKey, currentUser{})
+}
+
+// User returns the logged in user of request, nil when not logged in. The user
+// is loaded by the UserProvider once per request, provider errors are logged.
+func (c *Context) User() AuthUser {
+	if v, ok := c.Get(authUserKey).(currentUser); ok {
+		return v.user
+	}
+	user := c.loadUser()
+	c.Set(authUserKey, currentUser{user})
+	return user
+}
+
+// loadUser loads the user of id kept in session
+func (c *Context) loadUser() AuthUser {
+	opt := c.baa.auth
+	if opt == nil || c.baa.sessions == nil {
+		return nil
+	}
+	id, _ := c.Session().Get(authSessionKey).(string)
+	if id == "" {
+		return nil
+	}
+	user, err := opt.Provider.UserByID(id)
+	if err != nil {
+		c.baa.Logger().Printf("baa: load user %s: %v", id, err)
+		return nil
+	}
+	return user
+}
+
+// RequireLogin returns a handler allows requests of logged in users, API requests
+// are answered 401 by the error handler, web requests are redirected to the login
+// URL with the requested URL in query next.
+func RequireLogin() HandlerFunc {
+	return func(c *Context) {
+		if c.User() != nil {
+			c.Next()
+			return
+		}
+		opt := c.baa.auth
+		if opt == nil || opt.IsAPI(c) || (c.Req.Method != http.MethodGet && c.Req.Method != http.MethodHead) {
+			c.Error(NewHTTPError(http.StatusUnauthorized))
+			return
+		}
+		sep := "?"
+		if strings.Contains(opt.LoginURL, "?") {
+			sep = "&"
+		}
+		c.Redirect(http.StatusFound, opt.LoginURL+sep+"next="+url.QueryEscape(c.Req.URL.RequestURI()))
+	}
+}
@@ -0,0 +1,110 @@
+package baa
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testUser is an AuthUser of tests
+type testUser string
+
+func (u testUser) AuthID() string { return string(u) }
+
+func TestAuth1(t *testing.T) {
+	b2 := New()
+	clock := NewFakeClock(time.Now())
+	b2.SetDI("clock", clock)
+
+	Convey("auth configuration", t, func() {
+		So(func() { b2.SetAuth(AuthOptions{}) }, ShouldPanic)
+		So(func() { b2.SetAuth(AuthOptions{Provider: UserProviderFunc(nil)}) }, ShouldPanic)
+		c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), b2)
+		So(Login(c, testUser("tom")) == ErrAuthNotConfigured, ShouldBeTrue)
+		So(c.User(), ShouldBeNil)
+	})
+
+	loads := 0
+	b2.SetAuth(AuthOptions{Provider: UserProviderFunc(func(id string) (AuthUser, error) {
+		loads++
+		switch id {
+		case "tom", "jerry|1":
+			return testUser(id), nil
+		case "broken":
+			return nil, errors.New("db down")
+		}
+		return nil, nil
+	})})
+
+	Convey("login needs sessions", t, func() {
+		c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), b2)
+		So(Login(c, testUser("tom")) == ErrAuthNotConfigured, ShouldBeTrue)
+		So(c.User(), ShouldBeNil)
+	})
+
+	// sessions enabled after SetAuth
+	b2.SetSessions(SessionOptions{IdleTimeout: time.Hour, MaxLifetime: 24 * time.Hour})
+	b2.Post("/login/:name", func(c *Context) {
+		So(Login(c, testUser(c.Param("name"))), ShouldBeNil)
+		c.String(200, string(c.User().(testUser)))
+	})
+	b2.Post("/logout", func(c *Context) {
+		Logout(c)
+		So(c.User(), ShouldBeNil)
+	})
+	b2.Get("/account", RequireLogin(), func(c *Context) {
+		c.User()
+		c.String(200, "hello "+c.User().AuthID())
+	})
+	do := func(method, uri, accept string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, uri, nil)
+		req.Header.Set("Accept", accept)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		return w
+	}
+	login := func(name string) []*http.Cookie {
+		w := do("POST", "/login/"+name, "", nil)
+		So(w.Body.String(), ShouldEqual, name)
+		return w.Result().Cookies()
+	}
+
+	Convey("login and current user", t, func() {
+		cookies := login("tom")
+		So(cookies[0].Name, ShouldEqual, "baa_session")
+		So(cookies[0].HttpOnly, ShouldBeTrue)
+		loads = 0
+		So(do("GET", "/account", "", cookies).Body.String(), ShouldEqual, "hello tom")
+		So(loads, ShouldEqual, 1)
+		So(do("GET", "/account", "", login("jerry|1")).Body.String(), ShouldEqual, "hello jerry|1")
+
+		w := do("POST", "/logout", "", cookies)
+		So(w.Result().Cookies()[0].MaxAge, ShouldBeLessThan, 0)
+		// a copied cookie is revoked by logout
+		So(do("GET", "/account", "", cookies).Code, ShouldEqual, http.StatusUnauthorized)
+
+		cookies = login("tom")
+		clock.Advance(2 * time.Hour)
+		So(do("GET", "/account", "", cookies).Code, ShouldEqual, http.StatusUnauthorized)
+	})
+
+	Convey("unauthenticated requests", t, func() {
+		w := do("GET", "/account?tab=1", "text/html", nil)
+		So(w.Code, ShouldEqual, http.StatusFound)
+		So(w.Header().Get("Location"), ShouldEqual, "/login?next=%2Faccount%3Ftab%3D1")
+		So(do("GET", "/account", "application/json", nil).Code, ShouldEqual, http.StatusUnauthorized)
+
+		cookies := login("tom")
+		cookies[0].Value += "x"
+		So(do("GET", "/account", "", cookies).Code, ShouldEqual, http.StatusUnauthorized)
+		So(do("GET", "/account", "", login("nobody")).Code, ShouldEqual, http.StatusUnauthorized)
+		So(do("GET", "/account", "", login("broken")).Code, ShouldEqual, http.StatusUnauthorized)
+	})
+}
@@ -2,13 +2,14 @@ package baa
 
 import (
 	"errors"
+	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
-
-	"github.com/gorilla/websocket"
+	"time"
 )
 
 const (
@@ -26,13 +27,55 @@ var Env string
 // Baa provlider an application
 type Baa struct {
 	debug           bool
+	pretty          *bool
 	name            string
 	di              DIer
+	diMu            sync.RWMutex
 	router          Router
 	pool            sync.Pool
 	errorHandler    ErrorHandleFunc
 	notFoundHandler HandlerFunc
 	middleware      []HandlerFunc
+	serverOptions   []ServerOption
+	connStats       *connStats
+	viewData        []ViewDataProvider
+	funcs           template.FuncMap
+	validator       Validator
+	envelope        EnvelopeFunc
+	problemDetails  bool
+	requestMetrics  bool
+	poolWarmup      int
+	poolMaxSize     int
+	warmContexts    []*Context
+	warmMu          sync.Mutex
+	groupNotFounds  []groupNotFound
+	wsOptions       WebsocketOptions
+	wsConns         *connLimiter
+	bodyLimit       int64
+	dirListing      DirListing
+	wellKnowns      map[string]RouteNode
+	locales         []string
+	streams         *streams
+	redaction       Redaction
+	signKey         []byte
+	renderers       []Renderer
+	renderFallback  *RenderFallback
+	reloaders       []func() error
+	reloadMu        sync.Mutex
+	buildInfo       BuildInfo
+	maintenance     int32
+	maintenanceFn   HandlerFunc
+	maxConnsPerIP   int
+	keepAlivesOff   bool
+	reusePort       bool
+	shutdownSigSet  bool
+	shutdownSignals []os.Signal
+	shutdownTimeout time.Duration
+	sseHeartbeat    time.Duration
+	sseBuffer       int
+	streamSlow      time.Duration
+	auth            *AuthOptions
+	sessions        *SessionOptions
 }
 
 // Middleware middleware handler
@@ -54,8 +97,16 @@ const defaultAppName = "_default_"
 func New() *Baa {
 	b := new(Baa)
 	b.middleware = make([]HandlerFunc, 0)
+	b.connStats = newConnStats()
+	b.wsConns = newConnLimiter()
+	b.streams = newStreams()
+	b.redaction = DefaultRedaction
+	b.sseHeartbeat = defaultSSEHeartbeat
 	b.pool = sync.Pool{
 		New: func() interface{} {
+			if c := b.warmContext(); c != nil {
+				return c
+			}
 			return NewContext(nil, nil, b)
 		},
 	}
@@ -66,10 +117,30 @@ func New() *Baa {
 	b.SetDI("router", NewTree(b))
 	b.SetDI("logger", log.New(os.Stderr, "[Baa] ", log.LstdFlags))
 	b.SetDI("render", newRender())
+	b.AddTemplateFunc("json", JSONScript)
+	b.AddTemplateFunc("urlfor", b.URLFor)
+	for k, v := range formTemplateFuncs {
+		b.AddTemplateFunc(k, v)
+	}
+	b.SetDI("metrics", NewMetrics())
+	b.SetDI("httpclient", NewHTTPClient(0))
+	b.SetDI("clock", SystemClock)
+	b.SetDI("idgen", RandomID(nil, 16))
+	cache := NewMemoryCache(10000)
+	cache.SetClock(clockOf(b))
+	b.SetDI("cache", cache)
+	b.Metrics().OnCollect(b.collectConnStats)
 	b.SetNotFound(b.DefaultNotFoundHandler)
 	return b
 }
 
+// Classic create a baa application with the Recovery middleware
+func Classic() *Baa {
+	b := New()
+	b.Use(Recovery())
+	return b
+}
+
 // Instance register or returns named application
 func Instance(name string) *Baa {
 	if name == "" {
@@ -88,9 +159,9 @@ func Default() *Baa {
 }
 
 // Server returns the internal *http.Server.
-func (b *Baa) Server(addr string) *http.Server {
-	s := &http.Server{Addr: addr}
-	return s
+// the server has a default ReadHeaderTimeout and IdleTimeout, use options to change them.
+func (b *Baa) Server(addr string, opts ...ServerOption) *http.Server {
+	return b.newServer(addr, opts)
 }
 
 // Run runs a server.
@@ -103,6 +174,15 @@ func (b *Baa) RunTLS(addr, certfile, keyfile string) {
 	b.run(b.Server(addr), certfile, keyfile)
 }
 
+// RunNetwork runs a server on network, tcp4 binds IPv4 only, tcp6 binds IPv6 only,
+// tcp binds both when the host is empty or unspecified.
+//
+// Example:
+// 		b.RunNetwork("tcp6", "[::1]:8080")
+func (b *Baa) RunNetwork(network, addr string) {
+	b.runNetwork(network, b.Server(addr))
+}
+
 // RunServer runs a custom server.
 func (b *Baa) RunServer(s *http.Server) {
 	b.run(s)
@@ -114,43 +194,164 @@ func (b *Baa) RunTLSServer(s *http.Server, crtFile, keyFile string) {
 }
 
 func (b *Baa) run(s *http.Server, files ...string) {
+	b.runNetwork("tcp", s, files...)
+}
+
+// runNetwork listens on network then serves s, it exits by logger.Fatal on errors
+func (b *Baa) runNetwork(network string, s *http.Server, files ...string) {
+	if len(files) != 0 && len(files) != 2 {
+		panic("invalid TLS configuration")
+	}
+	if err := b.start(s); err != nil {
+		b.Logger().Fatal(err)
+	}
+	addr := s.Addr
+	if addr == "" {
+		addr = ":http"
+		if len(files) == 2 {
+			addr = ":https"
+		}
+	}
+	ln, err := b.Listen(network, addr)
+	if err != nil {
+		b.Logger().Fatal(err)
+	}
+	stop := b.handleSignals()
+	b.trackServer(s)
+	err = b.serve(s, ln, files...)
+	stop()
+	if err != nil {
+		b.Logger().Fatal(err)
+	}
+}
+
+// RunAsync starts a server on addr in background and returns the bound address,
+// it's useful to serve on ephemeral port :0 in integration tests and embedded usage.
+// The serving error is delivered to errc, nil is delivered after Shutdown.
+//
+// Example:
+// 		addr, errc, err := b.RunAsync("127.0.0.1:0")
+// 		resp, _ := http.Get("http://" + addr.String() + "/")
+// 		b.Shutdown(ctx)
+// 		<-errc
+func (b *Baa) RunAsync(addr string) (bound net.Addr, errc <-chan error, err error) {
+	s := b.Server(addr)
+	if err = b.start(s); err != nil {
+		return nil, nil, err
+	}
+	ln, err := b.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	// tracked before serving so a Shutdown right after RunAsync returns stops it
+	b.trackServer(s)
+	ch := make(chan error, 1)
+	go func() {
+		ch <- b.serve(s, ln)
+	}()
+	return ln.Addr(), ch, nil
+}
+
+// Serve serves on listener ln by a server built by b.Server, it returns
+// nil after Shutdown.
+func (b *Baa) Serve(ln net.Listener) error {
+	s := b.Server(ln.Addr().String())
+	if err := b.start(s); err != nil {
+		return err
+	}
+	b.trackServer(s)
+	return b.serve(s, ln)
+}
+
+// start prepares the application for serving s
+func (b *Baa) start(s *http.Server) error {
 	s.Handler = b
+	if err := b.ValidateDI(); err != nil {
+		return err
+	}
+	b.warmupPool()
+	b.logBuildInfo()
 	b.Logger().Printf("Run mode: %s", Env)
-	if len(files) == 0 {
-		b.Logger().Printf("Listen %s", s.Addr)
-		b.Logger().Fatal(s.ListenAndServe())
-	} else if len(files) == 2 {
-		b.Logger().Printf("Listen %s with TLS", s.Addr)
-		b.Logger().Fatal(s.ListenAndServeTLS(files[0], files[1]))
+	return nil
+}
+
+// serve serves s on ln with TLS when cert and key files are given, returns nil after Shutdown,
+// s must be tracked by trackServer before.
+func (b *Baa) serve(s *http.Server, ln net.Listener, files ...string) error {
+	var err error
+	if len(files) == 2 {
+		err = s.ServeTLS(ln, files[0], files[1])
 	} else {
-		panic("invalid TLS configuration")
+		err = s.Serve(ln)
+	}
+	// closed by Shutdown
+	if err == http.ErrServerClosed {
+		return nil
 	}
+	return err
 }
 
 func (b *Baa) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var start time.Time
+	if b.requestMetrics {
+		start = time.Now()
+	}
 	c := b.pool.Get().(*Context)
 	c.Reset(w, r)
 
 	// build handler chain
 	path := strings.Replace(r.URL.Path, "//", "/", -1)
+	if len(b.locales) > 0 {
+		path, c.locale = b.stripLocale(path)
+	}
+	c.path = path
 	h, name := b.Router().Match(r.Method, path, c)
 	c.routeName = name
 
 	// notFound
 	if h == nil {
-		c.handlers = append(c.handlers, b.notFoundHandler)
+		c.handlers = append(c.handlers, b.notFoundOf(path))
+	} else if mh := b.maintenanceOf(c); mh != nil {
+		c.handlers = append(c.handlers, mh)
 	} else {
 		c.handlers = append(c.handlers, h...)
 	}
 
+	b.dispatch(c)
+
+	if b.requestMetrics {
+		b.recordRequest(c, start)
+	}
+
+	b.releaseContext(c)
+}
+
+// dispatch executes handler chain, panics are recovered as PanicError,
+// cleanup callbacks registered by c.OnClose are executed at last.
+func (b *Baa) dispatch(c *Context) {
+	defer c.close()
+	defer b.recoverPanic(c)
 	c.Next()
+}
 
-	b.pool.Put(c)
+// SetBodyLimit sets max bytes of request body read by c.Body and c.DecodeStream,
+// reading more returns an error, 0 means no limit.
+func (b *Baa) SetBodyLimit(n int64) {
+	b.bodyLimit = n
 }
 
 // SetDIer set baa di
 func (b *Baa) SetDIer(v DIer) {
+	b.diMu.Lock()
 	b.di = v
+	b.diMu.Unlock()
+}
+
+// dier returns current DIer
+func (b *Baa) dier() DIer {
+	b.diMu.RLock()
+	defer b.diMu.RUnlock()
+	return b.di
 }
 
 // SetDebug set baa debug
@@ -163,6 +364,20 @@ func (b *Baa) Debug() bool {
 	return b.debug
 }
 
+// SetPrettyPrint set whether JSON and XML responses are indented,
+// by default they are indented in debug mode.
+func (b *Baa) SetPrettyPrint(v bool) {
+	b.pretty = &v
+}
+
+// prettyPrint returns whether JSON and XML responses are indented
+func (b *Baa) prettyPrint() bool {
+	if b.pretty != nil {
+		return *b.pretty
+	}
+	return b.debug
+}
+
 // Logger return baa logger
 func (b *Baa) Logger() Logger {
 	return b.GetDI("logger").(Logger)
@@ -190,6 +405,91 @@ func (b *Baa) Use(m ...Middleware) {
 	}
 }
 
+// UseIf registers middlewares only executed when pred returns true
+func (b *Baa) UseIf(pred func(*Context) bool, m ...Middleware) {
+	if pred == nil {
+		panic("baa.UseIf predicate can not be nil")
+	}
+	for i := range m {
+		if m[i] == nil {
+			continue
+		}
+		h := wrapMiddleware(m[i])
+		b.middleware = append(b.middleware, func(c *Context) {
+			if pred(c) {
+				h(c)
+				return
+			}
+			c.Next()
+		})
+	}
+}
+
+// UseFor registers middlewares only executed when request matches the pattern.
+// pattern is a path glob, * matches any characters, ? matches one character,
+// it can be prefixed by comma separated methods. The glob is matched against
+// the path used by router, duplicate slashes are collapsed and locale prefix is removed.
+//
+// Example:
+// 		baa.UseFor("/admin/*", auth)
+// 		baa.UseFor("POST,PUT /api/*", csrf)
+func (b *Baa) UseFor(pattern string, m ...Middleware) {
+	b.UseIf(matchRequest(pattern), m...)
+}
+
+// matchRequest returns a predicate checks request method and path by pattern
+func matchRequest(pattern string) func(*Context) bool {
+	pattern = strings.TrimSpace(pattern)
+	var methods map[string]bool
+	if i := strings.IndexByte(pattern, ' '); i > 0 {
+		methods = make(map[string]bool)
+		for _, m := range strings.Split(pattern[:i], ",") {
+			methods[strings.ToUpper(strings.TrimSpace(m))] = true
+		}
+		pattern = strings.TrimSpace(pattern[i+1:])
+	}
+	if pattern == "" {
+		panic("baa.UseFor pattern can not be empty")
+	}
+	return func(c *Context) bool {
+		if methods != nil && !methods[c.Req.Method] {
+			return false
+		}
+		path := c.path
+		if path == "" {
+			path = c.Req.URL.Path
+		}
+		return matchGlob(pattern, path)
+	}
+}
+
+// matchGlob reports whether s matches the glob pattern,
+// * matches any sequence of characters include /, ? matches any single character
+func matchGlob(pattern, s string) bool {
+	var p, i int
+	star, mark := -1, 0
+	for i < len(s) {
+		if p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]) {
+			p++
+			i++
+		} else if p < len(pattern) && pattern[p] == '*' {
+			star = p
+			mark = i
+			p++
+		} else if star >= 0 {
+			p = star + 1
+			mark++
+			i = mark
+		} else {
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
 // SetDI registers a dependency injection
 func (b *Baa) SetDI(name string, h interface{}) {
 	switch name {
@@ -201,17 +501,82 @@ func (b *Baa) SetDI(name string, h interface{}) {
 		if _, ok := h.(Renderer); !ok {
 			panic("DI render must be implement interface baa.Renderer")
 		}
+		if r, ok := h.(funcsRenderer); ok && len(b.funcs) > 0 {
+			r.Funcs(b.funcs)
+		}
 	case "router":
 		if _, ok := h.(Router); !ok {
 			panic("DI router must be implement interface baa.Router")
 		}
+	case "metrics":
+		if _, ok := h.(*Metrics); !ok {
+			panic("DI metrics must be a *baa.Metrics")
+		}
+	case "cache":
+		if _, ok := h.(Cache); !ok {
+			panic("DI cache must be implement interface baa.Cache")
+		}
+	case "clock":
+		if _, ok := h.(Clock); !ok {
+			panic("DI clock must be implement interface baa.Clock")
+		}
+	case "idgen":
+		if _, ok := h.(IDGenerator); !ok {
+			panic("DI idgen must be implement interface baa.IDGenerator")
+		}
+	case "httpclient":
+		if _, ok := h.(*HTTPClient); !ok {
+			panic("DI httpclient must be a *baa.HTTPClient")
+		}
 	}
-	b.di.Set(name, h)
+	b.dier().Set(name, h)
 }
 
 // GetDI fetch a registered dependency injection
 func (b *Baa) GetDI(name string) interface{} {
-	return b.di.Get(name)
+	return b.dier().Get(name)
+}
+
+// OverrideDI replaces the DIer with a child inherits it then sets name to v in the child,
+// the returned func restores the DIer. The overridden DIer is never mutated, so tests can
+// swap implementations safely. Values set before restore are discarded by restore.
+//
+// Example:
+// 		defer b.OverrideDI("cache", baa.NewMemoryCache(10))()
+func (b *Baa) OverrideDI(name string, v interface{}) (restore func()) {
+	b.diMu.Lock()
+	prev := b.di
+	b.di = NewChildDI(prev)
+	b.diMu.Unlock()
+	defer func() {
+		if e := recover(); e != nil {
+			b.SetDIer(prev)
+			panic(e)
+		}
+	}()
+	b.SetDI(name, v)
+	return func() {
+		b.SetDIer(prev)
+	}
+}
+
+// ProvideDI registers a lazy constructor of dependency name depends on deps,
+// the DIer must implement DIProvider, such as the default DI.
+func (b *Baa) ProvideDI(name string, fn func(DIer) (interface{}, error), deps ...string) {
+	p, ok := b.dier().(DIProvider)
+	if !ok {
+		panic("baa.ProvideDI DIer does not implement interface baa.DIProvider")
+	}
+	p.Provide(name, fn, deps...)
+}
+
+// ValidateDI validates declared dependencies when the DIer implements DIProvider,
+// it's called before the server starts.
+func (b *Baa) ValidateDI() error {
+	if p, ok := b.dier().(DIProvider); ok {
+		return p.Validate()
+	}
+	return nil
 }
 
 // Static set static file route
@@ -227,7 +592,7 @@ func (b *Baa) Static(prefix string, dir string, index bool, h HandlerFunc) {
 }
 
 // StaticFile shortcut for serve file
-func (b *Baa) StaticFile(pattern string, path string) RouteNode {
+func (b *Baa) StaticFile(pattern string, path string) MetaRouteNode {
 	return b.Get(pattern, func(c *Context) {
 		if err := serveFile(path, c); err != nil {
 			c.Error(err)
@@ -250,8 +615,8 @@ func (b *Baa) SetAutoTrailingSlash(v bool) {
 //
 // Example:
 // 		baa.Route("/", "GET,POST", h)
-func (b *Baa) Route(pattern, methods string, h ...HandlerFunc) RouteNode {
-	var ru RouteNode
+func (b *Baa) Route(pattern, methods string, h ...HandlerFunc) MetaRouteNode {
+	var ru routeNodes
 	var ms []string
 	if methods == "*" {
 		for m := range RouterMethods {
@@ -261,7 +626,7 @@ func (b *Baa) Route(pattern, methods string, h ...HandlerFunc) RouteNode {
 		ms = strings.Split(methods, ",")
 	}
 	for _, m := range ms {
-		ru = b.Router().Add(strings.TrimSpace(m), pattern, h)
+		ru = append(ru, b.Router().Add(strings.TrimSpace(m), pattern, h))
 	}
 	return ru
 }
@@ -272,68 +637,47 @@ func (b *Baa) Group(pattern string, f func(), h ...HandlerFunc) {
 }
 
 // Any is a shortcut for b.Router().handle("*", pattern, handlers)
-func (b *Baa) Any(pattern string, h ...HandlerFunc) RouteNode {
-	var ru RouteNode
+func (b *Baa) Any(pattern string, h ...HandlerFunc) MetaRouteNode {
+	var ru routeNodes
 	for m := range RouterMethods {
-		ru = b.Router().Add(m, pattern, h)
+		ru = append(ru, b.Router().Add(m, pattern, h))
 	}
 	return ru
 }
 
 // Delete is a shortcut for b.Route(pattern, "DELETE", handlers)
-func (b *Baa) Delete(pattern string, h ...HandlerFunc) RouteNode {
-	return b.Router().Add("DELETE", pattern, h)
+func (b *Baa) Delete(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return metaNode(b.Router().Add("DELETE", pattern, h))
 }
 
 // Get is a shortcut for b.Route(pattern, "GET", handlers)
-func (b *Baa) Get(pattern string, h ...HandlerFunc) RouteNode {
-	return b.Router().Add("GET", pattern, h)
+func (b *Baa) Get(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return metaNode(b.Router().Add("GET", pattern, h))
 }
 
 // Head is a shortcut forb.Route(pattern, "Head", handlers)
-func (b *Baa) Head(pattern string, h ...HandlerFunc) RouteNode {
-	return b.Router().Add("HEAD", pattern, h)
+func (b *Baa) Head(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return metaNode(b.Router().Add("HEAD", pattern, h))
 }
 
 // Options is a shortcut for b.Route(pattern, "Options", handlers)
-func (b *Baa) Options(pattern string, h ...HandlerFunc) RouteNode {
-	return b.Router().Add("OPTIONS", pattern, h)
+func (b *Baa) Options(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return metaNode(b.Router().Add("OPTIONS", pattern, h))
 }
 
 // Patch is a shortcut for b.Route(pattern, "PATCH", handlers)
-func (b *Baa) Patch(pattern string, h ...HandlerFunc) RouteNode {
-	return b.Router().Add("PATCH", pattern, h)
+func (b *Baa) Patch(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return metaNode(b.Router().Add("PATCH", pattern, h))
 }
 
 // Post is a shortcut for b.Route(pattern, "POST", handlers)
-func (b *Baa) Post(pattern string, h ...HandlerFunc) RouteNode {
-	return b.Router().Add("POST", pattern, h)
+func (b *Baa) Post(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return metaNode(b.Router().Add("POST", pattern, h))
 }
 
 // Put is a shortcut for b.Route(pattern, "Put", handlers)
-func (b *Baa) Put(pattern string, h ...HandlerFunc) RouteNode {
-	return b.Router().Add("PUT", pattern, h)
-}
-
-// Websocket register a websocket router handler
-func (b *Baa) Websocket(pattern string, h func(*websocket.Conn)) RouteNode {
-	var upgrader = websocket.Upgrader{
-		ReadBufferSize:    4096,
-		WriteBufferSize:   4096,
-		EnableCompression: true,
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
-	}
-
-	return b.Route(pattern, "GET,POST", func(c *Context) {
-		conn, err := upgrader.Upgrade(c.Resp, c.Req, nil)
-		if err != nil {
-			b.Logger().Printf("websocket upgrade connection error: %v", err)
-			return
-		}
-		h(conn)
-	})
+func (b *Baa) Put(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return metaNode(b.Router().Add("PUT", pattern, h))
 }
 
 // SetNotFound set not found route handler
@@ -343,8 +687,8 @@ func (b *Baa) SetNotFound(h HandlerFunc) {
 
 // NotFound execute not found handler
 func (b *Baa) NotFound(c *Context) {
-	if b.notFoundHandler != nil {
-		b.notFoundHandler(c)
+	if h := b.notFoundOf(c.Req.URL.Path); h != nil {
+		h(c)
 		return
 	}
 	http.NotFound(c.Resp, c.Req)
@@ -360,22 +704,42 @@ func (b *Baa) Error(err error, c *Context) {
 	if err == nil {
 		err = errors.New("Internal Server Error")
 	}
+	b.classifyError(err, c)
 	if b.errorHandler != nil {
 		b.errorHandler(err, c)
 		return
 	}
-	code := http.StatusInternalServerError
+	code := errorStatus(err)
+	if pe, ok := err.(*PanicError); ok {
+		b.Logger().Printf("%s\n%s", pe, pe.Stack)
+	} else {
+		b.Logger().Println(err)
+	}
+	if _, ok := err.(*Problem); ok || b.problemDetails {
+		c.Problem(problemOf(err, code, b.debug))
+		return
+	}
 	msg := http.StatusText(code)
-	if b.debug {
+	switch e := err.(type) {
+	case *HTTPError:
+		msg = e.clientMessage(b.debug)
+	case *ValidationError:
 		msg = err.Error()
+	default:
+		if b.debug {
+			msg = err.Error()
+		}
 	}
-	b.Logger().Println(err)
 	http.Error(c.Resp, msg, code)
 }
 
 // DefaultNotFoundHandler invokes the default HTTP error handler.
 func (b *Baa) DefaultNotFoundHandler(c *Context) {
 	code := http.StatusNotFound
+	if b.problemDetails {
+		c.Problem(NewProblem(code, ""))
+		return
+	}
 	msg := http.StatusText(code)
 	http.Error(c.Resp, msg, code)
 }
@@ -400,6 +764,8 @@ func wrapMiddleware(m Middleware) HandlerFunc {
 		return WrapHandlerFunc(func(c *Context) {
 			m(c.Resp, c.Req)
 		})
+	case func(*Context) error, func(*Context) (interface{}, error):
+		return WrapHandlerFunc(WrapHandler(m))
 	default:
 		panic("unknown middleware")
 	}
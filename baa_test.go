@@ -169,9 +169,149 @@ func TestServeHTTP1(t *testing.T) {
 	})
 }
 
+func TestUseIf1(t *testing.T) {
+	Convey("conditional middleware", t, func() {
+		b2 := New()
+		b2.UseIf(func(c *Context) bool {
+			return c.Req.Header.Get("X-Debug") != ""
+		}, func(c *Context) {
+			c.Resp.Header().Set("X-If", "true")
+			c.Next()
+		})
+		b2.UseFor("/admin/*", func(c *Context) {
+			c.Resp.Header().Set("X-Admin", "true")
+			c.Next()
+		})
+		b2.UseFor("POST,PUT /api/*", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Api", "true")
+		})
+		b2.Any("/*", func(c *Context) {
+			c.String(200, "ok")
+		})
+
+		req, _ := http.NewRequest("GET", "/admin/users/1", nil)
+		req.Header.Set("X-Debug", "1")
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("X-If"), ShouldEqual, "true")
+		So(w.Header().Get("X-Admin"), ShouldEqual, "true")
+		So(w.Header().Get("X-Api"), ShouldEqual, "")
+
+		req, _ = http.NewRequest("POST", "/api/users", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "ok")
+		So(w.Header().Get("X-If"), ShouldEqual, "")
+		So(w.Header().Get("X-Admin"), ShouldEqual, "")
+		So(w.Header().Get("X-Api"), ShouldEqual, "true")
+
+		req, _ = http.NewRequest("GET", "/api/users", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Header().Get("X-Api"), ShouldEqual, "")
+	})
+	Convey("conditional middleware matches path of router", t, func() {
+		b2 := New()
+		b2.SetLocales("en", "fr")
+		var admin bool
+		b2.UseFor("/admin/*", func(c *Context) {
+			admin = true
+			c.Next()
+		})
+		b2.Any("/*", func(c *Context) {
+			c.String(200, "ok")
+		})
+		for _, uri := range []string{"/admin/users", "//admin/users", "/admin//users", "/fr/admin/users"} {
+			admin = false
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.URL.Path = uri
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(admin, ShouldBeTrue)
+		}
+		admin = false
+		serveTo(b2, "/users/admin/1")
+		So(admin, ShouldBeFalse)
+	})
+	Convey("match glob", t, func() {
+		So(matchGlob("/static/*.js", "/static/js/app.js"), ShouldBeTrue)
+		So(matchGlob("/user/?", "/user/1"), ShouldBeTrue)
+		So(matchGlob("/user/?", "/user/12"), ShouldBeFalse)
+		So(matchGlob("/api", "/api/"), ShouldBeFalse)
+		So(matchGlob("*", ""), ShouldBeTrue)
+	})
+	Convey("invalid conditional middleware", t, func() {
+		So(func() { New().UseIf(nil, f) }, ShouldPanic)
+		So(func() { New().UseFor(" ", f) }, ShouldPanic)
+	})
+}
+
 func request(method, uri string) *httptest.ResponseRecorder {
 	req, _ := http.NewRequest(method, uri, nil)
 	w := httptest.NewRecorder()
 	b.ServeHTTP(w, req)
 	return w
 }
+
+func TestServeHTTPPanic1(t *testing.T) {
+	Convey("recover panic as error", t, func() {
+		b2 := New()
+		b2.SetDebug(false)
+		var perr *PanicError
+		b2.SetError(func(err error, c *Context) {
+			perr, _ = err.(*PanicError)
+			c.String(500, "recovered")
+		})
+		b2.Use(func(c *Context) {
+			c.Next()
+		})
+		b2.Get("/panic/:id", func(c *Context) {
+			panic("boom")
+		})
+		b2.Get("/panic-wrote", func(c *Context) {
+			c.String(200, "ok")
+			panic("boom")
+		})
+
+		req, _ := http.NewRequest("GET", "/panic/1?a=b", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(w.Body.String(), ShouldEqual, "recovered")
+		So(perr, ShouldNotBeNil)
+		So(perr.Value, ShouldEqual, "boom")
+		So(perr.Method, ShouldEqual, "GET")
+		So(perr.URL, ShouldEqual, "/panic/1?a=b")
+		So(perr.Route, ShouldEqual, "/panic/:id")
+		So(string(perr.Stack), ShouldContainSubstring, "goroutine")
+		So(perr.Error(), ShouldEqual, "panic: boom [GET /panic/1?a=b]")
+		So(perr.Unwrap(), ShouldBeNil)
+
+		perr = nil
+		req, _ = http.NewRequest("GET", "/panic-wrote", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "ok")
+		So(perr, ShouldBeNil)
+
+		b3 := New()
+		b3.SetDebug(false)
+		b3.Get("/panic", func(c *Context) {
+			panic(fmt.Errorf("failed"))
+		})
+		req, _ = http.NewRequest("GET", "/panic", nil)
+		w = httptest.NewRecorder()
+		b3.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(w.Body.String(), ShouldEqual, "Internal Server Error\n")
+
+		b3.Get("/abort", func(c *Context) {
+			panic(http.ErrAbortHandler)
+		})
+		req, _ = http.NewRequest("GET", "/abort", nil)
+		So(func() { b3.ServeHTTP(httptest.NewRecorder(), req) }, ShouldPanic)
+	})
+}
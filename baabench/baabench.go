@@ -0,0 +1,223 @@
+// Package baabench replays synthetic requests against a baa application in process,
+// it reports latency percentiles and allocations per route to guide optimization
+// without network and external load tools.
+package baabench
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-baa/baa"
+)
+
+// Request is a synthetic request
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Options benchmark config
+type Options struct {
+	// Requests to replay, requests are generated from registered GET routes when empty,
+	// params are filled with "1" and wildcards with "x".
+	Requests []Request
+	// N is the number of times each request replays, default 1000
+	N int
+	// Warmup is the number of times each request replays before measuring, default 10
+	Warmup int
+}
+
+// Result is the benchmark result of a request
+type Result struct {
+	Method      string
+	Path        string
+	Route       string
+	N           int
+	P50         time.Duration
+	P99         time.Duration
+	Mean        time.Duration
+	AllocsPerOp uint64
+	BytesPerOp  uint64
+	// Status counts responses by status code
+	Status map[int]int
+}
+
+// Report is the benchmark results, sorted by P99 descending
+type Report []Result
+
+// Run replays requests against b and returns the report
+//
+// Example:
+// 		report := baabench.Run(app, baabench.Options{N: 10000})
+// 		report.WriteTo(os.Stdout)
+func Run(b *baa.Baa, opt Options) Report {
+	if opt.N <= 0 {
+		opt.N = 1000
+	}
+	if opt.Warmup <= 0 {
+		opt.Warmup = 10
+	}
+	if len(opt.Requests) == 0 {
+		opt.Requests = Requests(b)
+	}
+	report := make(Report, 0, len(opt.Requests))
+	for _, req := range opt.Requests {
+		report = append(report, bench(b, req, opt))
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].P99 > report[j].P99
+	})
+	return report
+}
+
+// Requests generates a request for each registered GET route of b
+func Requests(b *baa.Baa) []Request {
+	var reqs []Request
+	for _, route := range b.Router().Routes()["GET"] {
+		reqs = append(reqs, Request{Method: "GET", Path: SamplePath(route)})
+	}
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].Path < reqs[j].Path
+	})
+	return reqs
+}
+
+// SamplePath fills params of a route pattern, eg: /users/:id/* -> /users/1/x
+func SamplePath(pattern string) string {
+	segs := strings.Split(pattern, "/")
+	for i, s := range segs {
+		switch {
+		case strings.HasPrefix(s, ":"):
+			segs[i] = "1"
+		case strings.HasPrefix(s, "*"):
+			segs[i] = "x"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// bench replays req for opt.N times
+func bench(b *baa.Baa, req Request, opt Options) Result {
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	r := Result{
+		Method: req.Method,
+		Path:   req.Path,
+		N:      opt.N,
+		Status: make(map[int]int),
+	}
+	for i := 0; i < opt.Warmup; i++ {
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, newRequest(req))
+	}
+	r.Route = route(b, req)
+
+	reqs := make([]*http.Request, opt.N)
+	recorders := make([]*httptest.ResponseRecorder, opt.N)
+	for i := range reqs {
+		reqs[i] = newRequest(req)
+		recorders[i] = httptest.NewRecorder()
+	}
+	durations := make([]time.Duration, opt.N)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for i := range reqs {
+		start := time.Now()
+		b.ServeHTTP(recorders[i], reqs[i])
+		durations[i] = time.Since(start)
+	}
+	runtime.ReadMemStats(&after)
+
+	r.AllocsPerOp = (after.Mallocs - before.Mallocs) / uint64(opt.N)
+	r.BytesPerOp = (after.TotalAlloc - before.TotalAlloc) / uint64(opt.N)
+	var total time.Duration
+	for i, d := range durations {
+		total += d
+		r.Status[recorders[i].Code]++
+	}
+	r.Mean = total / time.Duration(opt.N)
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	r.P50 = percentile(durations, 50)
+	r.P99 = percentile(durations, 99)
+	return r
+}
+
+// route returns the matched route pattern of req
+func route(b *baa.Baa, req Request) string {
+	r := newRequest(req)
+	c := baa.NewContext(httptest.NewRecorder(), r, b)
+	if h, _ := b.Router().Match(req.Method, r.URL.Path, c); h == nil {
+		return ""
+	}
+	return c.RoutePattern()
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// newRequest create a http request of req
+func newRequest(req Request) *http.Request {
+	var body io.Reader
+	if req.Body != nil {
+		body = bytes.NewReader(req.Body)
+	}
+	r := httptest.NewRequest(req.Method, req.Path, body)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	return r
+}
+
+// WriteTo writes report as a table
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	buf := new(bytes.Buffer)
+	tw := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tROUTE\tN\tP50\tP99\tMEAN\tALLOCS/OP\tBYTES/OP\tSTATUS")
+	for _, v := range r {
+		codes := make([]int, 0, len(v.Status))
+		for code := range v.Status {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		status := make([]string, len(codes))
+		for i, code := range codes {
+			status[i] = fmt.Sprintf("%d:%d", code, v.Status[code])
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			v.Method, v.Path, v.Route, v.N, v.P50, v.P99, v.Mean,
+			v.AllocsPerOp, v.BytesPerOp, strings.Join(status, ","))
+	}
+	tw.Flush()
+	return buf.WriteTo(w)
+}
+
+// String returns report as a table
+func (r Report) String() string {
+	buf := new(bytes.Buffer)
+	r.WriteTo(buf)
+	return buf.String()
+}
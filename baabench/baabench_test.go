@@ -0,0 +1,71 @@
+package baabench
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp() *baa.Baa {
+	app := baa.New()
+	app.Get("/", func(c *baa.Context) {
+		c.String(http.StatusOK, "index")
+	})
+	app.Get("/users/:id", func(c *baa.Context) {
+		c.JSON(http.StatusOK, map[string]string{"id": c.Param("id")})
+	})
+	app.Get("/files/*", func(c *baa.Context) {
+		c.String(http.StatusOK, c.Param(""))
+	})
+	app.Post("/users", func(c *baa.Context) {
+		c.String(http.StatusCreated, c.Req.Header.Get("X-Name"))
+	})
+	return app
+}
+
+func TestBaabench1(t *testing.T) {
+	Convey("sample path", t, func() {
+		So(SamplePath("/users/:id/*"), ShouldEqual, "/users/1/x")
+		So(SamplePath("/"), ShouldEqual, "/")
+	})
+	Convey("run generated requests", t, func() {
+		app := newApp()
+		reqs := Requests(app)
+		So(reqs, ShouldHaveLength, 3)
+		So(Requests(app), ShouldResemble, reqs)
+		report := Run(app, Options{N: 50})
+		So(report, ShouldHaveLength, 3)
+		routes := make(map[string]Result)
+		for _, v := range report {
+			routes[v.Route] = v
+			So(v.N, ShouldEqual, 50)
+			So(v.Status[http.StatusOK], ShouldEqual, 50)
+			So(v.P50, ShouldBeGreaterThan, 0)
+			So(v.P99, ShouldBeGreaterThanOrEqualTo, v.P50)
+			So(v.AllocsPerOp, ShouldBeGreaterThan, 0)
+		}
+		So(routes["/users/:id"].Path, ShouldEqual, "/users/1")
+		So(report.String(), ShouldContainSubstring, "/users/:id")
+	})
+	Convey("run custom requests", t, func() {
+		app := newApp()
+		report := Run(app, Options{N: 10, Requests: []Request{
+			{Method: "POST", Path: "/users", Header: http.Header{"X-Name": []string{"baa"}}},
+			{Path: "/missing"},
+		}})
+		So(report, ShouldHaveLength, 2)
+		for _, v := range report {
+			if v.Method == "POST" {
+				So(v.Route, ShouldEqual, "/users")
+				So(v.Status[http.StatusCreated], ShouldEqual, 10)
+			} else {
+				So(v.Route, ShouldEqual, "")
+				So(v.Status[http.StatusNotFound], ShouldEqual, 10)
+			}
+		}
+		So(strings.Count(report.String(), "\n"), ShouldEqual, 3)
+	})
+}
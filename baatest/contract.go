@@ -0,0 +1,309 @@
+package baatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-baa/baa"
+)
+
+// Contract verifies responses conform to an OpenAPI 3 document, it supports
+// a subset of JSON schema: type, nullable, enum, properties, required,
+// additionalProperties, items and local $ref.
+type Contract struct {
+	doc openAPI
+}
+
+// openAPI is the part of OpenAPI 3 document used by contract testing
+type openAPI struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// operation is an OpenAPI operation
+type operation struct {
+	Parameters []struct {
+		Name    string      `json:"name"`
+		In      string      `json:"in"`
+		Example interface{} `json:"example"`
+	} `json:"parameters"`
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema *Schema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+// Schema is a JSON schema
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []interface{}      `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+}
+
+// NewContract create a contract from an OpenAPI 3 document in JSON
+func NewContract(spec []byte) (*Contract, error) {
+	c := new(Contract)
+	if err := json.Unmarshal(spec, &c.doc); err != nil {
+		return nil, fmt.Errorf("baatest: parse OpenAPI document: %v", err)
+	}
+	return c, nil
+}
+
+// LoadContract create a contract from an OpenAPI 3 document file in JSON
+func LoadContract(file string) (*Contract, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewContract(data)
+}
+
+// Check serves req by h and checks the response is documented and its JSON body
+// conforms to the documented schema.
+func (c *Contract) Check(h http.Handler, req *http.Request) error {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return c.CheckResponse(req.Method, req.URL.Path, w)
+}
+
+// CheckResponse checks response w of method and path is documented and its
+// JSON body conforms to the documented schema.
+func (c *Contract) CheckResponse(method, path string, w *httptest.ResponseRecorder) error {
+	op := c.operation(method, path)
+	if op == nil {
+		return fmt.Errorf("%s %s: operation is not documented", method, path)
+	}
+	status := strconv.Itoa(w.Code)
+	resp, ok := op.Responses[status]
+	if !ok {
+		resp, ok = op.Responses[status[:1]+"XX"]
+	}
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not documented", method, path, w.Code)
+	}
+	if !isJSON(w.Header().Get("Content-Type")) {
+		return nil
+	}
+	var schema *Schema
+	for contentType, v := range resp.Content {
+		if isJSON(contentType) {
+			schema = v.Schema
+		}
+	}
+	if schema == nil {
+		return nil
+	}
+	var body interface{}
+	dec := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return fmt.Errorf("%s %s: invalid JSON body: %v", method, path, err)
+	}
+	var errs []string
+	c.validate(schema, body, "", &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("%s %s: status %d body does not conform to schema:\n\t%s",
+			method, path, w.Code, strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
+
+// Verify requests registered GET routes of b with params filled by parameter examples
+// or "1", it fails t when a route is not documented or a response drifts from its contract.
+func (c *Contract) Verify(t testing.TB, b *baa.Baa) {
+	t.Helper()
+	routes := b.Router().Routes()["GET"]
+	sort.Strings(routes)
+	for _, route := range routes {
+		template := openAPIPath(route)
+		op := c.doc.Paths[template]["get"]
+		if op == nil {
+			t.Errorf("GET %s: route is not documented", route)
+			continue
+		}
+		examples := make(map[string]string)
+		for _, p := range op.Parameters {
+			if p.In == "path" && p.Example != nil {
+				examples[p.Name] = fmt.Sprint(p.Example)
+			}
+		}
+		path := fillPath(template, examples)
+		if err := c.Check(b, httptest.NewRequest("GET", path, nil)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// operation finds the operation matches method and path
+func (c *Contract) operation(method, path string) *operation {
+	method = strings.ToLower(method)
+	if item, ok := c.doc.Paths[path]; ok && item[method] != nil {
+		return item[method]
+	}
+	segs := strings.Split(path, "/")
+	for template, item := range c.doc.Paths {
+		if item[method] == nil {
+			continue
+		}
+		tsegs := strings.Split(template, "/")
+		if len(tsegs) != len(segs) {
+			continue
+		}
+		matched := true
+		for i := range tsegs {
+			if tsegs[i] != segs[i] && !strings.HasPrefix(tsegs[i], "{") {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return item[method]
+		}
+	}
+	return nil
+}
+
+// validate validates v by schema s, errors are appended with JSON pointer of v
+func (c *Contract) validate(s *Schema, v interface{}, pointer string, errs *[]string) {
+	s = c.resolve(s)
+	if s == nil {
+		return
+	}
+	at := pointer
+	if at == "" {
+		at = "/"
+	}
+	if v == nil {
+		if !s.Nullable && s.Type != "" {
+			*errs = append(*errs, fmt.Sprintf("%s: null is not %s", at, s.Type))
+		}
+		return
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*errs = append(*errs, fmt.Sprintf("%s: %v is not one of %v", at, v, s.Enum))
+		}
+	}
+	switch s.Type {
+	case "object":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: %s is not object", at, jsonType(v)))
+			return
+		}
+		for _, k := range s.Required {
+			if _, ok := m[k]; !ok {
+				*errs = append(*errs, fmt.Sprintf("%s: missing required property %q", at, k))
+			}
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := s.Properties[k]; ok {
+				c.validate(ps, m[k], pointer+"/"+k, errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*errs = append(*errs, fmt.Sprintf("%s: unexpected property %q", at, k))
+			}
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: %s is not array", at, jsonType(v)))
+			return
+		}
+		for i := range a {
+			c.validate(s.Items, a[i], pointer+"/"+strconv.Itoa(i), errs)
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: %s is not integer", at, jsonType(v)))
+		} else if _, err := n.Int64(); err != nil {
+			*errs = append(*errs, fmt.Sprintf("%s: %s is not integer", at, n))
+		}
+	case "number", "string", "boolean":
+		if t := jsonType(v); t != s.Type {
+			*errs = append(*errs, fmt.Sprintf("%s: %s is not %s", at, t, s.Type))
+		}
+	}
+}
+
+// resolve resolves local $ref of schema
+func (c *Contract) resolve(s *Schema) *Schema {
+	for i := 0; s != nil && s.Ref != "" && i < 32; i++ {
+		s = c.doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// jsonType returns JSON type name of decoded value
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// openAPIPath converts a route pattern to OpenAPI path template, eg: /users/:id -> /users/{id}
+func openAPIPath(pattern string) string {
+	segs := strings.Split(pattern, "/")
+	for i, s := range segs {
+		if strings.HasPrefix(s, ":") {
+			segs[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// fillPath fills params of path template by examples or "1"
+func fillPath(template string, examples map[string]string) string {
+	segs := strings.Split(template, "/")
+	for i, s := range segs {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			if v, ok := examples[s[1:len(s)-1]]; ok {
+				segs[i] = v
+			} else {
+				segs[i] = "1"
+			}
+		}
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,67 @@
+package baatest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newContractApp(user func(c *baa.Context)) *baa.Baa {
+	app := baa.New()
+	app.Get("/users", func(c *baa.Context) {
+		c.JSON(http.StatusOK, []map[string]interface{}{
+			{"id": 1, "name": "baa", "role": "admin", "email": nil},
+		})
+	})
+	app.Get("/users/:id", user)
+	return app
+}
+
+func TestContract1(t *testing.T) {
+	contract, err := LoadContract("_fixture/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Convey("conforming routes", t, func() {
+		app := newContractApp(func(c *baa.Context) {
+			if c.ParamInt("id") != 7 {
+				c.JSON(http.StatusNotFound, map[string]string{"message": "not found"})
+				return
+			}
+			c.JSON(http.StatusOK, map[string]interface{}{"id": 7, "name": "baa"})
+		})
+		ft := new(fakeT)
+		contract.Verify(ft, app)
+		So(ft.failed, ShouldBeFalse)
+		So(contract.Check(app, httptest.NewRequest("GET", "/users/2", nil)), ShouldBeNil)
+	})
+	Convey("drifted routes", t, func() {
+		app := newContractApp(func(c *baa.Context) {
+			c.JSON(http.StatusOK, map[string]interface{}{"id": "7", "role": "guest", "extra": true})
+		})
+		err := contract.Check(app, httptest.NewRequest("GET", "/users/7", nil))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, `/: missing required property "name"`)
+		So(err.Error(), ShouldContainSubstring, `/: unexpected property "extra"`)
+		So(err.Error(), ShouldContainSubstring, "/id: string is not integer")
+		So(err.Error(), ShouldContainSubstring, "/role: guest is not one of [admin member]")
+
+		app.Get("/posts", func(c *baa.Context) {})
+		ft := new(fakeT)
+		contract.Verify(ft, app)
+		So(ft.failed, ShouldBeTrue)
+
+		err = contract.Check(app, httptest.NewRequest("DELETE", "/users/7", nil))
+		So(err.Error(), ShouldContainSubstring, "operation is not documented")
+	})
+	Convey("undocumented status", t, func() {
+		app := newContractApp(func(c *baa.Context) {
+			c.String(http.StatusInternalServerError, "error")
+		})
+		err := contract.Check(app, httptest.NewRequest("GET", "/users/7", nil))
+		So(err.Error(), ShouldContainSubstring, "status 500 is not documented")
+	})
+}
@@ -0,0 +1,125 @@
+// Package baatest provides helpers for testing baa applications.
+package baatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update rewrites golden files with actual responses, eg: go test -update-golden
+var update = flag.Bool("update-golden", false, "update golden files of baatest")
+
+// Golden compares handler responses with golden files, a golden file records
+// the status, a subset of headers and the body of a response.
+type Golden struct {
+	// Dir is the directory of golden files, default is _fixture/golden
+	Dir string
+	// Headers are the recorded response headers, default is Content-Type
+	Headers []string
+	// Update rewrites golden files instead of comparing, it's also enabled by -update-golden
+	Update bool
+}
+
+// NewGolden create a golden comparer stores files in dir and records headers
+func NewGolden(dir string, headers ...string) *Golden {
+	return &Golden{Dir: dir, Headers: headers}
+}
+
+// Do serves a request by h and returns the recorded response
+func Do(h http.Handler, method, path string, body io.Reader) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, body))
+	return w
+}
+
+// Assert compares response w with golden file name, the file is created when
+// not exists or updating. The test fails with both contents when they differ.
+func (g *Golden) Assert(t testing.TB, name string, w *httptest.ResponseRecorder) {
+	t.Helper()
+	actual := g.format(w)
+	file := g.file(name)
+	if g.Update || *update {
+		if err := g.write(file, actual); err != nil {
+			t.Fatalf("baatest: update golden file %s: %v", file, err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		if err = g.write(file, actual); err != nil {
+			t.Fatalf("baatest: create golden file %s: %v", file, err)
+		}
+		t.Logf("baatest: created golden file %s", file)
+		return
+	}
+	if err != nil {
+		t.Fatalf("baatest: read golden file %s: %v", file, err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("baatest: response does not match golden file %s, run with -update-golden to update\n--- expected\n%s\n+++ actual\n%s",
+			file, expected, actual)
+	}
+}
+
+// file returns path of golden file name
+func (g *Golden) file(name string) string {
+	dir := g.Dir
+	if dir == "" {
+		dir = filepath.Join("_fixture", "golden")
+	}
+	return filepath.Join(dir, name+".golden")
+}
+
+// write writes golden file
+func (g *Golden) write(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// format formats response as golden file content, JSON bodies are indented
+// to get readable diffs.
+func (g *Golden) format(w *httptest.ResponseRecorder) []byte {
+	headers := g.Headers
+	if len(headers) == 0 {
+		headers = []string{"Content-Type"}
+	}
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "HTTP %d\n", w.Code)
+	for _, k := range headers {
+		for _, v := range w.Header()[http.CanonicalHeaderKey(k)] {
+			fmt.Fprintf(buf, "%s: %s\n", http.CanonicalHeaderKey(k), v)
+		}
+	}
+	buf.WriteByte('\n')
+	body := w.Body.Bytes()
+	if isJSON(w.Header().Get("Content-Type")) {
+		indented := new(bytes.Buffer)
+		if err := json.Indent(indented, body, "", "  "); err == nil {
+			body = indented.Bytes()
+		}
+	}
+	buf.Write(body)
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// isJSON checks content type is JSON
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,68 @@
+package baatest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp() *baa.Baa {
+	app := baa.New()
+	app.Get("/users/:id", func(c *baa.Context) {
+		c.Resp.Header().Set("X-Version", "1")
+		c.JSON(http.StatusOK, map[string]interface{}{"id": c.ParamInt("id"), "name": "baa"})
+	})
+	app.Get("/hello", func(c *baa.Context) {
+		c.String(http.StatusOK, "hello "+c.Query("name"))
+	})
+	return app
+}
+
+// fakeT records failures of assertions
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper()                                   {}
+func (t *fakeT) Error(args ...interface{})                 { t.failed = true }
+func (t *fakeT) Logf(format string, args ...interface{})   {}
+func (t *fakeT) Errorf(format string, args ...interface{}) { t.failed = true }
+func (t *fakeT) Fatalf(format string, args ...interface{}) { t.failed = true }
+
+func TestGolden1(t *testing.T) {
+	app := newApp()
+	Convey("compare with golden files", t, func() {
+		g := NewGolden("", "Content-Type", "X-Version")
+		g.Assert(t, "user", Do(app, "GET", "/users/1", nil))
+		g.Assert(t, "hello", Do(app, "GET", "/hello?name=baa", nil))
+
+		ft := new(fakeT)
+		g.Assert(ft, "hello", Do(app, "GET", "/hello?name=world", nil))
+		So(ft.failed, ShouldBeTrue)
+	})
+	Convey("create and update golden files", t, func() {
+		dir, err := ioutil.TempDir("", "baa-golden")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		g := NewGolden(dir)
+		file := filepath.Join(dir, "hello.golden")
+
+		ft := new(fakeT)
+		g.Assert(ft, "hello", Do(app, "GET", "/hello?name=baa", nil))
+		So(ft.failed, ShouldBeFalse)
+		data, _ := ioutil.ReadFile(file)
+		So(string(data), ShouldEqual, "HTTP 200\nContent-Type: text/plain; charset=utf-8\n\nhello baa\n")
+
+		g.Update = true
+		g.Assert(ft, "hello", Do(app, "GET", "/hello?name=world", nil))
+		So(ft.failed, ShouldBeFalse)
+		data, _ = ioutil.ReadFile(file)
+		So(string(data), ShouldEndWith, "hello world\n")
+	})
+}
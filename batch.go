@@ -0,0 +1,143 @@
+package baa
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// BatchOptions batch endpoint config
+type BatchOptions struct {
+	// MaxRequests is the maximum number of sub-requests in a batch, default 20
+	MaxRequests int
+	// Headers are copied from the batch request to every sub-request when the
+	// sub-request does not set them, default is Authorization and Cookie.
+	Headers []string
+}
+
+// BatchRequest is a sub-request of a batch
+type BatchRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent as JSON when it's not a string
+	Body interface{} `json:"body,omitempty"`
+}
+
+// BatchResponse is the response of a sub-request
+type BatchResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the decoded JSON body, or the body in string for other content types
+	Body interface{} `json:"body,omitempty"`
+}
+
+// batchContextKey marks sub-requests dispatched by a batch
+type batchContextKey struct{}
+
+// Batch returns a handler accepts a JSON array of sub-requests, dispatches each
+// through the application in order without network, and responds a JSON array of
+// responses in the same order. Sub-requests share the context of the batch request,
+// nested batches are rejected.
+//
+// Example:
+// 		b.Post("/batch", baa.Batch(baa.BatchOptions{MaxRequests: 10}))
+//
+// 		POST /batch
+// 		[{"method": "GET", "path": "/users/1"}, {"method": "POST", "path": "/posts", "body": {"title": "baa"}}]
+func Batch(opt BatchOptions) HandlerFunc {
+	if opt.MaxRequests <= 0 {
+		opt.MaxRequests = 20
+	}
+	if opt.Headers == nil {
+		opt.Headers = []string{"Authorization", "Cookie"}
+	}
+	return func(c *Context) {
+		if c.Req.Context().Value(batchContextKey{}) != nil {
+			c.Error(NewHTTPError(http.StatusBadRequest, "nested batch request"))
+			return
+		}
+		var reqs []BatchRequest
+		body, err := c.Body().Bytes()
+		if err == nil {
+			err = Unmarshal(body, &reqs)
+		}
+		if err != nil {
+			c.Error(NewHTTPError(http.StatusBadRequest, "invalid batch request").WithError(err))
+			return
+		}
+		if len(reqs) > opt.MaxRequests {
+			c.Error(NewHTTPError(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("batch request exceeds %d sub-requests", opt.MaxRequests)))
+			return
+		}
+		resps := make([]BatchResponse, len(reqs))
+		for i := range reqs {
+			resps[i] = c.dispatchBatch(reqs[i], opt.Headers)
+		}
+		c.JSON(http.StatusOK, resps)
+	}
+}
+
+// dispatchBatch dispatches a sub-request through the application
+func (c *Context) dispatchBatch(r BatchRequest, headers []string) BatchResponse {
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		return BatchResponse{Status: http.StatusBadRequest, Body: "path must begin with /"}
+	}
+	var body io.Reader
+	contentType := ""
+	switch v := r.Body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(v)
+	default:
+		data, err := Marshal(v)
+		if err != nil {
+			return BatchResponse{Status: http.StatusBadRequest, Body: err.Error()}
+		}
+		body = bytes.NewReader(data)
+		contentType = ApplicationJSONCharsetUTF8
+	}
+	req, err := http.NewRequest(r.Method, r.Path, body)
+	if err != nil {
+		return BatchResponse{Status: http.StatusBadRequest, Body: err.Error()}
+	}
+	req = req.WithContext(context.WithValue(c.Req.Context(), batchContextKey{}, true))
+	req.RemoteAddr = c.Req.RemoteAddr
+	req.Host = c.Req.Host
+	for _, k := range headers {
+		if v := c.Req.Header.Get(k); v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+
+	w := c.baa.serveBuffered(req)
+	resp := BatchResponse{Status: w.Status, Headers: make(map[string]string, len(w.Header))}
+	for k := range w.Header {
+		resp.Headers[k] = w.Header.Get(k)
+	}
+	if len(w.Body) == 0 {
+		return resp
+	}
+	mediaType, _, _ := mime.ParseMediaType(w.Header.Get("Content-Type"))
+	if mediaType == ApplicationJSON || strings.HasSuffix(mediaType, "+json") {
+		if err := Unmarshal(w.Body, &resp.Body); err == nil {
+			return resp
+		}
+	}
+	resp.Body = string(w.Body)
+	return resp
+}
@@ -0,0 +1,72 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBatch1(t *testing.T) {
+	Convey("batch requests", t, func() {
+		b2 := New()
+		b2.Get("/users/:id", func(c *Context) {
+			c.JSON(http.StatusOK, map[string]interface{}{
+				"id":    c.ParamInt("id"),
+				"token": c.Req.Header.Get("Authorization"),
+				"lang":  c.Req.Header.Get("Accept-Language"),
+			})
+		})
+		b2.Post("/echo", func(c *Context) {
+			body, _ := c.Body().String()
+			c.String(http.StatusCreated, c.Req.Header.Get("Content-Type")+" "+body)
+		})
+		b2.Post("/batch", Batch(BatchOptions{MaxRequests: 5}))
+
+		batch := func(body string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("POST", "/batch", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer t")
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		w := batch(`[
+			{"path": "/users/1", "headers": {"Accept-Language": "en"}},
+			{"method": "POST", "path": "/echo", "body": {"a": 1}},
+			{"method": "POST", "path": "/echo", "body": "text"},
+			{"path": "/missing"},
+			{"path": "missing"},
+			{"method": "POST", "path": "/batch", "body": []}
+		]`)
+		So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+
+		w = batch(`[
+			{"path": "/users/1", "headers": {"Accept-Language": "en"}},
+			{"method": "POST", "path": "/echo", "body": {"a": 1}},
+			{"method": "POST", "path": "/echo", "body": "text"},
+			{"path": "/missing"},
+			{"method": "POST", "path": "/batch", "body": []}
+		]`)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		var resps []BatchResponse
+		So(Unmarshal(w.Body.Bytes(), &resps), ShouldBeNil)
+		So(resps, ShouldHaveLength, 5)
+		So(resps[0].Status, ShouldEqual, http.StatusOK)
+		So(resps[0].Body, ShouldResemble, map[string]interface{}{"id": 1.0, "token": "Bearer t", "lang": "en"})
+		So(resps[1].Status, ShouldEqual, http.StatusCreated)
+		So(resps[1].Body, ShouldEqual, `application/json; charset=utf-8 {"a":1}`)
+		So(resps[1].Headers["Content-Type"], ShouldStartWith, TextPlain)
+		So(resps[2].Body, ShouldEqual, " text")
+		So(resps[3].Status, ShouldEqual, http.StatusNotFound)
+		So(resps[4].Status, ShouldEqual, http.StatusBadRequest)
+
+		w = batch(`[{"path": "missing"}]`)
+		So(Unmarshal(w.Body.Bytes(), &resps), ShouldBeNil)
+		So(resps[0].Status, ShouldEqual, http.StatusBadRequest)
+
+		So(batch(`{}`).Code, ShouldEqual, http.StatusBadRequest)
+	})
+}
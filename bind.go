@@ -0,0 +1,386 @@
+package baa
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBindTarget is returned when bind target is not a pointer to struct.
+var ErrBindTarget = errors.New("bind target must be a pointer to struct")
+
+// BindError is returned when a value can not be converted to the field type.
+type BindError struct {
+	Source string // query, header, ...
+	Field  string // struct field name
+	Key    string // value key
+	Err    error
+}
+
+// Error implements error interface
+func (e *BindError) Error() string {
+	return fmt.Sprintf("bind %s %s to field %s error: %v", e.Source, e.Key, e.Field, e.Err)
+}
+
+// Validator is the interface validates bound data
+type Validator interface {
+	Validate(v interface{}) error
+}
+
+// selfValidator is implemented by data validates itself
+type selfValidator interface {
+	Validate() error
+}
+
+// SetValidator set validator used after binding, default is none, use
+// NewTagValidator to validate `valid` struct tags.
+func (b *Baa) SetValidator(v Validator) {
+	b.validator = v
+}
+
+// Validator returns the validator used after binding
+func (b *Baa) Validator() Validator {
+	return b.validator
+}
+
+// Validate validates v by the registered validator,
+// then calls v.Validate() if v implements it.
+func (c *Context) Validate(v interface{}) error {
+	if c.baa.validator != nil {
+		if err := c.baa.validator.Validate(v); err != nil {
+			return err
+		}
+	}
+	if sv, ok := v.(selfValidator); ok {
+		return sv.Validate()
+	}
+	return nil
+}
+
+// BindQuery binds URL query string into struct v by tag `query`, then validates it.
+// Field name is used when tag is empty, tag "-" means skip the field.
+//
+// Example:
+// 		type Filter struct {
+// 			Keyword string   `query:"q"`
+// 			Page    int      `query:"page"`
+// 			Tags    []string `query:"tag"`
+// 		}
+func (c *Context) BindQuery(v interface{}) error {
+	values := c.Req.URL.Query()
+	err := bindValues(v, "query", false, func(key string) []string {
+		return values[key]
+	})
+	if err != nil {
+		return err
+	}
+	return c.Validate(v)
+}
+
+// BindHeader binds request headers into struct v by tag `header`, then validates it.
+func (c *Context) BindHeader(v interface{}) error {
+	err := bindValues(v, "header", false, func(key string) []string {
+		return c.Req.Header[http.CanonicalHeaderKey(key)]
+	})
+	if err != nil {
+		return err
+	}
+	return c.Validate(v)
+}
+
+// Bind binds request data into struct v then validates it. The body is bound first,
+// then route params by tag `param` and query string by tag `query`, so they can not be
+// overridden by the body, fields without the tag are skipped.
+// The body is bound by Content-Type: JSON and XML are decoded by tags `json` and `xml`,
+// url-encoded and multipart forms are bound by tag `form` or field name except fields
+// tagged `param`, uploaded files are bound to fields of type *multipart.FileHeader or
+// []*multipart.FileHeader.
+// It returns HTTPError 415 for unsupported content types and 400 for malformed body.
+//
+// Example:
+// 		type CreatePost struct {
+// 			UserID int      `param:"uid"`
+// 			Draft  bool     `query:"draft"`
+// 			Title  string   `json:"title" form:"title"`
+// 			Tags   []string `json:"tags" form:"tag"`
+// 		}
+func (c *Context) Bind(v interface{}) error {
+	err := c.bindBody(v)
+	if err != nil {
+		return err
+	}
+	err = bindValues(v, "param", true, func(key string) []string {
+		for i := len(c.pNames) - 1; i >= 0; i-- {
+			if c.pNames[i] == key {
+				return []string{c.pValues[i]}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	query := c.Req.URL.Query()
+	err = bindValues(v, "query", true, func(key string) []string {
+		return query[key]
+	})
+	if err != nil {
+		return err
+	}
+	return c.Validate(v)
+}
+
+// bindBody binds request body into v by Content-Type
+func (c *Context) bindBody(v interface{}) error {
+	if c.Req.Body == nil || c.Req.Body == http.NoBody ||
+		(c.Req.ContentLength == 0 && len(c.Req.TransferEncoding) == 0) {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	switch {
+	case mediaType == ApplicationJSON || strings.HasSuffix(mediaType, "+json"):
+		body, err := c.Body().Bytes()
+		if err != nil {
+			return bodyError(err)
+		}
+		if err := Unmarshal(body, v); err != nil {
+			return NewHTTPError(http.StatusBadRequest, "invalid JSON body").WithError(err)
+		}
+	case mediaType == ApplicationXML || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		body, err := c.Body().Bytes()
+		if err != nil {
+			return bodyError(err)
+		}
+		if err := xml.Unmarshal(body, v); err != nil {
+			return NewHTTPError(http.StatusBadRequest, "invalid XML body").WithError(err)
+		}
+	case mediaType == ApplicationForm || mediaType == MultipartForm:
+		var err error
+		if mediaType == MultipartForm {
+			err = c.Req.ParseMultipartForm(defaultMaxMemory)
+		} else {
+			err = c.Req.ParseForm()
+		}
+		if err != nil {
+			return NewHTTPError(http.StatusBadRequest, "invalid form body").WithError(err)
+		}
+		form := c.Req.PostForm
+		if err = bindValues(v, "form", false, func(key string) []string {
+			return form[key]
+		}); err != nil {
+			return err
+		}
+		if c.Req.MultipartForm != nil {
+			bindFiles(reflect.ValueOf(v).Elem(), c.Req.MultipartForm.File)
+		}
+	default:
+		return NewHTTPError(http.StatusUnsupportedMediaType)
+	}
+	return nil
+}
+
+// bodyError returns HTTPError of request body read error, 413 when the body limit is
+// exceeded otherwise 400
+func bodyError(err error) error {
+	if err.Error() == "http: request body too large" {
+		return NewHTTPError(http.StatusRequestEntityTooLarge).WithError(err)
+	}
+	return NewHTTPError(http.StatusBadRequest, "read request body").WithError(err)
+}
+
+// fileHeaderType type of *multipart.FileHeader
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// bindFiles binds uploaded files into struct fields by tag `form` or field name
+func bindFiles(rv reflect.Value, files map[string][]*multipart.FileHeader) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		key := sf.Tag.Get("form")
+		if key == "-" || sf.Tag.Get("param") != "" {
+			continue
+		}
+		if sf.Anonymous && key == "" && fv.Kind() == reflect.Struct {
+			bindFiles(fv, files)
+			continue
+		}
+		if !fv.CanSet() {
+			continue
+		}
+		if key == "" {
+			key = sf.Name
+		}
+		fhs := files[key]
+		if len(fhs) == 0 {
+			continue
+		}
+		switch {
+		case sf.Type == fileHeaderType:
+			fv.Set(reflect.ValueOf(fhs[0]))
+		case sf.Type.Kind() == reflect.Slice && sf.Type.Elem() == fileHeaderType:
+			fv.Set(reflect.ValueOf(fhs))
+		}
+	}
+}
+
+// bindValues binds values into struct fields by tag, fields without the tag are
+// skipped when explicit, or bound by field name.
+func bindValues(v interface{}, tag string, explicit bool, get func(key string) []string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrBindTarget
+	}
+	return bindStruct(rv.Elem(), tag, explicit, get)
+}
+
+// bindStruct binds values into struct fields, embedded structs are supported
+func bindStruct(rv reflect.Value, tag string, explicit bool, get func(key string) []string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		key := sf.Tag.Get(tag)
+		if key == "-" {
+			continue
+		}
+		if sf.Anonymous && key == "" && fv.Kind() == reflect.Struct {
+			if err := bindStruct(fv, tag, explicit, get); err != nil {
+				return err
+			}
+			continue
+		}
+		if !fv.CanSet() || (explicit && key == "") {
+			continue
+		}
+		// route params are never taken from forms
+		if tag == "form" && sf.Tag.Get("param") != "" {
+			continue
+		}
+		if key == "" {
+			key = sf.Name
+		}
+		vals := get(key)
+		if len(vals) == 0 {
+			continue
+		}
+		if err := setField(fv, vals); err != nil {
+			return &BindError{Source: tag, Field: sf.Name, Key: key, Err: err}
+		}
+	}
+	return nil
+}
+
+// setField converts string values to the field type then set it
+func setField(fv reflect.Value, vals []string) error {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		nv := reflect.New(fv.Type().Elem())
+		if err := setField(nv.Elem(), vals); err != nil {
+			return err
+		}
+		fv.Set(nv)
+		return nil
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			fv.SetBytes([]byte(vals[0]))
+			return nil
+		}
+		sv := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i := range vals {
+			if err := setValue(sv.Index(i), vals[i]); err != nil {
+				return err
+			}
+		}
+		fv.Set(sv)
+		return nil
+	}
+	return setValue(fv, vals[0])
+}
+
+// durationType type of time.Duration
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// timeType type of time.Time
+var timeType = reflect.TypeOf(time.Time{})
+
+// setValue converts a string value to the value type then set it
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.Type() == timeType {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			v.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Ptr:
+		nv := reflect.New(v.Type().Elem())
+		if err := setValue(nv.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(nv)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package baa
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type bindFilter struct {
+	bindPage
+	Keyword string        `query:"q" header:"X-Keyword"`
+	Tags    []string      `query:"tag"`
+	Limit   *int          `query:"limit"`
+	Timeout time.Duration `query:"timeout"`
+	Since   time.Time     `query:"since"`
+	Ratio   float32       `query:"ratio"`
+	Deleted bool          `query:"deleted"`
+	Skip    string        `query:"-"`
+	Token   string        `header:"Authorization"`
+}
+
+type bindPage struct {
+	Page uint `query:"page"`
+}
+
+func (f *bindFilter) Validate() error {
+	if f.Keyword == "invalid" {
+		return errors.New("invalid keyword")
+	}
+	return nil
+}
+
+type bindValidator struct{}
+
+func (bindValidator) Validate(v interface{}) error {
+	if f, ok := v.(*bindFilter); ok && f.Page > 100 {
+		return errors.New("page too large")
+	}
+	return nil
+}
+
+func TestBindQuery1(t *testing.T) {
+	Convey("bind query", t, func() {
+		var filter bindFilter
+		var err error
+		b2 := New()
+		b2.SetValidator(bindValidator{})
+		So(b2.Validator(), ShouldNotBeNil)
+		b2.Get("/search", func(c *Context) {
+			filter = bindFilter{}
+			err = c.BindQuery(&filter)
+		})
+		b2.Get("/header", func(c *Context) {
+			filter = bindFilter{}
+			err = c.BindHeader(&filter)
+		})
+		b2.Get("/invalid", func(c *Context) {
+			err = c.BindQuery(filter)
+		})
+		serve := func(uri string, header http.Header) {
+			req, _ := http.NewRequest("GET", uri, nil)
+			if header != nil {
+				req.Header = header
+			}
+			b2.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		Convey("bind values", func() {
+			serve("/search?q=baa&tag=a&tag=b&limit=10&page=2&timeout=1s&since=2020-01-02T00:00:00Z&ratio=0.5&deleted=true&Skip=1", nil)
+			So(err, ShouldBeNil)
+			So(filter.Keyword, ShouldEqual, "baa")
+			So(filter.Tags, ShouldResemble, []string{"a", "b"})
+			So(*filter.Limit, ShouldEqual, 10)
+			So(filter.Page, ShouldEqual, 2)
+			So(filter.Timeout, ShouldEqual, time.Second)
+			So(filter.Since.Year(), ShouldEqual, 2020)
+			So(filter.Ratio, ShouldEqual, 0.5)
+			So(filter.Deleted, ShouldBeTrue)
+			So(filter.Skip, ShouldEqual, "")
+		})
+		Convey("bind headers", func() {
+			serve("/header", http.Header{"X-Keyword": {"baa"}, "Authorization": {"token"}})
+			So(err, ShouldBeNil)
+			So(filter.Keyword, ShouldEqual, "baa")
+			So(filter.Token, ShouldEqual, "token")
+		})
+		Convey("bind error", func() {
+			serve("/search?page=abc", nil)
+			So(err, ShouldNotBeNil)
+			e, ok := err.(*BindError)
+			So(ok, ShouldBeTrue)
+			So(e.Field, ShouldEqual, "Page")
+			So(e.Error(), ShouldContainSubstring, "bind query page")
+
+			serve("/search?since=2020", nil)
+			So(err, ShouldNotBeNil)
+			serve("/search?timeout=1x", nil)
+			So(err, ShouldNotBeNil)
+			serve("/invalid", nil)
+			So(err, ShouldEqual, ErrBindTarget)
+		})
+		Convey("validate error", func() {
+			serve("/search?q=invalid", nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "invalid keyword")
+			serve("/search?page=101", nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "page too large")
+		})
+	})
+}
+
+type bindPost struct {
+	UserID int                     `param:"uid"`
+	Draft  bool                    `query:"draft"`
+	Title  string                  `json:"title" xml:"title" form:"title"`
+	Tags   []string                `json:"tags" xml:"tag" form:"tag"`
+	Views  int                     `json:"views" xml:"views" form:"views"`
+	Cover  *multipart.FileHeader   `json:"-" xml:"-" form:"cover"`
+	Files  []*multipart.FileHeader `json:"-" xml:"-" form:"file"`
+}
+
+func TestBind1(t *testing.T) {
+	Convey("bind params, query and body", t, func() {
+		var post bindPost
+		var err error
+		b2 := New()
+		b2.Route("/users/:uid/posts", "GET,POST", func(c *Context) {
+			post = bindPost{}
+			err = c.Bind(&post)
+		})
+		serve := func(method, uri, contentType string, body io.Reader) {
+			req, _ := http.NewRequest(method, uri, body)
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
+			b2.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		serve("POST", "/users/7/posts?draft=true&title=ignored", ApplicationJSONCharsetUTF8,
+			strings.NewReader(`{"title": "baa", "tags": ["a", "b"], "views": 3}`))
+		So(err, ShouldBeNil)
+		So(post, ShouldResemble, bindPost{UserID: 7, Draft: true, Title: "baa", Tags: []string{"a", "b"}, Views: 3})
+
+		serve("POST", "/users/7/posts", ApplicationXML,
+			strings.NewReader(`<post><title>baa</title><tag>a</tag><views>3</views></post>`))
+		So(err, ShouldBeNil)
+		So(post.Title, ShouldEqual, "baa")
+		So(post.Tags, ShouldResemble, []string{"a"})
+
+		serve("POST", "/users/7/posts", ApplicationForm, strings.NewReader("title=baa&tag=a&tag=b&views=3"))
+		So(err, ShouldBeNil)
+		So(post, ShouldResemble, bindPost{UserID: 7, Title: "baa", Tags: []string{"a", "b"}, Views: 3})
+
+		body := new(bytes.Buffer)
+		mw := multipart.NewWriter(body)
+		mw.WriteField("title", "baa")
+		fw, _ := mw.CreateFormFile("cover", "cover.png")
+		fw.Write([]byte("png"))
+		mw.CreateFormFile("file", "a.txt")
+		mw.CreateFormFile("file", "b.txt")
+		mw.Close()
+		serve("POST", "/users/7/posts", mw.FormDataContentType(), body)
+		So(err, ShouldBeNil)
+		So(post.Title, ShouldEqual, "baa")
+		So(post.Cover.Filename, ShouldEqual, "cover.png")
+		So(len(post.Files), ShouldEqual, 2)
+
+		serve("GET", "/users/7/posts?draft=1", "", nil)
+		So(err, ShouldBeNil)
+		So(post, ShouldResemble, bindPost{UserID: 7, Draft: true})
+
+		serve("POST", "/users/7/posts", ApplicationJSON, strings.NewReader(`{"views": "x"}`))
+		So(err.(*HTTPError).Code, ShouldEqual, http.StatusBadRequest)
+		serve("POST", "/users/7/posts", "text/plain", strings.NewReader("baa"))
+		So(err.(*HTTPError).Code, ShouldEqual, http.StatusUnsupportedMediaType)
+		serve("POST", "/users/7/posts", ApplicationForm, strings.NewReader("views=x"))
+		So(err.(*BindError).Source, ShouldEqual, "form")
+		serve("GET", "/users/x/posts", "", nil)
+		So(err.(*BindError).Source, ShouldEqual, "param")
+
+		Convey("params and query can not be overridden by body", func() {
+			serve("POST", "/users/7/posts?draft=false", ApplicationJSON,
+				strings.NewReader(`{"userid": 999, "UserID": 998, "draft": true, "title": "baa"}`))
+			So(err, ShouldBeNil)
+			So(post, ShouldResemble, bindPost{UserID: 7, Title: "baa"})
+
+			serve("POST", "/users/7/posts", ApplicationForm, strings.NewReader("UserID=999&title=baa"))
+			So(err, ShouldBeNil)
+			So(post, ShouldResemble, bindPost{UserID: 7, Title: "baa"})
+		})
+
+		Convey("client errors", func() {
+			b2.Route("/bind/:uid", "GET,POST", func(c *Context) {
+				var post bindPost
+				c.Error(c.Bind(&post))
+			})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/bind/x", nil)
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+			b2.SetBodyLimit(4)
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequest("POST", "/bind/1", strings.NewReader(`{"title": "baa"}`))
+			req.Header.Set("Content-Type", ApplicationJSON)
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		})
+	})
+}
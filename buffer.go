@@ -0,0 +1,32 @@
+package baa
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize buffers grown larger than it are dropped instead of pooled,
+// avoid holding large memory after a huge response.
+const maxPooledBufferSize = 64 << 10 // 64 KB
+
+// bufferPool pool of byte buffers used by render and encoding helpers
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns an empty buffer from pool
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer puts the buffer back to pool
+func putBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,259 @@
+package baa
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss is returned by RedisClient when key does not exist
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache is the application shared cache, registered as DI "cache"
+type Cache interface {
+	// Get returns value of key, ok is false when key does not exist or expired
+	Get(key string) (v interface{}, ok bool)
+	// Set set value of key with ttl, 0 means never expire
+	Set(key string, v interface{}, ttl time.Duration) error
+	// Delete removes key
+	Delete(key string) error
+	// GetOrLoad returns value of key, loader is called to load and set the value
+	// when key does not exist, concurrent loads of same key are merged into one.
+	GetOrLoad(key string, ttl time.Duration, loader func() (interface{}, error)) (interface{}, error)
+}
+
+// Cache return baa shared cache
+func (b *Baa) Cache() Cache {
+	return b.GetDI("cache").(Cache)
+}
+
+// MemoryCache is an in-memory LRU cache with ttl
+type MemoryCache struct {
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	mu    sync.Mutex
+	group flightGroup
+	clock Clock
+}
+
+// memoryItem is an entry of memory cache
+type memoryItem struct {
+	key    string
+	value  interface{}
+	expire time.Time
+}
+
+// NewMemoryCache create a memory cache holds at most size items, 0 means no limit
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		clock: SystemClock,
+	}
+}
+
+// SetClock set the clock used by ttl, the cache of application uses DI clock
+func (m *MemoryCache) SetClock(c Clock) {
+	m.mu.Lock()
+	m.clock = c
+	m.mu.Unlock()
+}
+
+// Get returns value of key
+func (m *MemoryCache) Get(key string) (interface{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := e.Value.(*memoryItem)
+	if !item.expire.IsZero() && m.clock.Now().After(item.expire) {
+		m.ll.Remove(e)
+		delete(m.items, key)
+		return nil, false
+	}
+	m.ll.MoveToFront(e)
+	return item.value, true
+}
+
+// Set set value of key with ttl, the least recently used item is evicted when cache is full
+func (m *MemoryCache) Set(key string, v interface{}, ttl time.Duration) error {
+	item := &memoryItem{key: key, value: v}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ttl > 0 {
+		item.expire = m.clock.Now().Add(ttl)
+	}
+	if e, ok := m.items[key]; ok {
+		e.Value = item
+		m.ll.MoveToFront(e)
+		return nil
+	}
+	m.items[key] = m.ll.PushFront(item)
+	if m.size > 0 && m.ll.Len() > m.size {
+		e := m.ll.Back()
+		m.ll.Remove(e)
+		delete(m.items, e.Value.(*memoryItem).key)
+	}
+	return nil
+}
+
+// Delete removes key
+func (m *MemoryCache) Delete(key string) error {
+	m.mu.Lock()
+	if e, ok := m.items[key]; ok {
+		m.ll.Remove(e)
+		delete(m.items, key)
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// GetOrLoad returns value of key, loads it by loader when not exists
+func (m *MemoryCache) GetOrLoad(key string, ttl time.Duration, loader func() (interface{}, error)) (interface{}, error) {
+	return getOrLoad(m, &m.group, key, ttl, loader)
+}
+
+// Len returns the number of items, expired items may be counted
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+// Flush removes all items
+func (m *MemoryCache) Flush() error {
+	m.mu.Lock()
+	m.ll.Init()
+	m.items = make(map[string]*list.Element)
+	m.mu.Unlock()
+	return nil
+}
+
+// RedisClient is the minimal redis client used by RedisCache,
+// it can be implemented by a few lines adapter of any redis library.
+type RedisClient interface {
+	// Get returns value of key, returns ErrCacheMiss when key does not exist
+	Get(key string) ([]byte, error)
+	// Set set value of key with ttl, 0 means never expire
+	Set(key string, value []byte, ttl time.Duration) error
+	// Del removes key
+	Del(key string) error
+}
+
+// RedisCache is a cache stores values in redis, []byte and string values are
+// stored as is, others are encoded by JSON. Get returns stored value in []byte,
+// use Unmarshal to decode JSON encoded values.
+type RedisCache struct {
+	client RedisClient
+	prefix string
+	group  flightGroup
+}
+
+// NewRedisCache create a redis cache, prefix is prepended to all keys
+func NewRedisCache(client RedisClient, prefix string) *RedisCache {
+	return &RedisCache{client: client, prefix: prefix}
+}
+
+// Get returns value of key in []byte
+func (r *RedisCache) Get(key string) (interface{}, bool) {
+	data, err := r.client.Get(r.prefix + key)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Set set value of key with ttl
+func (r *RedisCache) Set(key string, v interface{}, ttl time.Duration) error {
+	var data []byte
+	switch v := v.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		var err error
+		if data, err = Marshal(v); err != nil {
+			return err
+		}
+	}
+	return r.client.Set(r.prefix+key, data, ttl)
+}
+
+// Delete removes key
+func (r *RedisCache) Delete(key string) error {
+	return r.client.Del(r.prefix + key)
+}
+
+// GetOrLoad returns value of key, loads it by loader when not exists.
+// the loaded value is returned as is, not in []byte.
+func (r *RedisCache) GetOrLoad(key string, ttl time.Duration, loader func() (interface{}, error)) (interface{}, error) {
+	return getOrLoad(r, &r.group, key, ttl, loader)
+}
+
+// getOrLoad returns cached value or loads it once for concurrent callers
+func getOrLoad(c Cache, g *flightGroup, key string, ttl time.Duration, loader func() (interface{}, error)) (interface{}, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+	return g.do(key, func() (interface{}, error) {
+		if v, ok := c.Get(key); ok {
+			return v, nil
+		}
+		v, err := loader()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Set(key, v, ttl); err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+// errFlightPanic is returned to waiters when the loader panics
+var errFlightPanic = errors.New("cache loader panicked")
+
+// flightGroup merges concurrent calls of same key into one
+type flightGroup struct {
+	calls map[string]*flightCall
+	mu    sync.Mutex
+}
+
+// flightCall is an in-flight call
+type flightCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// do executes fn once for concurrent callers of key
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := &flightCall{err: errFlightPanic}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err
+}
@@ -0,0 +1,120 @@
+package baa
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// memRedis is a fake redis client
+type memRedis struct {
+	data map[string][]byte
+}
+
+func (r *memRedis) Get(key string) ([]byte, error) {
+	v, ok := r.data[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return v, nil
+}
+
+func (r *memRedis) Set(key string, value []byte, ttl time.Duration) error {
+	r.data[key] = value
+	return nil
+}
+
+func (r *memRedis) Del(key string) error {
+	delete(r.data, key)
+	return nil
+}
+
+func TestCache1(t *testing.T) {
+	Convey("memory cache", t, func() {
+		m := NewMemoryCache(2)
+		So(b.Cache(), ShouldNotBeNil)
+
+		m.Set("a", 1, 0)
+		m.Set("b", 2, 0)
+		v, ok := m.Get("a")
+		So(ok, ShouldBeTrue)
+		So(v, ShouldEqual, 1)
+		m.Set("c", 3, 0)
+		_, ok = m.Get("b")
+		So(ok, ShouldBeFalse)
+		So(m.Len(), ShouldEqual, 2)
+
+		m.Set("a", 10, 0)
+		v, _ = m.Get("a")
+		So(v, ShouldEqual, 10)
+		m.Delete("a")
+		_, ok = m.Get("a")
+		So(ok, ShouldBeFalse)
+		So(m.Flush(), ShouldBeNil)
+		So(m.Len(), ShouldEqual, 0)
+
+		m.Set("ttl", 1, time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		_, ok = m.Get("ttl")
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("get or load with singleflight", t, func() {
+		m := NewMemoryCache(0)
+		var calls int32
+		var wg sync.WaitGroup
+		start := make(chan bool)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				m.GetOrLoad("k", 0, func() (interface{}, error) {
+					atomic.AddInt32(&calls, 1)
+					time.Sleep(20 * time.Millisecond)
+					return "v", nil
+				})
+			}()
+		}
+		close(start)
+		wg.Wait()
+		So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+		v, err := m.GetOrLoad("k", 0, nil)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, "v")
+
+		_, err = m.GetOrLoad("e", 0, func() (interface{}, error) {
+			return nil, errors.New("failed")
+		})
+		So(err, ShouldNotBeNil)
+		_, ok := m.Get("e")
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("redis cache", t, func() {
+		client := &memRedis{data: make(map[string][]byte)}
+		r := NewRedisCache(client, "app:")
+		r.Set("s", "str", 0)
+		r.Set("m", map[string]int{"a": 1}, time.Minute)
+		v, ok := r.Get("s")
+		So(ok, ShouldBeTrue)
+		So(string(v.([]byte)), ShouldEqual, "str")
+		v, _ = r.Get("m")
+		So(string(v.([]byte)), ShouldEqual, `{"a":1}`)
+		So(client.data, ShouldContainKey, "app:m")
+		r.Delete("m")
+		_, ok = r.Get("m")
+		So(ok, ShouldBeFalse)
+
+		v, err := r.GetOrLoad("l", 0, func() (interface{}, error) {
+			return []byte("loaded"), nil
+		})
+		So(err, ShouldBeNil)
+		So(string(v.([]byte)), ShouldEqual, "loaded")
+		So(string(client.data["app:l"]), ShouldEqual, "loaded")
+	})
+}
@@ -0,0 +1,169 @@
+package baa
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrCertNotFound is returned by CertStore when the certificate does not exist
+var ErrCertNotFound = errors.New("baa: certificate not found")
+
+// CertStore loads TLS certificates by name, eg: from a directory, a database or
+// a secrets manager.
+type CertStore interface {
+	// Certificate returns the certificate of name, name is a lower case hostname or
+	// a wildcard name like *.example.com. ErrCertNotFound is returned when it does
+	// not exist.
+	Certificate(name string) (*tls.Certificate, error)
+}
+
+// CertStoreFunc is an adapter allows functions to be used as CertStore
+type CertStoreFunc func(name string) (*tls.Certificate, error)
+
+// Certificate calls f(name)
+func (f CertStoreFunc) Certificate(name string) (*tls.Certificate, error) {
+	return f(name)
+}
+
+// DirCertStore loads certificates from name.crt and name.key in PEM in the directory,
+// * of wildcard names is replaced by _, eg: _.example.com.crt.
+type DirCertStore string
+
+// Certificate loads certificate of name from the directory
+func (d DirCertStore) Certificate(name string) (*tls.Certificate, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return nil, ErrCertNotFound
+	}
+	file := filepath.Join(string(d), strings.Replace(name, "*", "_", 1))
+	cert, err := tls.LoadX509KeyPair(file+".crt", file+".key")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrCertNotFound
+		}
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// CertManagerOptions certificate manager config
+type CertManagerOptions struct {
+	// Size maximum number of cached names, default 1000
+	Size int
+	// TTL of loaded certificates, default 1 hour
+	TTL time.Duration
+	// MissTTL of names without certificate, default 1 minute
+	MissTTL time.Duration
+	// Default is served when the client sends no SNI or the name has no certificate,
+	// the handshake fails when it's nil.
+	Default *tls.Certificate
+}
+
+// CertManager resolves certificates per SNI hostname from a store with caching, the
+// certificate of the wildcard name is used when the hostname has no certificate.
+// Concurrent loads of a name are merged into one.
+type CertManager struct {
+	store CertStore
+	opt   CertManagerOptions
+	cache *MemoryCache
+}
+
+// certMiss is cached for names without certificate
+type certMiss struct{}
+
+// NewCertManager create a certificate manager of store, Reload can be registered
+// by OnReload to drop cached certificates.
+//
+// Example:
+// 		m := baa.NewCertManager(baa.DirCertStore("/etc/certs"), baa.CertManagerOptions{})
+// 		b.OnReload(m.Reload)
+// 		b.SetServerOption(baa.ServerGetCertificate(m.GetCertificate))
+// 		b.RunTLS(":443", "", "")
+func NewCertManager(store CertStore, opt CertManagerOptions) *CertManager {
+	if store == nil {
+		panic("baa.NewCertManager store can not be nil")
+	}
+	if opt.Size <= 0 {
+		opt.Size = 1000
+	}
+	if opt.TTL <= 0 {
+		opt.TTL = time.Hour
+	}
+	if opt.MissTTL <= 0 {
+		opt.MissTTL = time.Minute
+	}
+	return &CertManager{
+		store: store,
+		opt:   opt,
+		cache: NewMemoryCache(opt.Size),
+	}
+}
+
+// SetClock set the clock used by ttl
+func (m *CertManager) SetClock(c Clock) {
+	m.cache.SetClock(c)
+}
+
+// GetCertificate returns the certificate of SNI hostname of hello, it can be used
+// as tls.Config.GetCertificate.
+func (m *CertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+	if name != "" {
+		cert, err := m.Certificate(name)
+		if err != ErrCertNotFound {
+			return cert, err
+		}
+	}
+	if m.opt.Default != nil {
+		return m.opt.Default, nil
+	}
+	return nil, errors.New("baa: no certificate for " + name)
+}
+
+// Certificate returns the certificate of hostname or its wildcard name
+func (m *CertManager) Certificate(host string) (*tls.Certificate, error) {
+	v, err := m.cache.GetOrLoad(host, m.opt.TTL, func() (interface{}, error) {
+		cert, err := m.store.Certificate(host)
+		if err == ErrCertNotFound {
+			if i := strings.IndexByte(host, '.'); i > 0 && strings.Count(host, ".") > 1 {
+				cert, err = m.store.Certificate("*" + host[i:])
+			}
+		}
+		return cert, err
+	})
+	if err == ErrCertNotFound {
+		m.cache.Set(host, certMiss{}, m.opt.MissTTL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(certMiss); ok {
+		return nil, ErrCertNotFound
+	}
+	return v.(*tls.Certificate), nil
+}
+
+// Invalidate drops the cached certificate of host, eg: after a tenant renewed it
+func (m *CertManager) Invalidate(host string) {
+	m.cache.Delete(host)
+}
+
+// Reload drops all cached certificates, they are loaded again on next handshakes
+func (m *CertManager) Reload() error {
+	return m.cache.Flush()
+}
+
+// ServerGetCertificate sets the function returns certificates by SNI of TLS handshakes,
+// eg: CertManager.GetCertificate.
+func ServerGetCertificate(fn func(*tls.ClientHelloInfo) (*tls.Certificate, error)) ServerOption {
+	return func(s *http.Server) {
+		if s.TLSConfig == nil {
+			s.TLSConfig = new(tls.Config)
+		}
+		s.TLSConfig.GetCertificate = fn
+	}
+}
@@ -0,0 +1,97 @@
+package baa
+
+import (
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCertManager1(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "baa-certs")
+	defer os.RemoveAll(dir)
+	certFile, keyFile, _, _, _ := newTestChain(dir, "")
+	for _, name := range []string{"a.example.com", "_.example.com"} {
+		os.Link(certFile, filepath.Join(dir, name+".crt"))
+		os.Link(keyFile, filepath.Join(dir, name+".key"))
+	}
+
+	Convey("directory store", t, func() {
+		store := DirCertStore(dir)
+		cert, err := store.Certificate("a.example.com")
+		So(err, ShouldBeNil)
+		So(cert.Certificate, ShouldNotBeEmpty)
+		_, err = store.Certificate("*.example.com")
+		So(err, ShouldBeNil)
+		_, err = store.Certificate("b.example.org")
+		So(err == ErrCertNotFound, ShouldBeTrue)
+		_, err = store.Certificate("../server")
+		So(err == ErrCertNotFound, ShouldBeTrue)
+	})
+
+	Convey("resolve by SNI with caching", t, func() {
+		loads := make(map[string]int)
+		var storeErr error
+		m := NewCertManager(CertStoreFunc(func(name string) (*tls.Certificate, error) {
+			loads[name]++
+			if storeErr != nil {
+				return nil, storeErr
+			}
+			return DirCertStore(dir).Certificate(name)
+		}), CertManagerOptions{TTL: time.Minute, MissTTL: time.Second})
+		clock := NewFakeClock(time.Now())
+		m.SetClock(clock)
+
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "A.example.com."})
+		So(err, ShouldBeNil)
+		So(cert, ShouldNotBeNil)
+		m.GetCertificate(&tls.ClientHelloInfo{ServerName: "a.example.com"})
+		So(loads["a.example.com"], ShouldEqual, 1)
+
+		_, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "b.example.com"})
+		So(err, ShouldBeNil)
+		So(loads["*.example.com"], ShouldEqual, 1)
+
+		_, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"})
+		So(err, ShouldNotBeNil)
+		m.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"})
+		So(loads["example.org"], ShouldEqual, 1)
+		clock.Advance(2 * time.Second)
+		m.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"})
+		So(loads["example.org"], ShouldEqual, 2)
+
+		_, err = m.GetCertificate(&tls.ClientHelloInfo{})
+		So(err, ShouldNotBeNil)
+
+		m.Invalidate("a.example.com")
+		m.GetCertificate(&tls.ClientHelloInfo{ServerName: "a.example.com"})
+		So(loads["a.example.com"], ShouldEqual, 2)
+
+		So(m.Reload(), ShouldBeNil)
+		storeErr = errors.New("store unavailable")
+		_, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "a.example.com"})
+		So(err == storeErr, ShouldBeTrue)
+		storeErr = nil
+		_, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "a.example.com"})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("default certificate", t, func() {
+		def, _ := tls.LoadX509KeyPair(certFile, keyFile)
+		m := NewCertManager(DirCertStore(dir), CertManagerOptions{Default: &def})
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"})
+		So(err, ShouldBeNil)
+		So(cert == &def, ShouldBeTrue)
+		cert, _ = m.GetCertificate(&tls.ClientHelloInfo{})
+		So(cert == &def, ShouldBeTrue)
+
+		s := New().Server(":0", ServerGetCertificate(m.GetCertificate))
+		So(s.TLSConfig.GetCertificate, ShouldNotBeNil)
+		So(func() { NewCertManager(nil, CertManagerOptions{}) }, ShouldPanic)
+	})
+}
@@ -0,0 +1,142 @@
+package baa
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PropagateHeaders headers copied from the incoming request to outbound requests,
+// request id and common tracing headers.
+var PropagateHeaders = []string{
+	"X-Request-ID",
+	"Traceparent",
+	"Tracestate",
+	"X-B3-TraceId",
+	"X-B3-SpanId",
+	"X-B3-ParentSpanId",
+	"X-B3-Sampled",
+	"X-Cloud-Trace-Context",
+}
+
+// HTTPClient is the outbound http client of application, registered as DI "httpclient".
+// connection pool can be configured per host by SetHostTransport.
+type HTTPClient struct {
+	client    *http.Client
+	transport http.RoundTripper
+	hosts     map[string]http.RoundTripper
+	mu        sync.RWMutex
+}
+
+// NewHTTPClient create a http client with timeout, 0 means no timeout
+func NewHTTPClient(timeout time.Duration) *HTTPClient {
+	h := new(HTTPClient)
+	h.transport = http.DefaultTransport
+	h.hosts = make(map[string]http.RoundTripper)
+	h.client = &http.Client{Transport: h, Timeout: timeout}
+	return h
+}
+
+// SetTransport set the default transport used by hosts without a host transport
+func (h *HTTPClient) SetTransport(rt http.RoundTripper) {
+	h.mu.Lock()
+	h.transport = rt
+	h.mu.Unlock()
+}
+
+// SetHostTransport set transport of host, host is in the form of host or host:port.
+//
+// Example:
+// 		b.HTTPClient().SetHostTransport("api.example.com", &http.Transport{MaxIdleConnsPerHost: 100})
+func (h *HTTPClient) SetHostTransport(host string, rt http.RoundTripper) {
+	h.mu.Lock()
+	h.hosts[strings.ToLower(host)] = rt
+	h.mu.Unlock()
+}
+
+// RoundTrip implements http.RoundTripper, dispatches request to transport of host
+func (h *HTTPClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.mu.RLock()
+	rt, ok := h.hosts[strings.ToLower(req.URL.Host)]
+	if !ok {
+		rt, ok = h.hosts[strings.ToLower(req.URL.Hostname())]
+	}
+	if !ok {
+		rt = h.transport
+	}
+	h.mu.RUnlock()
+	return rt.RoundTrip(req)
+}
+
+// Client returns the underlying *http.Client
+func (h *HTTPClient) Client() *http.Client {
+	return h.client
+}
+
+// HTTPClient return baa outbound http client
+func (b *Baa) HTTPClient() *HTTPClient {
+	return b.GetDI("httpclient").(*HTTPClient)
+}
+
+// ContextClient is an outbound http client bound to a request context,
+// request id, trace headers and deadline of the context are propagated.
+type ContextClient struct {
+	c *Context
+	h *HTTPClient
+}
+
+// HTTPClient returns an outbound http client propagates metadata of current request
+func (c *Context) HTTPClient() *ContextClient {
+	return &ContextClient{c: c, h: c.baa.HTTPClient()}
+}
+
+// Do sends an http request, headers in PropagateHeaders are copied from the incoming
+// request when not set, the request is canceled when the incoming request is done.
+// req is not modified, headers are set on a copy.
+func (cc *ContextClient) Do(req *http.Request) (*http.Response, error) {
+	if in := cc.c.Req; in != nil {
+		ctx := req.Context()
+		if ctx == context.Background() {
+			ctx = in.Context()
+		}
+		req = cloneRequest(req, ctx)
+		for _, k := range PropagateHeaders {
+			if v := in.Header.Get(k); v != "" && req.Header.Get(k) == "" {
+				req.Header.Set(k, v)
+			}
+		}
+	}
+	return cc.h.client.Do(req)
+}
+
+// cloneRequest returns a copy of req with ctx and a copied header
+func cloneRequest(req *http.Request, ctx context.Context) *http.Request {
+	r := req.WithContext(ctx)
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	return r
+}
+
+// Get issues a GET to the specified URL
+func (cc *ContextClient) Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return cc.Do(req)
+}
+
+// Post issues a POST to the specified URL
+func (cc *ContextClient) Post(url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return cc.Do(req)
+}
@@ -0,0 +1,103 @@
+package baa
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type countTransport struct {
+	n int
+}
+
+func (t *countTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.n++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestHTTPClient1(t *testing.T) {
+	Convey("outbound http client", t, func() {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+				return
+			}
+			w.Write([]byte(r.Header.Get("X-Request-ID") + "|" + r.Header.Get("Traceparent") + "|" + r.Header.Get("Content-Type")))
+		}))
+		defer upstream.Close()
+
+		b2 := New()
+		hostRT := new(countTransport)
+		b2.HTTPClient().SetHostTransport(strings.TrimPrefix(upstream.URL, "http://"), hostRT)
+		So(b2.HTTPClient().Client(), ShouldNotBeNil)
+
+		var body string
+		var clientErr error
+		b2.Get("/proxy", func(c *Context) {
+			resp, err := c.HTTPClient().Get(upstream.URL)
+			if err != nil {
+				clientErr = err
+				return
+			}
+			defer resp.Body.Close()
+			data, _ := ioutil.ReadAll(resp.Body)
+			body = string(data)
+		})
+		b2.Get("/post", func(c *Context) {
+			req, _ := http.NewRequest("POST", upstream.URL, nil)
+			req.Header.Set("X-Request-ID", "own")
+			resp, err := c.HTTPClient().Do(req)
+			if err != nil {
+				clientErr = err
+				return
+			}
+			resp.Body.Close()
+			// caller's request is not modified
+			So(req.Header.Get("Traceparent"), ShouldEqual, "")
+			So(req.Context() == context.Background(), ShouldBeTrue)
+			resp, err = c.HTTPClient().Post(upstream.URL, ApplicationJSON, strings.NewReader("{}"))
+			if err != nil {
+				clientErr = err
+				return
+			}
+			defer resp.Body.Close()
+			data, _ := ioutil.ReadAll(resp.Body)
+			body = string(data)
+		})
+		b2.Get("/slow", func(c *Context) {
+			_, clientErr = c.HTTPClient().Get(upstream.URL + "/slow")
+		})
+
+		req, _ := http.NewRequest("GET", "/proxy", nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		req.Header.Set("Traceparent", "00-abc-def-01")
+		b2.ServeHTTP(httptest.NewRecorder(), req)
+		So(clientErr, ShouldBeNil)
+		So(body, ShouldEqual, "req-1|00-abc-def-01|")
+		So(hostRT.n, ShouldEqual, 1)
+
+		req, _ = http.NewRequest("GET", "/post", nil)
+		req.Header.Set("X-Request-ID", "req-2")
+		req.Header.Set("Traceparent", "00-abc-def-02")
+		b2.ServeHTTP(httptest.NewRecorder(), req)
+		So(clientErr, ShouldBeNil)
+		So(body, ShouldEqual, "req-2|00-abc-def-02|"+ApplicationJSON)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		req, _ = http.NewRequest("GET", "/slow", nil)
+		b2.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+		So(clientErr, ShouldNotBeNil)
+
+		So(func() { b2.SetDI("httpclient", http.DefaultClient) }, ShouldPanic)
+	})
+}
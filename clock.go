@@ -0,0 +1,73 @@
+package baa
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time, it's registered as DI "clock" and used by
+// cache ttl, signed URLs and middlewares, tests can replace it by a FakeClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the clock returns the system time
+var SystemClock Clock = ClockFunc(time.Now)
+
+// ClockFunc is an adapter allows a func to be used as Clock
+type ClockFunc func() time.Time
+
+// Now returns f()
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// FakeClock is a manually controlled clock for tests
+//
+// Example:
+// 		clock := baa.NewFakeClock(time.Now())
+// 		defer b.OverrideDI("clock", clock)()
+// 		clock.Advance(time.Hour)
+type FakeClock struct {
+	now time.Time
+	mu  sync.RWMutex
+}
+
+// NewFakeClock create a fake clock frozen at t
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now returns the frozen time
+func (f *FakeClock) Now() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.now
+}
+
+// Set freezes the clock at t
+func (f *FakeClock) Set(t time.Time) {
+	f.mu.Lock()
+	f.now = t
+	f.mu.Unlock()
+}
+
+// Advance moves the clock forward by d
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
+// Clock return baa clock
+func (b *Baa) Clock() Clock {
+	return b.GetDI("clock").(Clock)
+}
+
+// clockOf returns a clock always reads the current DI clock of b, so services
+// created once follow DI overrides.
+func clockOf(b *Baa) Clock {
+	return ClockFunc(func() time.Time {
+		return b.Clock().Now()
+	})
+}
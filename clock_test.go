@@ -0,0 +1,47 @@
+package baa
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClock1(t *testing.T) {
+	Convey("fake clock", t, func() {
+		now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := NewFakeClock(now)
+		So(clock.Now(), ShouldEqual, now)
+		clock.Advance(time.Hour)
+		So(clock.Now(), ShouldEqual, now.Add(time.Hour))
+		clock.Set(now)
+		So(clock.Now(), ShouldEqual, now)
+
+		b2 := New()
+		So(b2.Clock(), ShouldNotBeNil)
+		So(func() { b2.SetDI("clock", "now") }, ShouldPanic)
+	})
+	Convey("cache ttl follows DI clock", t, func() {
+		b2 := New()
+		clock := NewFakeClock(time.Now())
+		defer b2.OverrideDI("clock", clock)()
+		b2.Cache().Set("k", "v", time.Minute)
+		clock.Advance(59 * time.Second)
+		_, ok := b2.Cache().Get("k")
+		So(ok, ShouldBeTrue)
+		clock.Advance(time.Second + 1)
+		_, ok = b2.Cache().Get("k")
+		So(ok, ShouldBeFalse)
+	})
+	Convey("signed url follows DI clock", t, func() {
+		b2 := New()
+		b2.SetSignKey([]byte("secret"))
+		clock := NewFakeClock(time.Now())
+		b2.SetDI("clock", clock)
+		u, _ := url.Parse(b2.SignURL("/download", time.Minute))
+		So(b2.VerifyURL(u), ShouldBeNil)
+		clock.Advance(2 * time.Minute)
+		So(b2.VerifyURL(u), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,44 @@
+package baa
+
+import (
+	"compress/gzip"
+	"strconv"
+)
+
+const (
+	// compressionDisabledKey is the context store key of DisableCompression
+	compressionDisabledKey = "baa.compressionDisabled"
+	// compressionLevelKey is the context store key of SetCompressionLevel
+	compressionLevelKey = "baa.compressionLevel"
+)
+
+// DisableCompression marks the response should not be compressed, eg: streaming or
+// pre-compressed payloads. It must be called before the response is written.
+func (c *Context) DisableCompression() {
+	c.Set(compressionDisabledKey, true)
+}
+
+// CompressionDisabled returns if the response should not be compressed, it's true
+// after c.DisableCompression or when the response should not be transformed.
+func (c *Context) CompressionDisabled() bool {
+	if v, ok := c.Get(compressionDisabledKey).(bool); ok && v {
+		return true
+	}
+	return c.NoTransform()
+}
+
+// SetCompressionLevel sets the gzip level of the response, it overrides the level
+// of compression middleware middleware/compress, eg: gzip.BestSpeed for large payloads.
+// It must be called before the response is written.
+func (c *Context) SetCompressionLevel(level int) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		panic("baa.Context.SetCompressionLevel invalid level " + strconv.Itoa(level))
+	}
+	c.Set(compressionLevelKey, level)
+}
+
+// CompressionLevel returns the level set by c.SetCompressionLevel
+func (c *Context) CompressionLevel() (level int, ok bool) {
+	level, ok = c.Get(compressionLevelKey).(int)
+	return
+}
@@ -3,6 +3,7 @@ package baa
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -13,9 +14,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -42,11 +45,15 @@ const (
 	ApplicationXML                   = "application/xml"
 	ApplicationXMLCharsetUTF8        = ApplicationXML + "; " + CharsetUTF8
 	ApplicationForm                  = "application/x-www-form-urlencoded"
+	ApplicationNDJSON                = "application/x-ndjson"
+	ApplicationMergePatch            = "application/merge-patch+json"
+	ApplicationJSONPatch             = "application/json-patch+json"
 	ApplicationProtobuf              = "application/protobuf"
 	TextHTML                         = "text/html"
 	TextHTMLCharsetUTF8              = TextHTML + "; " + CharsetUTF8
 	TextPlain                        = "text/plain"
 	TextPlainCharsetUTF8             = TextPlain + "; " + CharsetUTF8
+	TextXML                          = "text/xml"
 	MultipartForm                    = "multipart/form-data"
 )
 
@@ -57,12 +64,18 @@ type Context struct {
 	Resp       *Response
 	baa        *Baa
 	store      map[string]interface{}
-	storeMutex sync.RWMutex  // store rw lock
-	routeName  string        // route name
-	pNames     []string      // route params names
-	pValues    []string      // route params values
-	handlers   []HandlerFunc // middleware handler and route match handler
-	hi         int           // handlers execute position
+	storeMutex sync.RWMutex           // store rw lock
+	routeName  string                 // route name
+	routeNode  *Node                  // matched route node
+	path       string                 // path matched by router, cleaned and without locale
+	pNames     []string               // route params names
+	pValues    []string               // route params values
+	handlers   []HandlerFunc          // middleware handler and route match handler
+	hi         int                    // handlers execute position
+	closers    []func()               // cleanup callbacks executed when request finished
+	cache      map[string]interface{} // request scoped cache
+	locale     string                 // locale of request
+	sse        *sseStream             // server-sent events stream of request
 }
 
 // NewContext create a http context
@@ -70,8 +83,8 @@ func NewContext(w http.ResponseWriter, r *http.Request, b *Baa) *Context {
 	c := new(Context)
 	c.Resp = NewResponse(w, b)
 	c.baa = b
-	c.pNames = make([]string, 0, 16)
-	c.pValues = make([]string, 0, 16)
+	c.pNames = make([]string, 0, defaultContextParams)
+	c.pValues = make([]string, 0, defaultContextParams)
 	c.handlers = make([]HandlerFunc, len(b.middleware), len(b.middleware)+3)
 	copy(c.handlers, b.middleware)
 	c.Reset(w, r)
@@ -83,6 +96,50 @@ func (c *Context) RouteName() string {
 	return c.routeName
 }
 
+// RoutePattern returns pattern of matched route, returns empty string when not matched
+func (c *Context) RoutePattern() string {
+	if c.routeNode == nil {
+		return ""
+	}
+	return c.routeNode.Pattern()
+}
+
+// RouteMeta returns metadata of matched route
+func (c *Context) RouteMeta(key string) interface{} {
+	if c.routeNode == nil {
+		return nil
+	}
+	return c.routeNode.Meta(key)
+}
+
+// NoTransform returns if the response should not be transformed by compression
+// or minification middlewares. It is true when the matched route has meta
+// RouteMetaNoTransform, the flag is set by c.SetNoTransform or the response has
+// header Cache-Control: no-transform.
+func (c *Context) NoTransform() bool {
+	if v, ok := c.RouteMeta(RouteMetaNoTransform).(bool); ok && v {
+		return true
+	}
+	if v, ok := c.Get(RouteMetaNoTransform).(bool); ok && v {
+		return true
+	}
+	return strings.Contains(c.Resp.Header().Get("Cache-Control"), "no-transform")
+}
+
+// SetNoTransform marks the response of current request should not be transformed
+func (c *Context) SetNoTransform() {
+	c.Set(RouteMetaNoTransform, true)
+}
+
+// DisableTransform is a route handler marks the response should not be transformed,
+// it can be used as group handler to opt out a group of routes.
+//
+// Example:
+// 		b.Group("/events", func() { ... }, baa.DisableTransform)
+func DisableTransform(c *Context) {
+	c.SetNoTransform()
+}
+
 // Reset ...
 func (c *Context) Reset(w http.ResponseWriter, r *http.Request) {
 	c.Resp.reset(w)
@@ -90,13 +147,43 @@ func (c *Context) Reset(w http.ResponseWriter, r *http.Request) {
 	c.hi = 0
 	c.handlers = c.handlers[:len(c.baa.middleware)]
 	c.routeName = ""
+	c.routeNode = nil
+	c.path = ""
 	c.pNames = c.pNames[:0]
 	c.pValues = c.pValues[:0]
+	c.closers = c.closers[:0]
+	c.locale = ""
+	c.sse = nil
 	c.storeMutex.Lock()
 	c.store = nil
+	c.cache = nil
 	c.storeMutex.Unlock()
 }
 
+// OnClose registers a cleanup callback executed when the request finished, even on panic,
+// eg: close cursors, release locks. Callbacks are executed in LIFO order.
+func (c *Context) OnClose(fn func()) {
+	if fn != nil {
+		c.closers = append(c.closers, fn)
+	}
+}
+
+// close executes cleanup callbacks in LIFO order, a panicking callback does not stop others
+func (c *Context) close() {
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		func(fn func()) {
+			defer func() {
+				if v := recover(); v != nil {
+					c.baa.Logger().Printf("baa: OnClose callback panic: %v", v)
+				}
+			}()
+			fn()
+		}(c.closers[i])
+		c.closers[i] = nil
+	}
+	c.closers = c.closers[:0]
+}
+
 // Set store data in context
 func (c *Context) Set(key string, v interface{}) {
 	c.storeMutex.Lock()
@@ -128,12 +215,78 @@ func (c *Context) Gets() map[string]interface{} {
 	return vals
 }
 
+// Context returns the context.Context of request, it's canceled when the client
+// disconnected or the request finished, pass it to database calls and downstream
+// clients. Values set by c.Set are not visible through it, use c.WithValue.
+func (c *Context) Context() context.Context {
+	return c.Req.Context()
+}
+
+// SetContext replaces the context.Context of request, eg: with a deadline.
+//
+// Example:
+// 		ctx, cancel := context.WithTimeout(c.Context(), 2*time.Second)
+// 		c.OnClose(cancel)
+// 		c.SetContext(ctx)
+func (c *Context) SetContext(ctx context.Context) {
+	if ctx == nil {
+		panic("baa.Context.SetContext ctx can not be nil")
+	}
+	c.Req = c.Req.WithContext(ctx)
+}
+
+// WithValue adds value of key to the context.Context of request, it's visible to
+// next handlers and calls receive c.Context().
+func (c *Context) WithValue(key, val interface{}) {
+	c.SetContext(context.WithValue(c.Req.Context(), key, val))
+}
+
+// Cache returns the value of key cached in current request, loader is called to load
+// the value when it's not cached, errors are not cached. The cache is reset between requests.
+//
+// Example:
+// 		user, err := c.Cache("user", func() (interface{}, error) {
+// 			return models.FindUser(c.GetCookie("uid"))
+// 		})
+func (c *Context) Cache(key string, loader func() (interface{}, error)) (interface{}, error) {
+	c.storeMutex.RLock()
+	v, ok := c.cache[key]
+	c.storeMutex.RUnlock()
+	if ok {
+		return v, nil
+	}
+	v, err := loader()
+	if err != nil {
+		return nil, err
+	}
+	c.storeMutex.Lock()
+	if c.cache == nil {
+		c.cache = make(map[string]interface{})
+	}
+	c.cache[key] = v
+	c.storeMutex.Unlock()
+	return v, nil
+}
+
 // SetParam read route param value from uri
 func (c *Context) SetParam(name, value string) {
 	c.pNames = append(c.pNames, name)
 	c.pValues = append(c.pValues, value)
 }
 
+// keepParams removes params set after start by failed branches of matching,
+// the last n params of the matched route are kept
+func (c *Context) keepParams(start, n int) {
+	extra := len(c.pNames) - start - n
+	if extra <= 0 {
+		return
+	}
+	copy(c.pNames[start:], c.pNames[start+extra:])
+	copy(c.pValues[start:], c.pValues[start+extra:])
+	c.pNames = c.pNames[:start+n]
+	c.pValues = c.pValues[:start+n]
+}
+
 // Param get route param from context
 func (c *Context) Param(name string) string {
 	for i := len(c.pNames) - 1; i >= 0; i-- {
@@ -153,6 +306,31 @@ func (c *Context) Params() map[string]string {
 	return m
 }
 
+// ParamValues returns route param values in the order of pattern
+func (c *Context) ParamValues() []string {
+	vals := make([]string, len(c.pValues))
+	copy(vals, c.pValues)
+	return vals
+}
+
+// ParamAt get route param by index in the order of pattern, the index can be
+// resolved at registration by MetaRouteNode.ParamIndex, it avoids name comparing.
+//
+// Example:
+// 		id := b.Get("/users/:id", func(c *baa.Context) { ... }).ParamIndex("id")
+// 		c.ParamAt(id)
+func (c *Context) ParamAt(i int) string {
+	// params of matched route are at the end, previous ones may be set by backtracking
+	offset := 0
+	if c.routeNode != nil {
+		offset = len(c.pValues) - len(c.routeNode.params)
+	}
+	if i < 0 || offset < 0 || i+offset >= len(c.pValues) {
+		return ""
+	}
+	return c.pValues[i+offset]
+}
+
 // ParamInt get route param from context and format to int
 func (c *Context) ParamInt(name string) int {
 	v, _ := strconv.Atoi(c.Param(name))
@@ -203,6 +381,31 @@ func (c *Context) QueryStrings(name string) []string {
 	return []string{}
 }
 
+// QueryArray returns all values of the key in URL query string,
+// include bracketed keys, eg: ?id=1&id=2 or ?id[]=1&id[]=2
+func (c *Context) QueryArray(key string) []string {
+	values := c.Req.URL.Query()
+	vals := make([]string, 0, len(values[key])+len(values[key+"[]"]))
+	vals = append(vals, values[key]...)
+	vals = append(vals, values[key+"[]"]...)
+	return vals
+}
+
+// QueryMap returns values of bracketed keys in URL query string as a map,
+// eg: ?filter[name]=baa&filter[age]=3 returns {"name": "baa", "age": "3"} by prefix filter
+func (c *Context) QueryMap(prefix string) map[string]string {
+	m := make(map[string]string)
+	for k, v := range c.Req.URL.Query() {
+		if len(k) < len(prefix)+3 || k[:len(prefix)] != prefix || k[len(prefix)] != '[' {
+			continue
+		}
+		if i := strings.IndexByte(k[len(prefix)+1:], ']'); i > 0 && len(v) > 0 {
+			m[k[len(prefix)+1:len(prefix)+1+i]] = v[0]
+		}
+	}
+	return m
+}
+
 // QueryEscape returns escapred query result.
 func (c *Context) QueryEscape(name string) string {
 	c.ParseForm(0)
@@ -331,7 +534,55 @@ func (c *Context) SaveToFile(name, savePath string) error {
 
 // Body get raw request body and return RequestBody
 func (c *Context) Body() *RequestBody {
-	return NewRequestBody(c.Req.Body)
+	return NewRequestBody(c.bodyReader())
+}
+
+// bodyReader returns request body limited by body limit
+func (c *Context) bodyReader() io.ReadCloser {
+	if c.baa.bodyLimit > 0 {
+		return http.MaxBytesReader(c.Resp, c.Req.Body, c.baa.bodyLimit)
+	}
+	return c.Req.Body
+}
+
+// DecodeStream decodes JSON request body incrementally by fn with body limit applied,
+// it's suitable for large JSON arrays or NDJSON.
+//
+// Example:
+// 		err := c.DecodeStream(func(dec *baa.JSONDecoder) error {
+// 			for dec.More() {
+// 				var item Item
+// 				if err := dec.Decode(&item); err != nil {
+// 					return err
+// 				}
+// 				...
+// 			}
+// 			return nil
+// 		})
+func (c *Context) DecodeStream(fn func(dec *JSONDecoder) error) error {
+	body := c.bodyReader()
+	defer body.Close()
+	r := &readErrRecorder{r: body}
+	if err := fn(newJSONDecoder(r)); err != nil {
+		return err
+	}
+	// some decoders stop at read errors without reporting them, eg: the body limit
+	return r.err
+}
+
+// readErrRecorder records the first read error other than io.EOF
+type readErrRecorder struct {
+	r   io.Reader
+	err error
+}
+
+// Read reads from the underlying reader and records the error
+func (r *readErrRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
 }
 
 // SetCookie sets given cookie value to response header.
@@ -439,73 +690,123 @@ func (c *Context) Text(code int, s []byte) {
 	c.Resp.Write(s)
 }
 
-// JSON write data by json format
-func (c *Context) JSON(code int, v interface{}) {
-	var re []byte
-	var err error
-	if c.baa.debug {
-		re, err = MarshalIndent(v, "", "  ")
-	} else {
-		re, err = Marshal(v)
+// Blob write data with content type, Content-Length is set when the body
+// will not be transformed by a wrapped writer.
+func (c *Context) Blob(code int, contentType string, data []byte) {
+	c.Resp.Header().Set("Content-Type", contentType)
+	if !c.Resp.wrapped && c.Resp.Header().Get("Content-Encoding") == "" {
+		c.Resp.Header().Set("Content-Length", strconv.Itoa(len(data)))
 	}
-	if err != nil {
+	c.Resp.WriteHeader(code)
+	c.Resp.Write(data)
+}
+
+// JSON write data by json format, it's indented in debug mode or by SetPrettyPrint
+func (c *Context) JSON(code int, v interface{}) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeJSON(buf, v, c.baa.prettyPrint()); err != nil {
 		c.Error(err)
 		return
 	}
-
-	c.Resp.Header().Set("Content-Type", ApplicationJSONCharsetUTF8)
-	c.Resp.WriteHeader(code)
-	c.Resp.Write(re)
+	c.writeBuffer(code, ApplicationJSONCharsetUTF8, buf)
 }
 
 // JSONString return string by Marshal interface
 func (c *Context) JSONString(v interface{}) (string, error) {
-	var re []byte
-	var err error
-	if c.baa.debug {
-		re, err = MarshalIndent(v, "", "  ")
-	} else {
-		re, err = Marshal(v)
-	}
-	if err != nil {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeJSON(buf, v, c.baa.prettyPrint()); err != nil {
 		return "", err
 	}
-	return string(re), nil
+	return buf.String(), nil
 }
 
-// JSONP write data by jsonp format
+// jsonpCallbackRegexp matches safe JSONP callback names, eg: cb, jQuery123_456, app.cb
+var jsonpCallbackRegexp = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+
+// JSONP write data by jsonp format, it responds JSON when callback is empty,
+// and 400 Bad Request when callback is not a valid JavaScript identifier.
 func (c *Context) JSONP(code int, callback string, v interface{}) {
-	re, err := Marshal(v)
-	if err != nil {
+	if callback == "" {
+		c.JSON(code, v)
+		return
+	}
+	if !jsonpCallbackRegexp.MatchString(callback) {
+		c.Error(NewHTTPError(http.StatusBadRequest, "invalid JSONP callback"))
+		return
+	}
+	c.Resp.Header().Set("X-Content-Type-Options", "nosniff")
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.WriteString(callback + "(")
+	if err := encodeJSON(buf, v, false); err != nil {
 		c.Error(err)
 		return
 	}
+	buf.WriteString(");")
+	c.writeBuffer(code, ApplicationJavaScriptCharsetUTF8, buf)
+}
 
-	c.Resp.Header().Set("Content-Type", ApplicationJavaScriptCharsetUTF8)
+// NDJSON writes values received from ch as newline delimited JSON, each line is flushed
+// immediately. It returns nil when ch is closed, or the error when the client disconnected
+// or writing failed, the producer should stop sending then.
+func (c *Context) NDJSON(code int, ch <-chan interface{}) error {
+	c.Resp.Header().Set("Content-Type", ApplicationNDJSON)
 	c.Resp.WriteHeader(code)
-	c.Resp.Write([]byte(callback + "("))
-	c.Resp.Write(re)
-	c.Resp.Write([]byte(");"))
+	c.Resp.Flush()
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return c.Req.Context().Err()
+		case v, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			buf.Reset()
+			if err := encodeJSON(buf, v, false); err != nil {
+				return err
+			}
+			buf.WriteByte('\n')
+			if _, err := c.Resp.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			c.Resp.Flush()
+		}
+	}
 }
 
-// XML sends an XML response with status code.
+// XML sends an XML response with status code, it's indented in debug mode or by SetPrettyPrint
 func (c *Context) XML(code int, v interface{}) {
-	var re []byte
-	var err error
-	if c.baa.debug {
-		re, err = xml.MarshalIndent(v, "", "  ")
-	} else {
-		re, err = xml.Marshal(v)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	if c.baa.prettyPrint() {
+		enc.Indent("", "  ")
 	}
-	if err != nil {
+	if err := enc.Encode(v); err != nil {
 		c.Error(err)
 		return
 	}
+	c.writeBuffer(code, ApplicationXMLCharsetUTF8, buf)
+}
 
-	c.Resp.Header().Set("Content-Type", ApplicationXMLCharsetUTF8)
-	c.Resp.WriteHeader(code)
-	c.Resp.Write([]byte(xml.Header))
-	c.Resp.Write(re)
+// ServeContent replies the request using the content in the ReadSeeker,
+// Range, If-Modified-Since and If-Range requests are handled by http.ServeContent.
+// Content-Type is detected by extension of name when it is not set.
+func (c *Context) ServeContent(name string, modtime time.Time, content io.ReadSeeker) {
+	http.ServeContent(c.Resp, c.Req, name, modtime, content)
+}
+
+// ServeReaderAt replies the request using size bytes of content in the ReaderAt,
+// such as a blob store object, it supports Range requests so clients can seek.
+func (c *Context) ServeReaderAt(name string, modtime time.Time, content io.ReaderAt, size int64) {
+	c.ServeContent(name, modtime, io.NewSectionReader(content, 0, size))
 }
 
 // HTML write render data by html template engine use context.store
@@ -516,41 +817,112 @@ func (c *Context) HTML(code int, tpl string) {
 
 // Render write render data by html template engine use context.store
 func (c *Context) Render(code int, tpl string) {
-	re, err := c.Fetch(tpl)
+	data := c.baa.viewDataOf(c)
+	f := c.renderFallbackOf()
+	if f != nil && f.open(tpl) {
+		c.renderFallback(f, tpl, data)
+		return
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	err := c.Renderer().Render(buf, tpl, data)
+	if f != nil {
+		f.record(tpl, err)
+	}
 	if err != nil {
-		c.Error(err)
+		err = newTemplateError(tpl, err)
+		if f == nil {
+			c.Error(err)
+			return
+		}
+		c.baa.Logger().Println(err)
+		c.renderFallback(f, tpl, data)
 		return
 	}
-	c.Resp.Header().Set("Content-Type", TextHTMLCharsetUTF8)
-	c.Resp.WriteHeader(code)
-	c.Resp.Write(re)
+	out := getBuffer()
+	defer putBuffer(out)
+	clearBlankLines(out, buf)
+	c.writeBuffer(code, TextHTMLCharsetUTF8, out)
 }
 
-// Fetch render data by html template engine use context.store and returns data
+// Fetch render data by html template engine use context.store and returns data,
+// data of registered view data providers is injected.
 func (c *Context) Fetch(tpl string) ([]byte, error) {
-	buf := new(bytes.Buffer)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := c.Renderer().Render(buf, tpl, c.baa.viewDataOf(c)); err != nil {
+		return nil, newTemplateError(tpl, err)
+	}
 
-	if err := c.baa.Render().Render(buf, tpl, c.Gets()); err != nil {
-		return nil, err
+	out := new(bytes.Buffer)
+	clearBlankLines(out, buf)
+	return out.Bytes(), nil
+}
+
+// Fragment renders a single named block of template when the request is sent by
+// htmx (HX-Request) or Turbo (Turbo-Frame), otherwise renders the full template.
+// name is the template file and block name joined by #, eg: "users.html#list".
+// data of view data providers is used when data is nil.
+func (c *Context) Fragment(name string, data interface{}) {
+	tpl, block := name, ""
+	if i := strings.LastIndexByte(name, '#'); i >= 0 {
+		tpl, block = name[:i], name[i+1:]
+	}
+	if data == nil {
+		data = c.baa.viewDataOf(c)
 	}
 
-	// clear go template generated black lines
-	nbuf := new(bytes.Buffer)
-	r := bufio.NewReader(buf)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	var err error
+	if block != "" && c.IsFragmentRequest() {
+		fr, ok := c.Renderer().(FragmentRenderer)
+		if !ok {
+			c.Error(fmt.Errorf("baa.Fragment: render does not implement baa.FragmentRenderer"))
+			return
+		}
+		err = fr.RenderFragment(buf, tpl, block, data)
+	} else {
+		err = c.Renderer().Render(buf, tpl, data)
+	}
+	if err != nil {
+		c.Error(newTemplateError(name, err))
+		return
+	}
+
+	out := getBuffer()
+	defer putBuffer(out)
+	clearBlankLines(out, buf)
+	c.Resp.Header().Add("Vary", "HX-Request, Turbo-Frame")
+	c.writeBuffer(http.StatusOK, TextHTMLCharsetUTF8, out)
+}
+
+// IsFragmentRequest returns if the request asks for a partial page, by htmx or Turbo Frames
+func (c *Context) IsFragmentRequest() bool {
+	return c.Req.Header.Get("HX-Request") == "true" || c.Req.Header.Get("Turbo-Frame") != ""
+}
+
+// writeBuffer writes buffered response body with status code and content type.
+// Content-Length is set when the body will not be transformed by a wrapped writer,
+// such as gzip middleware.
+func (c *Context) writeBuffer(code int, contentType string, buf *bytes.Buffer) {
+	c.Blob(code, contentType, buf.Bytes())
+}
+
+// clearBlankLines clear go template generated blank lines, writes result to dst
+func clearBlankLines(dst, src *bytes.Buffer) {
+	r := bufio.NewReader(src)
 	for {
 		line, _, err := r.ReadLine()
 		if err != nil {
 			break
 		}
-		clearLine := strings.TrimSpace(string(line))
-		if len(clearLine) == 0 {
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
-		nbuf.Write(line)
-		nbuf.WriteRune('\n')
+		dst.Write(line)
+		dst.WriteByte('\n')
 	}
-
-	return nbuf.Bytes(), nil
 }
 
 // Redirect redirects the request using http.Redirect with status code.
@@ -693,6 +1065,13 @@ func (c *Context) Next() {
 	}
 }
 
+// Hijack takes over the underlying connection, the response is marked as handled,
+// returns ErrHijackNotSupported when the ResponseWriter can not be hijacked.
+// The caller is responsible for closing the connection.
+func (c *Context) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return c.Resp.Hijack()
+}
+
 // Break break the handles chain and Immediate return
 func (c *Context) Break() {
 	c.hi = len(c.handlers)
@@ -1,10 +1,13 @@
 package baa
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +16,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -32,6 +36,35 @@ func TestContextStore1(t *testing.T) {
 	})
 }
 
+// ctxKey is the key of context values in tests
+type ctxKey string
+
+func TestContextContext1(t *testing.T) {
+	Convey("context.Context of request", t, func() {
+		b2 := New()
+		var deadline bool
+		var val interface{}
+		var canceled error
+		b2.Use(func(c *Context) {
+			ctx, cancel := context.WithTimeout(c.Context(), time.Minute)
+			c.OnClose(cancel)
+			c.SetContext(ctx)
+			c.WithValue(ctxKey("user"), "baa")
+			c.Next()
+			canceled = c.Context().Err()
+		})
+		b2.Get("/ctx", func(c *Context) {
+			_, deadline = c.Context().Deadline()
+			val = c.Context().Value(ctxKey("user"))
+			So(func() { c.SetContext(nil) }, ShouldPanic)
+		})
+		serveTo(b2, "/ctx")
+		So(deadline, ShouldBeTrue)
+		So(val, ShouldEqual, "baa")
+		So(canceled, ShouldBeNil)
+	})
+}
+
 func TestContextParam1(t *testing.T) {
 	Convey("context route param", t, func() {
 		Convey("param", func() {
@@ -399,6 +432,18 @@ func TestContextWrite1(t *testing.T) {
 			})
 			w := request("GET", "/writer/json")
 			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "{\n  \"a\": \"1\"\n}")
+			So(w.Header().Get("Content-Length"), ShouldEqual, fmt.Sprint(w.Body.Len()))
+		})
+		Convey("write JSON with wrapped writer", func() {
+			b.Get("/writer/json/wrapped", func(c *Context) {
+				c.Resp.SetWriter(c.Resp.GetWriter())
+				c.JSONP(200, "cb", []int{1})
+			})
+			w := request("GET", "/writer/json/wrapped")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "cb([1]);")
+			So(w.Header().Get("Content-Length"), ShouldEqual, "")
 		})
 		Convey("write JSON error", func() {
 			b.Get("/writer/json/error", func(c *Context) {
@@ -437,6 +482,41 @@ func TestContextWrite1(t *testing.T) {
 			w := request("GET", "/writer/jsonp?callback=callback")
 			So(w.Code, ShouldEqual, http.StatusOK)
 		})
+		Convey("write JSONP callback", func() {
+			b.Get("/writer/jsonp/callback", func(c *Context) {
+				c.JSONP(200, c.Query("callback"), []int{1})
+			})
+			w := request("GET", "/writer/jsonp/callback?callback=app.cb_1")
+			So(w.Body.String(), ShouldEqual, "app.cb_1([1]);")
+			So(w.Header().Get("X-Content-Type-Options"), ShouldEqual, "nosniff")
+			So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationJavaScriptCharsetUTF8)
+			w = request("GET", "/writer/jsonp/callback")
+			So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationJSONCharsetUTF8)
+			b2 := New()
+			b2.Get("/", func(c *Context) {
+				c.JSONP(200, c.Query("callback"), []int{1})
+			})
+			w = serveTo(b2, "/?callback=alert(1)//")
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("write blob", func() {
+			b.Get("/writer/blob", func(c *Context) {
+				c.Blob(201, "image/png", []byte("png"))
+			})
+			w := request("GET", "/writer/blob")
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(w.Body.String(), ShouldEqual, "png")
+			So(w.Header().Get("Content-Type"), ShouldEqual, "image/png")
+			So(w.Header().Get("Content-Length"), ShouldEqual, "3")
+		})
+		Convey("write JSON with pretty print off", func() {
+			b2 := New()
+			b2.SetPrettyPrint(false)
+			b2.Get("/", func(c *Context) {
+				c.JSON(200, map[string]int{"a": 1})
+			})
+			So(serveTo(b2, "/").Body.String(), ShouldEqual, `{"a":1}`)
+		})
 		Convey("write JSONP error", func() {
 			b.Get("/writer/jsonp/error", func(c *Context) {
 				data := f
@@ -612,6 +692,57 @@ func TestContextBaa1(t *testing.T) {
 	})
 }
 
+func TestContextQueryArray1(t *testing.T) {
+	Convey("query array and map", t, func() {
+		b.Get("/context/array/:id/:name", func(c *Context) {
+			So(c.ParamValues(), ShouldResemble, []string{"1", "baa"})
+			So(c.QueryArray("id"), ShouldResemble, []string{"1", "2", "3"})
+			So(c.QueryArray("none"), ShouldResemble, []string{})
+			So(c.QueryMap("filter"), ShouldResemble, map[string]string{"name": "baa", "age": "3"})
+			So(c.QueryMap("none"), ShouldResemble, map[string]string{})
+		})
+		w := request("GET", "/context/array/1/baa?id=1&id=2&id[]=3&filter[name]=baa&filter[age]=3&filter[]=x&filterx[a]=1&filter[b=2")
+		So(w.Code, ShouldEqual, http.StatusOK)
+	})
+}
+
+func TestContextHijack1(t *testing.T) {
+	Convey("hijack connection", t, func() {
+		var hijacked bool
+		var writeErr, hijackErr error
+		done := make(chan struct{})
+		b2 := New()
+		b2.Use(func(c *Context) {
+			c.Next()
+			hijacked = c.Resp.Hijacked()
+			_, writeErr = c.Resp.Write([]byte("after"))
+			close(done)
+		})
+		b2.Get("/hijack", func(c *Context) {
+			conn, rw, err := c.Hijack()
+			if err != nil {
+				hijackErr = err
+				return
+			}
+			defer conn.Close()
+			rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+			rw.Flush()
+			_, _, hijackErr = c.Hijack()
+		})
+		ts := httptest.NewServer(b2)
+		defer ts.Close()
+		resp, err := http.Get(ts.URL + "/hijack")
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		So(string(body), ShouldEqual, "ok")
+		<-done
+		So(hijacked, ShouldBeTrue)
+		So(hijackErr, ShouldEqual, http.ErrHijacked)
+		So(writeErr, ShouldEqual, http.ErrHijacked)
+	})
+}
+
 // newfileUploadRequest Creates a new file upload http request with optional extra params
 func newfileUploadRequest(uri string, params map[string]string, paramName, path string) (*http.Request, error) {
 	file, err := os.Open(path)
@@ -640,3 +771,239 @@ func newfileUploadRequest(uri string, params map[string]string, paramName, path
 	req.Header.Add("Content-Type", writer.FormDataContentType())
 	return req, err
 }
+
+func TestContextParamAt1(t *testing.T) {
+	Convey("route param by index", t, func() {
+		var id, name, none string
+		ru := b.Get("/context/at/:id/:name", func(c *Context) {})
+		idx := ru.ParamIndex("name")
+		So(idx, ShouldEqual, 1)
+		So(ru.ParamIndex("none"), ShouldEqual, -1)
+		b.Get("/context/at2/:id/:name", func(c *Context) {
+			id, name, none = c.ParamAt(0), c.ParamAt(idx), c.ParamAt(2)
+		})
+		w := request("GET", "/context/at2/1/baa")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(id, ShouldEqual, "1")
+		So(name, ShouldEqual, "baa")
+		So(none, ShouldEqual, "")
+	})
+
+	Convey("params of failed branches are removed", t, func() {
+		c := NewContext(nil, nil, b)
+		c.SetParam("lang", "en")
+		c.SetParam("stale", "x")
+		c.SetParam("id", "1")
+		c.SetParam("name", "baa")
+		c.keepParams(1, 2)
+		So(c.ParamValues(), ShouldResemble, []string{"en", "1", "baa"})
+		So(c.Params(), ShouldResemble, map[string]string{"lang": "en", "id": "1", "name": "baa"})
+		c.keepParams(1, 2)
+		So(c.ParamValues(), ShouldResemble, []string{"en", "1", "baa"})
+	})
+}
+
+func BenchmarkContextParam(b2 *testing.B) {
+	c := NewContext(nil, nil, b)
+	c.routeNode = NewNode("/users/:uid/repos/:repo", nil)
+	c.SetParam("uid", "1")
+	c.SetParam("repo", "baa")
+	b2.ReportAllocs()
+	b2.ResetTimer()
+	for i := 0; i < b2.N; i++ {
+		c.Param("repo")
+	}
+}
+
+func BenchmarkContextParamAt(b2 *testing.B) {
+	c := NewContext(nil, nil, b)
+	c.routeNode = NewNode("/users/:uid/repos/:repo", nil)
+	c.SetParam("uid", "1")
+	c.SetParam("repo", "baa")
+	b2.ReportAllocs()
+	b2.ResetTimer()
+	for i := 0; i < b2.N; i++ {
+		c.ParamAt(1)
+	}
+}
+
+func BenchmarkContextParams(b2 *testing.B) {
+	c := NewContext(nil, nil, b)
+	c.SetParam("uid", "1")
+	c.SetParam("repo", "baa")
+	b2.ReportAllocs()
+	b2.ResetTimer()
+	for i := 0; i < b2.N; i++ {
+		c.Params()
+	}
+}
+
+func TestContextDecodeStream1(t *testing.T) {
+	Convey("decode json stream", t, func() {
+		b2 := New()
+		b2.SetBodyLimit(64)
+		var names []string
+		var decodeErr error
+		b2.Post("/stream", func(c *Context) {
+			names = names[:0]
+			decodeErr = c.DecodeStream(func(dec *JSONDecoder) error {
+				for dec.More() {
+					var item struct{ Name string }
+					if err := dec.Decode(&item); err != nil {
+						return err
+					}
+					names = append(names, item.Name)
+				}
+				return nil
+			})
+		})
+
+		req, _ := http.NewRequest("POST", "/stream", strings.NewReader("{\"Name\":\"a\"}\n{\"Name\":\"b\"}\n"))
+		b2.ServeHTTP(httptest.NewRecorder(), req)
+		So(decodeErr, ShouldBeNil)
+		So(names, ShouldResemble, []string{"a", "b"})
+
+		req, _ = http.NewRequest("POST", "/stream", strings.NewReader(strings.Repeat("{\"Name\":\"a\"}\n", 10)))
+		b2.ServeHTTP(httptest.NewRecorder(), req)
+		So(decodeErr, ShouldNotBeNil)
+		So(len(names), ShouldBeLessThan, 10)
+	})
+}
+
+func TestContextNDJSON1(t *testing.T) {
+	Convey("ndjson stream", t, func() {
+		b2 := New()
+		var streamErr error
+		b2.Get("/ndjson", func(c *Context) {
+			ch := make(chan interface{})
+			go func() {
+				defer close(ch)
+				for i := 1; i <= 3; i++ {
+					ch <- map[string]int{"id": i}
+				}
+			}()
+			streamErr = c.NDJSON(http.StatusOK, ch)
+		})
+		req, _ := http.NewRequest("GET", "/ndjson", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(streamErr, ShouldBeNil)
+		So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationNDJSON)
+		So(w.Body.String(), ShouldEqual, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n")
+		So(w.Flushed, ShouldBeTrue)
+
+		Convey("wrapped writer", func() {
+			w := httptest.NewRecorder()
+			b2.Get("/ndjson/wrapped", func(c *Context) {
+				c.Resp.SetWriter(bufio.NewWriter(c.Resp.GetWriter()))
+				ch := make(chan interface{}, 2)
+				ch <- 1
+				ch <- 2
+				close(ch)
+				streamErr = c.NDJSON(http.StatusOK, ch)
+			})
+			req, _ := http.NewRequest("GET", "/ndjson/wrapped", nil)
+			b2.ServeHTTP(w, req)
+			So(streamErr, ShouldBeNil)
+			// lines buffered by the wrapped writer are flushed
+			So(w.Body.String(), ShouldEqual, "1\n2\n")
+		})
+
+		Convey("client disconnected", func() {
+			b2.Get("/ndjson/cancel", func(c *Context) {
+				streamErr = c.NDJSON(http.StatusOK, make(chan interface{}))
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			req, _ := http.NewRequest("GET", "/ndjson/cancel", nil)
+			cancel()
+			b2.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+			So(streamErr, ShouldEqual, context.Canceled)
+		})
+	})
+}
+
+func TestContextServeContent1(t *testing.T) {
+	Convey("serve content with range", t, func() {
+		b2 := New()
+		modtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		b2.Get("/content", func(c *Context) {
+			c.ServeContent("a.txt", modtime, strings.NewReader("0123456789"))
+		})
+		b2.Get("/blob", func(c *Context) {
+			c.ServeReaderAt("b.json", modtime, strings.NewReader("0123456789"), 8)
+		})
+
+		req, _ := http.NewRequest("GET", "/content", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "0123456789")
+		So(w.Header().Get("Content-Type"), ShouldStartWith, "text/plain")
+
+		req, _ = http.NewRequest("GET", "/blob", nil)
+		req.Header.Set("Range", "bytes=2-")
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusPartialContent)
+		So(w.Body.String(), ShouldEqual, "234567")
+		So(w.Header().Get("Content-Range"), ShouldEqual, "bytes 2-7/8")
+		So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+
+		req, _ = http.NewRequest("GET", "/blob", nil)
+		req.Header.Set("If-Modified-Since", modtime.Format(http.TimeFormat))
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusNotModified)
+	})
+}
+
+func TestContextOnClose1(t *testing.T) {
+	Convey("request cleanup callbacks", t, func() {
+		b2 := New()
+		var order []int
+		b2.Use(func(c *Context) {
+			c.OnClose(func() { order = append(order, 1) })
+			c.Next()
+		})
+		b2.Get("/close", func(c *Context) {
+			c.OnClose(func() { order = append(order, 2) })
+			c.OnClose(func() { panic("cleanup") })
+			c.OnClose(func() { order = append(order, 3) })
+			c.OnClose(nil)
+			panic("boom")
+		})
+		req, _ := http.NewRequest("GET", "/close", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(order, ShouldResemble, []int{3, 2, 1})
+	})
+}
+
+func TestContextCache1(t *testing.T) {
+	Convey("request scoped cache", t, func() {
+		c2 := NewContext(nil, nil, b)
+		calls := 0
+		loader := func() (interface{}, error) {
+			calls++
+			return calls, nil
+		}
+		v, err := c2.Cache("user", loader)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		v, _ = c2.Cache("user", loader)
+		So(v, ShouldEqual, 1)
+		So(calls, ShouldEqual, 1)
+
+		_, err = c2.Cache("fail", func() (interface{}, error) {
+			return nil, fmt.Errorf("failed")
+		})
+		So(err, ShouldNotBeNil)
+		v, _ = c2.Cache("fail", loader)
+		So(v, ShouldEqual, 2)
+
+		c2.Reset(nil, nil)
+		v, _ = c2.Cache("user", loader)
+		So(v, ShouldEqual, 3)
+	})
+}
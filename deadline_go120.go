@@ -0,0 +1,18 @@
+// +build go1.20
+
+package baa
+
+import (
+	"net/http"
+	"time"
+)
+
+// writeDeadlineOf returns the function sets the write deadline of the connection
+// of w, nil when w does not support deadlines.
+func writeDeadlineOf(w http.ResponseWriter) func(time.Time) error {
+	rc := http.NewResponseController(w)
+	if rc.SetWriteDeadline(time.Time{}) != nil {
+		return nil
+	}
+	return rc.SetWriteDeadline
+}
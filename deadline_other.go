@@ -0,0 +1,13 @@
+// +build !go1.20
+
+package baa
+
+import (
+	"net/http"
+	"time"
+)
+
+// writeDeadlineOf returns nil as write deadlines of responses require Go 1.20
+func writeDeadlineOf(w http.ResponseWriter) func(time.Time) error {
+	return nil
+}
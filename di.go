@@ -1,6 +1,9 @@
 package baa
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -10,16 +13,66 @@ type DIer interface {
 	Get(name string) interface{}
 }
 
+// DIProvider is implemented by DIer supports lazy constructors and validation
+type DIProvider interface {
+	Provide(name string, fn func(DIer) (interface{}, error), deps ...string)
+	Validate() error
+	Graph() map[string][]string
+}
+
 // DI provlider a dependency injection service for baa
 type DI struct {
-	store map[string]interface{}
-	mutex sync.RWMutex
+	store     map[string]interface{}
+	made      map[string]interface{} // values created by providers
+	providers map[string]*diProvider
+	parent    DIer
+	mutex     sync.RWMutex
+}
+
+// diProvider is a lazy constructor of dependency
+type diProvider struct {
+	fn   func(DIer) (interface{}, error)
+	deps []string
+}
+
+// DIError is returned by DI.Validate when dependencies are not satisfiable
+type DIError struct {
+	Missing map[string][]string // dependency name -> missing dependencies
+	Cycles  [][]string          // dependency cycles, eg: [a b a]
+}
+
+// Error returns error message
+func (e *DIError) Error() string {
+	var msgs []string
+	names := make([]string, 0, len(e.Missing))
+	for name := range e.Missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s requires missing %s", name, strings.Join(e.Missing[name], ", ")))
+	}
+	for _, c := range e.Cycles {
+		msgs = append(msgs, "cycle "+strings.Join(c, " -> "))
+	}
+	return "baa DI: " + strings.Join(msgs, "; ")
 }
 
 // NewDI create a DI instance
 func NewDI() DIer {
 	d := new(DI)
 	d.store = make(map[string]interface{})
+	d.made = make(map[string]interface{})
+	d.providers = make(map[string]*diProvider)
+	return d
+}
+
+// NewChildDI create a DI instance inherits parent, names not registered in the child
+// are fetched from parent, values set to the child never change parent.
+// It lets tests and sub applications swap implementations without mutating shared state.
+func NewChildDI(parent DIer) *DI {
+	d := NewDI().(*DI)
+	d.parent = parent
 	return d
 }
 
@@ -32,9 +85,177 @@ func (d *DI) Set(name string, v interface{}) {
 }
 
 // Get fetch a di by name, return nil when name not set.
+// The provider of name is called at the first time when the value is not set,
+// it panics when the provider returns error. Parent is used when name is not registered,
+// providers of parent are called with the child so they get overrides of the child,
+// and the value is kept by the child.
 func (d *DI) Get(name string) interface{} {
 	d.mutex.RLock()
-	v := d.store[name]
+	v, ok := d.store[name]
+	if !ok {
+		v, ok = d.made[name]
+	}
+	p := d.providers[name]
 	d.mutex.RUnlock()
+	if ok {
+		return v
+	}
+	if p == nil {
+		if v, p = d.inherited(name); p == nil {
+			return v
+		}
+	}
+
+	v, err := p.fn(d)
+	if err != nil {
+		panic(fmt.Sprintf("baa DI provider %s error: %v", name, err))
+	}
+	d.mutex.Lock()
+	// keeps the value created by concurrent calls
+	if v2, ok := d.made[name]; ok {
+		v = v2
+	} else {
+		d.made[name] = v
+	}
+	d.mutex.Unlock()
 	return v
 }
+
+// inherited returns the value set or the provider of name in ancestors of d
+func (d *DI) inherited(name string) (interface{}, *diProvider) {
+	for parent := d.parent; parent != nil; {
+		pd, ok := parent.(*DI)
+		if !ok {
+			return parent.Get(name), nil
+		}
+		pd.mutex.RLock()
+		v, ok := pd.store[name]
+		p := pd.providers[name]
+		next := pd.parent
+		pd.mutex.RUnlock()
+		if ok {
+			return v, nil
+		}
+		if p != nil {
+			return nil, p
+		}
+		parent = next
+	}
+	return nil, nil
+}
+
+// Override set value of name and returns a func restores the previous value,
+// the value has higher priority than provider of name.
+//
+// Example:
+// 		defer di.Override("db", fakeDB)()
+func (d *DI) Override(name string, v interface{}) (restore func()) {
+	d.mutex.Lock()
+	prev, had := d.store[name]
+	d.store[name] = v
+	d.mutex.Unlock()
+	return func() {
+		d.mutex.Lock()
+		if had {
+			d.store[name] = prev
+		} else {
+			delete(d.store, name)
+		}
+		d.mutex.Unlock()
+	}
+}
+
+// Provide registers a lazy constructor of name depends on deps, fn is called by
+// the first Get of name and the result is kept. deps are declared for Validate and Graph.
+//
+// Example:
+// 		di.Provide("userService", func(d baa.DIer) (interface{}, error) {
+// 			return NewUserService(d.Get("db").(*sql.DB)), nil
+// 		}, "db")
+func (d *DI) Provide(name string, fn func(DIer) (interface{}, error), deps ...string) {
+	if fn == nil {
+		panic("baa DI provider can not be nil")
+	}
+	d.mutex.Lock()
+	d.providers[name] = &diProvider{fn: fn, deps: deps}
+	delete(d.store, name)
+	delete(d.made, name)
+	d.mutex.Unlock()
+}
+
+// Graph returns declared dependencies of every registered name, values set directly have no dependency.
+// Names of parent are included when parent implements DIProvider.
+func (d *DI) Graph() map[string][]string {
+	var g map[string][]string
+	if p, ok := d.parent.(DIProvider); ok {
+		g = p.Graph()
+	} else {
+		g = make(map[string][]string)
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	for name := range d.store {
+		g[name] = nil
+	}
+	for name, p := range d.providers {
+		g[name] = append([]string(nil), p.deps...)
+	}
+	return g
+}
+
+// Validate checks all declared dependencies of providers are registered and have no cycle,
+// returns *DIError when not satisfiable.
+func (d *DI) Validate() error {
+	g := d.Graph()
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	e := &DIError{Missing: make(map[string][]string)}
+	for _, name := range names {
+		for _, dep := range g[name] {
+			if _, ok := g[dep]; !ok {
+				e.Missing[name] = append(e.Missing[name], dep)
+			}
+		}
+	}
+
+	// depth first search, 1 visiting, 2 visited
+	state := make(map[string]int, len(g))
+	var path []string
+	var visit func(name string)
+	visit = func(name string) {
+		switch state[name] {
+		case 1:
+			for i := range path {
+				if path[i] == name {
+					cycle := append([]string(nil), path[i:]...)
+					e.Cycles = append(e.Cycles, append(cycle, name))
+					break
+				}
+			}
+			return
+		case 2:
+			return
+		}
+		state[name] = 1
+		path = append(path, name)
+		for _, dep := range g[name] {
+			if _, ok := g[dep]; ok {
+				visit(dep)
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = 2
+	}
+	for _, name := range names {
+		visit(name)
+	}
+
+	if len(e.Missing) == 0 && len(e.Cycles) == 0 {
+		return nil
+	}
+	return e
+}
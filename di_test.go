@@ -1,6 +1,7 @@
 package baa
 
 import (
+	"errors"
 	. "github.com/smartystreets/goconvey/convey"
 	"log"
 	"os"
@@ -66,3 +67,112 @@ func TestDIGet1(t *testing.T) {
 		So(v.(string), ShouldEqual, "hiDI")
 	})
 }
+
+func TestDIProvide1(t *testing.T) {
+	Convey("lazy provider", t, func() {
+		b2 := New()
+		calls := 0
+		b2.SetDI("dsn", "mysql://")
+		b2.ProvideDI("db", func(d DIer) (interface{}, error) {
+			calls++
+			return "db:" + d.Get("dsn").(string), nil
+		}, "dsn")
+		So(b2.ValidateDI(), ShouldBeNil)
+		So(b2.GetDI("db"), ShouldEqual, "db:mysql://")
+		So(b2.GetDI("db"), ShouldEqual, "db:mysql://")
+		So(calls, ShouldEqual, 1)
+
+		b2.ProvideDI("broken", func(d DIer) (interface{}, error) {
+			return nil, errors.New("boom")
+		})
+		So(func() { b2.GetDI("broken") }, ShouldPanic)
+	})
+	Convey("validate and graph", t, func() {
+		d := NewDI().(*DI)
+		fn := func(d DIer) (interface{}, error) { return nil, nil }
+		d.Set("config", 1)
+		d.Provide("a", fn, "b", "config")
+		d.Provide("b", fn, "c")
+		d.Provide("c", fn, "a")
+		d.Provide("x", fn, "missing")
+		So(d.Graph()["a"], ShouldResemble, []string{"b", "config"})
+		So(d.Graph()["config"], ShouldBeNil)
+
+		err := d.Validate()
+		So(err, ShouldNotBeNil)
+		e := err.(*DIError)
+		So(e.Missing, ShouldResemble, map[string][]string{"x": {"missing"}})
+		So(e.Cycles, ShouldResemble, [][]string{{"a", "b", "c", "a"}})
+		So(err.Error(), ShouldEqual, "baa DI: x requires missing missing; cycle a -> b -> c -> a")
+	})
+}
+
+func TestDIOverride1(t *testing.T) {
+	Convey("child and override", t, func() {
+		parent := NewDI().(*DI)
+		parent.Set("db", "real")
+		parent.Provide("svc", func(d DIer) (interface{}, error) {
+			return "svc", nil
+		}, "db")
+		child := NewChildDI(parent)
+		So(child.Get("db"), ShouldEqual, "real")
+		child.Set("db", "fake")
+		So(child.Get("db"), ShouldEqual, "fake")
+		So(parent.Get("db"), ShouldEqual, "real")
+		child.Provide("handler", func(d DIer) (interface{}, error) {
+			return d.Get("svc"), nil
+		}, "svc")
+		So(child.Validate(), ShouldBeNil)
+		So(child.Get("handler"), ShouldEqual, "svc")
+		So(child.Get("none"), ShouldBeNil)
+
+		restore := parent.Override("svc", "mock")
+		So(parent.Get("svc"), ShouldEqual, "mock")
+		restore()
+		So(parent.Get("svc"), ShouldEqual, "svc")
+		restore = parent.Override("new", 1)
+		restore()
+		So(parent.Get("new"), ShouldBeNil)
+	})
+	Convey("parent providers run with the child", t, func() {
+		parent := NewDI().(*DI)
+		parent.Set("db", "real")
+		parent.Provide("svc", func(d DIer) (interface{}, error) {
+			return "svc:" + d.Get("db").(string), nil
+		}, "db")
+		So(parent.Get("svc"), ShouldEqual, "svc:real")
+
+		child := NewChildDI(parent)
+		child.Set("db", "fake")
+		So(child.Get("svc"), ShouldEqual, "svc:fake")
+		So(parent.Get("svc"), ShouldEqual, "svc:real")
+
+		// values set in parent are shared
+		parent.Set("svc", "set")
+		So(NewChildDI(parent).Get("svc"), ShouldEqual, "set")
+	})
+	Convey("baa override", t, func() {
+		b2 := New()
+		cache := b2.Cache()
+		restore := b2.OverrideDI("cache", NewMemoryCache(1))
+		So(b2.Cache(), ShouldNotEqual, cache)
+		So(b2.Logger(), ShouldNotBeNil)
+		So(func() { b2.OverrideDI("cache", "not cache") }, ShouldPanic)
+		So(b2.Cache(), ShouldNotEqual, cache)
+		restore()
+		So(b2.Cache(), ShouldEqual, cache)
+
+		// requests can read DI while tests override it
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				b2.GetDI("cache")
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			b2.OverrideDI("cache", NewMemoryCache(1))()
+		}
+		<-done
+	})
+}
@@ -0,0 +1,48 @@
+package baa
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DispatchResponse is the response of a request dispatched in-process
+type DispatchResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Dispatch runs a request through middleware and routing in-process without network,
+// it's useful for batch endpoints, data loading of server side rendering and tests.
+// path must begin with "/" and may contain a query string.
+//
+// Example:
+// 		resp, err := b.Dispatch("GET", "/users/1", nil, nil)
+func (b *Baa) Dispatch(method, path string, body io.Reader, headers http.Header) (*DispatchResponse, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.New("baa: dispatch path must begin with /")
+	}
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.RemoteAddr = "127.0.0.1:0"
+	for k, v := range headers {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	return b.serveBuffered(req), nil
+}
+
+// serveBuffered serves req by b and buffers the response
+func (b *Baa) serveBuffered(req *http.Request) *DispatchResponse {
+	w := &bufferedWriter{header: make(http.Header), code: http.StatusOK, body: getBuffer()}
+	defer putBuffer(w.body)
+	b.ServeHTTP(w, req)
+	return &DispatchResponse{
+		Status: w.code,
+		Header: w.header,
+		Body:   append([]byte(nil), w.body.Bytes()...),
+	}
+}
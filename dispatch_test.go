@@ -0,0 +1,46 @@
+package baa
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDispatch1(t *testing.T) {
+	Convey("dispatch in-process", t, func() {
+		b2 := New()
+		b2.Use(func(c *Context) {
+			c.Resp.Header().Set("X-Middleware", "1")
+			c.Next()
+		})
+		b2.Get("/users/:id", func(c *Context) {
+			c.String(http.StatusOK, c.Param("id")+" "+c.Query("v")+" "+c.Req.Header.Get("Authorization"))
+		})
+		b2.Post("/echo", func(c *Context) {
+			body, _ := c.Body().String()
+			c.String(http.StatusCreated, body)
+		})
+
+		resp, err := b2.Dispatch("GET", "/users/1?v=2", nil, http.Header{"Authorization": {"Bearer t"}})
+		So(err, ShouldBeNil)
+		So(resp.Status, ShouldEqual, http.StatusOK)
+		So(resp.Header.Get("X-Middleware"), ShouldEqual, "1")
+		So(string(resp.Body), ShouldEqual, "1 2 Bearer t")
+
+		resp, err = b2.Dispatch("POST", "/echo", strings.NewReader("hello"), nil)
+		So(err, ShouldBeNil)
+		So(resp.Status, ShouldEqual, http.StatusCreated)
+		So(string(resp.Body), ShouldEqual, "hello")
+
+		resp, err = b2.Dispatch("GET", "/missing", nil, nil)
+		So(err, ShouldBeNil)
+		So(resp.Status, ShouldEqual, http.StatusNotFound)
+
+		_, err = b2.Dispatch("GET", "users", nil, nil)
+		So(err, ShouldNotBeNil)
+		_, err = b2.Dispatch("BAD METHOD", "/users/1", nil, nil)
+		So(err, ShouldNotBeNil)
+	})
+}
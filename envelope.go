@@ -0,0 +1,71 @@
+package baa
+
+import (
+	"net/http"
+)
+
+// Envelope is the default API response envelope
+type Envelope struct {
+	Code    int         `json:"code" xml:"code"`
+	Message string      `json:"message" xml:"message"`
+	Data    interface{} `json:"data" xml:"data,omitempty"`
+}
+
+// EnvelopeFunc builds the API response body by status, data and error
+type EnvelopeFunc func(c *Context, status int, data interface{}, err error) interface{}
+
+// SetEnvelope set the API response envelope builder used by c.OK, c.Created and c.Fail
+func (b *Baa) SetEnvelope(fn EnvelopeFunc) {
+	b.envelope = fn
+}
+
+// DefaultEnvelope builds Envelope, code is 0 when succeed, otherwise the status code,
+// the error message of server errors is hidden when not debug.
+func DefaultEnvelope(c *Context, status int, data interface{}, err error) interface{} {
+	if err == nil {
+		return &Envelope{Code: 0, Message: "ok", Data: data}
+	}
+	msg := err.Error()
+	if e, ok := err.(*HTTPError); ok {
+		msg = e.clientMessage(c.baa.Debug())
+	}
+	if status >= http.StatusInternalServerError && !c.baa.Debug() {
+		msg = http.StatusText(status)
+	}
+	return &Envelope{Code: status, Message: msg, Data: data}
+}
+
+// OK responds data with status 200 in the API envelope
+func (c *Context) OK(data interface{}) {
+	c.JSON(http.StatusOK, c.buildEnvelope(http.StatusOK, data, nil))
+}
+
+// Created responds data with status 201 in the API envelope, and sets Location header
+// when location is not empty.
+func (c *Context) Created(data interface{}, location string) {
+	if location != "" {
+		c.Resp.Header().Set("Location", location)
+	}
+	c.JSON(http.StatusCreated, c.buildEnvelope(http.StatusCreated, data, nil))
+}
+
+// NoContent responds status 204 without body
+func (c *Context) NoContent() {
+	c.Resp.WriteHeader(http.StatusNoContent)
+}
+
+// Fail responds error with status code in the API envelope
+func (c *Context) Fail(code int, err error) {
+	if err == nil {
+		err = NewHTTPError(code)
+	}
+	c.JSON(code, c.buildEnvelope(code, nil, err))
+}
+
+// buildEnvelope builds response body by registered envelope func
+func (c *Context) buildEnvelope(status int, data interface{}, err error) interface{} {
+	if c.baa.envelope != nil {
+		return c.baa.envelope(c, status, data, err)
+	}
+	return DefaultEnvelope(c, status, data, err)
+}
@@ -0,0 +1,74 @@
+package baa
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEnvelope1(t *testing.T) {
+	Convey("API response envelope", t, func() {
+		b2 := New()
+		b2.SetDebug(false)
+		b2.Get("/ok", func(c *Context) {
+			c.OK(map[string]int{"id": 1})
+		})
+		b2.Post("/created", func(c *Context) {
+			c.Created(map[string]int{"id": 1}, "/users/1")
+		})
+		b2.Delete("/nocontent", func(c *Context) {
+			c.NoContent()
+		})
+		b2.Get("/fail", func(c *Context) {
+			c.Fail(http.StatusBadRequest, errors.New("invalid id"))
+		})
+		b2.Get("/fail/500", func(c *Context) {
+			c.Fail(http.StatusInternalServerError, errors.New("db error"))
+		})
+		b2.Get("/fail/nil", func(c *Context) {
+			c.Fail(http.StatusForbidden, nil)
+		})
+		serve := func(method, uri string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest(method, uri, nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		w := serve("GET", "/ok")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, `{"code":0,"message":"ok","data":{"id":1}}`)
+
+		w = serve("POST", "/created")
+		So(w.Code, ShouldEqual, http.StatusCreated)
+		So(w.Header().Get("Location"), ShouldEqual, "/users/1")
+
+		w = serve("DELETE", "/nocontent")
+		So(w.Code, ShouldEqual, http.StatusNoContent)
+		So(w.Body.Len(), ShouldEqual, 0)
+
+		w = serve("GET", "/fail")
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+		So(w.Body.String(), ShouldEqual, `{"code":400,"message":"invalid id","data":null}`)
+
+		w = serve("GET", "/fail/500")
+		So(w.Body.String(), ShouldEqual, `{"code":500,"message":"Internal Server Error","data":null}`)
+
+		w = serve("GET", "/fail/nil")
+		So(w.Body.String(), ShouldEqual, `{"code":403,"message":"Forbidden","data":null}`)
+
+		b2.SetEnvelope(func(c *Context, status int, data interface{}, err error) interface{} {
+			if err != nil {
+				return map[string]interface{}{"success": false, "error": err.Error()}
+			}
+			return map[string]interface{}{"success": true, "result": data}
+		})
+		w = serve("GET", "/ok")
+		So(w.Body.String(), ShouldEqual, `{"result":{"id":1},"success":true}`)
+		w = serve("GET", "/fail")
+		So(w.Body.String(), ShouldEqual, `{"error":"invalid id","success":false}`)
+	})
+}
@@ -0,0 +1,117 @@
+package baa
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrorCategory classifies errors for monitoring, eg: dashboards split client and server errors
+type ErrorCategory string
+
+// error categories
+const (
+	ErrorCategoryClient     ErrorCategory = "client"     // caused by the request, status 4xx
+	ErrorCategoryServer     ErrorCategory = "server"     // caused by the server, status 5xx
+	ErrorCategoryDependency ErrorCategory = "dependency" // caused by a downstream service, eg: database, upstream API
+)
+
+// HTTPError is an error with HTTP status code
+type HTTPError struct {
+	Code       int           // HTTP status code
+	Message    string        // message responds to client, status text is used when empty
+	Err        error         // internal error
+	Category   ErrorCategory // error category, derived from code when empty
+	Retryable  bool          // whether the client can retry the request
+	RetryAfter time.Duration // responds as Retry-After header when greater than 0
+}
+
+// NewHTTPError create a HTTPError with status code and optional message
+func NewHTTPError(code int, message ...string) *HTTPError {
+	e := &HTTPError{Code: code}
+	if len(message) > 0 {
+		e.Message = message[0]
+	}
+	return e
+}
+
+// Error returns error message
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return http.StatusText(e.Code)
+}
+
+// clientMessage returns the message responds to client, the internal error is
+// only exposed in debug mode.
+func (e *HTTPError) clientMessage(debug bool) string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil && debug {
+		return e.Err.Error()
+	}
+	return http.StatusText(e.Code)
+}
+
+// Unwrap returns the internal error
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// WithError set internal error then returns self
+func (e *HTTPError) WithError(err error) *HTTPError {
+	e.Err = err
+	return e
+}
+
+// WithCategory set error category then returns self
+func (e *HTTPError) WithCategory(category ErrorCategory) *HTTPError {
+	e.Category = category
+	return e
+}
+
+// WithRetry marks the error retryable after d then returns self, d <= 0 means unknown
+func (e *HTTPError) WithRetry(d time.Duration) *HTTPError {
+	e.Retryable = true
+	e.RetryAfter = d
+	return e
+}
+
+// Class returns error category, it's derived from code when category is empty
+func (e *HTTPError) Class() ErrorCategory {
+	if e.Category != "" {
+		return e.Category
+	}
+	return statusCategory(e.Code)
+}
+
+// statusCategory returns error category of HTTP status code
+func statusCategory(code int) ErrorCategory {
+	if code >= 400 && code < 500 {
+		return ErrorCategoryClient
+	}
+	return ErrorCategoryServer
+}
+
+// classifyError sets Retry-After header for retryable errors and records error metrics
+func (b *Baa) classifyError(err error, c *Context) {
+	category, retryable := ErrorCategoryServer, false
+	switch e := err.(type) {
+	case *HTTPError:
+		category, retryable = e.Class(), e.Retryable
+		if e.Retryable && e.RetryAfter > 0 {
+			secs := int64((e.RetryAfter + time.Second - 1) / time.Second)
+			c.Resp.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+		}
+	case *Problem:
+		category = statusCategory(e.Status)
+	}
+	if b.requestMetrics {
+		b.Metrics().Counter("baa_http_errors_total", "route", c.RoutePattern(), "category", string(category), "retryable", strconv.FormatBool(retryable)).Inc()
+	}
+}
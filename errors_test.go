@@ -0,0 +1,81 @@
+package baa
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHTTPError1(t *testing.T) {
+	Convey("http error", t, func() {
+		err := NewHTTPError(http.StatusNotFound)
+		So(err.Error(), ShouldEqual, "Not Found")
+		err = NewHTTPError(http.StatusBadRequest, "invalid id")
+		So(err.Error(), ShouldEqual, "invalid id")
+		inner := errors.New("inner")
+		err = NewHTTPError(http.StatusBadRequest).WithError(inner)
+		So(err.Error(), ShouldEqual, "inner")
+		So(err.Unwrap(), ShouldEqual, inner)
+	})
+}
+
+func TestHTTPErrorClass1(t *testing.T) {
+	Convey("error category and retry", t, func() {
+		So(NewHTTPError(http.StatusNotFound).Class(), ShouldEqual, ErrorCategoryClient)
+		So(NewHTTPError(http.StatusInternalServerError).Class(), ShouldEqual, ErrorCategoryServer)
+		err := NewHTTPError(http.StatusServiceUnavailable).WithCategory(ErrorCategoryDependency).WithRetry(1500 * time.Millisecond)
+		So(err.Class(), ShouldEqual, ErrorCategoryDependency)
+		So(err.Retryable, ShouldBeTrue)
+
+		b2 := New()
+		b2.SetRequestMetrics(true)
+		b2.Get("/upstream", func(c *Context) {
+			c.Error(err)
+		})
+		b2.Get("/invalid", func(c *Context) {
+			c.Error(NewHTTPError(http.StatusBadRequest))
+		})
+		req, _ := http.NewRequest("GET", "/upstream", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+		So(w.Header().Get("Retry-After"), ShouldEqual, "2")
+
+		req, _ = http.NewRequest("GET", "/invalid", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+		So(w.Header().Get("Retry-After"), ShouldEqual, "")
+
+		m := b2.Metrics()
+		So(m.Counter("baa_http_errors_total", "route", "/upstream", "category", "dependency", "retryable", "true").Value(), ShouldEqual, 1)
+		So(m.Counter("baa_http_errors_total", "route", "/invalid", "category", "client", "retryable", "false").Value(), ShouldEqual, 1)
+	})
+}
+
+func TestHTTPErrorLeak1(t *testing.T) {
+	Convey("internal error is hidden from client", t, func() {
+		b2 := New()
+		b2.SetDebug(false)
+		b2.Get("/db", func(c *Context) {
+			c.Error(NewHTTPError(http.StatusInternalServerError).WithError(errors.New("pq: password authentication failed")))
+		})
+		w := serveTo(b2, "/db")
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(w.Body.String(), ShouldNotContainSubstring, "pq:")
+		So(w.Body.String(), ShouldContainSubstring, "Internal Server Error")
+
+		b2.SetProblemDetails(true)
+		w = serveTo(b2, "/db")
+		So(w.Body.String(), ShouldNotContainSubstring, "pq:")
+		So(w.Body.String(), ShouldContainSubstring, "Internal Server Error")
+
+		b2.SetDebug(true)
+		w = serveTo(b2, "/db")
+		So(w.Body.String(), ShouldContainSubstring, "pq:")
+	})
+}
@@ -0,0 +1,97 @@
+package baa
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ExportOptions static site export config
+type ExportOptions struct {
+	// Dir is the output directory, required
+	Dir string
+	// Paths are exported, default is GET routes without params
+	Paths []string
+	// FollowLinks exports local links found in exported HTML pages, eg: pages of
+	// routes with params and static files.
+	FollowLinks bool
+}
+
+// exportLinkRegexp matches root relative links in HTML
+var exportLinkRegexp = regexp.MustCompile(`(?i)(?:href|src)\s*=\s*["'](/[^/"'#?][^"'#?]*|/)["'#?]`)
+
+// Export renders GET pages to static files in dir for deploying to a CDN, requests
+// are dispatched in-process. /about is written to about/index.html, paths with a file
+// extension are written as is. It returns written files, a page not responds 200
+// stops exporting.
+//
+// Example:
+// 		files, err := b.Export(baa.ExportOptions{Dir: "dist", FollowLinks: true})
+func (b *Baa) Export(opt ExportOptions) ([]string, error) {
+	if opt.Dir == "" {
+		return nil, errors.New("baa: export dir is required")
+	}
+	paths := opt.Paths
+	if paths == nil {
+		for _, p := range b.Router().Routes()["GET"] {
+			if !strings.ContainsAny(p, ":*") {
+				paths = append(paths, p)
+			}
+		}
+		sort.Strings(paths)
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for len(paths) > 0 {
+		p := paths[0]
+		paths = paths[1:]
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		resp, err := b.Dispatch("GET", p, nil, nil)
+		if err != nil {
+			return files, fmt.Errorf("baa: export %s: %v", p, err)
+		}
+		if resp.Status != http.StatusOK {
+			return files, fmt.Errorf("baa: export %s: status %d", p, resp.Status)
+		}
+		file := filepath.Join(opt.Dir, filepath.FromSlash(exportFile(p)))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return files, err
+		}
+		if err := ioutil.WriteFile(file, resp.Body, 0644); err != nil {
+			return files, err
+		}
+		files = append(files, file)
+
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if opt.FollowLinks && mediaType == TextHTML {
+			for _, m := range exportLinkRegexp.FindAllSubmatch(resp.Body, -1) {
+				if link := string(m[1]); !seen[link] {
+					paths = append(paths, link)
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// exportFile returns file path of exported page p
+func exportFile(p string) string {
+	p = path.Clean("/" + p)
+	if path.Ext(p) != "" {
+		return p[1:]
+	}
+	return path.Join(p, "index.html")[1:]
+}
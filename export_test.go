@@ -0,0 +1,59 @@
+package baa
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExport1(t *testing.T) {
+	Convey("export static site", t, func() {
+		dir, err := ioutil.TempDir("", "baa-export")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		html := func(c *Context, s string) {
+			c.Resp.Header().Set("Content-Type", TextHTMLCharsetUTF8)
+			c.Resp.WriteHeader(http.StatusOK)
+			c.Resp.Write([]byte(s))
+		}
+		b2 := New()
+		b2.Get("/", func(c *Context) {
+			html(c, `<a href="/posts/1">post</a> <a href="//cdn.example.com/x.js">cdn</a> <a href="/about#team">about</a>`)
+		})
+		b2.Get("/about", func(c *Context) {
+			html(c, `<img src="/static/favicon.ico">`)
+		})
+		b2.Get("/posts/:id", func(c *Context) {
+			html(c, "post "+c.Param("id"))
+		})
+		b2.Get("/feed.xml", func(c *Context) {
+			c.String(http.StatusOK, "<rss></rss>")
+		})
+		b2.Static("/static", "./_fixture", false, nil)
+
+		files, err := b2.Export(ExportOptions{Dir: dir, FollowLinks: true})
+		So(err, ShouldBeNil)
+		rel := make([]string, len(files))
+		for i := range files {
+			rel[i], _ = filepath.Rel(dir, files[i])
+		}
+		So(rel, ShouldResemble, []string{
+			"index.html", "about/index.html", "feed.xml", "posts/1/index.html", "static/favicon.ico",
+		})
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "posts", "1", "index.html"))
+		So(string(data), ShouldEqual, "post 1")
+
+		files, err = b2.Export(ExportOptions{Dir: dir, Paths: []string{"/posts/2"}})
+		So(err, ShouldBeNil)
+		So(len(files), ShouldEqual, 1)
+		_, err = b2.Export(ExportOptions{Dir: dir, Paths: []string{"/missing"}})
+		So(err, ShouldNotBeNil)
+		_, err = b2.Export(ExportOptions{})
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,68 @@
+package baa
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// faviconCacheControl cache header of favicon and robots.txt
+const faviconCacheControl = "public, max-age=2592000" // 30 days
+
+// Favicon serves /favicon.ico with long cache headers, src can be:
+// a file path string, file content []byte, or a http.FileSystem contains favicon.ico.
+// The content is loaded at registration.
+func (b *Baa) Favicon(src interface{}) MetaRouteNode {
+	name := "favicon.ico"
+	modtime := time.Now()
+	var data []byte
+	var err error
+	switch v := src.(type) {
+	case string:
+		name = filepath.Base(v)
+		if fi, e := os.Stat(v); e == nil {
+			modtime = fi.ModTime()
+		}
+		data, err = ioutil.ReadFile(v)
+	case []byte:
+		data = v
+	case http.FileSystem:
+		var f http.File
+		if f, err = v.Open("/favicon.ico"); err == nil {
+			if fi, e := f.Stat(); e == nil {
+				modtime = fi.ModTime()
+			}
+			data, err = ioutil.ReadAll(f)
+			f.Close()
+		}
+	default:
+		panic("baa.Favicon src must be a path, []byte or http.FileSystem")
+	}
+	if err != nil {
+		panic("baa.Favicon load error: " + err.Error())
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" || filepath.Ext(name) == ".ico" {
+		contentType = "image/x-icon"
+	}
+	return b.Get("/favicon.ico", func(c *Context) {
+		c.Resp.Header().Set("Content-Type", contentType)
+		c.Resp.Header().Set("Cache-Control", faviconCacheControl)
+		c.ServeContent(name, modtime, bytes.NewReader(data))
+	})
+}
+
+// RobotsTxt serves /robots.txt with content and long cache headers
+func (b *Baa) RobotsTxt(content string) MetaRouteNode {
+	modtime := time.Now()
+	return b.Get("/robots.txt", func(c *Context) {
+		c.Resp.Header().Set("Content-Type", TextPlainCharsetUTF8)
+		c.Resp.Header().Set("Cache-Control", faviconCacheControl)
+		c.ServeContent("robots.txt", modtime, bytes.NewReader([]byte(content)))
+	})
+}
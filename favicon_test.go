@@ -0,0 +1,46 @@
+package baa
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFavicon1(t *testing.T) {
+	Convey("favicon and robots.txt", t, func() {
+		data, _ := ioutil.ReadFile("_fixture/favicon.ico")
+		serve := func(b2 *Baa, uri string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", uri, nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		b2 := New()
+		b2.Favicon("_fixture/favicon.ico")
+		b2.RobotsTxt("User-agent: *\nDisallow: /admin\n")
+		w := serve(b2, "/favicon.ico")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("Content-Type"), ShouldEqual, "image/x-icon")
+		So(w.Header().Get("Cache-Control"), ShouldEqual, faviconCacheControl)
+		So(w.Body.Bytes(), ShouldResemble, data)
+
+		w = serve(b2, "/robots.txt")
+		So(w.Header().Get("Content-Type"), ShouldEqual, TextPlainCharsetUTF8)
+		So(w.Body.String(), ShouldEqual, "User-agent: *\nDisallow: /admin\n")
+
+		b3 := New()
+		b3.Favicon(http.Dir("_fixture"))
+		So(serve(b3, "/favicon.ico").Body.Bytes(), ShouldResemble, data)
+
+		b4 := New()
+		b4.Favicon([]byte("icon"))
+		So(serve(b4, "/favicon.ico").Body.String(), ShouldEqual, "icon")
+
+		So(func() { New().Favicon(1) }, ShouldPanic)
+		So(func() { New().Favicon("_fixture/none.ico") }, ShouldPanic)
+	})
+}
@@ -0,0 +1,197 @@
+package baa
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldErrors is implemented by errors carry error messages of form fields,
+// keyed by field name in form.
+type FieldErrors interface {
+	FieldErrors() map[string]string
+}
+
+// FieldErrors returns error message of the bound key
+func (e *BindError) FieldErrors() map[string]string {
+	return map[string]string{e.Key: e.Err.Error()}
+}
+
+// FormField is the metadata of a form field for rendering
+type FormField struct {
+	Name     string   // input name, from tag `form` or field name
+	Label    string   // from tag `label` or field name
+	Type     string   // input type, from tag `input` or guessed by field type
+	Value    string   // current value
+	Values   []string // current values of slice field
+	Checked  bool     // value of bool field
+	Required bool     // tag `required:"true"`
+	Error    string   // validation error message
+}
+
+// Form is the metadata of form fields generated from a bound struct
+type Form struct {
+	Fields []*FormField
+	fields map[string]*FormField
+}
+
+// NewForm generates form fields from struct v, err is the bind or validation error,
+// messages of fields are taken when err implements FieldErrors.
+// It is used to render fields and re-populate values after a failed submission.
+//
+// Example:
+// 		type Signup struct {
+// 			Email string `form:"email" label:"Email" required:"true"`
+// 		}
+// 		var signup Signup
+// 		err := c.Bind(&signup)
+// 		c.Set("form", baa.NewForm(&signup, err))
+// 		{{ formInput .form "email" }}
+func NewForm(v interface{}, err error) *Form {
+	f := &Form{fields: make(map[string]*FormField)}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() == reflect.Struct {
+		f.addFields(rv)
+	}
+	if fe, ok := err.(FieldErrors); ok {
+		for k, msg := range fe.FieldErrors() {
+			f.SetError(k, msg)
+		}
+	}
+	return f
+}
+
+// addFields adds fields of struct, embedded structs are flattened
+func (f *Form) addFields(rv reflect.Value) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		name := sf.Tag.Get("form")
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			f.addFields(fv)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		field := &FormField{
+			Name:     name,
+			Label:    sf.Tag.Get("label"),
+			Type:     sf.Tag.Get("input"),
+			Required: sf.Tag.Get("required") == "true",
+		}
+		if field.Label == "" {
+			field.Label = sf.Name
+		}
+		setFormValue(field, fv)
+		f.Fields = append(f.Fields, field)
+		f.fields[name] = field
+	}
+}
+
+// setFormValue sets value and input type of field by field value
+func setFormValue(field *FormField, fv reflect.Value) {
+	for fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv = reflect.Zero(fv.Type().Elem())
+			break
+		}
+		fv = fv.Elem()
+	}
+	inputType := "text"
+	switch {
+	case fv.Type() == timeType:
+		if t := fv.Interface().(time.Time); !t.IsZero() {
+			field.Value = t.Format(time.RFC3339)
+		}
+	case fv.Type() == durationType:
+		field.Value = fv.Interface().(time.Duration).String()
+	case fv.Kind() == reflect.Bool:
+		inputType = "checkbox"
+		field.Checked = fv.Bool()
+		field.Value = "true"
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8:
+		for i := 0; i < fv.Len(); i++ {
+			field.Values = append(field.Values, fmt.Sprint(fv.Index(i).Interface()))
+		}
+		field.Value = strings.Join(field.Values, ",")
+	case fv.Kind() == reflect.Slice:
+		field.Value = string(fv.Bytes())
+	default:
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			inputType = "number"
+		}
+		field.Value = fmt.Sprint(fv.Interface())
+	}
+	if field.Type == "" {
+		field.Type = inputType
+	}
+}
+
+// Field returns field by name, returns an empty field when not found
+func (f *Form) Field(name string) *FormField {
+	if field, ok := f.fields[name]; ok {
+		return field
+	}
+	return &FormField{Name: name, Label: name, Type: "text"}
+}
+
+// SetError sets error message of field
+func (f *Form) SetError(name, msg string) {
+	if field, ok := f.fields[name]; ok {
+		field.Error = msg
+	}
+}
+
+// Valid returns true when no field has error
+func (f *Form) Valid() bool {
+	for _, v := range f.Fields {
+		if v.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// HTML renders the field as an input element followed by the error message
+func (field *FormField) HTML() template.HTML {
+	esc := template.HTMLEscapeString
+	s := `<input type="` + esc(field.Type) + `" name="` + esc(field.Name) + `" value="` + esc(field.Value) + `"`
+	if field.Checked {
+		s += " checked"
+	}
+	if field.Required {
+		s += " required"
+	}
+	if field.Error != "" {
+		s += ` class="is-invalid"`
+	}
+	s += ">"
+	if field.Error != "" {
+		s += `<span class="error">` + esc(field.Error) + "</span>"
+	}
+	return template.HTML(s)
+}
+
+// formTemplateFuncs template funcs of form rendering:
+// formField returns the field of form, formInput renders the field.
+var formTemplateFuncs = template.FuncMap{
+	"formField": func(f *Form, name string) *FormField {
+		return f.Field(name)
+	},
+	"formInput": func(f *Form, name string) template.HTML {
+		return f.Field(name).HTML()
+	},
+}
@@ -0,0 +1,67 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type signupForm struct {
+	Email    string   `form:"email" label:"Email" input:"email" required:"true"`
+	Age      int      `form:"age" label:"Age"`
+	Agree    bool     `form:"agree"`
+	Tags     []string `form:"tags"`
+	Nickname *string
+	Secret   string `form:"-"`
+	private  string
+}
+
+type fieldErrs map[string]string
+
+func (e fieldErrs) Error() string                  { return "invalid form" }
+func (e fieldErrs) FieldErrors() map[string]string { return e }
+
+func TestForm1(t *testing.T) {
+	Convey("form metadata", t, func() {
+		v := &signupForm{Email: "a@b.c", Age: 18, Agree: true, Tags: []string{"a", "b"}}
+		f := NewForm(v, fieldErrs{"email": "already taken", "none": "x"})
+		So(len(f.Fields), ShouldEqual, 5)
+		So(f.Valid(), ShouldBeFalse)
+
+		email := f.Field("email")
+		So(email.Label, ShouldEqual, "Email")
+		So(email.Type, ShouldEqual, "email")
+		So(email.Required, ShouldBeTrue)
+		So(email.Error, ShouldEqual, "already taken")
+		So(string(email.HTML()), ShouldEqual, `<input type="email" name="email" value="a@b.c" required class="is-invalid"><span class="error">already taken</span>`)
+
+		So(f.Field("age").Type, ShouldEqual, "number")
+		So(f.Field("age").Value, ShouldEqual, "18")
+		So(f.Field("agree").Checked, ShouldBeTrue)
+		So(string(f.Field("agree").HTML()), ShouldEqual, `<input type="checkbox" name="agree" value="true" checked>`)
+		So(f.Field("tags").Values, ShouldResemble, []string{"a", "b"})
+		So(f.Field("Nickname").Value, ShouldEqual, "")
+		So(f.Field("none").Type, ShouldEqual, "text")
+		So(NewForm(v, nil).Valid(), ShouldBeTrue)
+	})
+
+	Convey("re-populate after failed bind", t, func() {
+		b2 := New()
+		b2.Post("/signup", func(c *Context) {
+			var v signupForm
+			err := c.Bind(&v)
+			c.Set("form", NewForm(&v, err))
+			c.HTML(200, "_fixture/form.html")
+		})
+		req, _ := http.NewRequest("POST", "/signup", strings.NewReader("email=a%22b&age=x"))
+		req.Header.Set("Content-Type", ApplicationForm)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldContainSubstring, `value="a&#34;b"`)
+		So(w.Body.String(), ShouldContainSubstring, "Age=0")
+	})
+}
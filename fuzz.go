@@ -0,0 +1,11 @@
+// +build gofuzz
+
+package baa
+
+// Fuzz is the go-fuzz entry of route matching, seed corpus is in _fixture/fuzz/router/corpus
+//
+// 		go-fuzz-build github.com/go-baa/baa
+// 		go-fuzz -bin baa-fuzz.zip -workdir _fixture/fuzz/router
+func Fuzz(data []byte) int {
+	return FuzzRouter(data)
+}
@@ -0,0 +1,220 @@
+package baa
+
+import (
+	"strings"
+)
+
+// Group is a set of routes has same prefix and middlewares,
+// it can be passed to module constructors for registration.
+//
+// Example:
+// 		api := b.NewGroup("/api", auth)
+// 		user.Register(api.Group("/users"))
+type Group struct {
+	baa      *Baa
+	prefix   string
+	handlers []HandlerFunc
+	renderer Renderer
+	fallback *RenderFallback
+}
+
+// groupNotFound is a not found handler for routes under prefix
+type groupNotFound struct {
+	prefix  string
+	handler HandlerFunc
+}
+
+// NewGroup create a route group with prefix and handlers executed before route handlers
+func (b *Baa) NewGroup(prefix string, h ...HandlerFunc) *Group {
+	g := &Group{baa: b, prefix: strings.TrimSuffix(prefix, "/")}
+	g.handlers = append(g.handlers, h...)
+	return g
+}
+
+// GroupFunc create a route group then calls f to register routes on it, groups
+// can be nested by g.GroupFunc or g.Group.
+//
+// Example:
+// 		b.GroupFunc("/api", func(g *baa.Group) {
+// 			g.Get("/ping", ping)
+// 			g.GroupFunc("/users", func(g *baa.Group) {
+// 				g.Get("/:id", user)
+// 			}, loadUser)
+// 		}, auth)
+func (b *Baa) GroupFunc(prefix string, f func(g *Group), h ...HandlerFunc) *Group {
+	g := b.NewGroup(prefix, h...)
+	f(g)
+	return g
+}
+
+// Prefix returns the prefix of group
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+// Use registers middlewares for routes added after it
+func (g *Group) Use(m ...Middleware) {
+	for i := range m {
+		if m[i] != nil {
+			g.handlers = append(g.handlers, wrapMiddleware(m[i]))
+		}
+	}
+}
+
+// Group create a sub group inherits prefix and handlers of g
+func (g *Group) Group(prefix string, h ...HandlerFunc) *Group {
+	sg := &Group{baa: g.baa, prefix: g.prefix + strings.TrimSuffix(prefix, "/"), renderer: g.renderer, fallback: g.fallback}
+	sg.handlers = append(sg.handlers, g.handlers...)
+	sg.handlers = append(sg.handlers, h...)
+	return sg
+}
+
+// GroupFunc create a sub group of g then calls f to register routes on it
+func (g *Group) GroupFunc(prefix string, f func(g *Group), h ...HandlerFunc) *Group {
+	sg := g.Group(prefix, h...)
+	f(sg)
+	return sg
+}
+
+// chain returns group handlers followed by h, router wraps handlers in place so it's a new slice
+func (g *Group) chain(h []HandlerFunc) []HandlerFunc {
+	handlers := make([]HandlerFunc, 0, len(g.handlers)+len(h))
+	handlers = append(handlers, g.handlers...)
+	return append(handlers, h...)
+}
+
+// path returns full pattern of route
+func (g *Group) path(pattern string) string {
+	return g.prefix + pattern
+}
+
+// SetRenderer set renderer of routes added after it, it overrides the application renderer,
+// nil means routes of group have no renderer, eg: an API group.
+//
+// Example:
+// 		admin := b.NewGroup("/admin")
+// 		admin.SetRenderer(adminRender)
+func (g *Group) SetRenderer(r Renderer) {
+	if r == nil {
+		r = NoRenderer
+	}
+	g.baa.addRenderer(r)
+	g.renderer = r
+}
+
+// route set group route meta on n, group handlers are counted as the route prefix
+// so route middlewares run after them.
+func (g *Group) route(n MetaRouteNode) MetaRouteNode {
+	groupPrefix(n, len(g.handlers))
+	if g.renderer != nil {
+		n.SetMeta(RouteMetaRenderer, g.renderer)
+	}
+	if g.fallback != nil {
+		n.SetMeta(RouteMetaRenderFallback, g.fallback)
+	}
+	return n
+}
+
+// groupPrefix adds k group handlers to the prefix of routes n
+func groupPrefix(n RouteNode, k int) {
+	switch n := n.(type) {
+	case *Node:
+		n.addPrefix(k)
+	case routeNodes:
+		for i := range n {
+			groupPrefix(n[i], k)
+		}
+	}
+}
+
+// Route is a shortcut for same handlers but different HTTP methods.
+func (g *Group) Route(pattern, methods string, h ...HandlerFunc) MetaRouteNode {
+	return g.route(g.baa.Route(g.path(pattern), methods, g.chain(h)...))
+}
+
+// Any is a shortcut for all HTTP methods
+func (g *Group) Any(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return g.route(g.baa.Any(g.path(pattern), g.chain(h)...))
+}
+
+// Delete is a shortcut for g.Route(pattern, "DELETE", handlers)
+func (g *Group) Delete(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return g.route(g.baa.Delete(g.path(pattern), g.chain(h)...))
+}
+
+// Get is a shortcut for g.Route(pattern, "GET", handlers)
+func (g *Group) Get(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return g.route(g.baa.Get(g.path(pattern), g.chain(h)...))
+}
+
+// Head is a shortcut for g.Route(pattern, "HEAD", handlers)
+func (g *Group) Head(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return g.route(g.baa.Head(g.path(pattern), g.chain(h)...))
+}
+
+// Options is a shortcut for g.Route(pattern, "OPTIONS", handlers)
+func (g *Group) Options(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return g.route(g.baa.Options(g.path(pattern), g.chain(h)...))
+}
+
+// Patch is a shortcut for g.Route(pattern, "PATCH", handlers)
+func (g *Group) Patch(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return g.route(g.baa.Patch(g.path(pattern), g.chain(h)...))
+}
+
+// Post is a shortcut for g.Route(pattern, "POST", handlers)
+func (g *Group) Post(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return g.route(g.baa.Post(g.path(pattern), g.chain(h)...))
+}
+
+// Put is a shortcut for g.Route(pattern, "PUT", handlers)
+func (g *Group) Put(pattern string, h ...HandlerFunc) MetaRouteNode {
+	return g.route(g.baa.Put(g.path(pattern), g.chain(h)...))
+}
+
+// Static set static file route under group
+func (g *Group) Static(prefix string, dir string, index bool, h HandlerFunc) {
+	if prefix == "" {
+		panic("baa.Static prefix can not be empty")
+	}
+	if dir == "" {
+		panic("baa.Static dir can not be empty")
+	}
+	g.Get(prefix+"*", newStatic(g.path(prefix), dir, index, h))
+}
+
+// StaticFile shortcut for serve file under group
+func (g *Group) StaticFile(pattern string, path string) MetaRouteNode {
+	return g.Get(pattern, func(c *Context) {
+		if err := serveFile(path, c); err != nil {
+			c.Error(err)
+		}
+	})
+}
+
+// NotFound set not found handler for requests under group prefix
+func (g *Group) NotFound(h HandlerFunc) {
+	b := g.baa
+	for i := range b.groupNotFounds {
+		if b.groupNotFounds[i].prefix == g.prefix {
+			b.groupNotFounds[i].handler = h
+			return
+		}
+	}
+	b.groupNotFounds = append(b.groupNotFounds, groupNotFound{prefix: g.prefix, handler: h})
+}
+
+// notFoundOf returns the not found handler of the longest group prefix matches path
+func (b *Baa) notFoundOf(path string) HandlerFunc {
+	h := b.notFoundHandler
+	n := -1
+	for _, v := range b.groupNotFounds {
+		if len(v.prefix) <= n || !strings.HasPrefix(path, v.prefix) {
+			continue
+		}
+		if len(path) == len(v.prefix) || path[len(v.prefix)] == '/' {
+			h, n = v.handler, len(v.prefix)
+		}
+	}
+	return h
+}
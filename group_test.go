@@ -0,0 +1,107 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGroup1(t *testing.T) {
+	Convey("group object", t, func() {
+		b2 := New()
+		api := b2.NewGroup("/api/", func(c *Context) {
+			c.Resp.Header().Add("X-Group", "api")
+		})
+		So(api.Prefix(), ShouldEqual, "/api")
+		api.Use(func(c *Context) {
+			c.Resp.Header().Add("X-Use", "true")
+			c.Next()
+		})
+		api.Get("/ping", func(c *Context) {
+			c.String(200, "pong")
+		})
+		users := api.Group("/users", func(c *Context) {
+			c.Resp.Header().Add("X-Group", "users")
+		})
+		users.Post("/:id", func(c *Context) {
+			c.String(200, c.Param("id"))
+		}).Name("user")
+		users.Route("/:id/x", "PUT,DELETE", f)
+		users.Any("/any", f)
+		users.Patch("/:id", f)
+		users.Put("/:id", f)
+		users.Delete("/:id", f)
+		users.Options("/:id", f)
+		users.Head("/:id", f)
+		api.StaticFile("/file", "_fixture/index1.html")
+		api.Static("/static", "_fixture", false, nil)
+		api.NotFound(func(c *Context) {
+			c.String(404, "api not found")
+		})
+		b2.Get("/other", f)
+
+		serve := func(method, uri string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest(method, uri, nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		w := serve("GET", "/api/ping")
+		So(w.Body.String(), ShouldEqual, "pong")
+		So(w.Header()["X-Group"], ShouldResemble, []string{"api"})
+		So(w.Header().Get("X-Use"), ShouldEqual, "true")
+
+		w = serve("POST", "/api/users/1")
+		So(w.Body.String(), ShouldEqual, "1")
+		So(w.Header()["X-Group"], ShouldResemble, []string{"api", "users"})
+		So(b2.URLFor("user", 2), ShouldEqual, "/api/users/2")
+
+		So(serve("DELETE", "/api/users/1/x").Code, ShouldEqual, http.StatusOK)
+		So(serve("OPTIONS", "/api/users/any").Code, ShouldEqual, http.StatusOK)
+		So(serve("GET", "/api/file").Code, ShouldEqual, http.StatusOK)
+		So(serve("GET", "/api/static/index1.html").Code, ShouldEqual, http.StatusOK)
+
+		w = serve("GET", "/api/none")
+		So(w.Code, ShouldEqual, http.StatusNotFound)
+		So(w.Body.String(), ShouldEqual, "api not found")
+		w = serve("GET", "/apinone")
+		So(w.Body.String(), ShouldEqual, "Not Found\n")
+		So(serve("GET", "/none").Body.String(), ShouldEqual, "Not Found\n")
+
+		So(func() { api.Static("", "_fixture", false, nil) }, ShouldPanic)
+		So(func() { api.Static("/s", "", false, nil) }, ShouldPanic)
+	})
+}
+
+func TestGroupFunc1(t *testing.T) {
+	Convey("group with callback", t, func() {
+		b2 := New()
+		mark := func(v string) HandlerFunc {
+			return func(c *Context) {
+				c.Resp.Header().Add("X-Group", v)
+			}
+		}
+		api := b2.GroupFunc("/api", func(g *Group) {
+			g.Get("/ping", func(c *Context) {
+				c.String(200, "pong")
+			})
+			g.GroupFunc("/users/", func(g *Group) {
+				g.Get("/:id", func(c *Context) {
+					c.String(200, c.Param("id"))
+				})
+			}, mark("users"))
+		}, mark("api"))
+		So(api.Prefix(), ShouldEqual, "/api")
+
+		w := serveTo(b2, "/api/ping")
+		So(w.Body.String(), ShouldEqual, "pong")
+		So(w.Header()["X-Group"], ShouldResemble, []string{"api"})
+		w = serveTo(b2, "/api/users/7")
+		So(w.Body.String(), ShouldEqual, "7")
+		So(w.Header()["X-Group"], ShouldResemble, []string{"api", "users"})
+		So(serveTo(b2, "/users/7").Code, ShouldEqual, 404)
+	})
+}
@@ -0,0 +1,126 @@
+package baa
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WrapHandler converts handlers return values to HandlerFunc, supported handlers:
+// 		func(*Context)
+// 		func(*Context) error
+// 		func(*Context) (interface{}, error)
+// Returned error is handled by c.Error, returned non-nil value is sent by c.Negotiate
+// with status 200 when nothing has been written.
+//
+// Example:
+// 		b.Get("/users/:id", baa.WrapHandler(func(c *baa.Context) (interface{}, error) {
+// 			return findUser(c.ParamInt64("id"))
+// 		}))
+func WrapHandler(h interface{}) HandlerFunc {
+	switch h := h.(type) {
+	case HandlerFunc:
+		return h
+	case func(*Context):
+		return h
+	case func(*Context) error:
+		return func(c *Context) {
+			if err := h(c); err != nil {
+				c.Error(err)
+			}
+		}
+	case func(*Context) (interface{}, error):
+		return func(c *Context) {
+			v, err := h(c)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			if v != nil && !c.Resp.Wrote() {
+				c.Negotiate(http.StatusOK, v)
+			}
+		}
+	default:
+		panic("baa.WrapHandler unsupported handler type")
+	}
+}
+
+// negotiateOffers content types can be negotiated, the first is the default
+var negotiateOffers = []string{ApplicationJSON, ApplicationXML, TextPlain, TextXML}
+
+// Negotiate sends v in the format the client accepts by header Accept,
+// JSON, XML and text are supported, JSON is used when nothing matches.
+// text is only offered for string, []byte and fmt.Stringer values.
+func (c *Context) Negotiate(code int, v interface{}) {
+	switch negotiate(c.Req.Header.Get("Accept"), negotiateOffers) {
+	case ApplicationXML, TextXML:
+		c.XML(code, v)
+		return
+	case TextPlain:
+		switch t := v.(type) {
+		case string:
+			c.String(code, t)
+			return
+		case []byte:
+			c.String(code, string(t))
+			return
+		case interface{ String() string }:
+			c.String(code, t.String())
+			return
+		}
+	}
+	c.JSON(code, v)
+}
+
+// acceptRange a media range of header Accept
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiate returns the offer matches the highest quality media range of accept,
+// offers with q=0 are excluded, returns the first offer when accept is empty or nothing matches.
+func negotiate(accept string, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if accept == "" {
+		return offers[0]
+	}
+	var ranges []acceptRange
+	excluded := make(map[string]bool)
+	for _, s := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(s))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		} else {
+			excluded[mediaType] = true
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	for _, r := range ranges {
+		for _, offer := range offers {
+			if excluded[offer] {
+				continue
+			}
+			if r.mediaType == "*/*" || r.mediaType == offer ||
+				(strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(offer, r.mediaType[:len(r.mediaType)-1])) {
+				return offer
+			}
+		}
+	}
+	return offers[0]
+}
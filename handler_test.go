@@ -0,0 +1,74 @@
+package baa
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWrapHandler1(t *testing.T) {
+	Convey("handlers return values", t, func() {
+		b2 := New()
+		type user struct {
+			Name string `json:"name" xml:"name"`
+		}
+		b2.Get("/user", WrapHandler(func(c *Context) (interface{}, error) {
+			return &user{Name: "baa"}, nil
+		}))
+		b2.Get("/name", WrapHandler(func(c *Context) (interface{}, error) {
+			return "baa", nil
+		}))
+		b2.Get("/error", WrapHandler(func(c *Context) error {
+			return NewHTTPError(http.StatusConflict)
+		}))
+		b2.Get("/nothing", WrapHandler(func(c *Context) (interface{}, error) {
+			c.String(http.StatusAccepted, "accepted")
+			return nil, nil
+		}))
+		b2.Use(func(c *Context) error {
+			if c.Query("deny") != "" {
+				return errors.New("denied")
+			}
+			return nil
+		})
+		So(func() { WrapHandler(func() {}) }, ShouldPanic)
+
+		get := func(path, accept string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", path, nil)
+			req.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+		w := get("/user", "")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationJSONCharsetUTF8)
+		So(w.Body.String(), ShouldContainSubstring, `"name": "baa"`)
+
+		w = get("/user", "text/html;q=0.9, application/xml, */*;q=0.1")
+		So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationXMLCharsetUTF8)
+		So(w.Body.String(), ShouldContainSubstring, "<name>baa</name>")
+
+		w = get("/name", "text/*")
+		So(w.Body.String(), ShouldEqual, "baa")
+		w = get("/user", "text/plain")
+		So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationJSONCharsetUTF8)
+
+		So(get("/error", "").Code, ShouldEqual, http.StatusConflict)
+		w = get("/nothing", "")
+		So(w.Code, ShouldEqual, http.StatusAccepted)
+		So(w.Body.String(), ShouldEqual, "accepted")
+		So(get("/user?deny=1", "").Code, ShouldEqual, http.StatusInternalServerError)
+	})
+	Convey("negotiate", t, func() {
+		offers := []string{ApplicationJSON, ApplicationXML}
+		So(negotiate("", offers), ShouldEqual, ApplicationJSON)
+		So(negotiate("application/xml;q=0.5, application/json;q=0.8", offers), ShouldEqual, ApplicationJSON)
+		So(negotiate("application/json;q=0, application/*", offers), ShouldEqual, ApplicationXML)
+		So(negotiate("image/png", offers), ShouldEqual, ApplicationJSON)
+		So(negotiate("application/xml", offers), ShouldEqual, ApplicationXML)
+	})
+}
@@ -0,0 +1,125 @@
+package baa
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// IDGenerator generates unique ids, it's registered as DI "idgen" and used by
+// request ids, tests can replace it by a SequenceID to get deterministic ids.
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc is an adapter allows a func to be used as IDGenerator
+type IDGeneratorFunc func() string
+
+// NewID returns f()
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// RandomID returns an IDGenerator generates n random bytes from r in hex,
+// r is crypto/rand.Reader when nil. It's the default generator with 16 bytes.
+func RandomID(r io.Reader, n int) IDGenerator {
+	if r == nil {
+		r = rand.Reader
+	}
+	var mu sync.Mutex
+	return IDGeneratorFunc(func() string {
+		b := make([]byte, n)
+		mu.Lock()
+		_, err := io.ReadFull(r, b)
+		mu.Unlock()
+		if err != nil {
+			panic(fmt.Sprintf("baa.RandomID read random source error: %v", err))
+		}
+		return hex.EncodeToString(b)
+	})
+}
+
+// SequenceID returns an IDGenerator generates prefix followed by 1, 2, 3...
+func SequenceID(prefix string) IDGenerator {
+	var n uint64
+	return IDGeneratorFunc(func() string {
+		return prefix + strconv.FormatUint(atomic.AddUint64(&n, 1), 10)
+	})
+}
+
+// UUIDv7 returns an IDGenerator generates time ordered UUID version 7 by clock
+// and random source r, r is crypto/rand.Reader when nil.
+func UUIDv7(clock Clock, r io.Reader) IDGenerator {
+	if r == nil {
+		r = rand.Reader
+	}
+	var mu sync.Mutex
+	return IDGeneratorFunc(func() string {
+		var u [16]byte
+		ms := uint64(clock.Now().UnixNano() / 1e6)
+		binary.BigEndian.PutUint64(u[:8], ms<<16)
+		mu.Lock()
+		_, err := io.ReadFull(r, u[6:])
+		mu.Unlock()
+		if err != nil {
+			panic(fmt.Sprintf("baa.UUIDv7 read random source error: %v", err))
+		}
+		u[6] = u[6]&0x0f | 0x70
+		u[8] = u[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", u[:4], u[4:6], u[6:8], u[8:10], u[10:])
+	})
+}
+
+// IDGenerator return baa id generator
+func (b *Baa) IDGenerator() IDGenerator {
+	return b.GetDI("idgen").(IDGenerator)
+}
+
+// NewID generates an id by the application id generator
+func (c *Context) NewID() string {
+	return c.baa.IDGenerator().NewID()
+}
+
+// RequestID returns the request id set by RequestID middleware or sent by client
+func (c *Context) RequestID() string {
+	return c.Req.Header.Get("X-Request-ID")
+}
+
+// maxRequestIDLen is the maximum length of X-Request-ID accepted from clients
+const maxRequestIDLen = 128
+
+// RequestID returns a middleware ensures every request has an X-Request-ID, a new
+// id is generated by the application id generator when the client sends none or an
+// id longer than 128 bytes or has characters other than letters, digits, ".", "_", "-".
+// The id is set on the request so it's propagated by HTTPClient and access log,
+// and on the response.
+func RequestID() HandlerFunc {
+	return func(c *Context) {
+		id := c.Req.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = c.NewID()
+			c.Req.Header.Set("X-Request-ID", id)
+		}
+		c.Resp.Header().Set("X-Request-ID", id)
+		c.Next()
+	}
+}
+
+// validRequestID checks id is a non-empty bounded token of [A-Za-z0-9._-]
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package baa
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIDGenerator1(t *testing.T) {
+	Convey("id generators", t, func() {
+		id := RandomID(nil, 16).NewID()
+		So(id, ShouldHaveLength, 32)
+		So(RandomID(nil, 16).NewID(), ShouldNotEqual, id)
+		So(RandomID(bytes.NewReader([]byte{1, 2, 3, 4}), 4).NewID(), ShouldEqual, "01020304")
+		So(func() { RandomID(bytes.NewReader(nil), 4).NewID() }, ShouldPanic)
+
+		seq := SequenceID("req-")
+		So(seq.NewID(), ShouldEqual, "req-1")
+		So(seq.NewID(), ShouldEqual, "req-2")
+
+		clock := NewFakeClock(time.Unix(1700000000, 0))
+		uuid := UUIDv7(clock, bytes.NewReader(bytes.Repeat([]byte{0xff}, 10)))
+		So(uuid.NewID(), ShouldEqual, "018bcfe5-6800-7fff-bfff-ffffffffffff")
+		u1, u2 := UUIDv7(clock, nil).NewID(), UUIDv7(NewFakeClock(time.Unix(1700000001, 0)), nil).NewID()
+		So(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(u1), ShouldBeTrue)
+		So(u1 < u2, ShouldBeTrue)
+
+		So(func() { New().SetDI("idgen", 1) }, ShouldPanic)
+	})
+	Convey("request id middleware", t, func() {
+		b2 := New()
+		b2.SetDI("idgen", SequenceID("req-"))
+		b2.Use(RequestID())
+		var id string
+		b2.Get("/", func(c *Context) {
+			id = c.RequestID()
+		})
+		w := serveTo(b2, "/")
+		So(id, ShouldEqual, "req-1")
+		So(w.Header().Get("X-Request-ID"), ShouldEqual, "req-1")
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-ID", "client")
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(id, ShouldEqual, "client")
+		So(w.Header().Get("X-Request-ID"), ShouldEqual, "client")
+
+		for _, bad := range []string{"a b", "x\"y", "id\x1b[31m", strings.Repeat("a", 129)} {
+			req.Header.Set("X-Request-ID", bad)
+			w = httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(id, ShouldStartWith, "req-")
+			So(w.Header().Get("X-Request-ID"), ShouldEqual, id)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package baa
+
+import (
+	"runtime"
+	"time"
+)
+
+// BuildInfo describes the running application, usually populated by -ldflags at build time
+type BuildInfo struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	GitSHA    string    `json:"git_sha,omitempty"`
+	BuildTime string    `json:"build_time,omitempty"`
+	GoVersion string    `json:"go_version"`
+	Mode      string    `json:"mode"`
+	StartTime time.Time `json:"start_time"`
+}
+
+// SetBuildInfo set build info of application, GoVersion and Mode are filled when empty.
+// It's logged when the server starts and served by InfoHandler.
+//
+// Example:
+// 		// go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD)"
+// 		b.SetBuildInfo(baa.BuildInfo{Name: "api", Version: version, GitSHA: commit})
+// 		b.Get("/_info", b.InfoHandler())
+func (b *Baa) SetBuildInfo(info BuildInfo) {
+	b.buildInfo = info
+}
+
+// BuildInfo returns build info of application
+func (b *Baa) BuildInfo() BuildInfo {
+	info := b.buildInfo
+	if info.Name == "" {
+		info.Name = b.name
+	}
+	if info.GoVersion == "" {
+		info.GoVersion = runtime.Version()
+	}
+	if info.Mode == "" {
+		info.Mode = Env
+	}
+	info.StartTime = b.connStats.started
+	return info
+}
+
+// InfoHandler returns a handler responds build info in JSON, for deploy tooling
+func (b *Baa) InfoHandler() HandlerFunc {
+	return func(c *Context) {
+		c.Resp.Header().Set("Cache-Control", "no-store")
+		c.JSON(200, b.BuildInfo())
+	}
+}
+
+// logBuildInfo logs build info when the server starts
+func (b *Baa) logBuildInfo() {
+	info := b.BuildInfo()
+	if info.Name == "" && info.Version == "" {
+		return
+	}
+	sha := info.GitSHA
+	if len(sha) > 12 {
+		sha = sha[:12]
+	}
+	b.Logger().Printf("App: %s, Version: %s, Git: %s, Go: %s, Mode: %s", info.Name, info.Version, sha, info.GoVersion, info.Mode)
+}
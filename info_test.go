@@ -0,0 +1,46 @@
+package baa
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBuildInfo1(t *testing.T) {
+	Convey("build info endpoint", t, func() {
+		b2 := New()
+		b2.SetBuildInfo(BuildInfo{Name: "api", Version: "1.2.0", GitSHA: "0123456789abcdef0123"})
+		b2.Get("/_info", b2.InfoHandler())
+		info := b2.BuildInfo()
+		So(info.GoVersion, ShouldEqual, runtime.Version())
+		So(info.Mode, ShouldEqual, Env)
+		So(info.StartTime.IsZero(), ShouldBeFalse)
+
+		req, _ := http.NewRequest("GET", "/_info", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		var v map[string]interface{}
+		So(Unmarshal(w.Body.Bytes(), &v), ShouldBeNil)
+		So(v["name"], ShouldEqual, "api")
+		So(v["version"], ShouldEqual, "1.2.0")
+		So(v["git_sha"], ShouldEqual, "0123456789abcdef0123")
+		So(v["go_version"], ShouldEqual, runtime.Version())
+
+		buf := new(bytes.Buffer)
+		b2.SetDI("logger", log.New(buf, "", 0))
+		b2.logBuildInfo()
+		So(buf.String(), ShouldEqual, "App: api, Version: 1.2.0, Git: 0123456789ab, Go: "+runtime.Version()+", Mode: "+Env+"\n")
+
+		buf.Reset()
+		b3 := New()
+		b3.SetDI("logger", log.New(buf, "", 0))
+		b3.logBuildInfo()
+		So(buf.String(), ShouldEqual, "")
+	})
+}
@@ -2,10 +2,35 @@
 
 package baa
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
 
 var (
 	Marshal       = json.Marshal
 	Unmarshal     = json.Unmarshal
 	MarshalIndent = json.MarshalIndent
 )
+
+// JSONDecoder is the JSON stream decoder of current JSON implementation
+type JSONDecoder = json.Decoder
+
+// encodeJSON writes JSON encoding of v to buf, without trailing newline
+func encodeJSON(buf *bytes.Buffer, v interface{}, indent bool) error {
+	enc := json.NewEncoder(buf)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// newJSONDecoder returns a JSON stream decoder reads from r
+func newJSONDecoder(r io.Reader) *JSONDecoder {
+	return json.NewDecoder(r)
+}
@@ -2,7 +2,12 @@
 
 package baa
 
-import "github.com/json-iterator/go"
+import (
+	"bytes"
+	"io"
+
+	"github.com/json-iterator/go"
+)
 
 var (
 	json          = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -10,3 +15,24 @@ var (
 	Unmarshal     = json.Unmarshal
 	MarshalIndent = json.MarshalIndent
 )
+
+// JSONDecoder is the JSON stream decoder of current JSON implementation
+type JSONDecoder = jsoniter.Decoder
+
+// encodeJSON writes JSON encoding of v to buf, without trailing newline
+func encodeJSON(buf *bytes.Buffer, v interface{}, indent bool) error {
+	enc := json.NewEncoder(buf)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// newJSONDecoder returns a JSON stream decoder reads from r
+func newJSONDecoder(r io.Reader) *JSONDecoder {
+	return json.NewDecoder(r)
+}
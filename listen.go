@@ -0,0 +1,74 @@
+package baa
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// ValidateAddr checks addr is a valid host:port can be listened on network,
+// network is tcp, tcp4 or tcp6. The host is optional, port can be a service name.
+func ValidateAddr(network, addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("baa: invalid address %q: %v", addr, err)
+	}
+	if port == "" {
+		return fmt.Errorf("baa: invalid address %q: missing port", addr)
+	}
+	if _, err := net.LookupPort(network, port); err != nil {
+		return fmt.Errorf("baa: invalid address %q: %v", addr, err)
+	}
+	ip := net.ParseIP(host)
+	switch network {
+	case "tcp":
+	case "tcp4":
+		if ip != nil && ip.To4() == nil {
+			return fmt.Errorf("baa: invalid address %q: IPv6 host on network tcp4", addr)
+		}
+	case "tcp6":
+		if ip != nil && ip.To4() != nil {
+			return fmt.Errorf("baa: invalid address %q: IPv4 host on network tcp6", addr)
+		}
+	default:
+		return fmt.Errorf("baa: unsupported network %q, want tcp, tcp4 or tcp6", network)
+	}
+	return nil
+}
+
+// Listen validates addr then listens on network, the effective bound address is logged,
+// it's useful to find the port chosen by addr :0. The listener limits connections
+// per IP when SetMaxConnsPerIP is set. A listener passed by systemd socket activation
+// (LISTEN_FDS) bound to addr is used before creating a new one.
+//
+// Example:
+// 		ln, err := b.Listen("tcp", "127.0.0.1:0")
+// 		port := baa.ListenerPort(ln)
+func (b *Baa) Listen(network, addr string) (net.Listener, error) {
+	if err := ValidateAddr(network, addr); err != nil {
+		return nil, err
+	}
+	ln, err := b.listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if b.maxConnsPerIP > 0 {
+		b.Logger().Printf("Listen %s (%s), max %d connections per IP", ln.Addr(), network, b.maxConnsPerIP)
+		return LimitListenerPerIP(ln, b.maxConnsPerIP), nil
+	}
+	b.Logger().Printf("Listen %s (%s)", ln.Addr(), network)
+	return ln, nil
+}
+
+// ListenerPort returns the TCP port of ln, 0 if it's not a TCP listener
+func ListenerPort(ln net.Listener) int {
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
@@ -0,0 +1,117 @@
+package baa
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestListen1(t *testing.T) {
+	Convey("validate address", t, func() {
+		So(ValidateAddr("tcp", ":8080"), ShouldBeNil)
+		So(ValidateAddr("tcp", ":http"), ShouldBeNil)
+		So(ValidateAddr("tcp4", "127.0.0.1:0"), ShouldBeNil)
+		So(ValidateAddr("tcp6", "[::1]:80"), ShouldBeNil)
+		So(ValidateAddr("tcp", "localhost:80"), ShouldBeNil)
+		So(ValidateAddr("tcp", "8080"), ShouldNotBeNil)
+		So(ValidateAddr("tcp", "::1:80"), ShouldNotBeNil)
+		So(ValidateAddr("tcp", "127.0.0.1:"), ShouldNotBeNil)
+		So(ValidateAddr("tcp", ":70000"), ShouldNotBeNil)
+		So(ValidateAddr("tcp4", "[::1]:80").Error(), ShouldContainSubstring, "IPv6 host on network tcp4")
+		So(ValidateAddr("tcp6", "127.0.0.1:80").Error(), ShouldContainSubstring, "IPv4 host on network tcp6")
+		So(ValidateAddr("udp", ":80"), ShouldNotBeNil)
+	})
+	Convey("listen on ephemeral port", t, func() {
+		b2 := New()
+		logs := new(bytes.Buffer)
+		b2.SetDI("logger", log.New(logs, "", 0))
+		ln, err := b2.Listen("tcp4", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer ln.Close()
+		port := ListenerPort(ln)
+		So(port, ShouldBeGreaterThan, 0)
+		So(logs.String(), ShouldContainSubstring, "Listen 127.0.0.1:"+strconv.Itoa(port)+" (tcp4)")
+
+		_, err = b2.Listen("tcp4", "127.0.0.1:"+strconv.Itoa(port))
+		So(err, ShouldNotBeNil)
+		_, err = b2.Listen("tcp6", "127.0.0.1:0")
+		So(err, ShouldNotBeNil)
+
+		b2.SetMaxConnsPerIP(2)
+		ln2, err := b2.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer ln2.Close()
+		So(ListenerPort(ln2), ShouldBeGreaterThan, 0)
+		So(logs.String(), ShouldContainSubstring, "max 2 connections per IP")
+
+		if ln6, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+			ln6.Close()
+			ln3, err := b2.Listen("tcp6", "[::1]:0")
+			So(err, ShouldBeNil)
+			So(ln3.Addr().String(), ShouldStartWith, "[::1]:")
+			ln3.Close()
+		}
+	})
+}
+
+func TestRunAsync1(t *testing.T) {
+	Convey("run on ephemeral port", t, func() {
+		b2 := New()
+		b2.SetDI("logger", log.New(new(bytes.Buffer), "", 0))
+		b2.Get("/", func(c *Context) {
+			c.String(http.StatusOK, "hello")
+		})
+		addr, errc, err := b2.RunAsync("127.0.0.1:0")
+		So(err, ShouldBeNil)
+		So(addr.(*net.TCPAddr).Port, ShouldBeGreaterThan, 0)
+
+		client := &http.Client{Transport: &http.Transport{}}
+		resp, err := client.Get("http://" + addr.String() + "/")
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		So(string(body), ShouldEqual, "hello")
+		client.Transport.(*http.Transport).CloseIdleConnections()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		So(b2.Shutdown(ctx), ShouldBeNil)
+		So(<-errc, ShouldBeNil)
+
+		_, _, err = b2.RunAsync("127.0.0.1:99999")
+		So(err, ShouldNotBeNil)
+	})
+	Convey("shutdown right after run", t, func() {
+		b2 := New()
+		b2.SetDI("logger", log.New(new(bytes.Buffer), "", 0))
+		_, errc, err := b2.RunAsync("127.0.0.1:0")
+		So(err, ShouldBeNil)
+		So(b2.Shutdown(context.Background()), ShouldBeNil)
+		select {
+		case err = <-errc:
+			So(err, ShouldBeNil)
+		case <-time.After(time.Second):
+			So("server is still running", ShouldBeEmpty)
+		}
+	})
+	Convey("serve on listener", t, func() {
+		b2 := New()
+		b2.SetDI("logger", log.New(new(bytes.Buffer), "", 0))
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- b2.Serve(ln)
+		}()
+		ln.Close()
+		So(<-errc, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,74 @@
+package baa
+
+import (
+	"net"
+	"sync"
+)
+
+// LimitListenerPerIP returns a listener accepts at most max concurrent connections from
+// one remote IP, connections exceed the limit are closed immediately after accepted.
+// It blunts connection exhaustion attacks from a few sources.
+//
+// Example:
+// 		ln, _ := net.Listen("tcp", ":8080")
+// 		http.Serve(baa.LimitListenerPerIP(ln, 100), b)
+func LimitListenerPerIP(l net.Listener, max int) net.Listener {
+	if max <= 0 {
+		return l
+	}
+	return &limitListener{Listener: l, max: max, conns: newConnLimiter()}
+}
+
+// limitListener is a listener limits concurrent connections per ip
+type limitListener struct {
+	net.Listener
+	max   int
+	conns *connLimiter
+}
+
+// Accept waits for and returns the next connection within limit
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := remoteIP(conn.RemoteAddr())
+		if !l.conns.acquire(ip, l.max) {
+			conn.Close()
+			continue
+		}
+		return &limitConn{Conn: conn, release: func() {
+			l.conns.release(ip)
+		}}, nil
+	}
+}
+
+// limitConn releases the connection count when closed
+type limitConn struct {
+	net.Conn
+	release func()
+	once    sync.Once
+}
+
+// Close closes the connection
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+// remoteIP returns ip of address
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	if a, ok := addr.(*net.TCPAddr); ok {
+		return a.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
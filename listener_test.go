@@ -0,0 +1,106 @@
+package baa
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLimitListenerPerIP1(t *testing.T) {
+	Convey("limit connections per ip", t, func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		So(LimitListenerPerIP(ln, 0), ShouldEqual, ln)
+		ln = LimitListenerPerIP(ln, 1)
+		defer ln.Close()
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					io.Copy(conn, conn)
+					conn.Close()
+				}()
+			}
+		}()
+
+		echo := func(conn net.Conn) error {
+			conn.SetDeadline(time.Now().Add(time.Second))
+			if _, err := conn.Write([]byte("ping\n")); err != nil {
+				return err
+			}
+			_, err := bufio.NewReader(conn).ReadString('\n')
+			return err
+		}
+		c1, err := net.Dial("tcp", ln.Addr().String())
+		So(err, ShouldBeNil)
+		So(echo(c1), ShouldBeNil)
+
+		c2, err := net.Dial("tcp", ln.Addr().String())
+		So(err, ShouldBeNil)
+		So(echo(c2), ShouldNotBeNil)
+		c2.Close()
+
+		c1.Close()
+		var c3 net.Conn
+		for i := 0; i < 100; i++ {
+			c3, err = net.Dial("tcp", ln.Addr().String())
+			So(err, ShouldBeNil)
+			if err = echo(c3); err == nil {
+				break
+			}
+			c3.Close()
+			time.Sleep(10 * time.Millisecond)
+		}
+		So(err, ShouldBeNil)
+		c3.Close()
+	})
+}
+
+func TestKeepAlives1(t *testing.T) {
+	Convey("disable keep-alives of running server", t, func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		b2 := New()
+		b2.SetMaxConnsPerIP(10)
+		b2.Get("/", func(c *Context) {
+			c.String(http.StatusOK, "ok")
+		})
+		go b2.Run(addr)
+
+		client := &http.Client{Transport: &http.Transport{}}
+		var resp *http.Response
+		for i := 0; i < 100; i++ {
+			if resp, err = client.Get("http://" + addr + "/"); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		So(err, ShouldBeNil)
+		resp.Body.Close()
+		So(resp.Close, ShouldBeFalse)
+
+		b2.SetKeepAlivesEnabled(false)
+		resp, err = client.Get("http://" + addr + "/")
+		So(err, ShouldBeNil)
+		resp.Body.Close()
+		So(resp.Close, ShouldBeTrue)
+
+		// spare connections dialed by transport are not used yet
+		client.CloseIdleConnections()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		So(b2.Shutdown(ctx), ShouldBeNil)
+	})
+}
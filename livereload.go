@@ -0,0 +1,147 @@
+package baa
+
+import (
+	"bytes"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLiveReloadPath is the default path of live reload event stream
+const defaultLiveReloadPath = "/_baa/livereload"
+
+// LiveReloadOptions live reload config
+type LiveReloadOptions struct {
+	// Dirs are watched recursively, eg: template and static directories
+	Dirs []string
+	// Path of the server-sent events endpoint, default is /_baa/livereload
+	Path string
+	// Interval of polling changes, default is 500ms
+	Interval time.Duration
+}
+
+// liveReload broadcasts changes to connected browsers
+type liveReload struct {
+	changed chan struct{}
+	mu      sync.Mutex
+}
+
+// wait returns a channel closed on the next change
+func (l *liveReload) wait() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.changed
+}
+
+// notify wakes up waiting browsers
+func (l *liveReload) notify() {
+	l.mu.Lock()
+	close(l.changed)
+	l.changed = make(chan struct{})
+	l.mu.Unlock()
+}
+
+// LiveReload refreshes browsers automatically when files in watched directories
+// change, it works only in debug mode. It registers a server-sent events endpoint,
+// and injects a script connects to it into HTML pages requested by browsers.
+// Templates are parsed on each render, so changes are visible after refreshing.
+// The returned func stops watching.
+//
+// Example:
+// 		b.LiveReload(baa.LiveReloadOptions{Dirs: []string{"templates", "public"}})
+func (b *Baa) LiveReload(opt LiveReloadOptions) (stop func()) {
+	if !b.debug {
+		return func() {}
+	}
+	if len(opt.Dirs) == 0 {
+		panic("baa.LiveReload dirs can not be empty")
+	}
+	if opt.Path == "" {
+		opt.Path = defaultLiveReloadPath
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = 500 * time.Millisecond
+	}
+	l := &liveReload{changed: make(chan struct{})}
+	done := make(chan struct{})
+
+	b.Get(opt.Path, func(c *Context) {
+		h := c.Resp.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		c.Resp.WriteHeader(http.StatusOK)
+		c.Resp.Write([]byte(": connected\n\n"))
+		c.Resp.Flush()
+		select {
+		case <-l.wait():
+			c.Resp.Write([]byte("event: reload\ndata: reload\n\n"))
+			c.Resp.Flush()
+		case <-c.Req.Context().Done():
+		case <-b.Draining():
+		case <-done:
+		}
+	}).SetMeta(RouteMetaNoTransform, true)
+
+	script := []byte(`<script>(function(){var es=new EventSource("` + opt.Path +
+		`");es.addEventListener("reload",function(){es.close();location.reload()})})()</script>`)
+	inject := TransformBody(func(c *Context, body []byte) ([]byte, error) {
+		i := bytes.LastIndex(bytes.ToLower(body), []byte("</body>"))
+		if i < 0 {
+			return body, nil
+		}
+		out := make([]byte, 0, len(body)+len(script))
+		out = append(out, body[:i]...)
+		out = append(out, script...)
+		return append(out, body[i:]...), nil
+	}, TextHTML)
+	b.UseIf(func(c *Context) bool {
+		return c.Req.Method == http.MethodGet && c.Req.URL.Path != opt.Path &&
+			strings.Contains(c.Req.Header.Get("Accept"), TextHTML)
+	}, inject)
+
+	go func() {
+		ticker := time.NewTicker(opt.Interval)
+		defer ticker.Stop()
+		sum := dirsChecksum(opt.Dirs)
+		for {
+			select {
+			case <-ticker.C:
+				if s := dirsChecksum(opt.Dirs); s != sum {
+					sum = s
+					b.Logger().Println("baa livereload: files changed")
+					l.notify()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// dirsChecksum returns checksum of file names, sizes and modification times in dirs
+func dirsChecksum(dirs []string) uint64 {
+	h := fnv.New64a()
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			h.Write([]byte(path))
+			h.Write([]byte(strconv.FormatInt(info.Size(), 10)))
+			h.Write([]byte(strconv.FormatInt(info.ModTime().UnixNano(), 10)))
+			return nil
+		})
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,74 @@
+package baa
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLiveReload1(t *testing.T) {
+	Convey("live reload on change", t, func() {
+		dir, err := ioutil.TempDir("", "baa-livereload")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "index.html")
+		ioutil.WriteFile(file, []byte("v1"), 0644)
+
+		b2 := New()
+		stop := b2.LiveReload(LiveReloadOptions{Dirs: []string{dir}, Interval: 10 * time.Millisecond})
+		defer stop()
+		b2.Get("/", func(c *Context) {
+			c.Resp.Header().Set("Content-Type", TextHTMLCharsetUTF8)
+			c.Resp.Write([]byte("<html><body>baa</BODY></html>"))
+		})
+		b2.Get("/api", func(c *Context) {
+			c.String(http.StatusOK, "</body>")
+		})
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldStartWith, "<html><body>baa<script>")
+		So(w.Body.String(), ShouldContainSubstring, `new EventSource("/_baa/livereload")`)
+		So(w.Body.String(), ShouldEndWith, "</script></BODY></html>")
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "<html><body>baa</BODY></html>")
+		req, _ = http.NewRequest("GET", "/api", nil)
+		req.Header.Set("Accept", "text/html")
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "</body>")
+
+		ts := httptest.NewServer(b2)
+		defer ts.Close()
+		resp, err := http.Get(ts.URL + "/_baa/livereload")
+		So(err, ShouldBeNil)
+		defer resp.Body.Close()
+		So(resp.Header.Get("Content-Type"), ShouldEqual, "text/event-stream")
+		r := bufio.NewReader(resp.Body)
+		line, _ := r.ReadString('\n')
+		So(line, ShouldEqual, ": connected\n")
+
+		os.Chtimes(file, time.Now().Add(time.Hour), time.Now().Add(time.Hour))
+		body, _ := ioutil.ReadAll(r)
+		So(strings.TrimSpace(string(body)), ShouldEqual, "event: reload\ndata: reload")
+	})
+	Convey("live reload is disabled in production", t, func() {
+		b2 := New()
+		b2.SetDebug(false)
+		b2.LiveReload(LiveReloadOptions{})()
+		So(len(b2.Router().Routes()["GET"]), ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,120 @@
+package baa
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SetLocales sets locales recognized as path prefix, eg: /en/..., /zh/...,
+// the prefix is stripped before route matching and can be read by c.Locale().
+// The first locale is the default locale.
+func (b *Baa) SetLocales(locales ...string) {
+	b.locales = b.locales[:0]
+	for _, v := range locales {
+		if v = strings.Trim(v, "/ "); v != "" {
+			b.locales = append(b.locales, v)
+		}
+	}
+}
+
+// Locales returns registered locales
+func (b *Baa) Locales() []string {
+	return b.locales
+}
+
+// LocaleURLFor use named route return format url prefixed with locale
+func (b *Baa) LocaleURLFor(locale, name string, args ...interface{}) string {
+	u := b.URLFor(name, args...)
+	if u == "" || locale == "" {
+		return u
+	}
+	return "/" + locale + u
+}
+
+// stripLocale returns path without locale prefix and the locale
+func (b *Baa) stripLocale(path string) (string, string) {
+	if len(path) < 2 {
+		return path, ""
+	}
+	seg := path[1:]
+	if i := strings.IndexByte(seg, '/'); i >= 0 {
+		seg = seg[:i]
+	}
+	for _, v := range b.locales {
+		if strings.EqualFold(v, seg) {
+			path = path[len(seg)+1:]
+			if path == "" {
+				path = "/"
+			}
+			return path, v
+		}
+	}
+	return path, ""
+}
+
+// Locale returns locale of current request, it's the locale path prefix, or the best
+// match of Accept-Language header, or the default locale.
+// returns empty string when no locales registered.
+func (c *Context) Locale() string {
+	if c.locale != "" || len(c.baa.locales) == 0 {
+		return c.locale
+	}
+	c.locale = matchLocale(c.Req.Header.Get("Accept-Language"), c.baa.locales)
+	return c.locale
+}
+
+// URLFor use named route return format url, prefixed with locale of
+// current request when locales registered.
+func (c *Context) URLFor(name string, args ...interface{}) string {
+	return c.baa.LocaleURLFor(c.Locale(), name, args...)
+}
+
+// matchLocale returns the best locale matches Accept-Language, returns the first locale if none
+func matchLocale(header string, locales []string) string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		l := lang{tag: v, q: 1}
+		if i := strings.IndexByte(v, ';'); i >= 0 {
+			l.tag = strings.TrimSpace(v[:i])
+			if p := strings.TrimSpace(v[i+1:]); strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					l.q = q
+				}
+			}
+		}
+		if l.q > 0 {
+			langs = append(langs, l)
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	for _, l := range langs {
+		for _, v := range locales {
+			if strings.EqualFold(v, l.tag) {
+				return v
+			}
+		}
+		// base language, eg: zh-CN matches zh
+		base := l.tag
+		if i := strings.IndexByte(base, '-'); i >= 0 {
+			base = base[:i]
+		}
+		for _, v := range locales {
+			if strings.EqualFold(v, base) {
+				return v
+			}
+		}
+	}
+	return locales[0]
+}
@@ -0,0 +1,55 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLocale1(t *testing.T) {
+	Convey("locale routing", t, func() {
+		b2 := New()
+		b2.SetLocales("en", "/zh-CN/", "fr")
+		So(b2.Locales(), ShouldResemble, []string{"en", "zh-CN", "fr"})
+		var locale, link string
+		b2.Get("/", func(c *Context) {
+			locale = c.Locale()
+		})
+		b2.Get("/users/:id", func(c *Context) {
+			locale = c.Locale()
+			link = c.URLFor("user", 2)
+		}).Name("user")
+
+		serve := func(uri, lang string) int {
+			req, _ := http.NewRequest("GET", uri, nil)
+			req.Header.Set("Accept-Language", lang)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		So(serve("/zh-cn/users/1", ""), ShouldEqual, http.StatusOK)
+		So(locale, ShouldEqual, "zh-CN")
+		So(link, ShouldEqual, "/zh-CN/users/2")
+
+		So(serve("/fr", ""), ShouldEqual, http.StatusOK)
+		So(locale, ShouldEqual, "fr")
+
+		So(serve("/users/1", "de;q=0.9, fr;q=0.5, zh-TW;q=0.1"), ShouldEqual, http.StatusOK)
+		So(locale, ShouldEqual, "fr")
+		So(serve("/users/1", "de"), ShouldEqual, http.StatusOK)
+		So(locale, ShouldEqual, "en")
+		So(link, ShouldEqual, "/en/users/2")
+
+		So(serve("/english/users/1", ""), ShouldEqual, http.StatusNotFound)
+		So(b2.LocaleURLFor("", "user", 1), ShouldEqual, "/users/1")
+		So(b2.LocaleURLFor("en", "none"), ShouldEqual, "")
+	})
+	Convey("match locale", t, func() {
+		So(matchLocale("zh-CN,zh;q=0.9,en;q=0.8", []string{"en", "zh"}), ShouldEqual, "zh")
+		So(matchLocale("en;q=0, fr", []string{"en", "fr"}), ShouldEqual, "fr")
+		So(matchLocale("", []string{"en", "fr"}), ShouldEqual, "en")
+	})
+}
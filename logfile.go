@@ -0,0 +1,321 @@
+package baa
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logFileTimeFormat is the timestamp inserted into rotated file names
+const logFileTimeFormat = "20060102T150405.000"
+
+// LogFileOptions log file output config
+type LogFileOptions struct {
+	// Filename is the file to write logs to, the directory is created if not exists
+	Filename string
+	// MaxSize rotates the file when its size exceeds MaxSize bytes, 0 disables
+	MaxSize int64
+	// Interval rotates the file every interval, eg: 24h, 0 disables
+	Interval time.Duration
+	// MaxAge removes rotated files older than MaxAge, 0 keeps them
+	MaxAge time.Duration
+	// MaxBackups keeps at most MaxBackups rotated files, 0 keeps all
+	MaxBackups int
+	// Compress compresses rotated files by gzip
+	Compress bool
+}
+
+// LogFile is a io.WriteCloser writes to a file rotated by size and time,
+// rotated files are named like app-20060102T150405.000.log next to the file.
+type LogFile struct {
+	opt      LogFileOptions
+	file     *os.File
+	size     int64
+	rotateAt time.Time
+	closed   bool
+	pending  []string // rotated files waiting for compression and cleanup
+	working  bool     // worker of pending files is running
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+// logBackup is a rotated file, files are its plain and compressed names which exist
+type logBackup struct {
+	files []string
+	t     time.Time
+	n     int
+}
+
+// NewLogFile open or create the log file with options
+func NewLogFile(opt LogFileOptions) (*LogFile, error) {
+	if opt.Filename == "" {
+		return nil, fmt.Errorf("baa.NewLogFile filename can not be empty")
+	}
+	l := &LogFile{opt: opt}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Write writes p to the file, rotates it first when size or time is reached
+func (l *LogFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.reopen(); err != nil {
+		return 0, err
+	}
+	if (l.opt.MaxSize > 0 && l.size > 0 && l.size+int64(len(p)) > l.opt.MaxSize) ||
+		(!l.rotateAt.IsZero() && !time.Now().Before(l.rotateAt)) {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it with a timestamp then opens a new file
+func (l *LogFile) Rotate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.reopen(); err != nil {
+		return err
+	}
+	return l.rotate()
+}
+
+// Close closes the file and waits for compression and cleanup of rotated files
+func (l *LogFile) Close() error {
+	l.mu.Lock()
+	var err error
+	l.closed = true
+	if l.file != nil {
+		err = l.file.Close()
+		l.file = nil
+	}
+	l.mu.Unlock()
+	l.wg.Wait()
+	return err
+}
+
+// open opens the log file for appending
+func (l *LogFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.opt.Filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.opt.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file = f
+	l.size = info.Size()
+	if l.opt.Interval > 0 {
+		l.rotateAt = time.Now().Truncate(l.opt.Interval).Add(l.opt.Interval)
+	}
+	return nil
+}
+
+// reopen opens the file again when a rotation failed to, must be called with lock held
+func (l *LogFile) reopen() error {
+	if l.closed {
+		return os.ErrClosed
+	}
+	if l.file == nil {
+		return l.open()
+	}
+	return nil
+}
+
+// rotate renames the current file and opens a new one, must be called with lock held.
+// The file is reopened at the original path when the rename fails, and by the next
+// write when the reopen fails.
+func (l *LogFile) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	name := l.backupName(time.Now())
+	if err := os.Rename(l.opt.Filename, name); err != nil {
+		l.open()
+		return err
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+	l.pending = append(l.pending, name)
+	if !l.working {
+		l.working = true
+		l.wg.Add(1)
+		go l.work()
+	}
+	return nil
+}
+
+// work compresses pending rotated files and cleans up backups one by one,
+// there is at most one worker so they never race on the same files.
+func (l *LogFile) work() {
+	defer l.wg.Done()
+	for {
+		l.mu.Lock()
+		if len(l.pending) == 0 {
+			l.working = false
+			l.mu.Unlock()
+			return
+		}
+		name := l.pending[0]
+		l.pending = l.pending[1:]
+		l.mu.Unlock()
+		if l.opt.Compress {
+			compressLogFile(name)
+		}
+		l.cleanup()
+	}
+}
+
+// backupName returns a unused rotated file name of time t
+func (l *LogFile) backupName(t time.Time) string {
+	ext := filepath.Ext(l.opt.Filename)
+	prefix := strings.TrimSuffix(l.opt.Filename, ext) + "-" + t.Format(logFileTimeFormat)
+	name := prefix + ext
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s.%d%s", prefix, i, ext)
+	}
+	return name
+}
+
+// backups returns rotated files newest first by timestamp and counter,
+// a file and its compressed copy are one backup.
+func (l *LogFile) backups() []*logBackup {
+	ext := filepath.Ext(l.opt.Filename)
+	prefix := strings.TrimSuffix(l.opt.Filename, ext) + "-"
+	files, _ := filepath.Glob(prefix + "*")
+	var backups []*logBackup
+	index := make(map[string]*logBackup)
+	for _, name := range files {
+		base := strings.TrimSuffix(name, ".gz")
+		if v, ok := index[base]; ok {
+			v.files = append(v.files, name)
+			continue
+		}
+		t, n, ok := parseLogBackup(strings.TrimPrefix(base, prefix), ext)
+		if !ok {
+			continue
+		}
+		v := &logBackup{files: []string{name}, t: t, n: n}
+		index[base] = v
+		backups = append(backups, v)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].t.Equal(backups[j].t) {
+			return backups[i].t.After(backups[j].t)
+		}
+		return backups[i].n > backups[j].n
+	})
+	return backups
+}
+
+// parseLogBackup parses name made of a timestamp with an optional .N counter followed by ext,
+// the suffix of rotated file names made by backupName.
+func parseLogBackup(name, ext string) (time.Time, int, bool) {
+	if !strings.HasSuffix(name, ext) || len(name) < len(logFileTimeFormat)+len(ext) {
+		return time.Time{}, 0, false
+	}
+	t, err := time.Parse(logFileTimeFormat, name[:len(logFileTimeFormat)])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	s := name[len(logFileTimeFormat) : len(name)-len(ext)]
+	if s == "" {
+		return t, 0, true
+	}
+	if len(s) < 2 || s[0] != '.' {
+		return time.Time{}, 0, false
+	}
+	var n int
+	for i := 1; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return time.Time{}, 0, false
+		}
+		n = n*10 + int(s[i]-'0')
+	}
+	return t, n, true
+}
+
+// cleanup removes rotated files exceed MaxBackups or MaxAge
+func (l *LogFile) cleanup() {
+	if l.opt.MaxAge <= 0 && l.opt.MaxBackups <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-l.opt.MaxAge)
+	for i, v := range l.backups() {
+		for _, name := range v.files {
+			if l.opt.MaxBackups > 0 && i >= l.opt.MaxBackups {
+				os.Remove(name)
+				continue
+			}
+			if l.opt.MaxAge > 0 {
+				if info, err := os.Stat(name); err == nil && info.ModTime().Before(deadline) {
+					os.Remove(name)
+				}
+			}
+		}
+	}
+}
+
+// compressLogFile compresses name to name.gz then removes it
+func compressLogFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
+
+// fileExists checks name exists
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// SetLogFile replaces the default logger with a logger writes to a rotated file,
+// the returned LogFile can be closed on shutdown or shared with other loggers,
+// eg: as the sink of access log.
+func (b *Baa) SetLogFile(opt LogFileOptions) (*LogFile, error) {
+	f, err := NewLogFile(opt)
+	if err != nil {
+		return nil, err
+	}
+	b.SetDI("logger", log.New(f, "[Baa] ", log.LstdFlags))
+	return f, nil
+}
@@ -0,0 +1,164 @@
+package baa
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogFile1(t *testing.T) {
+	Convey("log file rotation", t, func() {
+		dir, err := ioutil.TempDir("", "baa-log")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		name := filepath.Join(dir, "logs", "app.log")
+
+		Convey("rotate by size and compress", func() {
+			f, err := NewLogFile(LogFileOptions{Filename: name, MaxSize: 10, Compress: true})
+			So(err, ShouldBeNil)
+			for _, s := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n"} {
+				_, err = f.Write([]byte(s))
+				So(err, ShouldBeNil)
+			}
+			So(f.Close(), ShouldBeNil)
+			_, err = f.Write([]byte("x"))
+			So(err, ShouldNotBeNil)
+
+			data, _ := ioutil.ReadFile(name)
+			So(string(data), ShouldEqual, "cccccccc\n")
+			backups := f.backups()
+			So(backups, ShouldHaveLength, 2)
+			var all []string
+			for _, v := range backups {
+				So(v.files, ShouldHaveLength, 1)
+				So(strings.HasSuffix(v.files[0], ".log.gz"), ShouldBeTrue)
+				r, _ := os.Open(v.files[0])
+				zr, err := gzip.NewReader(r)
+				So(err, ShouldBeNil)
+				data, _ := ioutil.ReadAll(zr)
+				r.Close()
+				all = append(all, string(data))
+			}
+			So(all, ShouldContain, "aaaaaaaa\n")
+			So(all, ShouldContain, "bbbbbbbb\n")
+		})
+
+		Convey("max backups and max age", func() {
+			f, err := NewLogFile(LogFileOptions{Filename: name, MaxBackups: 2, MaxAge: time.Hour})
+			So(err, ShouldBeNil)
+			old := filepath.Join(dir, "logs", "app-20000101T000000.000.log")
+			So(ioutil.WriteFile(old, []byte("old"), 0644), ShouldBeNil)
+			past := time.Now().Add(-2 * time.Hour)
+			So(os.Chtimes(old, past, past), ShouldBeNil)
+			for i := 0; i < 4; i++ {
+				f.Write([]byte("line\n"))
+				So(f.Rotate(), ShouldBeNil)
+			}
+			So(f.Close(), ShouldBeNil)
+			So(f.backups(), ShouldHaveLength, 2)
+			So(fileExists(old), ShouldBeFalse)
+		})
+
+		Convey("backups are ordered by timestamp and counter", func() {
+			f, err := NewLogFile(LogFileOptions{Filename: name, MaxBackups: 2})
+			So(err, ShouldBeNil)
+			for _, v := range []string{"app-20000101T000000.000.log", "app-20000101T000000.000.1.log.gz", "app-20000101T000000.000.2.log",
+				"app-20000101T000000.000.2.log.gz", "app-20000101T000000.000.10.log", "app-19990101T000000.000.11.log"} {
+				So(ioutil.WriteFile(filepath.Join(dir, "logs", v), []byte("old"), 0644), ShouldBeNil)
+			}
+			backups := f.backups()
+			So(backups, ShouldHaveLength, 5)
+			var names []string
+			for _, v := range backups {
+				names = append(names, filepath.Base(v.files[0]))
+			}
+			So(names, ShouldResemble, []string{"app-20000101T000000.000.10.log", "app-20000101T000000.000.2.log",
+				"app-20000101T000000.000.1.log.gz", "app-20000101T000000.000.log", "app-19990101T000000.000.11.log"})
+			So(backups[1].files, ShouldHaveLength, 2)
+
+			f.cleanup()
+			So(f.Close(), ShouldBeNil)
+			So(f.backups(), ShouldHaveLength, 2)
+			So(fileExists(filepath.Join(dir, "logs", "app-20000101T000000.000.10.log")), ShouldBeTrue)
+			So(fileExists(filepath.Join(dir, "logs", "app-20000101T000000.000.2.log.gz")), ShouldBeTrue)
+			So(fileExists(filepath.Join(dir, "logs", "app-20000101T000000.000.1.log.gz")), ShouldBeFalse)
+		})
+
+		Convey("rotations are compressed and cleaned up in order", func() {
+			f, err := NewLogFile(LogFileOptions{Filename: name, MaxBackups: 3, Compress: true})
+			So(err, ShouldBeNil)
+			for i := 0; i < 20; i++ {
+				f.Write([]byte("line\n"))
+				So(f.Rotate(), ShouldBeNil)
+			}
+			So(f.Close(), ShouldBeNil)
+			backups := f.backups()
+			So(backups, ShouldHaveLength, 3)
+			for _, v := range backups {
+				So(v.files, ShouldHaveLength, 1)
+				So(strings.HasSuffix(v.files[0], ".log.gz"), ShouldBeTrue)
+			}
+		})
+
+		Convey("unrelated files are kept", func() {
+			f, err := NewLogFile(LogFileOptions{Filename: name, MaxBackups: 1})
+			So(err, ShouldBeNil)
+			others := []string{"app-access.log", "app-access-20000101T000000.000.log", "app-20000101T000000.000.x.log"}
+			for _, v := range others {
+				So(ioutil.WriteFile(filepath.Join(dir, "logs", v), []byte("other"), 0644), ShouldBeNil)
+			}
+			for i := 0; i < 3; i++ {
+				f.Write([]byte("line\n"))
+				So(f.Rotate(), ShouldBeNil)
+			}
+			So(f.Close(), ShouldBeNil)
+			So(f.backups(), ShouldHaveLength, 1)
+			for _, v := range others {
+				So(fileExists(filepath.Join(dir, "logs", v)), ShouldBeTrue)
+			}
+		})
+
+		Convey("failed rotation reopens the file", func() {
+			f, err := NewLogFile(LogFileOptions{Filename: name})
+			So(err, ShouldBeNil)
+			So(os.RemoveAll(filepath.Join(dir, "logs")), ShouldBeNil)
+			So(f.Rotate(), ShouldNotBeNil)
+			_, err = f.Write([]byte("a\n"))
+			So(err, ShouldBeNil)
+			So(f.Close(), ShouldBeNil)
+			data, _ := ioutil.ReadFile(name)
+			So(string(data), ShouldEqual, "a\n")
+		})
+
+		Convey("rotate by interval", func() {
+			f, err := NewLogFile(LogFileOptions{Filename: name, Interval: time.Hour})
+			So(err, ShouldBeNil)
+			f.Write([]byte("a\n"))
+			f.rotateAt = time.Now().Add(-time.Second)
+			f.Write([]byte("b\n"))
+			So(f.Close(), ShouldBeNil)
+			So(f.backups(), ShouldHaveLength, 1)
+			data, _ := ioutil.ReadFile(name)
+			So(string(data), ShouldEqual, "b\n")
+		})
+
+		Convey("set log file", func() {
+			b2 := New()
+			f, err := b2.SetLogFile(LogFileOptions{Filename: name})
+			So(err, ShouldBeNil)
+			b2.Logger().Println("hello")
+			So(f.Close(), ShouldBeNil)
+			data, _ := ioutil.ReadFile(name)
+			So(string(data), ShouldContainSubstring, "[Baa] ")
+			So(string(data), ShouldContainSubstring, "hello")
+			_, err = b2.SetLogFile(LogFileOptions{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
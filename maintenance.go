@@ -0,0 +1,50 @@
+package baa
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// RouteMetaMaintenanceExempt is the route meta key marks the route is served in maintenance mode,
+// eg: health checks and admin routes.
+const RouteMetaMaintenanceExempt = "baa.maintenanceExempt"
+
+// SetMaintenance switches maintenance mode, requests of routes not exempted are answered
+// by the maintenance handler, middlewares are still executed. It's safe for concurrent use.
+func (b *Baa) SetMaintenance(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&b.maintenance, v)
+}
+
+// Maintenance returns if maintenance mode is on
+func (b *Baa) Maintenance() bool {
+	return atomic.LoadInt32(&b.maintenance) == 1
+}
+
+// SetMaintenanceHandler set the handler answers requests in maintenance mode,
+// the default responds 503 Service Unavailable.
+func (b *Baa) SetMaintenanceHandler(h HandlerFunc) {
+	b.maintenanceFn = h
+}
+
+// maintenanceOf returns the maintenance handler when the matched route should not be served
+func (b *Baa) maintenanceOf(c *Context) HandlerFunc {
+	if !b.Maintenance() {
+		return nil
+	}
+	if v, ok := c.RouteMeta(RouteMetaMaintenanceExempt).(bool); ok && v {
+		return nil
+	}
+	if b.maintenanceFn != nil {
+		return b.maintenanceFn
+	}
+	return b.defaultMaintenanceHandler
+}
+
+// defaultMaintenanceHandler responds 503 Service Unavailable
+func (b *Baa) defaultMaintenanceHandler(c *Context) {
+	c.Error(NewHTTPError(http.StatusServiceUnavailable).WithRetry(0))
+}
@@ -0,0 +1,41 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMaintenance1(t *testing.T) {
+	Convey("maintenance mode", t, func() {
+		b2 := New()
+		b2.Get("/page", func(c *Context) {
+			c.String(http.StatusOK, "page")
+		})
+		b2.Get("/health", func(c *Context) {
+			c.String(http.StatusOK, "ok")
+		}).SetMeta(RouteMetaMaintenanceExempt, true)
+		get := func(path string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		So(get("/page").Code, ShouldEqual, http.StatusOK)
+		b2.SetMaintenance(true)
+		So(b2.Maintenance(), ShouldBeTrue)
+		So(get("/page").Code, ShouldEqual, http.StatusServiceUnavailable)
+		So(get("/health").Code, ShouldEqual, http.StatusOK)
+		So(get("/none").Code, ShouldEqual, http.StatusNotFound)
+
+		b2.SetMaintenanceHandler(func(c *Context) {
+			c.String(http.StatusServiceUnavailable, "back soon")
+		})
+		So(get("/page").Body.String(), ShouldEqual, "back soon")
+		b2.SetMaintenance(false)
+		So(get("/page").Body.String(), ShouldEqual, "page")
+	})
+}
@@ -0,0 +1,448 @@
+package baa
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets default histogram buckets, in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// metricKind type of metric
+type metricKind int
+
+const (
+	metricCounter metricKind = iota
+	metricGauge
+	metricHistogram
+)
+
+// String returns metric type name in text exposition format
+func (k metricKind) String() string {
+	switch k {
+	case metricCounter:
+		return "counter"
+	case metricGauge:
+		return "gauge"
+	default:
+		return "histogram"
+	}
+}
+
+// Metrics is a registry of counters, gauges and histograms,
+// it can be exposed in the Prometheus text format.
+type Metrics struct {
+	mu         sync.RWMutex
+	kinds      map[string]metricKind
+	counters   map[string]*Counter
+	gauges     map[string]*Gauge
+	histograms map[string]*Histogram
+	collectors []func()
+}
+
+// NewMetrics create a metrics registry
+func NewMetrics() *Metrics {
+	m := new(Metrics)
+	m.kinds = make(map[string]metricKind)
+	m.counters = make(map[string]*Counter)
+	m.gauges = make(map[string]*Gauge)
+	m.histograms = make(map[string]*Histogram)
+	return m
+}
+
+// Counter is a metric only increases
+type Counter struct {
+	name   string
+	labels string
+	mu     sync.Mutex
+	value  float64
+}
+
+// Inc increases the counter by 1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increases the counter by v, negative value is ignored
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.value += v
+	c.mu.Unlock()
+}
+
+// set set the counter to v read from a monotonic source, smaller value is ignored
+func (c *Counter) set(v float64) {
+	c.mu.Lock()
+	if v > c.value {
+		c.value = v
+	}
+	c.mu.Unlock()
+}
+
+// Value returns current value
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// Gauge is a metric can go up and down
+type Gauge struct {
+	name   string
+	labels string
+	mu     sync.Mutex
+	value  float64
+}
+
+// Set set the gauge to v
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+// Add adds v to the gauge, v can be negative
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.value += v
+	g.mu.Unlock()
+}
+
+// Inc increases the gauge by 1
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec decreases the gauge by 1
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Value returns current value
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+// Histogram samples observations and counts them in buckets
+type Histogram struct {
+	name    string
+	labels  string
+	buckets []float64
+	mu      sync.Mutex
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// Observe adds an observation
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	for i := range h.buckets {
+		if v <= h.buckets[i] {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+// ObserveDuration adds a duration observation in seconds since start
+func (h *Histogram) ObserveDuration(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Count returns count and sum of observations
+func (h *Histogram) Count() (uint64, float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count, h.sum
+}
+
+// Counter returns the counter by name and label pairs, creates it when not exists
+//
+// Example:
+// 		m.Counter("orders_created_total", "type", "vip").Inc()
+func (m *Metrics) Counter(name string, labelPairs ...string) *Counter {
+	key, labels := metricKey(name, labelPairs)
+	m.mu.RLock()
+	c, ok := m.counters[key]
+	m.mu.RUnlock()
+	if ok {
+		return c
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok = m.counters[key]; ok {
+		return c
+	}
+	m.checkKind(name, metricCounter)
+	c = &Counter{name: name, labels: labels}
+	m.counters[key] = c
+	return c
+}
+
+// Gauge returns the gauge by name and label pairs, creates it when not exists
+func (m *Metrics) Gauge(name string, labelPairs ...string) *Gauge {
+	key, labels := metricKey(name, labelPairs)
+	m.mu.RLock()
+	g, ok := m.gauges[key]
+	m.mu.RUnlock()
+	if ok {
+		return g
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if g, ok = m.gauges[key]; ok {
+		return g
+	}
+	m.checkKind(name, metricGauge)
+	g = &Gauge{name: name, labels: labels}
+	m.gauges[key] = g
+	return g
+}
+
+// Histogram returns the histogram by name and label pairs with DefaultBuckets,
+// creates it when not exists
+func (m *Metrics) Histogram(name string, labelPairs ...string) *Histogram {
+	return m.HistogramWithBuckets(name, DefaultBuckets, labelPairs...)
+}
+
+// HistogramWithBuckets returns the histogram by name and label pairs, creates it
+// with given buckets when not exists
+func (m *Metrics) HistogramWithBuckets(name string, buckets []float64, labelPairs ...string) *Histogram {
+	key, labels := metricKey(name, labelPairs)
+	m.mu.RLock()
+	h, ok := m.histograms[key]
+	m.mu.RUnlock()
+	if ok {
+		return h
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok = m.histograms[key]; ok {
+		return h
+	}
+	m.checkKind(name, metricHistogram)
+	bs := make([]float64, len(buckets))
+	copy(bs, buckets)
+	sort.Float64s(bs)
+	h = &Histogram{name: name, labels: labels, buckets: bs, counts: make([]uint64, len(bs))}
+	m.histograms[key] = h
+	return h
+}
+
+// OnCollect registers a function called before metrics are written,
+// it can be used to update gauges from other sources.
+func (m *Metrics) OnCollect(fn func()) {
+	m.mu.Lock()
+	m.collectors = append(m.collectors, fn)
+	m.mu.Unlock()
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format
+func (m *Metrics) WriteText(w io.Writer) error {
+	m.mu.RLock()
+	collectors := make([]func(), len(m.collectors))
+	copy(collectors, m.collectors)
+	m.mu.RUnlock()
+	for _, fn := range collectors {
+		fn()
+	}
+
+	m.mu.RLock()
+	names := make([]string, 0, len(m.kinds))
+	for name := range m.kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	series := make(map[string][]string)
+	for _, c := range m.counters {
+		series[c.name] = append(series[c.name], c.name+wrapLabels(c.labels)+" "+formatFloat(c.Value()))
+	}
+	for _, g := range m.gauges {
+		series[g.name] = append(series[g.name], g.name+wrapLabels(g.labels)+" "+formatFloat(g.Value()))
+	}
+	for _, h := range m.histograms {
+		series[h.name] = append(series[h.name], strings.Join(h.lines(), "\n"))
+	}
+	kinds := make(map[string]metricKind, len(m.kinds))
+	for k, v := range m.kinds {
+		kinds[k] = v
+	}
+	m.mu.RUnlock()
+
+	for _, name := range names {
+		// one line per series, one block per histogram
+		lines := series[name]
+		sort.Strings(lines)
+		if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", name, kinds[name]); err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lines returns histogram series in text format
+func (h *Histogram) lines() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	lines := make([]string, 0, len(h.buckets)+3)
+	for i, b := range h.buckets {
+		lines = append(lines, h.name+"_bucket"+wrapLabels(joinLabels(h.labels, `le="`+formatFloat(b)+`"`))+" "+strconv.FormatUint(h.counts[i], 10))
+	}
+	lines = append(lines, h.name+"_bucket"+wrapLabels(joinLabels(h.labels, `le="+Inf"`))+" "+strconv.FormatUint(h.count, 10))
+	lines = append(lines, h.name+"_sum"+wrapLabels(h.labels)+" "+formatFloat(h.sum))
+	lines = append(lines, h.name+"_count"+wrapLabels(h.labels)+" "+strconv.FormatUint(h.count, 10))
+	return lines
+}
+
+// checkKind registers metric name with kind, panics when used as another kind
+func (m *Metrics) checkKind(name string, kind metricKind) {
+	if k, ok := m.kinds[name]; ok && k != kind {
+		panic("baa.Metrics: metric " + name + " already registered as " + k.String())
+	}
+	m.kinds[name] = kind
+}
+
+// metricKey returns unique key and formatted labels of metric
+func metricKey(name string, labelPairs []string) (string, string) {
+	if len(labelPairs)%2 != 0 {
+		panic("baa.Metrics: label pairs must be key value pairs")
+	}
+	if len(labelPairs) == 0 {
+		return name, ""
+	}
+	pairs := make([]string, 0, len(labelPairs)/2)
+	for i := 0; i < len(labelPairs); i += 2 {
+		pairs = append(pairs, labelPairs[i]+"="+strconv.Quote(labelPairs[i+1]))
+	}
+	sort.Strings(pairs)
+	labels := strings.Join(pairs, ",")
+	return name + "{" + labels + "}", labels
+}
+
+// joinLabels joins formatted labels
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+// wrapLabels wraps formatted labels with braces
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+// formatFloat formats float in text exposition format
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// RouteMetrics is a metrics registry view, metrics are pre-labeled with
+// route pattern and request method.
+type RouteMetrics struct {
+	metrics *Metrics
+	labels  []string
+}
+
+// Counter returns the counter labeled with route and method
+func (r *RouteMetrics) Counter(name string, labelPairs ...string) *Counter {
+	return r.metrics.Counter(name, r.labelPairs(labelPairs)...)
+}
+
+// Gauge returns the gauge labeled with route and method
+func (r *RouteMetrics) Gauge(name string, labelPairs ...string) *Gauge {
+	return r.metrics.Gauge(name, r.labelPairs(labelPairs)...)
+}
+
+// Histogram returns the histogram labeled with route and method
+func (r *RouteMetrics) Histogram(name string, labelPairs ...string) *Histogram {
+	return r.metrics.Histogram(name, r.labelPairs(labelPairs)...)
+}
+
+// labelPairs returns labelPairs followed by route labels in a new slice, the
+// caller's slice is not modified
+func (r *RouteMetrics) labelPairs(labelPairs []string) []string {
+	pairs := make([]string, 0, len(labelPairs)+len(r.labels))
+	pairs = append(pairs, labelPairs...)
+	return append(pairs, r.labels...)
+}
+
+// Metrics returns the application metrics registry
+func (b *Baa) Metrics() *Metrics {
+	return b.GetDI("metrics").(*Metrics)
+}
+
+// SetRequestMetrics set whether records framework request metrics,
+// baa_http_requests_total and baa_http_request_duration_seconds by method, route and status,
+// baa_http_response_bytes_total by method and route,
+// and baa_http_errors_total by route, error category and retryable.
+func (b *Baa) SetRequestMetrics(v bool) {
+	b.requestMetrics = v
+}
+
+// MetricsHandler returns a handler exposes metrics in the Prometheus text format
+func (b *Baa) MetricsHandler() HandlerFunc {
+	return func(c *Context) {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err := b.Metrics().WriteText(buf); err != nil {
+			c.Error(err)
+			return
+		}
+		c.writeBuffer(200, "text/plain; version=0.0.4; "+CharsetUTF8, buf)
+	}
+}
+
+// collectConnStats updates connection metrics from b.Stats
+func (b *Baa) collectConnStats() {
+	st := b.Stats()
+	m := b.Metrics()
+	m.Gauge("baa_connections_open").Set(float64(st.Open))
+	m.Gauge("baa_connections_active").Set(float64(st.Active))
+	m.Gauge("baa_connections_idle").Set(float64(st.Idle))
+	m.Counter("baa_connections_accepted_total").set(float64(st.Accepted))
+	m.Gauge("baa_connections_accept_rate").Set(st.AcceptRate)
+}
+
+// recordRequest records framework request metrics
+func (b *Baa) recordRequest(c *Context, start time.Time) {
+	m := b.Metrics()
+	route := c.RoutePattern()
+	status := strconv.Itoa(c.Resp.Status())
+	m.Counter("baa_http_requests_total", "method", c.Req.Method, "route", route, "status", status).Inc()
+	m.Histogram("baa_http_request_duration_seconds", "method", c.Req.Method, "route", route).ObserveDuration(start)
+	m.Counter("baa_http_response_bytes_total", "method", c.Req.Method, "route", route).Add(float64(c.Resp.Size()))
+}
+
+// Metrics returns metrics registry pre-labeled with matched route pattern and method
+func (c *Context) Metrics() *RouteMetrics {
+	return &RouteMetrics{
+		metrics: c.baa.Metrics(),
+		labels:  []string{"method", c.Req.Method, "route", c.RoutePattern()},
+	}
+}
@@ -0,0 +1,92 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetrics1(t *testing.T) {
+	Convey("metrics registry", t, func() {
+		m := NewMetrics()
+		m.Counter("c_total", "b", "2", "a", "1").Inc()
+		m.Counter("c_total", "a", "1", "b", "2").Add(2)
+		m.Counter("c_total", "a", "1", "b", "2").Add(-1)
+		So(m.Counter("c_total", "a", "1", "b", "2").Value(), ShouldEqual, 3)
+
+		g := m.Gauge("g")
+		g.Set(5)
+		g.Inc()
+		g.Dec()
+		g.Add(-2)
+		So(g.Value(), ShouldEqual, 3)
+
+		h := m.HistogramWithBuckets("h", []float64{1, 0.5})
+		h.Observe(0.3)
+		h.Observe(0.7)
+		h.Observe(3)
+		n, sum := h.Count()
+		So(n, ShouldEqual, 3)
+		So(sum, ShouldEqual, 4)
+		So(m.Histogram("h"), ShouldEqual, h)
+
+		buf := new(strings.Builder)
+		So(m.WriteText(buf), ShouldBeNil)
+		So(buf.String(), ShouldEqual, `# TYPE c_total counter
+c_total{a="1",b="2"} 3
+# TYPE g gauge
+g 3
+# TYPE h histogram
+h_bucket{le="0.5"} 1
+h_bucket{le="1"} 2
+h_bucket{le="+Inf"} 3
+h_sum 4
+h_count 3
+`)
+
+		So(func() { m.Gauge("c_total") }, ShouldPanic)
+		So(func() { m.Counter("x", "a") }, ShouldPanic)
+	})
+
+	Convey("route metrics", t, func() {
+		b2 := New()
+		b2.SetRequestMetrics(true)
+		b2.Post("/orders/:id", func(c *Context) {
+			c.Metrics().Counter("orders_created_total", "type", "vip").Inc()
+			c.Metrics().Gauge("orders_pending").Inc()
+			c.Metrics().Histogram("orders_amount").Observe(0.1)
+			c.String(201, "ok")
+		})
+		b2.Get("/metrics", b2.MetricsHandler())
+
+		req, _ := http.NewRequest("POST", "/orders/1", nil)
+		b2.ServeHTTP(httptest.NewRecorder(), req)
+		req, _ = http.NewRequest("GET", "/metrics", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		body := w.Body.String()
+		So(body, ShouldContainSubstring, `orders_created_total{method="POST",route="/orders/:id",type="vip"} 1`)
+		So(body, ShouldContainSubstring, `orders_pending{method="POST",route="/orders/:id"} 1`)
+		So(body, ShouldContainSubstring, `orders_amount_count{method="POST",route="/orders/:id"} 1`)
+		So(body, ShouldContainSubstring, `baa_http_requests_total{method="POST",route="/orders/:id",status="201"} 1`)
+		So(body, ShouldContainSubstring, `baa_http_request_duration_seconds_count{method="POST",route="/orders/:id"} 1`)
+		So(body, ShouldContainSubstring, `baa_http_response_bytes_total{method="POST",route="/orders/:id"} 2`)
+		So(body, ShouldContainSubstring, "baa_connections_open 0")
+		So(body, ShouldContainSubstring, "# TYPE baa_connections_accepted_total counter")
+
+		// caller's label pairs are not overwritten by route labels
+		c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), b2)
+		pairs := make([]string, 2, 8)
+		copy(pairs, []string{"type", "a"})
+		c.Metrics().Counter("pairs_total", pairs...).Inc()
+		So(pairs[:cap(pairs)][2], ShouldEqual, "")
+	})
+
+	Convey("register metrics di", t, func() {
+		So(func() { New().SetDI("metrics", "metrics") }, ShouldPanic)
+	})
+}
@@ -0,0 +1,176 @@
+// Package accesslog provides an access log middleware for baa, it writes JSON,
+// text or Apache combined lines to a sink independent of the application logger.
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-baa/baa"
+)
+
+// Entry is an access log record, URI is the escaped path with redacted query
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Proto     string    `json:"proto,omitempty"`
+	Path      string    `json:"path"`
+	Query     string    `json:"query,omitempty"`
+	URI       string    `json:"uri"`
+	Route     string    `json:"route,omitempty"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	Duration  float64   `json:"duration_ms"`
+	RemoteIP  string    `json:"remote_ip"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Referer   string    `json:"referer,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// Format is the format of log lines, text and combined lines escape control and
+// non-printable bytes as \xhh so request values can not forge lines.
+type Format int
+
+const (
+	// FormatJSON writes entries in JSON, it's the default
+	FormatJSON Format = iota
+	// FormatText writes entries in a human readable line, eg:
+	// 2006-01-02T15:04:05Z GET /users/1?page=2 200 4B 1.25ms 127.0.0.1 id="r1"
+	FormatText
+	// FormatCombined writes entries in Apache combined log format
+	FormatCombined
+)
+
+// Options accesslog middleware config
+type Options struct {
+	// Format of log lines, default FormatJSON
+	Format Format
+	// Writer is the sink of log lines, eg: a rotating file, syslog or socket writer,
+	// default is os.Stdout. Wrap it by NewAsyncWriter to avoid blocking requests.
+	Writer io.Writer
+	// Skip skips logging of request, eg: health checks
+	Skip func(c *baa.Context) bool
+	// Fields adds custom fields to entry before it is written
+	Fields func(c *baa.Context, e *Entry)
+}
+
+// AccessLog returns a middleware writes an entry for every request after handled in
+// the format of options, query fields are masked by the application redaction.
+func AccessLog(opt Options) baa.HandlerFunc {
+	if opt.Writer == nil {
+		opt.Writer = os.Stdout
+	}
+	var format func(e *Entry) ([]byte, error)
+	switch opt.Format {
+	case FormatJSON:
+		format = func(e *Entry) ([]byte, error) {
+			return baa.Marshal(e)
+		}
+	case FormatText:
+		format = formatText
+	case FormatCombined:
+		format = formatCombined
+	default:
+		panic("accesslog.AccessLog unknown format " + strconv.Itoa(int(opt.Format)))
+	}
+	return func(c *baa.Context) {
+		if opt.Skip != nil && opt.Skip(c) {
+			c.Next()
+			return
+		}
+		start := time.Now()
+
+		c.Next()
+
+		e := &Entry{
+			Time:      start,
+			Method:    c.Req.Method,
+			Proto:     c.Req.Proto,
+			Path:      c.Req.URL.Path,
+			Route:     c.RoutePattern(),
+			Status:    c.Resp.Status(),
+			Bytes:     c.Resp.Size(),
+			Duration:  float64(time.Since(start)) / float64(time.Millisecond),
+			RemoteIP:  c.RemoteAddr(),
+			UserAgent: c.Req.UserAgent(),
+			Referer:   c.Req.Referer(),
+			RequestID: c.RequestID(),
+		}
+		e.URI = c.Req.URL.EscapedPath()
+		if c.Req.URL.RawQuery != "" {
+			e.Query = c.Baa().Redaction().Values(c.Req.URL.Query()).Encode()
+			e.URI += "?" + e.Query
+		}
+		if opt.Fields != nil {
+			opt.Fields(c, e)
+		}
+		line, err := format(e)
+		if err != nil {
+			c.Baa().Logger().Printf("accesslog: %v", err)
+			return
+		}
+		if _, err := opt.Writer.Write(append(line, '\n')); err != nil && err != ErrDropped {
+			c.Baa().Logger().Printf("accesslog: %v", err)
+		}
+	}
+}
+
+// formatText formats entry in a human readable line
+func formatText(e *Entry) ([]byte, error) {
+	line := fmt.Sprintf("%s %s %s %d %dB %.2fms %s",
+		e.Time.Format(time.RFC3339), escape(e.Method), escape(e.URI), e.Status, e.Bytes, e.Duration,
+		escape(orDash(e.RemoteIP)))
+	if e.RequestID != "" {
+		line += " id=" + quote(e.RequestID)
+	}
+	return []byte(line), nil
+}
+
+// formatCombined formats entry in Apache combined log format
+func formatCombined(e *Entry) ([]byte, error) {
+	size := "-"
+	if e.Bytes > 0 {
+		size = strconv.FormatInt(e.Bytes, 10)
+	}
+	line := fmt.Sprintf("%s - - [%s] %s %d %s %s %s",
+		escape(orDash(e.RemoteIP)), e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		quote(e.Method+" "+e.URI+" "+e.Proto), e.Status, size, quote(e.Referer), quote(e.UserAgent))
+	return []byte(line), nil
+}
+
+// orDash returns s, "-" when s is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// quote quotes and escapes s, empty is logged as "-"
+func quote(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+	return `"` + escape(s) + `"`
+}
+
+// escape escapes backslash, quote, control and non-printable bytes as Apache does
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' || c == '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package accesslog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp(opt Options) *baa.Baa {
+	app := baa.New()
+	app.Use(AccessLog(opt))
+	app.Get("/users/:id", func(c *baa.Context) {
+		c.String(http.StatusOK, "user")
+	})
+	app.Get("/health", func(c *baa.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return app
+}
+
+func request(app *baa.Baa, path string) {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("X-Request-ID", "r1")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+// blockWriter blocks writes until released
+type blockWriter struct {
+	buf     bytes.Buffer
+	release chan struct{}
+	mu      sync.Mutex
+}
+
+func (w *blockWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestAccessLog1(t *testing.T) {
+	Convey("access log entry", t, func() {
+		buf := new(bytes.Buffer)
+		app := newApp(Options{
+			Writer: buf,
+			Skip: func(c *baa.Context) bool {
+				return c.Req.URL.Path == "/health"
+			},
+			Fields: func(c *baa.Context, e *Entry) {
+				e.Route = "custom:" + e.Route
+			},
+		})
+		request(app, "/users/1?token=secret&page=2")
+		request(app, "/health")
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		So(lines, ShouldHaveLength, 1)
+		var e Entry
+		So(baa.Unmarshal([]byte(lines[0]), &e), ShouldBeNil)
+		So(e.Method, ShouldEqual, "GET")
+		So(e.Path, ShouldEqual, "/users/1")
+		So(e.Query, ShouldEqual, "page=2&token=%5BREDACTED%5D")
+		So(e.URI, ShouldEqual, "/users/1?page=2&token=%5BREDACTED%5D")
+		So(e.Route, ShouldEqual, "custom:/users/:id")
+		So(e.Status, ShouldEqual, http.StatusOK)
+		So(e.Bytes, ShouldEqual, 4)
+		So(e.UserAgent, ShouldEqual, "test")
+		So(e.RequestID, ShouldEqual, "r1")
+	})
+	Convey("text and combined formats", t, func() {
+		buf := new(bytes.Buffer)
+		app := newApp(Options{Writer: buf, Format: FormatText})
+		request(app, "/users/1?page=2")
+		So(buf.String(), ShouldContainSubstring, " GET /users/1?page=2 200 4B ")
+		So(buf.String(), ShouldEndWith, `ms - id="r1"`+"\n")
+
+		buf.Reset()
+		app = newApp(Options{Writer: buf, Format: FormatCombined})
+		request(app, "/users/1?page=2")
+		So(buf.String(), ShouldStartWith, "- - - [")
+		So(buf.String(), ShouldEndWith, `] "GET /users/1?page=2 HTTP/1.1" 200 4 "-" "test"`+"\n")
+
+		buf.Reset()
+		req, _ := http.NewRequest("GET", "/users/a%0a6.6.6.6%20-%20-%20%5Bfake%5D", nil)
+		req.Header.Set("User-Agent", "evil\n\x01\"")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+		So(strings.Count(buf.String(), "\n"), ShouldEqual, 1)
+		So(buf.String(), ShouldContainSubstring, `"GET /users/a%0a6.6.6.6%20-%20-%20%5Bfake%5D HTTP/1.1"`)
+		So(buf.String(), ShouldEndWith, `"evil\x0a\x01\""`+"\n")
+
+		buf.Reset()
+		app = newApp(Options{Writer: buf, Format: FormatText})
+		req.Header.Set("X-Request-ID", "r1\nforged")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+		So(strings.Count(buf.String(), "\n"), ShouldEqual, 1)
+
+		So(func() { AccessLog(Options{Format: Format(9)}) }, ShouldPanic)
+	})
+	Convey("async writer drops under backpressure", t, func() {
+		w := &blockWriter{release: make(chan struct{})}
+		a := NewAsyncWriter(w, 1)
+		app := newApp(Options{Writer: a})
+		for i := 0; i < 5; i++ {
+			request(app, "/users/1")
+		}
+		So(a.Dropped(), ShouldBeGreaterThan, 0)
+		close(w.release)
+		So(a.Close(), ShouldBeNil)
+		So(a.Close(), ShouldBeNil)
+		n := strings.Count(w.buf.String(), "\n")
+		So(uint64(n)+a.Dropped(), ShouldEqual, 5)
+		_, err := a.Write([]byte("x"))
+		So(err, ShouldEqual, ErrClosed)
+	})
+}
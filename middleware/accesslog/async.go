@@ -0,0 +1,87 @@
+package accesslog
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrDropped is returned by AsyncWriter.Write when the buffer is full and the line is dropped
+var ErrDropped = errors.New("accesslog: buffer full, dropped")
+
+// ErrClosed is returned by AsyncWriter.Write after closed
+var ErrClosed = errors.New("accesslog: writer closed")
+
+// AsyncWriter writes to the underlying writer in a goroutine, Write never blocks,
+// lines are dropped when the buffer is full, so a slow sink does not slow requests.
+type AsyncWriter struct {
+	w       io.Writer
+	lines   chan []byte
+	done    chan struct{}
+	dropped uint64
+	closed  bool
+	mu      sync.RWMutex
+}
+
+// NewAsyncWriter create an async writer buffers at most size lines, default 1024
+func NewAsyncWriter(w io.Writer, size int) *AsyncWriter {
+	if size <= 0 {
+		size = 1024
+	}
+	a := &AsyncWriter{
+		w:     w,
+		lines: make(chan []byte, size),
+		done:  make(chan struct{}),
+	}
+	go a.loop()
+	return a
+}
+
+// Write queues a copy of p, returns ErrDropped when the buffer is full
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return 0, ErrClosed
+	}
+	line := make([]byte, len(p))
+	copy(line, p)
+	select {
+	case a.lines <- line:
+		return len(p), nil
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+		return 0, ErrDropped
+	}
+}
+
+// Dropped returns the number of dropped lines
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Close flushes buffered lines then closes the underlying writer when it's an io.Closer
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.lines)
+	a.mu.Unlock()
+	<-a.done
+	if c, ok := a.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// loop writes queued lines
+func (a *AsyncWriter) loop() {
+	defer close(a.done)
+	for line := range a.lines {
+		a.w.Write(line)
+	}
+}
@@ -0,0 +1,107 @@
+// Package botfilter provides a middleware filters requests by User-Agent for baa.
+package botfilter
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/go-baa/baa"
+)
+
+// Verdict is the classification result of a request
+type Verdict int
+
+const (
+	// Pass continues rule matching, the request is allowed when no rule matches
+	Pass Verdict = iota
+	// Allow allows the request
+	Allow
+	// Deny blocks the request
+	Deny
+	// Challenge responds the request by the challenge handler
+	Challenge
+)
+
+// blockedMetric counter name of blocked requests, labeled by verdict
+const blockedMetric = "baa_bot_blocked_total"
+
+// Options botfilter middleware config
+type Options struct {
+	// Allow User-Agent regexp patterns always allowed, eg: Googlebot
+	Allow []string
+	// Deny User-Agent regexp patterns blocked
+	Deny []string
+	// DenyEmpty blocks requests without User-Agent
+	DenyEmpty bool
+	// Classify classifies the request before rules, returns Pass to continue rules
+	Classify func(c *baa.Context) Verdict
+	// Challenge handles challenged requests, eg: a captcha page, default responds 403
+	Challenge baa.HandlerFunc
+	// ChallengeDenied challenges instead of 403 for requests matched deny rules
+	ChallengeDenied bool
+}
+
+// BotFilter returns a middleware filters requests by User-Agent rules,
+// blocked requests are counted by metric baa_bot_blocked_total.
+func BotFilter(opt Options) baa.HandlerFunc {
+	allow := compile(opt.Allow)
+	deny := compile(opt.Deny)
+
+	return func(c *baa.Context) {
+		v := Pass
+		if opt.Classify != nil {
+			v = opt.Classify(c)
+		}
+		if v == Pass {
+			v = classify(c.UserAgent(), allow, deny, opt.DenyEmpty)
+		}
+		if v == Deny && opt.ChallengeDenied && opt.Challenge != nil {
+			v = Challenge
+		}
+
+		switch v {
+		case Deny:
+			c.Baa().Metrics().Counter(blockedMetric, "verdict", "deny").Inc()
+			http.Error(c.Resp, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		case Challenge:
+			c.Baa().Metrics().Counter(blockedMetric, "verdict", "challenge").Inc()
+			if opt.Challenge != nil {
+				opt.Challenge(c)
+			} else {
+				http.Error(c.Resp, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			}
+		default:
+			c.Next()
+		}
+	}
+}
+
+// classify matches User-Agent with allow rules first, then deny rules
+func classify(ua string, allow, deny []*regexp.Regexp, denyEmpty bool) Verdict {
+	if ua == "" {
+		if denyEmpty {
+			return Deny
+		}
+		return Allow
+	}
+	for _, re := range allow {
+		if re.MatchString(ua) {
+			return Allow
+		}
+	}
+	for _, re := range deny {
+		if re.MatchString(ua) {
+			return Deny
+		}
+	}
+	return Allow
+}
+
+// compile compiles patterns, it panics with invalid pattern
+func compile(patterns []string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		res = append(res, regexp.MustCompile(p))
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package botfilter
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp(opt Options) *baa.Baa {
+	app := baa.New()
+	app.Use(BotFilter(opt))
+	app.Get("/*", func(c *baa.Context) {
+		c.String(200, "ok")
+	})
+	return app
+}
+
+func request(app *baa.Baa, uri, ua string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", uri, nil)
+	req.Header.Set("User-Agent", ua)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func TestBotFilter1(t *testing.T) {
+	Convey("user agent rules", t, func() {
+		app := newApp(Options{
+			Allow:     []string{`(?i)googlebot`},
+			Deny:      []string{`(?i)bot`, `(?i)curl`},
+			DenyEmpty: true,
+		})
+		So(request(app, "/", "Mozilla/5.0").Code, ShouldEqual, http.StatusOK)
+		So(request(app, "/", "Googlebot/2.1").Code, ShouldEqual, http.StatusOK)
+		So(request(app, "/", "EvilBot/1.0").Code, ShouldEqual, http.StatusForbidden)
+		So(request(app, "/", "curl/7.0").Code, ShouldEqual, http.StatusForbidden)
+		So(request(app, "/", "").Code, ShouldEqual, http.StatusForbidden)
+
+		buf := new(bytes.Buffer)
+		app.Metrics().WriteText(buf)
+		So(buf.String(), ShouldContainSubstring, `baa_bot_blocked_total{verdict="deny"} 3`)
+	})
+
+	Convey("classify and challenge", t, func() {
+		app := newApp(Options{
+			Deny:            []string{`scraper`},
+			ChallengeDenied: true,
+			Classify: func(c *baa.Context) Verdict {
+				switch c.Req.URL.Path {
+				case "/trusted":
+					return Allow
+				case "/suspicious":
+					return Challenge
+				}
+				return Pass
+			},
+			Challenge: func(c *baa.Context) {
+				c.String(http.StatusTooManyRequests, "challenge")
+			},
+		})
+		So(request(app, "/trusted", "scraper").Code, ShouldEqual, http.StatusOK)
+		w := request(app, "/suspicious", "Mozilla/5.0")
+		So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+		So(w.Body.String(), ShouldEqual, "challenge")
+		So(request(app, "/", "scraper").Body.String(), ShouldEqual, "challenge")
+		So(request(app, "/", "").Code, ShouldEqual, http.StatusOK)
+
+		So(func() { BotFilter(Options{Deny: []string{"("}}) }, ShouldPanic)
+	})
+}
@@ -0,0 +1,137 @@
+// Package canonical provides a middleware redirects requests to the canonical host for baa.
+package canonical
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-baa/baa"
+)
+
+// Options canonical middleware config
+type Options struct {
+	// Host canonical host, eg: example.com or www.example.com, empty keeps request host
+	Host string
+	// Scheme enforced scheme, eg: https, empty keeps request scheme
+	Scheme string
+	// StripPort removes port from request host
+	StripPort bool
+	// Except paths not redirected, eg: health check, path ends with * matches prefix
+	Except []string
+	// Code redirect status code, default 301
+	Code int
+	// TrustedProxies IPs or CIDRs of proxies X-Forwarded-Proto is trusted from,
+	// eg: 10.0.0.0/8, the header is ignored by default
+	TrustedProxies []string
+}
+
+// Canonical returns a middleware redirects requests to canonical host and scheme
+func Canonical(opt Options) baa.HandlerFunc {
+	if opt.Code == 0 {
+		opt.Code = http.StatusMovedPermanently
+	}
+	opt.Host = strings.ToLower(opt.Host)
+	opt.Scheme = strings.ToLower(opt.Scheme)
+	proxies := parseProxies(opt.TrustedProxies)
+
+	return func(c *baa.Context) {
+		if excepted(c.Req.URL.Path, opt.Except) {
+			c.Next()
+			return
+		}
+
+		scheme := requestScheme(c.Req, proxies)
+		host := strings.ToLower(c.Req.Host)
+		targetScheme, targetHost := scheme, host
+		if opt.Scheme != "" {
+			targetScheme = opt.Scheme
+		}
+		if opt.StripPort {
+			if h, _, err := net.SplitHostPort(targetHost); err == nil {
+				targetHost = h
+			}
+		}
+		if opt.Host != "" {
+			targetHost = opt.Host
+		}
+
+		if targetScheme == scheme && targetHost == host {
+			c.Next()
+			return
+		}
+		c.Redirect(opt.Code, targetScheme+"://"+targetHost+c.Req.URL.RequestURI())
+	}
+}
+
+// requestScheme returns scheme of request, X-Forwarded-Proto is respected when
+// the request comes from a trusted proxy
+func requestScheme(r *http.Request, proxies []*net.IPNet) string {
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" && trusted(r.RemoteAddr, proxies) {
+		return strings.ToLower(strings.TrimSpace(strings.Split(proto, ",")[0]))
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// parseProxies parses trusted proxy IPs and CIDRs, it panics on invalid ones
+func parseProxies(list []string) []*net.IPNet {
+	proxies := make([]*net.IPNet, 0, len(list))
+	for _, v := range list {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				panic("canonical: invalid trusted proxy " + v)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			panic("canonical: invalid trusted proxy " + v)
+		}
+		proxies = append(proxies, n)
+	}
+	return proxies
+}
+
+// trusted checks the remote address is a trusted proxy
+func trusted(remoteAddr string, proxies []*net.IPNet) bool {
+	if len(proxies) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// excepted checks path is in except list
+func excepted(path string, except []string) bool {
+	for _, v := range except {
+		if strings.HasSuffix(v, "*") {
+			if strings.HasPrefix(path, v[:len(v)-1]) {
+				return true
+			}
+		} else if path == v {
+			return true
+		}
+	}
+	return false
+}
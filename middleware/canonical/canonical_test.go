@@ -0,0 +1,76 @@
+package canonical
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp(opt Options) *baa.Baa {
+	app := baa.New()
+	app.Use(Canonical(opt))
+	app.Get("/*", func(c *baa.Context) {
+		c.String(200, "ok")
+	})
+	return app
+}
+
+func request(app *baa.Baa, uri string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", uri, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func TestCanonical1(t *testing.T) {
+	Convey("canonical host redirect", t, func() {
+		app := newApp(Options{Host: "example.com", Scheme: "https", StripPort: true, Except: []string{"/health", "/internal/*"}})
+
+		w := request(app, "http://www.example.com:8080/a/b?x=1", nil)
+		So(w.Code, ShouldEqual, http.StatusMovedPermanently)
+		So(w.Header().Get("Location"), ShouldEqual, "https://example.com/a/b?x=1")
+
+		// X-Forwarded-Proto of untrusted clients is ignored
+		w = request(app, "http://example.com/a", map[string]string{"X-Forwarded-Proto": "https"})
+		So(w.Code, ShouldEqual, http.StatusMovedPermanently)
+
+		w = request(app, "http://www.example.com/health", nil)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		w = request(app, "http://www.example.com/internal/metrics", nil)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		w = request(app, "http://www.example.com/healthz", nil)
+		So(w.Code, ShouldEqual, http.StatusMovedPermanently)
+	})
+
+	Convey("trusted proxies", t, func() {
+		So(func() { newApp(Options{TrustedProxies: []string{"10.0.0"}}) }, ShouldPanic)
+		So(func() { newApp(Options{TrustedProxies: []string{"10.0.0.0/33"}}) }, ShouldPanic)
+
+		// requests of httptest come from 192.0.2.1
+		app := newApp(Options{Scheme: "https", TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"}})
+		w := request(app, "http://example.com/a", map[string]string{"X-Forwarded-Proto": "https"})
+		So(w.Code, ShouldEqual, http.StatusOK)
+		w = request(app, "http://example.com/a", map[string]string{"X-Forwarded-Proto": "http"})
+		So(w.Code, ShouldEqual, http.StatusMovedPermanently)
+
+		app = newApp(Options{Scheme: "https", TrustedProxies: []string{"10.0.0.0/8"}})
+		w = request(app, "http://example.com/a", map[string]string{"X-Forwarded-Proto": "https"})
+		So(w.Code, ShouldEqual, http.StatusMovedPermanently)
+	})
+
+	Convey("strip port only", t, func() {
+		app := newApp(Options{StripPort: true, Code: http.StatusFound})
+		w := request(app, "http://Example.com:8080/a", nil)
+		So(w.Code, ShouldEqual, http.StatusFound)
+		So(w.Header().Get("Location"), ShouldEqual, "http://example.com/a")
+
+		w = request(app, "http://example.com/a", nil)
+		So(w.Code, ShouldEqual, http.StatusOK)
+	})
+}
@@ -0,0 +1,178 @@
+// Package charset provides a middleware converts UTF-8 responses to legacy charsets
+// negotiated by Accept-Charset for baa, eg: GBK, Big5.
+package charset
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-baa/baa"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// RouteMetaCharset is the route meta key forces the response charset of route to
+// one of Options.Charsets, eg: "gbk", Accept-Charset is ignored.
+const RouteMetaCharset = "charset.charset"
+
+// contextKey context store key of negotiated charset
+const contextKey = "baa.charset"
+
+// defaultCharsets charsets supported by default
+var defaultCharsets = []string{"gbk", "gb18030", "big5", "shift_jis", "euc-jp", "euc-kr"}
+
+// defaultContentTypes media types converted by default
+var defaultContentTypes = []string{
+	"text/html", "text/plain", "text/xml", "text/css", "text/csv",
+	"text/javascript", "application/javascript", "application/xml",
+}
+
+// Options charset middleware config
+type Options struct {
+	// Charsets supported besides UTF-8 by WHATWG names, default gbk, gb18030, big5,
+	// shift_jis, euc-jp and euc-kr.
+	Charsets []string
+	// ContentTypes media types are converted, default text types except JSON which
+	// must be UTF-8.
+	ContentTypes []string
+}
+
+// Charset returns a middleware converts responses to the charset negotiated by
+// Accept-Charset or forced by route meta RouteMetaCharset, UTF-8 is preferred when
+// the client accepts it. Characters not in the charset are replaced by the SUB
+// character of the charset, by numeric character references in HTML. Request bodies declared in a supported charset
+// are converted to UTF-8 before binding.
+//
+// Example:
+// 		b.Use(charset.Charset(charset.Options{}))
+// 		b.Get("/legacy", h).SetMeta(charset.RouteMetaCharset, "gbk")
+func Charset(opt Options) baa.HandlerFunc {
+	if len(opt.Charsets) == 0 {
+		opt.Charsets = defaultCharsets
+	}
+	if len(opt.ContentTypes) == 0 {
+		opt.ContentTypes = defaultContentTypes
+	}
+	encodings := make(map[string]encoding.Encoding, len(opt.Charsets))
+	for _, name := range opt.Charsets {
+		name = strings.ToLower(name)
+		enc, err := htmlindex.Get(name)
+		if err != nil {
+			panic("charset.Charset unsupported charset " + name)
+		}
+		encodings[name] = enc
+	}
+	convert := baa.TransformBody(func(c *baa.Context, body []byte) ([]byte, error) {
+		name := c.Get(contextKey).(string)
+		return encodeBody(c.Resp.Header(), body, name, encodings[name])
+	}, opt.ContentTypes...)
+
+	return func(c *baa.Context) {
+		decodeRequest(c.Req, encodings)
+		name, ok := c.RouteMeta(RouteMetaCharset).(string)
+		if ok {
+			name = strings.ToLower(name)
+		} else {
+			c.Resp.Header().Add("Vary", "Accept-Charset")
+			name = negotiate(c.Req.Header.Get("Accept-Charset"), encodings)
+		}
+		if encodings[name] == nil {
+			c.Next()
+			return
+		}
+		c.Set(contextKey, name)
+		convert(c)
+	}
+}
+
+// Get returns the charset of response negotiated by the middleware, empty for UTF-8
+func Get(c *baa.Context) string {
+	name, _ := c.Get(contextKey).(string)
+	return name
+}
+
+// negotiate returns the preferred charset of Accept-Charset, empty for UTF-8
+func negotiate(accept string, encodings map[string]encoding.Encoding) string {
+	if strings.TrimSpace(accept) == "" {
+		return ""
+	}
+	best, bestQ := "", 0.0
+	utf8Q, wildcardQ := -1.0, -1.0
+	for _, v := range strings.Split(accept, ",") {
+		parts := strings.Split(v, ";")
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		q := 1.0
+		for _, p := range parts[1:] {
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				if f, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		switch {
+		case name == "utf-8" || name == "utf8":
+			utf8Q = q
+		case name == "*":
+			wildcardQ = q
+		case encodings[name] != nil && q > bestQ:
+			best, bestQ = name, q
+		}
+	}
+	if utf8Q < 0 {
+		utf8Q = wildcardQ
+	}
+	if best == "" || utf8Q >= bestQ {
+		return ""
+	}
+	return best
+}
+
+// encodeBody encodes UTF-8 body into charset name, the charset of Content-Type in
+// header h is updated. Bodies declared in other charsets are returned as is.
+func encodeBody(h http.Header, body []byte, name string, enc encoding.Encoding) ([]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return body, nil
+	}
+	if cs := strings.ToLower(params["charset"]); cs != "" && cs != "utf-8" && cs != "utf8" {
+		return body, nil
+	}
+	encoder := encoding.ReplaceUnsupported(enc.NewEncoder())
+	if mediaType == "text/html" {
+		encoder = encoding.HTMLEscapeUnsupported(enc.NewEncoder())
+	}
+	out, _, err := transform.Bytes(encoder, body)
+	if err != nil {
+		return nil, err
+	}
+	params["charset"] = name
+	h.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	return out, nil
+}
+
+// decodeRequest converts request body declared in a supported charset to UTF-8
+func decodeRequest(req *http.Request, encodings map[string]encoding.Encoding) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return
+	}
+	enc := encodings[strings.ToLower(params["charset"])]
+	if enc == nil {
+		return
+	}
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{transform.NewReader(req.Body, enc.NewDecoder()), req.Body}
+	req.ContentLength = -1
+	req.Header.Del("Content-Length")
+	params["charset"] = "utf-8"
+	req.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+}
@@ -0,0 +1,100 @@
+package charset
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func newApp(opt Options) *baa.Baa {
+	app := baa.New()
+	app.Use(Charset(opt))
+	app.Get("/text", func(c *baa.Context) {
+		c.String(200, "你好 baa ☃")
+	})
+	app.Get("/html", func(c *baa.Context) {
+		c.Text(200, []byte("<p>你好 ☃</p>"))
+	})
+	app.Get("/json", func(c *baa.Context) {
+		c.JSON(200, map[string]string{"s": "你好"})
+	})
+	app.Get("/legacy", func(c *baa.Context) {
+		c.String(200, "你好")
+	}).SetMeta(RouteMetaCharset, "GBK")
+	app.Post("/form", func(c *baa.Context) {
+		c.String(200, c.Posts()["name"].(string))
+	})
+	return app
+}
+
+func request(app *baa.Baa, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func get(app *baa.Baa, uri, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", uri, nil)
+	if accept != "" {
+		req.Header.Set("Accept-Charset", accept)
+	}
+	return request(app, req)
+}
+
+func TestCharset1(t *testing.T) {
+	app := newApp(Options{})
+
+	Convey("negotiate charset", t, func() {
+		So(negotiate("", nil), ShouldEqual, "")
+		w := get(app, "/text", "gbk, utf-8;q=0.5")
+		So(w.Header().Get("Content-Type"), ShouldEqual, "text/plain; charset=gbk")
+		So(w.Header().Get("Vary"), ShouldEqual, "Accept-Charset")
+		body, _ := simplifiedchinese.GBK.NewDecoder().String(w.Body.String())
+		So(body, ShouldEqual, "你好 baa \x1a")
+
+		w = get(app, "/text", "big5;q=0.8, *;q=0.5")
+		So(w.Header().Get("Content-Type"), ShouldEqual, "text/plain; charset=big5")
+		body, _ = traditionalchinese.Big5.NewDecoder().String(w.Body.String())
+		So(body, ShouldEqual, "你好 baa \x1a")
+
+		for _, accept := range []string{"", "utf-8, gbk", "gbk;q=0.5, *", "iso-8859-2"} {
+			w = get(app, "/text", accept)
+			So(w.Header().Get("Content-Type"), ShouldEqual, baa.TextPlainCharsetUTF8)
+			So(w.Body.String(), ShouldEqual, "你好 baa ☃")
+		}
+	})
+
+	Convey("html and json", t, func() {
+		w := get(app, "/html", "gbk")
+		body, _ := simplifiedchinese.GBK.NewDecoder().String(w.Body.String())
+		So(body, ShouldEqual, "<p>你好 &#9731;</p>")
+		w = get(app, "/json", "gbk")
+		So(w.Header().Get("Content-Type"), ShouldEqual, baa.ApplicationJSONCharsetUTF8)
+		So(w.Body.String(), ShouldContainSubstring, "你好")
+	})
+
+	Convey("forced by route", t, func() {
+		w := get(app, "/legacy", "utf-8")
+		So(w.Header().Get("Content-Type"), ShouldEqual, "text/plain; charset=gbk")
+		So(w.Header().Get("Vary"), ShouldBeEmpty)
+		gbk, _ := simplifiedchinese.GBK.NewEncoder().String("你好")
+		So(w.Body.String(), ShouldEqual, gbk)
+	})
+
+	Convey("decode request body", t, func() {
+		name, _ := simplifiedchinese.GBK.NewEncoder().String("name=张三")
+		req := httptest.NewRequest("POST", "/form", strings.NewReader(name))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=GBK")
+		So(request(app, req).Body.String(), ShouldEqual, "张三")
+	})
+
+	Convey("unsupported charset", t, func() {
+		So(func() { Charset(Options{Charsets: []string{"none"}}) }, ShouldPanic)
+	})
+}
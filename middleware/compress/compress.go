@@ -0,0 +1,178 @@
+// Package compress provides a middleware compresses responses by gzip for baa.
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-baa/baa"
+)
+
+// defaultContentTypes media types compressed by default
+var defaultContentTypes = []string{
+	"text/html", "text/plain", "text/css", "text/javascript", "text/xml", "text/csv",
+	"application/json", "application/javascript", "application/xml", "image/svg+xml",
+}
+
+// gzipPools pools gzip writers by level, indexed by level - gzip.HuffmanOnly
+var gzipPools [gzip.BestCompression - gzip.HuffmanOnly + 1]sync.Pool
+
+// Options compress middleware config
+type Options struct {
+	// Level gzip compression level, default gzip.DefaultCompression
+	Level int
+	// MinLength responses with a smaller Content-Length are not compressed, default 256
+	MinLength int
+	// ContentTypes media types are compressed, default common text types
+	ContentTypes []string
+}
+
+// Compress returns a middleware compresses responses by gzip when the client accepts
+// it. Handlers can opt out by c.DisableCompression or change the level by
+// c.SetCompressionLevel before writing, responses marked no-transform and responses
+// have Content-Encoding are sent as is.
+//
+// Example:
+// 		b.Use(compress.Compress(compress.Options{Level: gzip.BestSpeed}))
+// 		b.Get("/download", func(c *baa.Context) {
+// 			c.DisableCompression()
+// 			c.ServeContent(name, modtime, f)
+// 		})
+func Compress(opt Options) baa.HandlerFunc {
+	if opt.Level == 0 {
+		opt.Level = gzip.DefaultCompression
+	}
+	if opt.Level < gzip.HuffmanOnly || opt.Level > gzip.BestCompression {
+		panic("compress.Compress invalid level " + strconv.Itoa(opt.Level))
+	}
+	if opt.MinLength <= 0 {
+		opt.MinLength = 256
+	}
+	if len(opt.ContentTypes) == 0 {
+		opt.ContentTypes = defaultContentTypes
+	}
+	return func(c *baa.Context) {
+		if !acceptsGzip(c.Req.Header.Get("Accept-Encoding")) || c.CompressionDisabled() {
+			c.Next()
+			return
+		}
+		w := &compressWriter{c: c, opt: &opt, w: c.Resp.GetWriter()}
+		c.Resp.OnWriteHeader(w.writeHeader)
+
+		c.Next()
+
+		if w.gz != nil {
+			c.Resp.SetWriter(w.w)
+			w.close()
+		}
+	}
+}
+
+// acceptsGzip checks Accept-Encoding allows gzip
+func acceptsGzip(accept string) bool {
+	for _, v := range strings.Split(accept, ",") {
+		parts := strings.Split(v, ";")
+		if name := strings.TrimSpace(parts[0]); name != "gzip" && name != "*" {
+			continue
+		}
+		for _, p := range parts[1:] {
+			if q := strings.Replace(strings.TrimSpace(p), " ", "", -1); strings.HasPrefix(q, "q=") {
+				if f, err := strconv.ParseFloat(q[2:], 64); err == nil && f == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// matchContentType checks media type of contentType is in list
+func matchContentType(contentType string, list []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	}
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter decides to compress when the header is written, it's set as the
+// writer of response when compressing, the response itself is kept so Hijack and
+// Push reach the connection.
+type compressWriter struct {
+	c     *baa.Context
+	opt   *Options
+	w     io.Writer
+	gz    *gzip.Writer
+	level int
+}
+
+// writeHeader starts compression when the response of code can be compressed
+func (w *compressWriter) writeHeader(code int) {
+	h := w.c.Resp.Header()
+	if !w.compressible(code, h) {
+		return
+	}
+	w.level = w.opt.Level
+	if v, ok := w.c.CompressionLevel(); ok {
+		w.level = v
+	}
+	if gz, ok := gzipPools[w.level-gzip.HuffmanOnly].Get().(*gzip.Writer); ok {
+		gz.Reset(w.w)
+		w.gz = gz
+	} else {
+		w.gz, _ = gzip.NewWriterLevel(w.w, w.level)
+	}
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	h.Del("Accept-Ranges")
+	w.c.Resp.SetWriter(w)
+}
+
+// compressible checks the response of code and header h should be compressed
+func (w *compressWriter) compressible(code int, h http.Header) bool {
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified ||
+		code == http.StatusPartialContent || w.c.Req.Method == http.MethodHead {
+		return false
+	}
+	if !matchContentType(h.Get("Content-Type"), w.opt.ContentTypes) {
+		return false
+	}
+	h.Add("Vary", "Accept-Encoding")
+	if w.c.CompressionDisabled() || h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if n, err := strconv.Atoi(h.Get("Content-Length")); err == nil && n < w.opt.MinLength {
+		return false
+	}
+	return true
+}
+
+// Write writes compressed data
+func (w *compressWriter) Write(b []byte) (int, error) {
+	return w.gz.Write(b)
+}
+
+// Flush flushes compressed data, it's called by c.Resp.Flush before the response
+// is flushed to the client
+func (w *compressWriter) Flush() error {
+	return w.gz.Flush()
+}
+
+// close finishes the gzip stream and puts the writer back to the pool
+func (w *compressWriter) close() {
+	w.gz.Close()
+	w.gz.Reset(nil)
+	gzipPools[w.level-gzip.HuffmanOnly].Put(w.gz)
+	w.gz = nil
+}
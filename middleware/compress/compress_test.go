@@ -0,0 +1,109 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// pushRecorder is a response recorder supports server push
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (r *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	r.pushed = append(r.pushed, target)
+	return nil
+}
+
+func TestCompress1(t *testing.T) {
+	text := strings.Repeat("baa compress ", 100)
+	b2 := baa.New()
+	b2.Use(Compress(Options{}))
+	b2.Get("/text", func(c *baa.Context) {
+		c.String(200, text)
+	})
+	b2.Get("/small", func(c *baa.Context) {
+		c.Blob(200, baa.TextPlainCharsetUTF8, []byte("small"))
+	})
+	b2.Get("/off", func(c *baa.Context) {
+		c.DisableCompression()
+		c.String(200, text)
+	})
+	b2.Get("/fast", func(c *baa.Context) {
+		c.SetCompressionLevel(gzip.BestSpeed)
+		c.String(200, text)
+	})
+	b2.Get("/stream", func(c *baa.Context) {
+		c.String(200, text)
+	}).SetMeta(baa.RouteMetaNoTransform, true)
+	b2.Get("/push", func(c *baa.Context) {
+		So(c.Resp.Push("/app.js", nil), ShouldBeNil)
+		c.String(200, text[:10])
+		c.Resp.Flush()
+		c.String(200, text[10:])
+	})
+	get := func(uri, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", uri, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		return w
+	}
+
+	Convey("compress responses", t, func() {
+		for i := 0; i < 2; i++ {
+			for _, uri := range []string{"/text", "/fast"} {
+				w := get(uri, "deflate, gzip;q=0.8")
+				So(w.Header().Get("Content-Encoding"), ShouldEqual, "gzip")
+				So(w.Header().Get("Content-Length"), ShouldBeEmpty)
+				So(w.Header().Get("Vary"), ShouldEqual, "Accept-Encoding")
+				r, err := gzip.NewReader(w.Body)
+				So(err, ShouldBeNil)
+				body, _ := ioutil.ReadAll(r)
+				So(string(body), ShouldEqual, text)
+			}
+		}
+	})
+
+	Convey("push and flush reach the response", t, func() {
+		req, _ := http.NewRequest("GET", "/push", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		b2.ServeHTTP(w, req)
+		So(w.pushed, ShouldResemble, []string{"/app.js"})
+		So(w.Flushed, ShouldBeTrue)
+		r, err := gzip.NewReader(w.Body)
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(r)
+		So(string(body), ShouldEqual, text)
+	})
+
+	Convey("responses sent as is", t, func() {
+		w := get("/text", "gzip;q=0")
+		So(w.Header().Get("Content-Encoding"), ShouldBeEmpty)
+		So(w.Body.String(), ShouldEqual, text)
+		So(get("/text", "").Header().Get("Content-Encoding"), ShouldBeEmpty)
+		So(get("/small", "gzip").Header().Get("Content-Encoding"), ShouldBeEmpty)
+		w = get("/off", "gzip")
+		So(w.Header().Get("Content-Encoding"), ShouldBeEmpty)
+		So(w.Header().Get("Vary"), ShouldEqual, "Accept-Encoding")
+		So(w.Body.String(), ShouldEqual, text)
+		So(get("/stream", "gzip").Body.String(), ShouldEqual, text)
+	})
+
+	Convey("invalid levels", t, func() {
+		So(func() { Compress(Options{Level: 10}) }, ShouldPanic)
+		c := baa.NewContext(httptest.NewRecorder(), new(http.Request), baa.New())
+		So(func() { c.SetCompressionLevel(10) }, ShouldPanic)
+		_, ok := c.CompressionLevel()
+		So(ok, ShouldBeFalse)
+	})
+}
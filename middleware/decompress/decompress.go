@@ -0,0 +1,117 @@
+// Package decompress provides a middleware transparently decompresses request body for baa.
+package decompress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-baa/baa"
+)
+
+// defaultMaxSize default maximum decompressed body size, 32 MB
+const defaultMaxSize = 32 << 20
+
+// ErrBodyTooLarge is returned when reading a decompressed body exceeds the size limit.
+var ErrBodyTooLarge = errors.New("decompress: request body too large")
+
+// Decoder creates a decompressing reader for the compressed body
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// Options decompress middleware config
+type Options struct {
+	// MaxSize maximum decompressed body size in bytes, default 32 MB
+	MaxSize int64
+	// Decoders extra decoders by Content-Encoding, eg: register "zstd"
+	// with a zstd implementation, gzip and deflate are built in.
+	Decoders map[string]Decoder
+}
+
+// Decompress returns a middleware decompresses gzip/deflate request body before binding
+func Decompress(opt Options) baa.HandlerFunc {
+	if opt.MaxSize <= 0 {
+		opt.MaxSize = defaultMaxSize
+	}
+	decoders := map[string]Decoder{
+		"gzip":   gzipDecoder,
+		"x-gzip": gzipDecoder,
+		"deflate": func(r io.Reader) (io.ReadCloser, error) {
+			return zlib.NewReader(r)
+		},
+	}
+	for k, v := range opt.Decoders {
+		if v != nil {
+			decoders[strings.ToLower(k)] = v
+		}
+	}
+
+	return func(c *baa.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.Req.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Req.Body == nil || c.Req.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		decoder, ok := decoders[encoding]
+		if !ok {
+			http.Error(c.Resp, "Unsupported Content-Encoding", http.StatusUnsupportedMediaType)
+			return
+		}
+		rc, err := decoder(c.Req.Body)
+		if err != nil {
+			http.Error(c.Resp, "Invalid compressed body", http.StatusBadRequest)
+			return
+		}
+
+		c.Req.Body = &body{
+			reader: rc,
+			origin: c.Req.Body,
+			remain: opt.MaxSize,
+		}
+		c.Req.Header.Del("Content-Encoding")
+		c.Req.Header.Del("Content-Length")
+		c.Req.ContentLength = -1
+
+		c.Next()
+	}
+}
+
+func gzipDecoder(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// body is a decompressed request body with size limit
+type body struct {
+	reader io.ReadCloser
+	origin io.ReadCloser
+	remain int64
+}
+
+// Read reads decompressed data, returns ErrBodyTooLarge when exceed the limit
+func (b *body) Read(p []byte) (int, error) {
+	if b.remain <= 0 {
+		// check if there is more data
+		var one [1]byte
+		if n, _ := b.reader.Read(one[:]); n > 0 {
+			return 0, ErrBodyTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remain {
+		p = p[:b.remain]
+	}
+	n, err := b.reader.Read(p)
+	b.remain -= int64(n)
+	return n, err
+}
+
+// Close closes the decoder and the original body
+func (b *body) Close() error {
+	err := b.reader.Close()
+	if e := b.origin.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package decompress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp(opt Options) *baa.Baa {
+	app := baa.New()
+	app.Use(Decompress(opt))
+	app.Post("/", func(c *baa.Context) {
+		s, err := c.Body().String()
+		if err != nil {
+			c.String(http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		c.String(200, s)
+	})
+	return app
+}
+
+func compress(encoding, s string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	var w io.WriteCloser
+	if encoding == "gzip" {
+		w = gzip.NewWriter(buf)
+	} else {
+		w = zlib.NewWriter(buf)
+	}
+	w.Write([]byte(s))
+	w.Close()
+	return buf
+}
+
+func TestDecompress1(t *testing.T) {
+	Convey("decompress request body", t, func() {
+		app := newApp(Options{MaxSize: 16})
+
+		Convey("plain body", func() {
+			req, _ := http.NewRequest("POST", "/", strings.NewReader("plain"))
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			So(w.Body.String(), ShouldEqual, "plain")
+		})
+		Convey("gzip and deflate body", func() {
+			for _, enc := range []string{"gzip", "deflate"} {
+				req, _ := http.NewRequest("POST", "/", compress(enc, "hello baa"))
+				req.Header.Set("Content-Encoding", enc)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, req)
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, "hello baa")
+			}
+		})
+		Convey("body too large", func() {
+			req, _ := http.NewRequest("POST", "/", compress("gzip", strings.Repeat("a", 64)))
+			req.Header.Set("Content-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+			So(w.Body.String(), ShouldEqual, ErrBodyTooLarge.Error())
+		})
+		Convey("invalid and unsupported encoding", func() {
+			req, _ := http.NewRequest("POST", "/", strings.NewReader("not gzip"))
+			req.Header.Set("Content-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+			req, _ = http.NewRequest("POST", "/", strings.NewReader("data"))
+			req.Header.Set("Content-Encoding", "br")
+			w = httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusUnsupportedMediaType)
+		})
+		Convey("custom decoder", func() {
+			app := newApp(Options{Decoders: map[string]Decoder{
+				"upper": func(r io.Reader) (io.ReadCloser, error) {
+					b, _ := ioutil.ReadAll(r)
+					return ioutil.NopCloser(strings.NewReader(strings.ToUpper(string(b)))), nil
+				},
+			}})
+			req, _ := http.NewRequest("POST", "/", strings.NewReader("baa"))
+			req.Header.Set("Content-Encoding", "Upper")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			So(w.Body.String(), ShouldEqual, "BAA")
+		})
+	})
+}
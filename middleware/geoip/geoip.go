@@ -0,0 +1,72 @@
+// Package geoip provides a middleware annotates request with geo location for baa.
+package geoip
+
+import (
+	"net"
+
+	"github.com/go-baa/baa"
+)
+
+// contextKey context store key of location
+const contextKey = "baa.geoip"
+
+// Location is the geo location of client ip
+type Location struct {
+	Country string // ISO country code, eg: US
+	Region  string // region or subdivision code
+	City    string
+	ASN     uint   // autonomous system number
+	Org     string // autonomous system organization
+}
+
+// Resolver resolves location of ip, eg: an adapter of MaxMind database reader
+type Resolver interface {
+	Lookup(ip net.IP) (*Location, error)
+}
+
+// ResolverFunc is an adapter allows ordinary functions as Resolver
+type ResolverFunc func(ip net.IP) (*Location, error)
+
+// Lookup calls f(ip)
+func (f ResolverFunc) Lookup(ip net.IP) (*Location, error) {
+	return f(ip)
+}
+
+// Options geoip middleware config
+type Options struct {
+	// Resolver resolves location, required
+	Resolver Resolver
+	// IP returns client ip of request, default is c.RemoteAddr()
+	IP func(c *baa.Context) string
+}
+
+// GeoIP returns a middleware resolves location of client ip and sets it on context,
+// it can be read by geoip.Get in handlers and other middlewares.
+// Lookup errors are logged and the request continues without location.
+func GeoIP(opt Options) baa.HandlerFunc {
+	if opt.Resolver == nil {
+		panic("geoip.GeoIP resolver can not be nil")
+	}
+	if opt.IP == nil {
+		opt.IP = func(c *baa.Context) string {
+			return c.RemoteAddr()
+		}
+	}
+	return func(c *baa.Context) {
+		if ip := net.ParseIP(opt.IP(c)); ip != nil {
+			loc, err := opt.Resolver.Lookup(ip)
+			if err != nil {
+				c.Baa().Logger().Printf("geoip: lookup %s error: %v", ip, err)
+			} else if loc != nil {
+				c.Set(contextKey, loc)
+			}
+		}
+		c.Next()
+	}
+}
+
+// Get returns location of current request, returns nil when not resolved
+func Get(c *baa.Context) *Location {
+	loc, _ := c.Get(contextKey).(*Location)
+	return loc
+}
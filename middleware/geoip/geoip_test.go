@@ -0,0 +1,47 @@
+package geoip
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGeoIP1(t *testing.T) {
+	Convey("geoip enrichment", t, func() {
+		resolver := ResolverFunc(func(ip net.IP) (*Location, error) {
+			if ip.Equal(net.ParseIP("8.8.8.8")) {
+				return &Location{Country: "US", Region: "CA", ASN: 15169, Org: "GOOGLE"}, nil
+			}
+			return nil, errors.New("not found")
+		})
+		app := baa.New()
+		app.Use(GeoIP(Options{Resolver: resolver}))
+		var loc *Location
+		app.Get("/", func(c *baa.Context) {
+			loc = Get(c)
+		})
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Forwarded-For", "8.8.8.8")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(loc, ShouldNotBeNil)
+		So(loc.Country, ShouldEqual, "US")
+		So(loc.ASN, ShouldEqual, 15169)
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w = httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(loc, ShouldBeNil)
+
+		So(func() { GeoIP(Options{}) }, ShouldPanic)
+	})
+}
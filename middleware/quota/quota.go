@@ -0,0 +1,146 @@
+// Package quota provides a middleware limits response bytes per API key or tenant
+// over a time window for baa.
+package quota
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-baa/baa"
+)
+
+// Store records egress usage, it can be implemented by a shared store, eg: redis,
+// when the application runs on multiple instances.
+type Store interface {
+	// Usage returns bytes used by key in the current window and when the window resets
+	Usage(key string, window time.Duration) (used int64, reset time.Time)
+	// Add adds n bytes to usage of key in the current window
+	Add(key string, n int64, window time.Duration)
+}
+
+// Options quota middleware config
+type Options struct {
+	// Limit maximum response bytes of a key in a window, required
+	Limit int64
+	// Window length of quota window, default is one hour
+	Window time.Duration
+	// Key returns the API key or tenant of request, requests with empty key are not limited.
+	// Default is header X-API-Key.
+	Key func(c *baa.Context) string
+	// Store records usage, default is an in-memory store
+	Store Store
+	// Clock provides the current time, default is the DI clock of the application
+	Clock baa.Clock
+}
+
+// Quota returns a middleware responds 429 Too Many Requests when the key used up
+// its egress quota in the current window, bytes written by responses are counted.
+// A response started within quota is not truncated.
+func Quota(opt Options) baa.HandlerFunc {
+	if opt.Limit <= 0 {
+		panic("quota.Options.Limit must be greater than 0")
+	}
+	if opt.Window <= 0 {
+		opt.Window = time.Hour
+	}
+	if opt.Key == nil {
+		opt.Key = func(c *baa.Context) string {
+			return c.Req.Header.Get("X-API-Key")
+		}
+	}
+	var once sync.Once
+	return func(c *baa.Context) {
+		key := opt.Key(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		once.Do(func() {
+			if opt.Clock == nil {
+				app := c.Baa()
+				opt.Clock = baa.ClockFunc(func() time.Time {
+					return app.Clock().Now()
+				})
+			}
+			if opt.Store == nil {
+				opt.Store = newMemoryStore(opt.Clock)
+			}
+		})
+		used, reset := opt.Store.Usage(key, opt.Window)
+		h := c.Resp.Header()
+		h.Set("X-Quota-Limit", strconv.FormatInt(opt.Limit, 10))
+		h.Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if used >= opt.Limit {
+			retry := int64(reset.Sub(opt.Clock.Now())/time.Second) + 1
+			h.Set("X-Quota-Remaining", "0")
+			h.Set("Retry-After", strconv.FormatInt(retry, 10))
+			http.Error(c.Resp, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		h.Set("X-Quota-Remaining", strconv.FormatInt(opt.Limit-used, 10))
+
+		c.Next()
+
+		if n := c.Resp.Size(); n > 0 {
+			opt.Store.Add(key, n, opt.Window)
+		}
+	}
+}
+
+// memoryStore is an in-memory Store with fixed windows
+type memoryStore struct {
+	items map[string]*usage
+	clock baa.Clock
+	mu    sync.Mutex
+}
+
+// usage is the usage of a key in a window
+type usage struct {
+	used  int64
+	reset time.Time
+}
+
+// NewMemoryStore create an in-memory store, expired windows are removed lazily
+func NewMemoryStore() Store {
+	return newMemoryStore(baa.SystemClock)
+}
+
+// newMemoryStore create an in-memory store uses clock
+func newMemoryStore(clock baa.Clock) Store {
+	return &memoryStore{items: make(map[string]*usage), clock: clock}
+}
+
+// Usage returns bytes used by key in the current window
+func (s *memoryStore) Usage(key string, window time.Duration) (int64, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.current(key, window)
+	return u.used, u.reset
+}
+
+// Add adds n bytes to usage of key
+func (s *memoryStore) Add(key string, n int64, window time.Duration) {
+	s.mu.Lock()
+	s.current(key, window).used += n
+	s.mu.Unlock()
+}
+
+// current returns usage of key in the current window, starts a new window when expired
+func (s *memoryStore) current(key string, window time.Duration) *usage {
+	now := s.clock.Now()
+	u, ok := s.items[key]
+	if !ok || !now.Before(u.reset) {
+		if len(s.items) > 1024 {
+			for k, v := range s.items {
+				if !now.Before(v.reset) {
+					delete(s.items, k)
+				}
+			}
+		}
+		u = &usage{reset: now.Add(window)}
+		s.items[key] = u
+	}
+	return u
+}
@@ -0,0 +1,59 @@
+package quota
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp(opt Options) *baa.Baa {
+	app := baa.New()
+	app.Use(Quota(opt))
+	app.Get("/data", func(c *baa.Context) {
+		c.String(http.StatusOK, strings.Repeat("x", 60))
+	})
+	return app
+}
+
+func request(app *baa.Baa, key string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/data", nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func TestQuota1(t *testing.T) {
+	Convey("egress quota", t, func() {
+		clock := baa.NewFakeClock(time.Now())
+		app := newApp(Options{Limit: 100, Window: time.Minute})
+		app.SetDI("clock", clock)
+		w := request(app, "k1")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("X-Quota-Remaining"), ShouldEqual, "100")
+		w = request(app, "k1")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("X-Quota-Remaining"), ShouldEqual, "40")
+		w = request(app, "k1")
+		So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+		So(w.Header().Get("Retry-After"), ShouldEqual, "61")
+
+		So(request(app, "k2").Code, ShouldEqual, http.StatusOK)
+		So(request(app, "").Code, ShouldEqual, http.StatusOK)
+		So(request(app, "").Code, ShouldEqual, http.StatusOK)
+
+		clock.Advance(30 * time.Second)
+		So(request(app, "k1").Code, ShouldEqual, http.StatusTooManyRequests)
+		clock.Advance(30 * time.Second)
+		So(request(app, "k1").Code, ShouldEqual, http.StatusOK)
+
+		So(func() { Quota(Options{}) }, ShouldPanic)
+	})
+}
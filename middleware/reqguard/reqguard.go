@@ -0,0 +1,121 @@
+// Package reqguard provides a middleware rejects ambiguous requests for baa, hardening
+// applications against request smuggling when fronted by diverse proxies.
+package reqguard
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/go-baa/baa"
+)
+
+// defaultUniqueHeaders headers must not appear more than once by default
+var defaultUniqueHeaders = []string{"Host", "Content-Length", "Transfer-Encoding", "Content-Type", "Authorization"}
+
+// Options reqguard middleware config
+type Options struct {
+	// UniqueHeaders headers must not appear more than once, default is Host,
+	// Content-Length, Transfer-Encoding, Content-Type and Authorization.
+	UniqueHeaders []string
+	// PathChars extra characters disallowed in the raw path, control characters,
+	// backslash and encoded NUL, CR, LF are always disallowed.
+	PathChars string
+}
+
+// ReqGuard returns a middleware rejects requests with conflicting Content-Length and
+// Transfer-Encoding, disallowed characters in the path or duplicate unique headers,
+// it logs the reason and responds 400 with the connection closed.
+//
+// net/http already rejects invalid Content-Length and unsupported Transfer-Encoding,
+// and drops Content-Length sent with chunked, so the body length checks only apply
+// when requests are built by other front ends, eg: an adapter of another server.
+func ReqGuard(opt Options) baa.HandlerFunc {
+	if opt.UniqueHeaders == nil {
+		opt.UniqueHeaders = defaultUniqueHeaders
+	}
+	unique := make([]string, len(opt.UniqueHeaders))
+	for i, k := range opt.UniqueHeaders {
+		unique[i] = textproto.CanonicalMIMEHeaderKey(k)
+	}
+	return func(c *baa.Context) {
+		msg := checkLength(c.Req)
+		if msg == "" {
+			msg = checkPath(c.Req, opt.PathChars)
+		}
+		if msg == "" {
+			msg = checkUnique(c.Req.Header, unique)
+		}
+		if msg != "" {
+			reject(c, msg)
+			return
+		}
+		c.Next()
+	}
+}
+
+// reject logs and responds the request, the connection is not reused
+func reject(c *baa.Context, msg string) {
+	c.Baa().Logger().Printf("reqguard: %s %s %q from %s", msg, c.Req.Method, c.Req.RequestURI, c.RemoteAddr())
+	c.Resp.Header().Set("Connection", "close")
+	http.Error(c.Resp, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+}
+
+// checkLength returns the ambiguity of body length, requests parsed by net/http
+// always pass it
+func checkLength(r *http.Request) string {
+	cl := r.Header["Content-Length"]
+	te := append([]string(nil), r.TransferEncoding...)
+	te = append(te, r.Header["Transfer-Encoding"]...)
+	if len(te) > 0 && len(cl) > 0 {
+		return "both Content-Length and Transfer-Encoding"
+	}
+	for _, v := range cl {
+		if v == "" || strings.Trim(v, "0123456789") != "" {
+			return "invalid Content-Length"
+		}
+	}
+	if len(te) > 0 {
+		// only a single chunked coding is accepted, proxies disagree on others
+		if len(te) > 1 || !strings.EqualFold(te[0], "chunked") {
+			return "unsupported Transfer-Encoding"
+		}
+	}
+	return ""
+}
+
+// checkPath returns the disallowed character found in the raw path
+func checkPath(r *http.Request, chars string) string {
+	path := r.RequestURI
+	if path == "" {
+		path = r.URL.RequestURI()
+	}
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] < 0x20 || path[i] == 0x7f {
+			return "control character in path"
+		}
+	}
+	if strings.IndexByte(path, '\\') >= 0 || (chars != "" && strings.ContainsAny(path, chars)) {
+		return "disallowed character in path"
+	}
+	lower := strings.ToLower(path)
+	for _, s := range []string{"%00", "%0a", "%0d"} {
+		if strings.Contains(lower, s) {
+			return "encoded control character in path"
+		}
+	}
+	return ""
+}
+
+// checkUnique returns the unique header appears more than once
+func checkUnique(h http.Header, unique []string) string {
+	for _, k := range unique {
+		if len(h[k]) > 1 {
+			return "duplicate " + k + " header"
+		}
+	}
+	return ""
+}
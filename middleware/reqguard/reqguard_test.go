@@ -0,0 +1,127 @@
+package reqguard
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp(opt Options, logs *bytes.Buffer) *baa.Baa {
+	app := baa.New()
+	app.SetDI("logger", log.New(logs, "", 0))
+	app.Use(ReqGuard(opt))
+	app.Any("/*", func(c *baa.Context) {
+		c.String(200, "ok")
+	})
+	return app
+}
+
+func request(app *baa.Baa, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+// rawRequest sends raw to server addr and returns the response status code
+func rawRequest(addr, raw string) int {
+	conn, err := net.Dial("tcp", addr)
+	So(err, ShouldBeNil)
+	defer conn.Close()
+	_, err = conn.Write([]byte(raw))
+	So(err, ShouldBeNil)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	So(err, ShouldBeNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestReqGuard1(t *testing.T) {
+	Convey("body length", t, func() {
+		logs := new(bytes.Buffer)
+		app := newApp(Options{}, logs)
+
+		req := httptest.NewRequest("POST", "/", strings.NewReader("a"))
+		req.Header.Set("Content-Length", "1")
+		So(request(app, req).Code, ShouldEqual, http.StatusOK)
+
+		req = httptest.NewRequest("POST", "/", strings.NewReader("a"))
+		req.Header.Set("Content-Length", "1")
+		req.TransferEncoding = []string{"chunked"}
+		w := request(app, req)
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+		So(w.Header().Get("Connection"), ShouldEqual, "close")
+		So(logs.String(), ShouldContainSubstring, "reqguard: both Content-Length and Transfer-Encoding POST")
+
+		req = httptest.NewRequest("POST", "/", strings.NewReader("a"))
+		req.Header.Set("Content-Length", "+1")
+		So(request(app, req).Code, ShouldEqual, http.StatusBadRequest)
+
+		req = httptest.NewRequest("POST", "/", strings.NewReader("a"))
+		req.Header["Transfer-Encoding"] = []string{"chunked", "identity"}
+		So(request(app, req).Code, ShouldEqual, http.StatusBadRequest)
+
+		req = httptest.NewRequest("POST", "/", strings.NewReader("a"))
+		req.TransferEncoding = []string{"Chunked"}
+		So(request(app, req).Code, ShouldEqual, http.StatusOK)
+	})
+
+	Convey("raw requests to a server", t, func() {
+		logs := new(bytes.Buffer)
+		ts := httptest.NewServer(newApp(Options{PathChars: ";"}, logs))
+		defer ts.Close()
+		addr := ts.Listener.Addr().String()
+
+		So(rawRequest(addr, "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 1\r\n\r\na"), ShouldEqual, http.StatusOK)
+		// rejected by net/http before the middleware
+		So(rawRequest(addr, "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: +1\r\n\r\na"), ShouldEqual, http.StatusBadRequest)
+		So(rawRequest(addr, "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: gzip, chunked\r\n\r\n0\r\n\r\n"), ShouldNotEqual, http.StatusOK)
+		So(logs.String(), ShouldBeEmpty)
+
+		So(rawRequest(addr, "GET /a;b HTTP/1.1\r\nHost: a\r\n\r\n"), ShouldEqual, http.StatusBadRequest)
+		So(rawRequest(addr, "GET /a%0d%0ab HTTP/1.1\r\nHost: a\r\n\r\n"), ShouldEqual, http.StatusBadRequest)
+		So(rawRequest(addr, "GET / HTTP/1.1\r\nHost: a\r\nAuthorization: a\r\nAuthorization: b\r\n\r\n"), ShouldEqual, http.StatusBadRequest)
+		So(logs.String(), ShouldContainSubstring, "reqguard: disallowed character in path")
+		So(logs.String(), ShouldContainSubstring, "reqguard: encoded control character in path")
+		So(logs.String(), ShouldContainSubstring, "reqguard: duplicate Authorization header")
+	})
+
+	Convey("path characters", t, func() {
+		logs := new(bytes.Buffer)
+		app := newApp(Options{PathChars: ";"}, logs)
+		So(request(app, httptest.NewRequest("GET", "/a/b?q=%00", nil)).Code, ShouldEqual, http.StatusOK)
+
+		for _, uri := range []string{"/a\x00b", "/a\\b", "/a%0D%0Ab", "/a;b"} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RequestURI = uri
+			So(request(app, req).Code, ShouldEqual, http.StatusBadRequest)
+		}
+		So(logs.String(), ShouldContainSubstring, "reqguard: control character in path")
+		So(logs.String(), ShouldContainSubstring, "reqguard: encoded control character in path")
+	})
+
+	Convey("duplicate headers", t, func() {
+		logs := new(bytes.Buffer)
+		app := newApp(Options{}, logs)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Add("Authorization", "Bearer a")
+		req.Header.Add("Authorization", "Bearer b")
+		So(request(app, req).Code, ShouldEqual, http.StatusBadRequest)
+		So(logs.String(), ShouldContainSubstring, "reqguard: duplicate Authorization header")
+
+		req = httptest.NewRequest("GET", "/", nil)
+		req.Header.Add("Accept", "text/html")
+		req.Header.Add("Accept", "text/plain")
+		So(request(app, req).Code, ShouldEqual, http.StatusOK)
+
+		app = newApp(Options{UniqueHeaders: []string{"accept"}}, logs)
+		So(request(app, req).Code, ShouldEqual, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,157 @@
+// Package reqlimit provides a middleware limits request headers, query params and
+// multipart parts for baa, hardening public-facing gateways.
+package reqlimit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/go-baa/baa"
+)
+
+// defaultMaxMemory maximum memory of multipart form parsing, same as baa
+const defaultMaxMemory = 32 << 20
+
+// errTooManyParts is returned when multipart body has too many parts
+var errTooManyParts = errors.New("reqlimit: too many multipart parts")
+
+// Options reqlimit middleware config, 0 means no limit
+type Options struct {
+	// MaxHeaders maximum number of header values
+	MaxHeaders int
+	// MaxHeaderBytes maximum size of one header, name and value
+	MaxHeaderBytes int
+	// MaxTotalHeaderBytes maximum size of all headers
+	MaxTotalHeaderBytes int
+	// MaxQueryParams maximum number of query params
+	MaxQueryParams int
+	// MaxMultipartParts maximum number of multipart parts, the form is parsed
+	// by the middleware when it is set.
+	MaxMultipartParts int
+	// MaxMultipartMemory maximum memory of multipart form parsing, default 32 MB
+	MaxMultipartMemory int64
+}
+
+// ReqLimit returns a middleware rejects requests exceed limits, responds 431 for
+// headers and 400 for query params and multipart parts.
+func ReqLimit(opt Options) baa.HandlerFunc {
+	if opt.MaxMultipartMemory <= 0 {
+		opt.MaxMultipartMemory = defaultMaxMemory
+	}
+	return func(c *baa.Context) {
+		if msg := checkHeader(c.Req.Header, opt); msg != "" {
+			reject(c, http.StatusRequestHeaderFieldsTooLarge, msg)
+			return
+		}
+		if opt.MaxQueryParams > 0 && countQuery(c.Req.URL.RawQuery) > opt.MaxQueryParams {
+			reject(c, http.StatusBadRequest, "too many query params")
+			return
+		}
+		if opt.MaxMultipartParts > 0 {
+			if err := parseMultipart(c.Req, opt); err != nil {
+				if err == errTooManyParts {
+					reject(c, http.StatusBadRequest, "too many multipart parts")
+				} else {
+					reject(c, http.StatusBadRequest, "invalid multipart body")
+				}
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// reject logs and responds the request
+func reject(c *baa.Context, code int, msg string) {
+	c.Baa().Logger().Printf("reqlimit: %s %s %s from %s", msg, c.Req.Method, c.Req.URL.Path, c.RemoteAddr())
+	http.Error(c.Resp, http.StatusText(code), code)
+}
+
+// checkHeader returns the violated limit of headers
+func checkHeader(h http.Header, opt Options) string {
+	var count, total int
+	for k, vs := range h {
+		for _, v := range vs {
+			count++
+			size := len(k) + len(v)
+			total += size
+			if opt.MaxHeaderBytes > 0 && size > opt.MaxHeaderBytes {
+				return "header " + k + " too large"
+			}
+		}
+	}
+	if opt.MaxHeaders > 0 && count > opt.MaxHeaders {
+		return "too many headers"
+	}
+	if opt.MaxTotalHeaderBytes > 0 && total > opt.MaxTotalHeaderBytes {
+		return "headers too large"
+	}
+	return ""
+}
+
+// countQuery returns number of params in raw query without parsing
+func countQuery(raw string) int {
+	if raw == "" {
+		return 0
+	}
+	return strings.Count(raw, "&") + strings.Count(raw, ";") + 1
+}
+
+// parseMultipart parses multipart form with parts counted
+func parseMultipart(r *http.Request, opt Options) error {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return nil
+	}
+	body := &partCounter{
+		reader: r.Body,
+		origin: r.Body,
+		delim:  []byte("\r\n--" + params["boundary"]),
+		remain: opt.MaxMultipartParts + 1, // the last delimiter closes body
+		tail:   []byte("\r\n"),            // first delimiter has no leading CRLF
+	}
+	r.Body = body
+	err = r.ParseMultipartForm(opt.MaxMultipartMemory)
+	if body.exceeded {
+		return errTooManyParts
+	}
+	return err
+}
+
+// partCounter is a request body counts multipart delimiters
+type partCounter struct {
+	reader   io.Reader
+	origin   io.ReadCloser
+	delim    []byte
+	tail     []byte
+	remain   int
+	exceeded bool
+}
+
+// Read reads body and counts delimiters, returns error when parts exceed limit
+func (p *partCounter) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	if n > 0 {
+		data := append(p.tail, b[:n]...)
+		p.remain -= bytes.Count(data, p.delim)
+		if p.remain < 0 {
+			p.exceeded = true
+			return n, errTooManyParts
+		}
+		keep := len(p.delim) - 1
+		if len(data) < keep {
+			keep = len(data)
+		}
+		p.tail = append(p.tail[:0], data[len(data)-keep:]...)
+	}
+	return n, err
+}
+
+// Close closes the original body
+func (p *partCounter) Close() error {
+	return p.origin.Close()
+}
@@ -0,0 +1,114 @@
+package reqlimit
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp(opt Options) *baa.Baa {
+	app := baa.New()
+	app.Use(ReqLimit(opt))
+	app.Any("/", func(c *baa.Context) {
+		c.String(200, c.Req.FormValue("f0"))
+	})
+	return app
+}
+
+func multipartBody(parts int) (*bytes.Buffer, string) {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	for i := 0; i < parts; i++ {
+		w.WriteField("f"+string(rune('0'+i)), strings.Repeat("v", 100))
+	}
+	w.Close()
+	return body, w.FormDataContentType()
+}
+
+func TestReqLimit1(t *testing.T) {
+	Convey("request limits", t, func() {
+		app := newApp(Options{
+			MaxHeaders:          5,
+			MaxHeaderBytes:      128,
+			MaxTotalHeaderBytes: 160,
+			MaxQueryParams:      3,
+			MaxMultipartParts:   3,
+		})
+		serve := func(req *http.Request) int {
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		req, _ := http.NewRequest("GET", "/?a=1&b=2&c=3", nil)
+		So(serve(req), ShouldEqual, http.StatusOK)
+
+		req, _ = http.NewRequest("GET", "/?a=1&b=2&c=3&d=4", nil)
+		So(serve(req), ShouldEqual, http.StatusBadRequest)
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Large", strings.Repeat("x", 128))
+		So(serve(req), ShouldEqual, http.StatusRequestHeaderFieldsTooLarge)
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		for i := 0; i < 6; i++ {
+			req.Header.Add("X-Many", "1")
+		}
+		So(serve(req), ShouldEqual, http.StatusRequestHeaderFieldsTooLarge)
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		for i := 0; i < 5; i++ {
+			req.Header.Add("X-Total", strings.Repeat("x", 40))
+		}
+		So(serve(req), ShouldEqual, http.StatusRequestHeaderFieldsTooLarge)
+
+		body, contentType := multipartBody(3)
+		req, _ = http.NewRequest("POST", "/", body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, strings.Repeat("v", 100))
+
+		body, contentType = multipartBody(4)
+		req, _ = http.NewRequest("POST", "/", body)
+		req.Header.Set("Content-Type", contentType)
+		So(serve(req), ShouldEqual, http.StatusBadRequest)
+
+		req, _ = http.NewRequest("POST", "/", strings.NewReader("invalid"))
+		req.Header.Set("Content-Type", contentType)
+		So(serve(req), ShouldEqual, http.StatusBadRequest)
+	})
+
+	Convey("delimiter across reads", t, func() {
+		body, contentType := multipartBody(5)
+		boundary := contentType[strings.Index(contentType, "=")+1:]
+		p := &partCounter{
+			reader: &oneByteReader{body},
+			delim:  []byte("\r\n--" + boundary),
+			remain: 100,
+			tail:   []byte("\r\n"),
+		}
+		buf := make([]byte, 1)
+		for {
+			if _, err := p.Read(buf); err != nil {
+				break
+			}
+		}
+		So(p.remain, ShouldEqual, 100-6)
+	})
+}
+
+type oneByteReader struct {
+	r *bytes.Buffer
+}
+
+func (r *oneByteReader) Read(b []byte) (int, error) {
+	return r.r.Read(b[:1])
+}
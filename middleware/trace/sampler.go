@@ -0,0 +1,99 @@
+package trace
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sync"
+	"time"
+)
+
+// Parameters are the inputs of sampling decision
+type Parameters struct {
+	TraceID       string
+	HasParent     bool // request carries a valid traceparent
+	ParentSampled bool // the caller sampled the trace
+	Method        string
+	Route         string // matched route pattern
+}
+
+// Sampler decides whether a trace is sampled
+type Sampler interface {
+	ShouldSample(p Parameters) bool
+}
+
+// SamplerFunc is an adapter allows ordinary functions as Sampler
+type SamplerFunc func(p Parameters) bool
+
+// ShouldSample calls f(p)
+func (f SamplerFunc) ShouldSample(p Parameters) bool {
+	return f(p)
+}
+
+// AlwaysSample samples every trace
+func AlwaysSample() Sampler {
+	return SamplerFunc(func(Parameters) bool {
+		return true
+	})
+}
+
+// NeverSample samples no trace
+func NeverSample() Sampler {
+	return SamplerFunc(func(Parameters) bool {
+		return false
+	})
+}
+
+// RatioSampler samples the ratio of traces, the decision is derived from trace id,
+// so services use the same ratio make the same decision of a trace.
+func RatioSampler(ratio float64) Sampler {
+	if ratio >= 1 {
+		return AlwaysSample()
+	}
+	if ratio <= 0 {
+		return NeverSample()
+	}
+	bound := uint64(ratio * math.MaxUint64)
+	return SamplerFunc(func(p Parameters) bool {
+		id, err := hex.DecodeString(p.TraceID)
+		if err != nil || len(id) < 16 {
+			return false
+		}
+		return binary.BigEndian.Uint64(id[8:16]) < bound
+	})
+}
+
+// RateLimitedSampler samples at most perSecond traces per second
+func RateLimitedSampler(perSecond float64) Sampler {
+	if perSecond <= 0 {
+		return NeverSample()
+	}
+	var mu sync.Mutex
+	tokens, last := perSecond, time.Now()
+	return SamplerFunc(func(Parameters) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		tokens += now.Sub(last).Seconds() * perSecond
+		if tokens > perSecond {
+			tokens = perSecond
+		}
+		last = now
+		if tokens < 1 {
+			return false
+		}
+		tokens--
+		return true
+	})
+}
+
+// ParentBased follows the decision of the caller when request has a parent,
+// root uses for requests without parent.
+func ParentBased(root Sampler) Sampler {
+	return SamplerFunc(func(p Parameters) bool {
+		if p.HasParent {
+			return p.ParentSampled
+		}
+		return root.ShouldSample(p)
+	})
+}
@@ -0,0 +1,143 @@
+// Package trace provides a request tracing middleware with W3C trace context and
+// configurable sampling for baa.
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/go-baa/baa"
+)
+
+// contextKey context store key of span
+const contextKey = "baa.trace"
+
+// Span is the server span of a request
+type Span struct {
+	TraceID  string // 32 hex chars
+	SpanID   string // 16 hex chars
+	ParentID string // span id of the caller, empty for root spans
+	Sampled  bool
+	Name     string // method and route pattern, eg: GET /users/:id
+	Start    time.Time
+	End      time.Time
+	Status   int
+}
+
+// Traceparent returns W3C traceparent header value of span
+func (s *Span) Traceparent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return "00-" + s.TraceID + "-" + s.SpanID + "-" + flags
+}
+
+// Exporter exports finished sampled spans, eg: an adapter of OpenTelemetry or Zipkin client
+type Exporter interface {
+	Export(s *Span)
+}
+
+// ExporterFunc is an adapter allows ordinary functions as Exporter
+type ExporterFunc func(s *Span)
+
+// Export calls f(s)
+func (f ExporterFunc) Export(s *Span) {
+	f(s)
+}
+
+// Options trace middleware config
+type Options struct {
+	// Exporter exports sampled spans, required
+	Exporter Exporter
+	// Sampler decides whether a request is sampled, default is ParentBased(AlwaysSample())
+	Sampler Sampler
+	// Routes overrides sampler by route pattern, eg: {"/health": NeverSample()}
+	Routes map[string]Sampler
+}
+
+// Trace returns a middleware starts a span for every request, continues the trace of
+// incoming traceparent header. The incoming header is replaced by the current span,
+// so baa HTTP client propagates it to outbound requests.
+func Trace(opt Options) baa.HandlerFunc {
+	if opt.Exporter == nil {
+		panic("trace.Options.Exporter can not be nil")
+	}
+	if opt.Sampler == nil {
+		opt.Sampler = ParentBased(AlwaysSample())
+	}
+	return func(c *baa.Context) {
+		s := &Span{
+			SpanID: newID(8),
+			Name:   c.Req.Method + " " + c.RoutePattern(),
+			Start:  time.Now(),
+		}
+		p := Parameters{Method: c.Req.Method, Route: c.RoutePattern()}
+		if traceID, parentID, sampled, ok := parseTraceparent(c.Req.Header.Get("Traceparent")); ok {
+			s.TraceID, s.ParentID = traceID, parentID
+			p.HasParent, p.ParentSampled = true, sampled
+		} else {
+			s.TraceID = newID(16)
+		}
+		p.TraceID = s.TraceID
+
+		sampler := opt.Sampler
+		if v, ok := opt.Routes[p.Route]; ok {
+			sampler = v
+		}
+		s.Sampled = sampler.ShouldSample(p)
+		c.Set(contextKey, s)
+		c.Req.Header.Set("Traceparent", s.Traceparent())
+
+		defer func() {
+			if s.Sampled {
+				s.End = time.Now()
+				s.Status = c.Resp.Status()
+				opt.Exporter.Export(s)
+			}
+		}()
+		c.Next()
+	}
+}
+
+// Get returns span of current request, nil when trace middleware not used
+func Get(c *baa.Context) *Span {
+	s, _ := c.Get(contextKey).(*Span)
+	return s
+}
+
+// parseTraceparent parses W3C traceparent header, eg: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceparent(v string) (traceID, parentID string, sampled bool, ok bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		!isHex(parts[1], 32) || !isHex(parts[2], 16) || !isHex(parts[3], 2) {
+		return "", "", false, false
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", "", false, false
+	}
+	flags, _ := hex.DecodeString(parts[3])
+	return parts[1], parts[2], flags[0]&1 == 1, true
+}
+
+// isHex checks s is n lower hex chars
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !(s[i] >= '0' && s[i] <= '9' || s[i] >= 'a' && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// newID returns n random bytes in hex
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,87 @@
+package trace
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newApp(opt Options) (*baa.Baa, *[]*Span) {
+	spans := new([]*Span)
+	opt.Exporter = ExporterFunc(func(s *Span) {
+		*spans = append(*spans, s)
+	})
+	app := baa.New()
+	app.Use(Trace(opt))
+	app.Get("/users/:id", func(c *baa.Context) {
+		c.String(http.StatusOK, c.Req.Header.Get("Traceparent"))
+	})
+	app.Get("/health", func(c *baa.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return app, spans
+}
+
+func request(app *baa.Baa, path, traceparent string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if traceparent != "" {
+		req.Header.Set("Traceparent", traceparent)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func TestTrace1(t *testing.T) {
+	Convey("trace spans", t, func() {
+		app, spans := newApp(Options{Routes: map[string]Sampler{"/health": NeverSample()}})
+		parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		w := request(app, "/users/1", parent)
+		So(*spans, ShouldHaveLength, 1)
+		s := (*spans)[0]
+		So(s.TraceID, ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+		So(s.ParentID, ShouldEqual, "00f067aa0ba902b7")
+		So(s.Name, ShouldEqual, "GET /users/:id")
+		So(s.Status, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, s.Traceparent())
+
+		// parent not sampled
+		request(app, "/users/1", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		So(*spans, ShouldHaveLength, 1)
+		// route override
+		request(app, "/health", "")
+		So(*spans, ShouldHaveLength, 1)
+		// invalid parent starts a new root trace
+		request(app, "/users/1", "00-xyz-00f067aa0ba902b7-01")
+		So(*spans, ShouldHaveLength, 2)
+		So((*spans)[1].ParentID, ShouldEqual, "")
+		So((*spans)[1].TraceID, ShouldHaveLength, 32)
+
+		So(func() { Trace(Options{}) }, ShouldPanic)
+	})
+	Convey("samplers", t, func() {
+		p := Parameters{TraceID: "4bf92f3577b34da6ffffffffffffffff"}
+		So(RatioSampler(0.5).ShouldSample(p), ShouldBeFalse)
+		p.TraceID = "4bf92f3577b34da60000000000000001"
+		So(RatioSampler(0.5).ShouldSample(p), ShouldBeTrue)
+		So(RatioSampler(1).ShouldSample(p), ShouldBeTrue)
+		So(RatioSampler(0).ShouldSample(p), ShouldBeFalse)
+		So(RatioSampler(0.5).ShouldSample(Parameters{TraceID: "bad"}), ShouldBeFalse)
+
+		rl := RateLimitedSampler(2)
+		So(rl.ShouldSample(p), ShouldBeTrue)
+		So(rl.ShouldSample(p), ShouldBeTrue)
+		So(rl.ShouldSample(p), ShouldBeFalse)
+		time.Sleep(600 * time.Millisecond)
+		So(rl.ShouldSample(p), ShouldBeTrue)
+		So(RateLimitedSampler(0).ShouldSample(p), ShouldBeFalse)
+
+		pb := ParentBased(NeverSample())
+		So(pb.ShouldSample(p), ShouldBeFalse)
+		So(pb.ShouldSample(Parameters{HasParent: true, ParentSampled: true}), ShouldBeTrue)
+	})
+}
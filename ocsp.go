@@ -0,0 +1,172 @@
+package baa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	// sha1 is required by OCSP CertID
+	_ "crypto/sha1"
+)
+
+// ocspMaxResponseSize maximum size of OCSP response
+const ocspMaxResponseSize = 1 << 20
+
+var (
+	oidSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidOCSPBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+)
+
+// ocspCertID identifies a certificate in OCSP requests and responses, RFC 6960
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+// ocspRequest is an unsigned OCSP request
+type ocspRequest struct {
+	TBSRequest struct {
+		Version     int `asn1:"explicit,tag:0,default:0,optional"`
+		RequestList []struct {
+			Cert ocspCertID
+		}
+	}
+}
+
+// ocspResponse is the OCSP response envelope
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response struct {
+		ResponseType asn1.ObjectIdentifier
+		Response     []byte
+	} `asn1:"explicit,tag:0,optional"`
+}
+
+// ocspBasicResponse is the basic OCSP response
+type ocspBasicResponse struct {
+	TBSResponseData struct {
+		Version     int `asn1:"optional,default:0,explicit,tag:0"`
+		ResponderID asn1.RawValue
+		ProducedAt  time.Time `asn1:"generalized"`
+		Responses   []ocspSingleResponse
+	}
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+// ocspSingleResponse is the status of a certificate in OCSP response
+type ocspSingleResponse struct {
+	CertID  ocspCertID
+	Good    asn1.Flag `asn1:"tag:0,optional"`
+	Revoked struct {
+		RevocationTime time.Time       `asn1:"generalized"`
+		Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+	} `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// newOCSPCertID returns the CertID of leaf issued by issuer
+func newOCSPCertID(leaf, issuer *x509.Certificate) (ocspCertID, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return ocspCertID{}, err
+	}
+	nameHash := crypto.SHA1.New()
+	nameHash.Write(issuer.RawSubject)
+	keyHash := crypto.SHA1.New()
+	keyHash.Write(spki.PublicKey.RightAlign())
+	return ocspCertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.RawValue{Tag: asn1.TagNull}},
+		NameHash:      nameHash.Sum(nil),
+		IssuerKeyHash: keyHash.Sum(nil),
+		SerialNumber:  leaf.SerialNumber,
+	}, nil
+}
+
+// FetchOCSP requests the OCSP response of leaf issued by issuer from the first OCSP
+// server of leaf, it returns the raw response for stapling and its next update time.
+// The response is checked to be successful, for leaf, good and fresh. The signature
+// is not verified, clients verify stapled responses.
+func FetchOCSP(client *http.Client, leaf, issuer *x509.Certificate) (staple []byte, nextUpdate time.Time, err error) {
+	if len(leaf.OCSPServer) == 0 {
+		return nil, nextUpdate, errors.New("baa: certificate has no OCSP server")
+	}
+	id, err := newOCSPCertID(leaf, issuer)
+	if err != nil {
+		return nil, nextUpdate, err
+	}
+	var req ocspRequest
+	req.TBSRequest.RequestList = append(req.TBSRequest.RequestList, struct{ Cert ocspCertID }{id})
+	body, err := asn1.Marshal(req)
+	if err != nil {
+		return nil, nextUpdate, err
+	}
+	resp, err := client.Post(leaf.OCSPServer[0], "application/ocsp-request", bytes.NewReader(body))
+	if err != nil {
+		return nil, nextUpdate, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nextUpdate, fmt.Errorf("baa: OCSP server responded %s", resp.Status)
+	}
+	staple, err = ioutil.ReadAll(io.LimitReader(resp.Body, ocspMaxResponseSize))
+	if err != nil {
+		return nil, nextUpdate, err
+	}
+	nextUpdate, err = checkOCSPResponse(staple, id, time.Now())
+	if err != nil {
+		return nil, nextUpdate, err
+	}
+	return staple, nextUpdate, nil
+}
+
+// checkOCSPResponse checks raw OCSP response is successful and good for id at now,
+// returns next update time of the response.
+func checkOCSPResponse(raw []byte, id ocspCertID, now time.Time) (time.Time, error) {
+	var resp ocspResponse
+	if _, err := asn1.Unmarshal(raw, &resp); err != nil {
+		return time.Time{}, fmt.Errorf("baa: invalid OCSP response: %v", err)
+	}
+	if resp.Status != 0 {
+		return time.Time{}, fmt.Errorf("baa: OCSP response status %d", resp.Status)
+	}
+	if !resp.Response.ResponseType.Equal(oidOCSPBasicResponse) {
+		return time.Time{}, errors.New("baa: unsupported OCSP response type")
+	}
+	var basic ocspBasicResponse
+	if _, err := asn1.Unmarshal(resp.Response.Response, &basic); err != nil {
+		return time.Time{}, fmt.Errorf("baa: invalid OCSP basic response: %v", err)
+	}
+	for _, r := range basic.TBSResponseData.Responses {
+		if r.CertID.SerialNumber == nil || r.CertID.SerialNumber.Cmp(id.SerialNumber) != 0 ||
+			!bytes.Equal(r.CertID.IssuerKeyHash, id.IssuerKeyHash) {
+			continue
+		}
+		if !r.Good {
+			return time.Time{}, errors.New("baa: OCSP certificate status is not good")
+		}
+		if now.Before(r.ThisUpdate) || (!r.NextUpdate.IsZero() && !now.Before(r.NextUpdate)) {
+			return time.Time{}, errors.New("baa: OCSP response is not fresh")
+		}
+		return r.NextUpdate, nil
+	}
+	return time.Time{}, errors.New("baa: OCSP response does not match the certificate")
+}
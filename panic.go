@@ -0,0 +1,66 @@
+package baa
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+)
+
+// PanicError is the error passed to error handler when a handler or middleware panics
+type PanicError struct {
+	Value      interface{} // value passed to panic
+	Stack      []byte      // stack of the panicking goroutine
+	Method     string      // request method
+	URL        string      // request url
+	RemoteAddr string      // request remote address
+	Route      string      // matched route pattern
+}
+
+// Error implements error interface
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v [%s %s]", e.Value, e.Method, e.URL)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// newPanicError create a panic error with stack and request metadata
+func newPanicError(v interface{}, c *Context) *PanicError {
+	stack := make([]byte, 64<<10)
+	stack = stack[:runtime.Stack(stack, false)]
+	e := &PanicError{
+		Value: v,
+		Stack: stack,
+		Route: c.RoutePattern(),
+	}
+	if c.Req != nil {
+		e.Method = c.Req.Method
+		e.URL = c.Req.URL.String()
+		e.RemoteAddr = c.Req.RemoteAddr
+	}
+	return e
+}
+
+// recoverPanic converts a panic into PanicError and passes it to error handler,
+// http.ErrAbortHandler is panicked again for net/http aborts the response.
+func (b *Baa) recoverPanic(c *Context) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	err := newPanicError(v, c)
+	if c.Resp.Hijacked() || c.Resp.Wrote() {
+		// response has been sent, only log it
+		b.Logger().Printf("%s\n%s", err, err.Stack)
+		return
+	}
+	b.Error(err, c)
+}
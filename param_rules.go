@@ -0,0 +1,185 @@
+package baa
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RouteMetaParams is the route meta key of ParamRules checked by ValidateParams
+const RouteMetaParams = "baa.params"
+
+// ParamRules declares constraints of route params and query values, rules use the
+// syntax of TagValidator tags, eg: "int,min=1", "omitempty,oneof=asc desc".
+// The first rule can be a type: int, float or bool, the value is converted before
+// other rules so min and max compare numbers, string values compare lengths.
+// Empty values are only checked by required.
+type ParamRules struct {
+	Params map[string]string
+	Query  map[string]string
+}
+
+// paramTypes messages of type rules
+var paramTypes = map[string]string{
+	"int":   "must be an integer",
+	"float": "must be a number",
+	"bool":  "must be a boolean",
+}
+
+// routeMetaParamRules is the route meta key of rules parsed from ParamRules
+const routeMetaParamRules = "baa.params.parsed"
+
+// paramValidator validates rules when the app validator is not a TagValidator
+var paramValidator = NewTagValidator()
+
+// paramCheck is a parsed rule with its param
+type paramCheck struct {
+	rule  string
+	param string
+}
+
+// paramRule is the parsed rules of a param or query value
+type paramRule struct {
+	name     string
+	typ      string
+	required bool
+	checks   []paramCheck
+}
+
+// parsedParamRules is ParamRules parsed by SetMeta, checks use validator t
+type parsedParamRules struct {
+	t      *TagValidator
+	params []paramRule
+	query  []paramRule
+}
+
+// parseParamRules parses rules for the app validator of b, unknown rules are errors
+func parseParamRules(b *Baa, rules ParamRules) (*parsedParamRules, error) {
+	t := paramValidator
+	if b != nil {
+		if v, ok := b.validator.(*TagValidator); ok {
+			t = v
+		}
+	}
+	p := &parsedParamRules{t: t}
+	var err error
+	if p.params, err = t.parseParamRules(rules.Params); err != nil {
+		return nil, err
+	}
+	if p.query, err = t.parseParamRules(rules.Query); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseParamRules parses rules of values, names are sorted so they are checked in order
+func (t *TagValidator) parseParamRules(rules map[string]string) ([]paramRule, error) {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	parsed := make([]paramRule, 0, len(names))
+	for _, name := range names {
+		rs := strings.Split(rules[name], ",")
+		p := paramRule{name: name}
+		if typ := strings.TrimSpace(rs[0]); paramTypes[typ] != "" {
+			p.typ = typ
+			rs = rs[1:]
+		}
+		for _, r := range rs {
+			r = strings.TrimSpace(r)
+			switch r {
+			case "", "omitempty":
+				continue
+			case "required":
+				p.required = true
+				continue
+			}
+			c := paramCheck{rule: r}
+			if i := strings.IndexByte(r, '='); i >= 0 {
+				c.rule, c.param = r[:i], r[i+1:]
+			}
+			if _, ok := t.rules[c.rule]; !ok {
+				return nil, fmt.Errorf("baa.ParamRules unknown rule %q of %s", c.rule, name)
+			}
+			p.checks = append(p.checks, c)
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
+
+// ValidateParams returns a middleware checks params and query values of requests
+// by ParamRules in route meta RouteMetaParams, invalid requests are answered by the
+// error handler with *ValidationError, 422 Unprocessable Entity by default.
+// Rules are parsed when they are set by SetMeta, unknown rules panic there. Custom
+// rules of the app TagValidator are available when SetValidator is called before
+// the routes are declared.
+//
+// Example:
+// 		b.Use(baa.ValidateParams())
+// 		b.Get("/users/:id/posts", h).SetMeta(baa.RouteMetaParams, baa.ParamRules{
+// 			Params: map[string]string{"id": "int,min=1"},
+// 			Query:  map[string]string{"page": "int,min=1,max=100", "sort": "oneof=asc desc"},
+// 		})
+func ValidateParams() HandlerFunc {
+	return func(c *Context) {
+		rules, ok := c.RouteMeta(routeMetaParamRules).(*parsedParamRules)
+		if !ok {
+			c.Next()
+			return
+		}
+		var fields []FieldError
+		for _, r := range rules.params {
+			rules.t.validateValue(r, c.Param(r.name), &fields)
+		}
+		for _, r := range rules.query {
+			rules.t.validateValue(r, c.Query(r.name), &fields)
+		}
+		if len(fields) > 0 {
+			c.Error(&ValidationError{Fields: fields})
+			return
+		}
+		c.Next()
+	}
+}
+
+// validateValue converts value by the type rule then checks the other rules,
+// present values satisfy required and zero numbers are valid.
+func (t *TagValidator) validateValue(r paramRule, value string, fields *[]FieldError) {
+	if value == "" {
+		if r.required {
+			*fields = append(*fields, FieldError{Field: r.name, Rule: "required", Message: t.ruleMessage("required", "")})
+		}
+		return
+	}
+	var v interface{} = value
+	var err error
+	switch r.typ {
+	case "int":
+		v, err = strconv.ParseInt(value, 10, 64)
+	case "float":
+		v, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		v, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		*fields = append(*fields, FieldError{Field: r.name, Rule: r.typ, Message: paramTypes[r.typ]})
+		return
+	}
+	rv := reflect.ValueOf(v)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, c := range r.checks {
+		rule, ok := t.rules[c.rule]
+		if ok && !rule.check(rv, c.param) {
+			*fields = append(*fields, FieldError{Field: r.name, Rule: c.rule, Message: rule.format(c.param)})
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package baa
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidateParams1(t *testing.T) {
+	Convey("validate params and query by route meta", t, func() {
+		b2 := New()
+		b2.Use(ValidateParams())
+		b2.Get("/users/:id/posts", func(c *Context) {
+			c.String(200, "ok")
+		}).SetMeta(RouteMetaParams, ParamRules{
+			Params: map[string]string{"id": "int,min=1"},
+			Query: map[string]string{
+				"page":  "int,min=1,max=100",
+				"sort":  "oneof=asc desc",
+				"draft": "bool",
+				"q":     "required,min=2",
+			},
+		})
+		b2.Get("/free/:id", func(c *Context) {
+			c.String(200, "ok")
+		})
+
+		So(serveTo(b2, "/users/1/posts?q=go").Body.String(), ShouldEqual, "ok")
+		So(serveTo(b2, "/users/2/posts?q=go&page=100&sort=asc&draft=true").Code, ShouldEqual, http.StatusOK)
+		So(serveTo(b2, "/free/x").Code, ShouldEqual, http.StatusOK)
+
+		w := serveTo(b2, "/users/x/posts?page=0&sort=up&draft=maybe")
+		So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+		So(w.Body.String(), ShouldContainSubstring, "id must be an integer")
+		So(w.Body.String(), ShouldContainSubstring, "draft must be a boolean")
+		So(w.Body.String(), ShouldContainSubstring, "page must be at least 1")
+		So(w.Body.String(), ShouldContainSubstring, "q is required")
+		So(w.Body.String(), ShouldContainSubstring, "sort must be one of [asc desc]")
+		So(serveTo(b2, "/users/0/posts?q=g").Body.String(), ShouldEqual,
+			"validation failed: id must be at least 1; q must be at least 2\n")
+	})
+
+	Convey("custom rules of app validator", t, func() {
+		b2 := New()
+		v := NewTagValidator()
+		v.RegisterRule("even", func(v reflect.Value, _ string) bool {
+			return v.Int()%2 == 0
+		}, "must be even")
+		b2.SetValidator(v)
+		b2.Use(ValidateParams())
+		b2.Get("/n/:n", func(c *Context) {
+			c.String(200, "ok")
+		}).SetMeta(RouteMetaParams, ParamRules{Params: map[string]string{"n": "int,even"}})
+		So(serveTo(b2, "/n/2").Code, ShouldEqual, http.StatusOK)
+		So(serveTo(b2, "/n/3").Code, ShouldEqual, http.StatusUnprocessableEntity)
+	})
+
+	Convey("unknown rules panic when declared", t, func() {
+		b2 := New()
+		So(func() {
+			b2.Get("/n/:n", func(c *Context) {}).SetMeta(RouteMetaParams, ParamRules{Params: map[string]string{"n": "int,mni=1"}})
+		}, ShouldPanicWith, `baa.ParamRules unknown rule "mni" of n`)
+		So(func() {
+			b2.Get("/m/:n", func(c *Context) {}).SetMeta(RouteMetaParams, ParamRules{Query: map[string]string{"n": "min=1,int"}})
+		}, ShouldPanic)
+	})
+}
@@ -0,0 +1,412 @@
+package baa
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchError is returned when a JSON Patch operation can not be applied
+type PatchError struct {
+	Op   string
+	Path string
+	Err  string
+}
+
+// Error implements error interface
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("json patch %s %s: %s", e.Op, e.Path, e.Err)
+}
+
+// MergePatch applies RFC 7386 JSON Merge Patch to JSON document doc:
+// members of patch replace members of doc, null removes the member,
+// objects are merged recursively and other values replace the target.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodePatchJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodePatchJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(mergePatch(target, p))
+}
+
+// mergePatch merges patch into target
+func mergePatch(target, patch interface{}) interface{} {
+	pm, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	tm, ok := target.(map[string]interface{})
+	if !ok {
+		tm = make(map[string]interface{})
+	}
+	for k, v := range pm {
+		if v == nil {
+			delete(tm, k)
+			continue
+		}
+		tm[k] = mergePatch(tm[k], v)
+	}
+	return tm
+}
+
+// patchOperation is an operation of JSON Patch, members are kept as decoded
+// to tell a null value from a missing one.
+type patchOperation map[string]interface{}
+
+// str returns string member key
+func (op patchOperation) str(key string) (string, bool) {
+	s, ok := op[key].(string)
+	return s, ok
+}
+
+// JSONPatch applies RFC 6902 JSON Patch to JSON document doc, operations are
+// add, remove, replace, move, copy and test. Operations are applied in order and
+// the document is not changed when any operation fails.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodePatchJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	var ops []patchOperation
+	dec := newJSONDecoder(bytes.NewReader(patch))
+	dec.UseNumber()
+	if err := dec.Decode(&ops); err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if target, err = applyPatchOperation(target, op); err != nil {
+			return nil, err
+		}
+	}
+	return Marshal(target)
+}
+
+// applyPatchOperation applies an operation to doc and returns the new document
+func applyPatchOperation(doc interface{}, op patchOperation) (interface{}, error) {
+	name, _ := op.str("op")
+	path, ok := op.str("path")
+	if !ok {
+		return nil, &PatchError{Op: name, Err: "missing path"}
+	}
+	fail := func(format string, args ...interface{}) error {
+		return &PatchError{Op: name, Path: path, Err: fmt.Sprintf(format, args...)}
+	}
+	value, hasValue := op["value"]
+	from, hasFrom := op.str("from")
+	switch name {
+	case "add", "replace", "test":
+		if !hasValue {
+			return nil, fail("missing value")
+		}
+	case "move", "copy":
+		if !hasFrom {
+			return nil, fail("missing from")
+		}
+	case "remove":
+	default:
+		return nil, fail("unsupported operation")
+	}
+
+	var err error
+	switch name {
+	case "add":
+		doc, err = pointerSet(doc, path, value, true)
+	case "remove":
+		doc, _, err = pointerRemove(doc, path)
+	case "replace":
+		if _, err = pointerGet(doc, path); err == nil {
+			doc, err = pointerSet(doc, path, value, false)
+		}
+	case "move":
+		if strings.HasPrefix(path, from+"/") {
+			return nil, fail("can not move %s into its child", from)
+		}
+		var v interface{}
+		if doc, v, err = pointerRemove(doc, from); err == nil {
+			doc, err = pointerSet(doc, path, v, true)
+		}
+	case "copy":
+		var v interface{}
+		if v, err = pointerGet(doc, from); err == nil {
+			doc, err = pointerSet(doc, path, copyPatchValue(v), true)
+		}
+	case "test":
+		var v interface{}
+		if v, err = pointerGet(doc, path); err == nil && !patchValueEqual(v, value) {
+			return nil, fail("test failed")
+		}
+	}
+	if err != nil {
+		return nil, fail("%v", err)
+	}
+	return doc, nil
+}
+
+// parsePointer parses RFC 6901 JSON Pointer into tokens
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if path[0] != '/' {
+		return nil, fmt.Errorf("invalid pointer")
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(tokens[i], "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses array index token, "-" is the index after the last element when allowed
+func arrayIndex(token string, n int, end bool) (int, error) {
+	if token == "-" && end {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > n || (i == n && !end) {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+// pointerGet returns value at path
+func pointerGet(doc interface{}, path string) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[t]; !ok {
+				return nil, fmt.Errorf("path not found")
+			}
+		case []interface{}:
+			i, err := arrayIndex(t, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = v[i]
+		default:
+			return nil, fmt.Errorf("path not found")
+		}
+	}
+	return doc, nil
+}
+
+// pointerSet sets value at path, insert means inserting into arrays instead of replacing,
+// it returns the new document as the root may be replaced.
+func pointerSet(doc interface{}, path string, value interface{}, insert bool) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, pointerJoin(tokens[:len(tokens)-1]))
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		v[last] = value
+		return doc, nil
+	case []interface{}:
+		i, err := arrayIndex(last, len(v), insert)
+		if err != nil {
+			return nil, err
+		}
+		if !insert {
+			v[i] = value
+			return doc, nil
+		}
+		v = append(v, nil)
+		copy(v[i+1:], v[i:])
+		v[i] = value
+		return pointerSet(doc, pointerJoin(tokens[:len(tokens)-1]), v, false)
+	}
+	return nil, fmt.Errorf("path not found")
+}
+
+// pointerRemove removes value at path, returns the new document and the removed value
+func pointerRemove(doc interface{}, path string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	parentPath := pointerJoin(tokens[:len(tokens)-1])
+	parent, err := pointerGet(doc, parentPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		removed, ok := v[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path not found")
+		}
+		delete(v, last)
+		return doc, removed, nil
+	case []interface{}:
+		i, err := arrayIndex(last, len(v), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		removed := v[i]
+		v = append(v[:i:i], v[i+1:]...)
+		doc, err = pointerSet(doc, parentPath, v, false)
+		return doc, removed, err
+	}
+	return nil, nil, fmt.Errorf("path not found")
+}
+
+// pointerJoin builds JSON Pointer from tokens
+func pointerJoin(tokens []string) string {
+	var buf strings.Builder
+	for _, t := range tokens {
+		buf.WriteByte('/')
+		buf.WriteString(strings.Replace(strings.Replace(t, "~", "~0", -1), "/", "~1", -1))
+	}
+	return buf.String()
+}
+
+// copyPatchValue deep copies a decoded JSON value
+func copyPatchValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyPatchValue(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i := range v {
+			a[i] = copyPatchValue(v[i])
+		}
+		return a
+	}
+	return v
+}
+
+// patchValueEqual compares decoded JSON values, numbers are compared by value
+func patchValueEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k := range av {
+			if e, ok := bv[k]; !ok || !patchValueEqual(av[k], e) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !patchValueEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case nil, string, bool:
+		return a == b
+	}
+	// json.Number
+	af, aerr := strconv.ParseFloat(fmt.Sprint(a), 64)
+	bf, berr := strconv.ParseFloat(fmt.Sprint(b), 64)
+	return aerr == nil && berr == nil && af == bf
+}
+
+// decodePatchJSON decodes JSON data with numbers kept as json.Number
+func decodePatchJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	dec := newJSONDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ApplyPatch applies patch to v, contentType selects JSON Merge Patch
+// (application/merge-patch+json) or JSON Patch (application/json-patch+json).
+// v must be a pointer, it's replaced by the patched value, so fields removed by
+// the patch become zero values.
+func ApplyPatch(v interface{}, contentType string, patch []byte) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("baa.ApplyPatch target must be a non-nil pointer")
+	}
+	doc, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case ApplicationMergePatch:
+		doc, err = MergePatch(doc, patch)
+	case ApplicationJSONPatch:
+		doc, err = JSONPatch(doc, patch)
+	default:
+		return fmt.Errorf("baa.ApplyPatch unsupported patch type %q", contentType)
+	}
+	if err != nil {
+		return err
+	}
+	nv := reflect.New(rv.Elem().Type())
+	if err := Unmarshal(doc, nv.Interface()); err != nil {
+		return err
+	}
+	rv.Elem().Set(nv.Elem())
+	return nil
+}
+
+// BindPatch applies the request body as JSON Merge Patch or JSON Patch by Content-Type
+// to v then validates it, v should be loaded with the current resource before.
+// It returns HTTPError 415 for other content types and 422 when the patch can not be applied.
+//
+// Example:
+// 		user := loadUser(c.Param("id"))
+// 		if err := c.BindPatch(user); err != nil {
+// 			c.Error(err)
+// 			return
+// 		}
+func (c *Context) BindPatch(v interface{}) error {
+	contentType := c.Req.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != ApplicationMergePatch && mediaType != ApplicationJSONPatch {
+		return NewHTTPError(http.StatusUnsupportedMediaType)
+	}
+	patch, err := c.Body().Bytes()
+	if err != nil {
+		return err
+	}
+	if err := ApplyPatch(v, mediaType, patch); err != nil {
+		return NewHTTPError(http.StatusUnprocessableEntity, err.Error()).WithError(err)
+	}
+	return c.Validate(v)
+}
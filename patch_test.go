@@ -0,0 +1,156 @@
+package baa
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type patchUser struct {
+	Name  string   `json:"name"`
+	Email string   `json:"email,omitempty"`
+	Age   int      `json:"age"`
+	Tags  []string `json:"tags"`
+}
+
+func (u *patchUser) Validate() error {
+	if u.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func TestMergePatch1(t *testing.T) {
+	Convey("json merge patch", t, func() {
+		cases := [][3]string{
+			{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+			{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+			{`{"a":"b"}`, `{"a":null}`, `{}`},
+			{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+			{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+			{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+			{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+			{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+			{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+			{`{"a":"b"}`, `["c"]`, `["c"]`},
+			{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+			{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+			{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+			{`{"n":12345678901234567890}`, `{"m":1}`, `{"m":1,"n":12345678901234567890}`},
+		}
+		for _, v := range cases {
+			out, err := MergePatch([]byte(v[0]), []byte(v[1]))
+			So(err, ShouldBeNil)
+			So(string(out), ShouldEqual, v[2])
+		}
+		_, err := MergePatch([]byte(`{`), []byte(`{}`))
+		So(err, ShouldNotBeNil)
+		_, err = MergePatch([]byte(`{}`), []byte(`{`))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestJSONPatch1(t *testing.T) {
+	Convey("json patch", t, func() {
+		cases := [][3]string{
+			{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+			{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+			{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`},
+			{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+			{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+			{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+			{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+				`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+				`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+			{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+				`{"foo":["all","cows","eat","grass"]}`},
+			{`{"foo":{"a":1}}`, `[{"op":"copy","from":"/foo","path":"/bar"},{"op":"replace","path":"/bar/a","value":2}]`,
+				`{"bar":{"a":2},"foo":{"a":1}}`},
+			{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+				`{"baz":"qux","foo":["a",2,"c"]}`},
+			{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`},
+			{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+			{`{"foo":"bar"}`, `[{"op":"add","path":"/foo","value":null}]`, `{"foo":null}`},
+			{`{"foo":"bar"}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		}
+		for _, v := range cases {
+			out, err := JSONPatch([]byte(v[0]), []byte(v[1]))
+			So(err, ShouldBeNil)
+			So(string(out), ShouldEqual, v[2])
+		}
+
+		errs := [][2]string{
+			{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`},
+			{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+			{`{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`},
+			{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`},
+			{`{"foo":["a"]}`, `[{"op":"add","path":"/foo/2","value":1}]`},
+			{`{"foo":["a"]}`, `[{"op":"add","path":"/foo/01","value":1}]`},
+			{`{"foo":["a"]}`, `[{"op":"remove","path":"/foo/-"}]`},
+			{`{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`},
+			{`{"foo":"bar"}`, `[{"op":"copy","path":"/baz"}]`},
+			{`{"foo":"bar"}`, `[{"op":"add","value":1}]`},
+			{`{"foo":"bar"}`, `[{"op":"invalid","path":"/foo"}]`},
+			{`{"foo":"bar"}`, `[{"op":"add","path":"foo","value":1}]`},
+			{`{"foo":{"a":1}}`, `[{"op":"move","from":"/foo","path":"/foo/b"}]`},
+			{`{"foo":"bar"}`, `{"op":"add"}`},
+		}
+		for _, v := range errs {
+			_, err := JSONPatch([]byte(v[0]), []byte(v[1]))
+			So(err, ShouldNotBeNil)
+		}
+		_, err := JSONPatch([]byte(`{"a":1}`), []byte(`[{"op":"test","path":"/a","value":2}]`))
+		So(err.Error(), ShouldEqual, "json patch test /a: test failed")
+	})
+}
+
+func TestApplyPatch1(t *testing.T) {
+	Convey("apply patch to struct", t, func() {
+		u := &patchUser{Name: "baa", Email: "baa@example.com", Age: 3, Tags: []string{"go"}}
+		So(ApplyPatch(u, ApplicationMergePatch, []byte(`{"email":null,"age":4}`)), ShouldBeNil)
+		So(u, ShouldResemble, &patchUser{Name: "baa", Age: 4, Tags: []string{"go"}})
+		So(ApplyPatch(u, ApplicationJSONPatch, []byte(`[{"op":"add","path":"/tags/-","value":"web"}]`)), ShouldBeNil)
+		So(u.Tags, ShouldResemble, []string{"go", "web"})
+		So(ApplyPatch(u, ApplicationMergePatch, []byte(`{"age":"old"}`)), ShouldNotBeNil)
+		So(ApplyPatch(u, ApplicationJSON, []byte(`{}`)), ShouldNotBeNil)
+		So(ApplyPatch(*u, ApplicationMergePatch, []byte(`{}`)), ShouldNotBeNil)
+
+		m := map[string]interface{}{"a": 1.0}
+		So(ApplyPatch(&m, ApplicationMergePatch+"; charset=utf-8", []byte(`{"b":2}`)), ShouldBeNil)
+		So(m, ShouldResemble, map[string]interface{}{"a": 1.0, "b": 2.0})
+	})
+	Convey("bind patch", t, func() {
+		b2 := New()
+		b2.Patch("/users", func(c *Context) {
+			u := &patchUser{Name: "baa", Age: 3}
+			if err := c.BindPatch(u); err != nil {
+				c.Error(err)
+				return
+			}
+			c.JSON(http.StatusOK, u)
+		})
+		patch := func(contentType, body string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("PATCH", "/users", strings.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+		w := patch(ApplicationMergePatch, `{"age":5}`)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		var u patchUser
+		So(Unmarshal(w.Body.Bytes(), &u), ShouldBeNil)
+		So(u, ShouldResemble, patchUser{Name: "baa", Age: 5})
+		w = patch(ApplicationJSONPatch, `[{"op":"replace","path":"/name","value":"go"}]`)
+		So(Unmarshal(w.Body.Bytes(), &u), ShouldBeNil)
+		So(u.Name, ShouldEqual, "go")
+
+		So(patch(ApplicationJSON, `{}`).Code, ShouldEqual, http.StatusUnsupportedMediaType)
+		So(patch(ApplicationJSONPatch, `[{"op":"remove","path":"/x"}]`).Code, ShouldEqual, http.StatusUnprocessableEntity)
+		So(patch(ApplicationMergePatch, `{"name":null}`).Code, ShouldEqual, http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,64 @@
+package baa
+
+// defaultContextParams default capacity of route params in a new context
+const defaultContextParams = 16
+
+// SetPoolWarmup sets the number of contexts created when the server starts, they are
+// kept until the pool needs new contexts, so they are not dropped by GC like pooled
+// ones. It avoids allocations of the first requests after a cold start.
+func (b *Baa) SetPoolWarmup(n int) {
+	b.poolWarmup = n
+}
+
+// SetPoolMaxSize sets the max capacity of route params and handlers kept by a pooled context,
+// oversized internal buffers are reset before the context put back to pool.
+// it avoids holding large memory after traffic spikes, 0 means no limit.
+func (b *Baa) SetPoolMaxSize(n int) {
+	b.poolMaxSize = n
+}
+
+// warmupPool creates warm contexts, middlewares must be registered before
+func (b *Baa) warmupPool() {
+	if b.poolWarmup <= 0 {
+		return
+	}
+	cs := make([]*Context, b.poolWarmup)
+	for i := range cs {
+		cs[i] = NewContext(nil, nil, b)
+	}
+	b.warmMu.Lock()
+	b.warmContexts = cs
+	b.warmMu.Unlock()
+}
+
+// warmContext takes a warm context, returns nil when all are taken
+func (b *Baa) warmContext() *Context {
+	b.warmMu.Lock()
+	defer b.warmMu.Unlock()
+	n := len(b.warmContexts)
+	if n == 0 {
+		return nil
+	}
+	c := b.warmContexts[n-1]
+	b.warmContexts[n-1] = nil
+	b.warmContexts = b.warmContexts[:n-1]
+	return c
+}
+
+// releaseContext puts context back to pool, resets oversized buffers first
+func (b *Baa) releaseContext(c *Context) {
+	if max := b.poolMaxSize; max > 0 {
+		if cap(c.pNames) > max {
+			c.pNames = make([]string, 0, defaultContextParams)
+			c.pValues = make([]string, 0, defaultContextParams)
+		}
+		if cap(c.handlers) > len(b.middleware)+max {
+			handlers := make([]HandlerFunc, len(b.middleware), len(b.middleware)+3)
+			copy(handlers, b.middleware)
+			c.handlers = handlers
+		}
+	}
+	c.Req = nil
+	c.Resp.reset(nil)
+	b.pool.Put(c)
+}
@@ -0,0 +1,54 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPool1(t *testing.T) {
+	Convey("context pool", t, func() {
+		b2 := New()
+		b2.SetPoolWarmup(4)
+		b2.warmupPool()
+		So(len(b2.warmContexts), ShouldEqual, 4)
+		// warm contexts are used when the pool is empty, eg: after GC
+		c := b2.pool.New().(*Context)
+		So(len(b2.warmContexts), ShouldEqual, 3)
+		So(c.baa, ShouldEqual, b2)
+		for i := 0; i < 3; i++ {
+			b2.pool.New()
+		}
+		So(b2.warmContext(), ShouldBeNil)
+		So(b2.pool.New(), ShouldNotBeNil)
+
+		Convey("oversized buffers are reset", func() {
+			b2.SetPoolMaxSize(4)
+			c := NewContext(nil, nil, b2)
+			for i := 0; i < 20; i++ {
+				c.SetParam("p", "v")
+			}
+			c.handlers = append(c.handlers, make([]HandlerFunc, 10)...)
+			b2.releaseContext(c)
+			So(cap(c.pNames), ShouldEqual, defaultContextParams)
+			So(cap(c.pValues), ShouldEqual, defaultContextParams)
+			So(len(c.handlers), ShouldEqual, 0)
+			So(c.Req, ShouldBeNil)
+		})
+
+		Convey("serve with limited pool", func() {
+			b2.SetPoolMaxSize(1)
+			b2.Get("/pool/:a/:b", func(c *Context) {
+				c.String(200, c.Param("a")+c.Param("b"))
+			})
+			for i := 0; i < 3; i++ {
+				req, _ := http.NewRequest("GET", "/pool/1/2", nil)
+				w := httptest.NewRecorder()
+				b2.ServeHTTP(w, req)
+				So(w.Body.String(), ShouldEqual, "12")
+			}
+		})
+	})
+}
@@ -0,0 +1,131 @@
+package baa
+
+import (
+	"net/http"
+)
+
+// ApplicationProblemJSON media type of RFC 7807 problem details
+const ApplicationProblemJSON = "application/problem+json"
+
+// Problem is an error response body defined by RFC 7807 (Problem Details for HTTP APIs)
+type Problem struct {
+	Type       string                 // URI reference identifies the problem type, default about:blank
+	Title      string                 // short summary of the problem type
+	Status     int                    // HTTP status code
+	Detail     string                 // explanation specific to this occurrence
+	Instance   string                 // URI reference identifies this occurrence
+	Extensions map[string]interface{} // extension members
+}
+
+// NewProblem create a problem with status code and detail
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Error returns detail or title of the problem
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	if p.Title != "" {
+		return p.Title
+	}
+	return http.StatusText(p.Status)
+}
+
+// With set an extension member then returns self
+func (p *Problem) With(key string, v interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = v
+	return p
+}
+
+// MarshalJSON encodes problem with extension members
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	if p.Type == "" {
+		m["type"] = "about:blank"
+	}
+	m["title"] = p.Title
+	if p.Title == "" {
+		m["title"] = http.StatusText(p.Status)
+	}
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return Marshal(m)
+}
+
+// SetProblemDetails set whether the default error handlers respond RFC 7807
+// application/problem+json instead of plain text.
+func (b *Baa) SetProblemDetails(v bool) {
+	b.problemDetails = v
+}
+
+// Problem responds a problem details, instance is set to request path when empty.
+func (c *Context) Problem(p *Problem) {
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Instance == "" {
+		p.Instance = c.Req.URL.Path
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeJSON(buf, p, false); err != nil {
+		c.Resp.Header().Set("Content-Type", TextPlainCharsetUTF8)
+		c.Resp.WriteHeader(p.Status)
+		c.Resp.Write([]byte(p.Error()))
+		return
+	}
+	c.writeBuffer(p.Status, ApplicationProblemJSON, buf)
+}
+
+// problemOf converts error to problem details
+func problemOf(err error, code int, debug bool) *Problem {
+	switch e := err.(type) {
+	case *Problem:
+		return e
+	case *ValidationError:
+		return validationProblem(e)
+	}
+	p := NewProblem(code, "")
+	if e, ok := err.(*HTTPError); ok {
+		p.Detail = e.clientMessage(debug)
+	} else if debug || code < http.StatusInternalServerError {
+		p.Detail = err.Error()
+	}
+	return p
+}
+
+// errorStatus returns HTTP status code of error, default 500
+func errorStatus(err error) int {
+	switch e := err.(type) {
+	case *HTTPError:
+		if e.Code > 0 {
+			return e.Code
+		}
+	case *Problem:
+		if e.Status > 0 {
+			return e.Status
+		}
+	case *ValidationError:
+		return http.StatusUnprocessableEntity
+	case *BindError:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,70 @@
+package baa
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProblem1(t *testing.T) {
+	Convey("problem details", t, func() {
+		b2 := New()
+		b2.SetDebug(false)
+		b2.Get("/problem", func(c *Context) {
+			p := NewProblem(http.StatusConflict, "user exists").With("field", "email")
+			p.Type = "https://example.com/probs/exists"
+			c.Error(p)
+		})
+		b2.Get("/http", func(c *Context) {
+			c.Error(NewHTTPError(http.StatusBadRequest, "invalid id"))
+		})
+		b2.Get("/error", func(c *Context) {
+			c.Error(errors.New("db error"))
+		})
+		serve := func(uri string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", uri, nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		Convey("problem error always responds problem json", func() {
+			w := serve("/problem")
+			So(w.Code, ShouldEqual, http.StatusConflict)
+			So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationProblemJSON)
+			So(w.Body.String(), ShouldEqual, `{"detail":"user exists","field":"email","instance":"/problem","status":409,"title":"Conflict","type":"https://example.com/probs/exists"}`)
+		})
+		Convey("plain text without problem details", func() {
+			w := serve("/http")
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(w.Body.String(), ShouldEqual, "invalid id\n")
+			w = serve("/error")
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Body.String(), ShouldEqual, "Internal Server Error\n")
+		})
+		Convey("problem details enabled", func() {
+			b2.SetProblemDetails(true)
+			w := serve("/http")
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(w.Body.String(), ShouldEqual, `{"detail":"invalid id","instance":"/http","status":400,"title":"Bad Request","type":"about:blank"}`)
+			w = serve("/error")
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Body.String(), ShouldEqual, `{"instance":"/error","status":500,"title":"Internal Server Error","type":"about:blank"}`)
+			w = serve("/notfound")
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationProblemJSON)
+			b2.SetProblemDetails(false)
+		})
+	})
+	Convey("problem error message", t, func() {
+		So(NewProblem(400, "detail").Error(), ShouldEqual, "detail")
+		So((&Problem{Title: "title"}).Error(), ShouldEqual, "title")
+		So(NewProblem(404, "").Error(), ShouldEqual, "Not Found")
+		So(errorStatus(&HTTPError{}), ShouldEqual, 500)
+		So(errorStatus(&Problem{}), ShouldEqual, 500)
+		So(errorStatus(&BindError{}), ShouldEqual, 400)
+	})
+}
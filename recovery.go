@@ -0,0 +1,136 @@
+package baa
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+const (
+	// panicPageFrames maximum stack frames on the panic page
+	panicPageFrames = 32
+	// panicPageLines source lines around the line of frames
+	panicPageLines = 5
+)
+
+// Recovery returns a middleware recovers panics of subsequent handlers, in debug mode
+// requests accept HTML are answered by an error page with stack frames and source
+// context. Otherwise the panic is converted into PanicError and passed to the error
+// handler, which logs the stack and responds 500 by default.
+func Recovery() HandlerFunc {
+	return func(c *Context) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler || !c.baa.Debug() || c.Resp.Wrote() ||
+				!strings.Contains(c.Req.Header.Get("Accept"), "text/html") {
+				// recovered by the application
+				panic(v)
+			}
+			err := newPanicError(v, c)
+			c.baa.Logger().Printf("%s\n%s", err, err.Stack)
+			renderPanicPage(c, err, panicFrames())
+		}()
+		c.Next()
+	}
+}
+
+// panicFrame is a stack frame on the panic page
+type panicFrame struct {
+	Function string
+	File     string
+	Line     int
+	Source   []panicLine
+}
+
+// panicLine is a source line of frame
+type panicLine struct {
+	Number  int
+	Text    string
+	Current bool
+}
+
+// panicFrames returns frames of the panicking goroutine with source context,
+// it's called in the deferred recover function.
+func panicFrames() []panicFrame {
+	pcs := make([]uintptr, panicPageFrames+8)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	var list []panicFrame
+	for len(list) < panicPageFrames {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			list = append(list, panicFrame{
+				Function: f.Function,
+				File:     f.File,
+				Line:     f.Line,
+				Source:   sourceLines(f.File, f.Line),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	return list
+}
+
+// sourceLines returns lines of file around line, nil when the file is not readable
+func sourceLines(file string, line int) []panicLine {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(string(data), "\n")
+	var list []panicLine
+	for i := line - panicPageLines; i <= line+panicPageLines; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		list = append(list, panicLine{Number: i, Text: lines[i-1], Current: i == line})
+	}
+	return list
+}
+
+// panicPage is the template of panic page
+var panicPage = template.Must(template.New("panic").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Error.Value }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #333; }
+h1 { color: #c00; font-size: 1.5em; }
+.frame { margin: 1em 0; border: 1px solid #ddd; }
+.frame h2 { font-size: 1em; margin: 0; padding: .5em; background: #f5f5f5; }
+pre { margin: 0; padding: .5em; overflow: auto; }
+.current { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>panic: {{ .Error.Value }}</h1>
+<p>{{ .Error.Method }} {{ .Error.URL }}{{ if .Error.Route }} (route {{ .Error.Route }}){{ end }}</p>
+{{ range .Frames }}<div class="frame">
+<h2>{{ .Function }} <small>{{ .File }}:{{ .Line }}</small></h2>
+{{ if .Source }}<pre>{{ range .Source }}<span{{ if .Current }} class="current"{{ end }}>{{ printf "%5d" .Number }}  {{ .Text }}</span>
+{{ end }}</pre>{{ end }}
+</div>
+{{ end }}<h2>Stack</h2>
+<pre>{{ printf "%s" .Error.Stack }}</pre>
+</body>
+</html>
+`))
+
+// renderPanicPage responds 500 with the panic page
+func renderPanicPage(c *Context, err *PanicError, frames []panicFrame) {
+	buf := new(bytes.Buffer)
+	if e := panicPage.Execute(buf, map[string]interface{}{"Error": err, "Frames": frames}); e != nil {
+		c.baa.Error(err, c)
+		return
+	}
+	c.Resp.Header().Set("Cache-Control", "no-store")
+	c.Blob(http.StatusInternalServerError, TextHTMLCharsetUTF8, buf.Bytes())
+}
@@ -0,0 +1,50 @@
+package baa
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecovery1(t *testing.T) {
+	Convey("recover panics", t, func() {
+		logs := new(bytes.Buffer)
+		b2 := Classic()
+		b2.SetDI("logger", log.New(logs, "", 0))
+		b2.Get("/panic", func(c *Context) {
+			panic("boom <b>")
+		})
+		get := func(accept string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", "/panic", nil)
+			req.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		b2.SetDebug(true)
+		w := get("text/html,application/xhtml+xml")
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(w.Header().Get("Content-Type"), ShouldEqual, TextHTMLCharsetUTF8)
+		body := w.Body.String()
+		So(body, ShouldContainSubstring, "<h1>panic: boom &lt;b&gt;</h1>")
+		So(body, ShouldContainSubstring, "TestRecovery1")
+		So(body, ShouldContainSubstring, "recovery_test.go")
+		So(body, ShouldContainSubstring, `class="current">`)
+		So(body, ShouldContainSubstring, "panic(&#34;boom &lt;b&gt;&#34;)")
+		So(logs.String(), ShouldContainSubstring, "panic: boom <b> [GET /panic]")
+
+		w = get("application/json")
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(w.Body.String(), ShouldNotContainSubstring, "<h1>")
+
+		b2.SetDebug(false)
+		w = get("text/html")
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(w.Body.String(), ShouldEqual, "Internal Server Error\n")
+	})
+}
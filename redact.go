@@ -0,0 +1,167 @@
+package baa
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// Redaction configures what is masked before requests are written to logs,
+// dumps or audit records.
+type Redaction struct {
+	Headers []string // header names to mask, case insensitive
+	Fields  []string // body and query field names to mask, case insensitive
+	Mask    string   // replacement value, default is "[REDACTED]"
+}
+
+// DefaultRedaction masks credentials in headers and common secret fields
+var DefaultRedaction = Redaction{
+	Headers: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+	Fields:  []string{"password", "token"},
+	Mask:    "[REDACTED]",
+}
+
+// SetRedaction set the redaction used by request dumps and logging helpers
+func (b *Baa) SetRedaction(r Redaction) {
+	if r.Mask == "" {
+		r.Mask = DefaultRedaction.Mask
+	}
+	b.redaction = r
+}
+
+// Redaction returns the redaction used by request dumps and logging helpers
+func (b *Baa) Redaction() Redaction {
+	return b.redaction
+}
+
+// mask returns the replacement value
+func (r Redaction) mask() string {
+	if r.Mask == "" {
+		return DefaultRedaction.Mask
+	}
+	return r.Mask
+}
+
+// isHeader checks header name should be masked
+func (r Redaction) isHeader(name string) bool {
+	for _, v := range r.Headers {
+		if strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isField checks field name should be masked
+func (r Redaction) isField(name string) bool {
+	for _, v := range r.Fields {
+		if strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Header returns a copy of h with configured headers masked
+func (r Redaction) Header(h http.Header) http.Header {
+	h2 := make(http.Header, len(h))
+	for k, v := range h {
+		if r.isHeader(k) {
+			h2[k] = []string{r.mask()}
+			continue
+		}
+		h2[k] = append([]string(nil), v...)
+	}
+	return h2
+}
+
+// Values returns a copy of v with configured fields masked
+func (r Redaction) Values(v url.Values) url.Values {
+	v2 := make(url.Values, len(v))
+	for k, vs := range v {
+		if r.isField(k) {
+			v2[k] = []string{r.mask()}
+			continue
+		}
+		v2[k] = append([]string(nil), vs...)
+	}
+	return v2
+}
+
+// Body returns body with configured fields masked, JSON and urlencoded form bodies are supported.
+// Other bodies, and bodies can not be parsed, are returned as is.
+func (r Redaction) Body(contentType string, body []byte) []byte {
+	if len(r.Fields) == 0 || len(body) == 0 {
+		return body
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == ApplicationForm:
+		v, err := url.ParseQuery(string(body))
+		if err != nil {
+			return body
+		}
+		return []byte(r.Values(v).Encode())
+	case mediaType == ApplicationJSON || strings.HasSuffix(mediaType, "+json"):
+		var v interface{}
+		dec := newJSONDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return body
+		}
+		buf := new(bytes.Buffer)
+		if err := encodeJSON(buf, r.value(v), false); err != nil {
+			return body
+		}
+		return buf.Bytes()
+	}
+	return body
+}
+
+// value masks configured fields of decoded JSON value recursively
+func (r Redaction) value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k := range t {
+			if r.isField(k) {
+				t[k] = r.mask()
+			} else {
+				t[k] = r.value(t[k])
+			}
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = r.value(t[i])
+		}
+	}
+	return v
+}
+
+// DumpRequest returns the wire representation of request with headers, query and body
+// fields masked by the application redaction. The request body can still be read after.
+func (c *Context) DumpRequest(body bool) ([]byte, error) {
+	r := c.baa.redaction
+	req := new(http.Request)
+	*req = *c.Req
+	req.Header = r.Header(c.Req.Header)
+	if c.Req.URL != nil && c.Req.URL.RawQuery != "" {
+		u := *c.Req.URL
+		u.RawQuery = r.Values(u.Query()).Encode()
+		req.URL = &u
+	}
+	if body && c.Req.Body != nil {
+		data, err := c.Body().Bytes()
+		if err != nil {
+			return nil, err
+		}
+		c.Req.Body = ioutil.NopCloser(bytes.NewReader(data))
+		data = r.Body(c.Req.Header.Get("Content-Type"), data)
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+		req.ContentLength = int64(len(data))
+	}
+	return httputil.DumpRequest(req, body)
+}
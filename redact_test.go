@@ -0,0 +1,59 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedaction1(t *testing.T) {
+	Convey("redact header and body", t, func() {
+		r := DefaultRedaction
+		h := http.Header{}
+		h.Set("Authorization", "Bearer secret")
+		h.Set("Accept", "text/html")
+		h2 := r.Header(h)
+		So(h2.Get("Authorization"), ShouldEqual, "[REDACTED]")
+		So(h2.Get("Accept"), ShouldEqual, "text/html")
+		So(h.Get("Authorization"), ShouldEqual, "Bearer secret")
+
+		body := r.Body(ApplicationJSONCharsetUTF8, []byte(`{"user":{"name":"baa","Password":"123"},"items":[{"token":"t"}],"id":12345678901234567}`))
+		So(string(body), ShouldEqual, `{"id":12345678901234567,"items":[{"token":"[REDACTED]"}],"user":{"Password":"[REDACTED]","name":"baa"}}`)
+		body = r.Body(ApplicationForm, []byte("name=baa&password=123"))
+		So(string(body), ShouldEqual, "name=baa&password=%5BREDACTED%5D")
+		body = r.Body(TextPlain, []byte("password=123"))
+		So(string(body), ShouldEqual, "password=123")
+		body = r.Body(ApplicationJSON, []byte(`{"password":`))
+		So(string(body), ShouldEqual, `{"password":`)
+
+		v := r.Values(url.Values{"token": {"t"}, "q": {"baa"}})
+		So(v.Get("token"), ShouldEqual, "[REDACTED]")
+		So(v.Get("q"), ShouldEqual, "baa")
+	})
+	Convey("dump request", t, func() {
+		b2 := New()
+		b2.SetRedaction(Redaction{Headers: []string{"X-Api-Key"}, Fields: []string{"secret"}})
+		So(b2.Redaction().Mask, ShouldEqual, "[REDACTED]")
+		var dump, rest string
+		b2.Post("/dump", func(c *Context) {
+			data, err := c.DumpRequest(true)
+			So(err, ShouldBeNil)
+			dump = string(data)
+			rest, _ = c.Body().String()
+		})
+		req, _ := http.NewRequest("POST", "/dump?secret=1&page=2", strings.NewReader(`{"secret":"s","name":"baa"}`))
+		req.Header.Set("Content-Type", ApplicationJSON)
+		req.Header.Set("X-Api-Key", "key")
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(dump, ShouldContainSubstring, "/dump?page=2&secret=%5BREDACTED%5D")
+		So(dump, ShouldContainSubstring, "X-Api-Key: [REDACTED]")
+		So(dump, ShouldContainSubstring, `{"name":"baa","secret":"[REDACTED]"}`)
+		So(dump, ShouldNotContainSubstring, "key\r\n")
+		So(rest, ShouldEqual, `{"secret":"s","name":"baa"}`)
+	})
+}
@@ -0,0 +1,206 @@
+package baa
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Config holds a configuration value swapped atomically on reload, readers always
+// see a complete value, the value is kept when loading fails.
+//
+// Example:
+// 		flags, _ := baa.NewConfig(baa.JSONConfigLoader("flags.json", func() interface{} { return new(Flags) }))
+// 		b.AddConfig(flags)
+// 		b.ReloadOnSignal()
+// 		f := flags.Load().(*Flags)
+type Config struct {
+	value atomic.Value
+	load  func() (interface{}, error)
+	mu    sync.Mutex
+}
+
+// NewConfig create a config then loads the initial value
+func NewConfig(load func() (interface{}, error)) (*Config, error) {
+	if load == nil {
+		panic("baa.NewConfig load func can not be nil")
+	}
+	c := &Config{load: load}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load returns the current value
+func (c *Config) Load() interface{} {
+	return c.value.Load()
+}
+
+// Reload loads a new value and swaps it, the current value is kept when error returned
+func (c *Config) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, err := c.load()
+	if err != nil {
+		return err
+	}
+	c.value.Store(v)
+	return nil
+}
+
+// JSONConfigLoader returns a config loader decodes JSON file into a new value created by newValue,
+// newValue should return a pointer.
+func JSONConfigLoader(file string, newValue func() interface{}) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		v := newValue()
+		if err := Unmarshal(data, v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// ReloadError is returned by Reload when some reload callbacks failed
+type ReloadError []error
+
+// Error returns joined error messages
+func (e ReloadError) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return "baa reload: " + strings.Join(msgs, "; ")
+}
+
+// OnReload registers callbacks called by Reload in order, eg: reapply rate limits
+func (b *Baa) OnReload(fn ...func() error) {
+	b.reloadMu.Lock()
+	for i := range fn {
+		if fn[i] != nil {
+			b.reloaders = append(b.reloaders, fn[i])
+		}
+	}
+	b.reloadMu.Unlock()
+}
+
+// AddConfig registers config reloaded by Reload
+func (b *Baa) AddConfig(c *Config) {
+	b.OnReload(c.Reload)
+}
+
+// Reload calls all reload callbacks, a failed callback does not stop others,
+// errors are returned as ReloadError.
+func (b *Baa) Reload() error {
+	b.reloadMu.Lock()
+	fns := append([]func() error(nil), b.reloaders...)
+	b.reloadMu.Unlock()
+	var errs ReloadError
+	for _, fn := range fns {
+		if err := fn(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ReloadOnSignal calls Reload when the process receives signals, default is SIGHUP.
+// The returned func stops listening.
+func (b *Baa) ReloadOnSignal(sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sig...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				b.logReload(b.Reload())
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// ReloadOnChange polls modification time of files every interval and calls Reload
+// when any file changed. The returned func stops watching.
+func (b *Baa) ReloadOnChange(interval time.Duration, files ...string) (stop func()) {
+	if len(files) == 0 {
+		panic("baa.ReloadOnChange files can not be empty")
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	modTimes := make([]time.Time, len(files))
+	for i := range files {
+		modTimes[i] = modTime(files[i])
+	}
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				changed := false
+				for i := range files {
+					if t := modTime(files[i]); !t.Equal(modTimes[i]) {
+						modTimes[i] = t
+						changed = true
+					}
+				}
+				if changed {
+					b.logReload(b.Reload())
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// logReload logs result of reload
+func (b *Baa) logReload(err error) {
+	if err != nil {
+		b.Logger().Println(err)
+		return
+	}
+	b.Logger().Println("baa reload: done")
+}
+
+// modTime returns modification time of file, zero time when file not exists
+func modTime(file string) time.Time {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
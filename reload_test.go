@@ -0,0 +1,90 @@
+package baa
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReload1(t *testing.T) {
+	Convey("config atomic reload", t, func() {
+		dir, err := ioutil.TempDir("", "baa-reload")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "flags.json")
+		ioutil.WriteFile(file, []byte(`{"beta": false}`), 0644)
+
+		type flags struct {
+			Beta bool `json:"beta"`
+		}
+		cfg, err := NewConfig(JSONConfigLoader(file, func() interface{} { return new(flags) }))
+		So(err, ShouldBeNil)
+		So(cfg.Load().(*flags).Beta, ShouldBeFalse)
+
+		b2 := New()
+		b2.AddConfig(cfg)
+		calls := 0
+		b2.OnReload(func() error {
+			calls++
+			return nil
+		})
+
+		ioutil.WriteFile(file, []byte(`{"beta": true}`), 0644)
+		So(b2.Reload(), ShouldBeNil)
+		So(cfg.Load().(*flags).Beta, ShouldBeTrue)
+		So(calls, ShouldEqual, 1)
+
+		// invalid config keeps current value
+		ioutil.WriteFile(file, []byte(`{"beta": `), 0644)
+		b2.OnReload(func() error {
+			return errors.New("limits")
+		})
+		err = b2.Reload()
+		So(err, ShouldNotBeNil)
+		So(err.(ReloadError), ShouldHaveLength, 2)
+		So(cfg.Load().(*flags).Beta, ShouldBeTrue)
+		So(calls, ShouldEqual, 2)
+
+		_, err = NewConfig(JSONConfigLoader(filepath.Join(dir, "none.json"), func() interface{} { return new(flags) }))
+		So(err, ShouldNotBeNil)
+	})
+	Convey("reload on signal and change", t, func() {
+		dir, err := ioutil.TempDir("", "baa-reload")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "app.conf")
+		ioutil.WriteFile(file, []byte("a"), 0644)
+
+		b2 := New()
+		reloaded := make(chan bool, 4)
+		b2.OnReload(func() error {
+			reloaded <- true
+			return nil
+		})
+
+		stop := b2.ReloadOnSignal(syscall.SIGUSR1)
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+		select {
+		case <-reloaded:
+		case <-time.After(time.Second):
+			t.Error("reload on signal timeout")
+		}
+		stop()
+		stop()
+
+		stop = b2.ReloadOnChange(10*time.Millisecond, file)
+		defer stop()
+		os.Chtimes(file, time.Now().Add(time.Hour), time.Now().Add(time.Hour))
+		select {
+		case <-reloaded:
+		case <-time.After(time.Second):
+			t.Error("reload on change timeout")
+		}
+	})
+}
@@ -1,10 +1,16 @@
 package baa
 
 import (
+	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 // Renderer is the interface that wraps the Render method.
@@ -12,21 +18,104 @@ type Renderer interface {
 	Render(w io.Writer, tpl string, data interface{}) error
 }
 
+// FragmentRenderer is the interface that renders a named block/partial of a template.
+type FragmentRenderer interface {
+	RenderFragment(w io.Writer, tpl, name string, data interface{}) error
+}
+
+// RouteMetaRenderer is the route meta key of the renderer overrides the application renderer,
+// it's set by Group.SetRenderer or on a single route by SetMeta.
+const RouteMetaRenderer = "baa.renderer"
+
+// ErrNoRenderer is returned when render templates on routes without renderer
+var ErrNoRenderer = errors.New("baa: no renderer for the route")
+
+// NoRenderer is a renderer always fails with ErrNoRenderer, it disables rendering of routes
+var NoRenderer Renderer = noRenderer{}
+
+// noRenderer a renderer always fails
+type noRenderer struct{}
+
+// Render returns ErrNoRenderer
+func (noRenderer) Render(w io.Writer, tpl string, data interface{}) error {
+	return ErrNoRenderer
+}
+
+// TemplateError is a template render error with template name and line, render errors
+// are sent to the error handler as *TemplateError, the response is not written.
+type TemplateError struct {
+	Template string
+	// Line is the line of template the error occurs, 0 if unknown
+	Line int
+	Err  error
+}
+
+// templateLineRegexp matches template name and line in text/template and html/template errors
+var templateLineRegexp = regexp.MustCompile(`template: ?[^:\s]+:(\d+)`)
+
+// newTemplateError wraps render error of template tpl
+func newTemplateError(tpl string, err error) error {
+	if _, ok := err.(*TemplateError); ok || err == nil {
+		return err
+	}
+	e := &TemplateError{Template: tpl, Err: err}
+	if m := templateLineRegexp.FindStringSubmatch(err.Error()); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+	}
+	return e
+}
+
+// Error implements error interface
+func (e *TemplateError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("baa: render %s:%d: %v", e.Template, e.Line, e.Err)
+	}
+	return fmt.Sprintf("baa: render %s: %v", e.Template, e.Err)
+}
+
+// Unwrap returns the render error
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// ViewDataProvider returns data shared by every HTML render, eg: current user, navigation
+type ViewDataProvider func(c *Context) map[string]interface{}
+
 // Render default baa template engine
 type Render struct {
+	funcs template.FuncMap
 }
 
 // Render ...
 func (r *Render) Render(w io.Writer, tpl string, data interface{}) error {
-	t, err := parseFile(tpl)
+	t, err := parseFile(tpl, r.funcs)
 	if err != nil {
 		return err
 	}
 	return t.Execute(w, data)
 }
 
+// RenderFragment renders the named block defined in template file
+func (r *Render) RenderFragment(w io.Writer, tpl, name string, data interface{}) error {
+	t, err := parseFile(tpl, r.funcs)
+	if err != nil {
+		return err
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
+
+// Funcs adds the elements of the argument map to the template function map
+func (r *Render) Funcs(funcMap template.FuncMap) {
+	if r.funcs == nil {
+		r.funcs = make(template.FuncMap)
+	}
+	for k, v := range funcMap {
+		r.funcs[k] = v
+	}
+}
+
 // parseFile ...
-func parseFile(filename string) (*template.Template, error) {
+func parseFile(filename string, funcs template.FuncMap) (*template.Template, error) {
 	var t *template.Template
 	b, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -35,6 +124,9 @@ func parseFile(filename string) (*template.Template, error) {
 	s := string(b)
 	name := filepath.Base(filename)
 	t = template.New(name)
+	if funcs != nil {
+		t.Funcs(funcs)
+	}
 	_, err = t.Parse(s)
 	if err != nil {
 		return nil, err
@@ -47,3 +139,99 @@ func newRender() *Render {
 	r := new(Render)
 	return r
 }
+
+// funcsRenderer is a renderer supports template functions
+type funcsRenderer interface {
+	Funcs(funcMap template.FuncMap)
+}
+
+// AddTemplateFunc registers a template function, it works when the render
+// implements method Funcs(template.FuncMap), such as the default render.
+func (b *Baa) AddTemplateFunc(name string, fn interface{}) {
+	if b.funcs == nil {
+		b.funcs = make(template.FuncMap)
+	}
+	b.funcs[name] = fn
+	if r, ok := b.Render().(funcsRenderer); ok {
+		r.Funcs(template.FuncMap{name: fn})
+	}
+	for i := range b.renderers {
+		if r, ok := b.renderers[i].(funcsRenderer); ok {
+			r.Funcs(template.FuncMap{name: fn})
+		}
+	}
+}
+
+// addRenderer registers a route renderer, template functions are added to it,
+// registered renderer is skipped
+func (b *Baa) addRenderer(r Renderer) {
+	if reflect.TypeOf(r).Comparable() {
+		for i := range b.renderers {
+			if b.renderers[i] == r {
+				return
+			}
+		}
+	}
+	if fr, ok := r.(funcsRenderer); ok && len(b.funcs) > 0 {
+		fr.Funcs(b.funcs)
+	}
+	b.renderers = append(b.renderers, r)
+}
+
+// Renderer returns renderer of matched route, it's the application renderer
+// when the route has no renderer override.
+func (c *Context) Renderer() Renderer {
+	if r, ok := c.RouteMeta(RouteMetaRenderer).(Renderer); ok {
+		return r
+	}
+	return c.baa.Render()
+}
+
+// AddViewData registers view data providers, the data will be injected into every
+// HTML render data, the data set by c.Set has higher priority.
+func (b *Baa) AddViewData(p ...ViewDataProvider) {
+	for i := range p {
+		if p[i] != nil {
+			b.viewData = append(b.viewData, p[i])
+		}
+	}
+}
+
+// viewDataOf returns render data merged view data providers and context store
+func (b *Baa) viewDataOf(c *Context) map[string]interface{} {
+	if len(b.viewData) == 0 {
+		return c.Gets()
+	}
+	data := make(map[string]interface{})
+	for _, p := range b.viewData {
+		for k, v := range p(c) {
+			data[k] = v
+		}
+	}
+	for k, v := range c.Gets() {
+		data[k] = v
+	}
+	return data
+}
+
+// jsonScriptReplacer escapes characters of JSON unsafe in script element
+var jsonScriptReplacer = strings.NewReplacer(
+	"<", "\\u003c",
+	">", "\\u003e",
+	"&", "\\u0026",
+	"\u2028", "\\u2028",
+	"\u2029", "\\u2029",
+)
+
+// JSONScript marshals v to JSON safe for embedding in <script> element, it's registered as
+// template func json. </script>, <!-- and line terminators U+2028, U+2029 are escaped.
+//
+// Example:
+// 		<script>var user = {{ json .user }};</script>
+func JSONScript(v interface{}) (template.JS, error) {
+	data, err := Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(jsonScriptReplacer.Replace(string(data))), nil
+}
@@ -0,0 +1,123 @@
+package baa
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RouteMetaRenderFallback is the route meta key of *RenderFallback overrides the
+// application render fallback, it's set by Group.SetRenderFallback or by SetMeta.
+const RouteMetaRenderFallback = "baa.renderFallback"
+
+// RenderFallback is the response sent when template rendering fails, the real error
+// is logged. The fallback template is rendered with the same data, Message is sent
+// as plain text when there is no fallback template or it fails too.
+//
+// When Threshold > 0, a template failed Threshold times in a row is not rendered
+// for Cooldown, the fallback is sent directly until the cooldown ends.
+type RenderFallback struct {
+	Template  string
+	Message   string
+	Status    int
+	Threshold int
+	Cooldown  time.Duration
+
+	breakers map[string]*renderBreaker
+	mu       sync.Mutex
+}
+
+// renderBreaker is the failure state of a template
+type renderBreaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+// SetRenderFallback set the application render fallback, nil restores the default
+// behavior that sends render errors to the error handler.
+//
+// Example:
+// 		b.SetRenderFallback(&baa.RenderFallback{Template: "error.html", Message: "Service Unavailable"})
+func (b *Baa) SetRenderFallback(f *RenderFallback) {
+	b.renderFallback = f
+}
+
+// SetRenderFallback set render fallback of routes added after it
+func (g *Group) SetRenderFallback(f *RenderFallback) {
+	g.fallback = f
+}
+
+// renderFallbackOf returns render fallback of matched route or application
+func (c *Context) renderFallbackOf() *RenderFallback {
+	if f, ok := c.RouteMeta(RouteMetaRenderFallback).(*RenderFallback); ok {
+		return f
+	}
+	return c.baa.renderFallback
+}
+
+// status returns fallback status, default is 500
+func (f *RenderFallback) status() int {
+	if f.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return f.Status
+}
+
+// open checks the breaker of tpl is open
+func (f *RenderFallback) open(tpl string) bool {
+	if f.Threshold <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.breakers[tpl]
+	return s != nil && time.Now().Before(s.openUntil)
+}
+
+// record records render result of tpl, the breaker opens when failures reach the threshold
+func (f *RenderFallback) record(tpl string, err error) {
+	if f.Threshold <= 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.breakers, tpl)
+		return
+	}
+	if f.breakers == nil {
+		f.breakers = make(map[string]*renderBreaker)
+	}
+	s := f.breakers[tpl]
+	if s == nil {
+		s = new(renderBreaker)
+		f.breakers[tpl] = s
+	}
+	s.failures++
+	if s.failures >= f.Threshold {
+		s.failures = 0
+		s.openUntil = time.Now().Add(f.Cooldown)
+	}
+}
+
+// renderFallback sends fallback f of failed template tpl
+func (c *Context) renderFallback(f *RenderFallback, tpl string, data interface{}) {
+	if f.Template != "" && f.Template != tpl {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		err := c.Renderer().Render(buf, f.Template, data)
+		if err == nil {
+			out := getBuffer()
+			defer putBuffer(out)
+			clearBlankLines(out, buf)
+			c.writeBuffer(f.status(), TextHTMLCharsetUTF8, out)
+			return
+		}
+		c.baa.Logger().Printf("render fallback: %v", newTemplateError(f.Template, err))
+	}
+	msg := f.Message
+	if msg == "" {
+		msg = http.StatusText(f.status())
+	}
+	c.String(f.status(), msg)
+}
@@ -0,0 +1,79 @@
+package baa
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// serveTo serves a GET request by app
+func serveTo(app *Baa, uri string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", uri, nil)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func TestRenderFallback1(t *testing.T) {
+	Convey("render fallback", t, func() {
+		b2 := New()
+		logs := new(bytes.Buffer)
+		b2.SetDI("logger", log.New(logs, "", 0))
+		handler := func(c *Context) {
+			c.Set("name", "Baa")
+			c.Set("list", []int{1})
+			c.HTML(200, "_fixture/broken.html")
+		}
+		b2.Get("/default", handler)
+		b2.SetRenderFallback(&RenderFallback{Template: "_fixture/fallback.html", Status: http.StatusServiceUnavailable})
+		b2.Get("/app", handler)
+		g := b2.NewGroup("/admin")
+		g.SetRenderFallback(&RenderFallback{Template: "_fixture/missing.html", Message: "admin unavailable"})
+		g.Get("/page", handler)
+
+		b3 := New()
+		b3.Get("/default", handler)
+		w := serveTo(b3, "/default")
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+
+		w = serveTo(b2, "/app")
+		So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+		So(w.Body.String(), ShouldEqual, "<h1>Sorry Baa</h1>\n")
+		So(logs.String(), ShouldContainSubstring, "render _fixture/broken.html")
+
+		w = serveTo(b2, "/admin/page")
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(w.Body.String(), ShouldEqual, "admin unavailable")
+		So(logs.String(), ShouldContainSubstring, "render fallback: baa: render _fixture/missing.html")
+	})
+	Convey("render circuit breaker", t, func() {
+		b2 := New()
+		b2.SetDI("logger", log.New(new(bytes.Buffer), "", 0))
+		fail := true
+		f := &RenderFallback{Message: "unavailable", Threshold: 2, Cooldown: time.Hour}
+		b2.Get("/page", func(c *Context) {
+			c.Set("name", "Baa")
+			if !fail {
+				c.Set("list", []int{1, 2, 3, 4, 5, 6})
+			}
+			c.HTML(200, "_fixture/broken.html")
+		}).SetMeta(RouteMetaRenderFallback, f)
+
+		So(serveTo(b2, "/page").Body.String(), ShouldEqual, "unavailable")
+		fail = false
+		So(serveTo(b2, "/page").Code, ShouldEqual, http.StatusOK)
+		fail = true
+		serveTo(b2, "/page")
+		serveTo(b2, "/page")
+		So(f.open("_fixture/broken.html"), ShouldBeTrue)
+		fail = false
+		So(serveTo(b2, "/page").Body.String(), ShouldEqual, "unavailable")
+		f.breakers["_fixture/broken.html"].openUntil = time.Now()
+		So(serveTo(b2, "/page").Code, ShouldEqual, http.StatusOK)
+	})
+}
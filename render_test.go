@@ -1,8 +1,11 @@
 package baa
 
 import (
+	"html/template"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -41,3 +44,183 @@ func TestRender1(t *testing.T) {
 		So(w.Code, ShouldEqual, http.StatusInternalServerError)
 	})
 }
+
+func TestRenderViewData1(t *testing.T) {
+	Convey("render with view data providers", t, func() {
+		b2 := New()
+		b2.AddViewData(func(c *Context) map[string]interface{} {
+			return map[string]interface{}{"user": "guest", "name": "default"}
+		}, nil)
+		b2.AddViewData(func(c *Context) map[string]interface{} {
+			return map[string]interface{}{"user": c.Query("user")}
+		})
+		b2.Get("/view", func(c *Context) {
+			c.Set("name", "Baa")
+			c.HTML(200, "_fixture/view.html")
+		})
+
+		req, _ := http.NewRequest("GET", "/view?user=baa", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "<p>baa:Baa</p>\n")
+	})
+}
+
+func TestRenderFragment1(t *testing.T) {
+	Convey("render template fragment", t, func() {
+		b2 := New()
+		b2.Get("/fragment", func(c *Context) {
+			c.Fragment("_fixture/fragment.html#list", map[string]string{"name": "Baa"})
+		})
+		b2.Get("/fragment/error", func(c *Context) {
+			c.Fragment("_fixture/fragment.html#none", nil)
+		})
+
+		req, _ := http.NewRequest("GET", "/fragment", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "<html>\n<body>\n<ul><li>Baa</li></ul>\n</body>\n</html>\n")
+
+		for _, h := range [][]string{{"HX-Request", "true"}, {"Turbo-Frame", "list"}} {
+			req, _ = http.NewRequest("GET", "/fragment", nil)
+			req.Header.Set(h[0], h[1])
+			w = httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "<ul><li>Baa</li></ul>\n")
+		}
+
+		req, _ = http.NewRequest("GET", "/fragment/error", nil)
+		req.Header.Set("HX-Request", "true")
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+	})
+}
+
+func TestRenderJSONScript1(t *testing.T) {
+	Convey("json embedded in script", t, func() {
+		js, err := JSONScript(map[string]string{"s": "</script><!--&\u2028\u2029"})
+		So(err, ShouldBeNil)
+		So(string(js), ShouldEqual, `{"s":"\u003c/script\u003e\u003c!--\u0026\u2028\u2029"}`)
+		_, err = JSONScript(make(chan int))
+		So(err, ShouldNotBeNil)
+
+		b2 := New()
+		b2.Get("/json", func(c *Context) {
+			c.Set("data", map[string]interface{}{"name": "</script>", "n": 1})
+			c.HTML(200, "_fixture/json.html")
+		})
+		req, _ := http.NewRequest("GET", "/json", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(strings.TrimSpace(w.Body.String()), ShouldEqual, `<script>var data = {"n":1,"name":"\u003c/script\u003e"};</script>`)
+	})
+}
+
+func TestRenderURLFor1(t *testing.T) {
+	Convey("urlfor template func", t, func() {
+		b2 := New()
+		b2.Get("/files/*path", func(c *Context) {
+			c.Set("path", c.Param("path"))
+			c.HTML(200, "_fixture/urlfor.html")
+		}).Name("fileShow")
+		req, _ := http.NewRequest("GET", "/files/docs/a%20b.txt", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(strings.TrimSpace(w.Body.String()), ShouldEqual, `<a href="/files/docs/a%20b.txt">docs/a b.txt</a>`)
+	})
+}
+
+// prefixRender renders template name with prefix
+type prefixRender struct {
+	prefix string
+	funcs  int
+}
+
+func (r *prefixRender) Render(w io.Writer, tpl string, data interface{}) error {
+	_, err := io.WriteString(w, r.prefix+tpl)
+	return err
+}
+
+func (r *prefixRender) Funcs(funcMap template.FuncMap) {
+	r.funcs += len(funcMap)
+}
+
+func TestRenderRouteRenderer1(t *testing.T) {
+	Convey("route scoped renderer", t, func() {
+		b2 := New()
+		render := func(c *Context) {
+			c.HTML(200, "index.html")
+		}
+		admin := b2.NewGroup("/admin")
+		r := &prefixRender{prefix: "admin:"}
+		admin.SetRenderer(r)
+		So(r.funcs, ShouldBeGreaterThan, 0)
+		admin.Get("/", render)
+		admin.Group("/users").Get("/", render)
+		api := b2.NewGroup("/api")
+		api.SetRenderer(nil)
+		api.Get("/", render)
+		b2.AddTemplateFunc("lower", strings.ToLower)
+		pr := &prefixRender{prefix: "page:"}
+		b2.Get("/page", render).SetMeta(RouteMetaRenderer, pr)
+		So(pr.funcs, ShouldBeGreaterThan, 0)
+
+		n, pn := r.funcs, pr.funcs
+		b2.AddTemplateFunc("upper", strings.ToUpper)
+		So(r.funcs, ShouldEqual, n+1)
+		So(pr.funcs, ShouldEqual, pn+1)
+
+		get := func(path string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+		w := get("/admin/")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "admin:index.html\n")
+		So(get("/admin/users/").Body.String(), ShouldEqual, "admin:index.html\n")
+		So(get("/page").Body.String(), ShouldEqual, "page:index.html\n")
+		So(get("/api/").Code, ShouldEqual, http.StatusInternalServerError)
+	})
+}
+
+func TestTemplateError1(t *testing.T) {
+	Convey("template error with name and line", t, func() {
+		b2 := New()
+		var rerr error
+		b2.SetError(func(err error, c *Context) {
+			rerr = err
+			c.String(http.StatusInternalServerError, "error")
+		})
+		b2.Get("/broken", func(c *Context) {
+			c.Set("name", "Baa")
+			c.Set("list", []int{1})
+			c.HTML(200, "_fixture/broken.html")
+		})
+		b2.Get("/missing", func(c *Context) {
+			c.HTML(200, "_fixture/index3.html")
+		})
+
+		w := serveTo(b2, "/broken")
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(w.Body.String(), ShouldEqual, "error")
+		terr, ok := rerr.(*TemplateError)
+		So(ok, ShouldBeTrue)
+		So(terr.Template, ShouldEqual, "_fixture/broken.html")
+		So(terr.Line, ShouldEqual, 2)
+		So(terr.Unwrap(), ShouldNotBeNil)
+		So(terr.Error(), ShouldStartWith, "baa: render _fixture/broken.html:2: ")
+
+		serveTo(b2, "/missing")
+		terr, ok = rerr.(*TemplateError)
+		So(ok, ShouldBeTrue)
+		So(terr.Line, ShouldEqual, 0)
+		So(terr.Error(), ShouldStartWith, "baa: render _fixture/index3.html: open ")
+		So(newTemplateError("a.html", terr), ShouldEqual, terr)
+	})
+}
@@ -2,18 +2,42 @@ package baa
 
 import (
 	"bufio"
+	"errors"
 	"io"
 	"net"
 	"net/http"
 )
 
+// ErrHijackNotSupported is returned when the underlying ResponseWriter does not support hijack.
+var ErrHijackNotSupported = errors.New("http: response does not support hijack")
+
+// ResponseWriter is the http.ResponseWriter of baa, it reports what was sent to the
+// client, eg: for logging and metrics middlewares.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	// Status returns the status code sent, 200 when the header is not written
+	Status() int
+	// Size returns number of bytes written in body
+	Size() int64
+	// Wrote returns if the header has been written
+	Wrote() bool
+}
+
+var _ ResponseWriter = (*Response)(nil)
+
 // Response implement ResponseWriter
 type Response struct {
 	wroteHeader bool  // reply header has been (logically) written
+	hijacked    bool  // connection has been hijacked
+	wrapped     bool  // writer has been replaced by SetWriter
 	written     int64 // number of bytes written in body
 	status      int   // status code passed to WriteHeader
 	resp        http.ResponseWriter
 	writer      io.Writer
+	headerHooks []func(code int)
 	baa         *Baa
 }
 
@@ -42,6 +66,9 @@ func (r *Response) Header() http.Header {
 // Content-Type line, Write adds a Content-Type set to the result of passing
 // the initial 512 bytes of written data to DetectContentType.
 func (r *Response) Write(b []byte) (int, error) {
+	if r.hijacked {
+		return 0, http.ErrHijacked
+	}
 	if !r.wroteHeader {
 		r.WriteHeader(http.StatusOK)
 	}
@@ -56,27 +83,90 @@ func (r *Response) Write(b []byte) (int, error) {
 // Thus explicit calls to WriteHeader are mainly used to
 // send error codes.
 func (r *Response) WriteHeader(code int) {
+	if r.hijacked {
+		return
+	}
 	if r.wroteHeader {
-		r.baa.Logger().Println("http: multiple response.WriteHeader calls")
+		r.baa.Logger().Printf("http: superfluous response.WriteHeader call with status %d, status %d was sent", code, r.status)
 		return
 	}
+	for i, fn := range r.headerHooks {
+		r.headerHooks[i] = nil
+		fn(code)
+	}
+	r.headerHooks = r.headerHooks[:0]
 	r.wroteHeader = true
 	r.status = code
 	r.resp.WriteHeader(code)
 }
 
+// OnWriteHeader registers fn called with the status code before the header is
+// written, eg: set cookies of state changed by handlers or choose the content
+// encoding. Hooks are called once and cleared when the header is written.
+func (r *Response) OnWriteHeader(fn func(code int)) {
+	r.headerHooks = append(r.headerHooks, fn)
+}
+
 // Flush implements the http.Flusher interface to allow an HTTP handler to flush
-// buffered data to the client.
+// buffered data to the client. The writer set by SetWriter is flushed first,
+// eg: a gzip writer, it does nothing when the underlying writer can't flush.
 // See [http.Flusher](https://golang.org/pkg/net/http/#Flusher)
 func (r *Response) Flush() {
-	r.resp.(http.Flusher).Flush()
+	if r.wrapped {
+		switch w := r.writer.(type) {
+		case interface{ Flush() error }:
+			w.Flush()
+		case http.Flusher:
+			w.Flush()
+		}
+	}
+	if f, ok := r.resp.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
 // Hijack implements the http.Hijacker interface to allow an HTTP handler to
 // take over the connection.
+// After a successful hijack the response is marked as written, so the handle chain
+// will break and later writes return http.ErrHijacked.
 // See [http.Hijacker](https://golang.org/pkg/net/http/#Hijacker)
 func (r *Response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	return r.resp.(http.Hijacker).Hijack()
+	if r.hijacked {
+		return nil, nil, http.ErrHijacked
+	}
+	h, ok := r.resp.(http.Hijacker)
+	if !ok {
+		return nil, nil, ErrHijackNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	r.hijacked = true
+	r.wroteHeader = true
+	r.status = http.StatusSwitchingProtocols
+	return conn, rw, nil
+}
+
+// Push implements the http.Pusher interface to initiate HTTP/2 server pushes,
+// http.ErrNotSupported is returned when the underlying writer does not support it.
+// See [http.Pusher](https://golang.org/pkg/net/http/#Pusher)
+func (r *Response) Push(target string, opts *http.PushOptions) error {
+	if p, ok := r.resp.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it's used by
+// http.ResponseController to reach the connection.
+func (r *Response) Unwrap() http.ResponseWriter {
+	return r.resp
+}
+
+// Hijacked returns if the connection has been hijacked
+func (r *Response) Hijacked() bool {
+	return r.hijacked
 }
 
 // CloseNotify implements the http.CloseNotifier interface to allow detecting
@@ -93,6 +183,12 @@ func (r *Response) reset(w http.ResponseWriter) {
 	r.resp = w
 	r.writer = w
 	r.wroteHeader = false
+	r.hijacked = false
+	r.wrapped = false
+	for i := range r.headerHooks {
+		r.headerHooks[i] = nil
+	}
+	r.headerHooks = r.headerHooks[:0]
 	r.written = 0
 	r.status = http.StatusOK
 }
@@ -120,4 +216,5 @@ func (r *Response) GetWriter() io.Writer {
 // SetWriter set response io writer
 func (r *Response) SetWriter(w io.Writer) {
 	r.writer = w
+	r.wrapped = true
 }
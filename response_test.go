@@ -12,20 +12,20 @@ func TestResponse1(t *testing.T) {
 	Convey("response method", t, func() {
 
 		b.Get("/response", func(c *Context) {
-			c.Resp.Size()
+			So(c.Resp.Size(), ShouldEqual, 0)
+			So(c.Resp.Wrote(), ShouldBeFalse)
 			c.Resp.Write([]byte("1"))
 			c.Resp.Write([]byte("2"))
 			c.Resp.WriteHeader(200)
 			c.Resp.Flush()
-			c.Resp.Status()
+			So(c.Resp.Status(), ShouldEqual, 200)
+			So(c.Resp.Size(), ShouldEqual, 2)
+			So(c.Resp.Wrote(), ShouldBeTrue)
+			So(c.Resp.Push("/app.js", nil), ShouldEqual, http.ErrNotSupported)
 
-			func() {
-				defer func() {
-					e := recover()
-					So(e, ShouldNotBeNil)
-				}()
-				c.Resp.Hijack()
-			}()
+			_, _, err := c.Resp.Hijack()
+			So(err, ShouldEqual, ErrHijackNotSupported)
+			So(c.Resp.Hijacked(), ShouldBeFalse)
 
 			func() {
 				defer func() {
@@ -0,0 +1,146 @@
+package baa
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is the time waiting in-flight requests on shutdown signals
+const defaultShutdownTimeout = 30 * time.Second
+
+// listenFdsStart is the first file descriptor passed by socket activation
+var listenFdsStart = 3
+
+// SetShutdownSignals set signals trigger graceful shutdown of servers started by Run,
+// RunTLS, RunNetwork, RunServer and RunTLSServer, in-flight requests have at most
+// timeout to finish. Default is SIGINT and SIGTERM with 30s, no signals disables it.
+func (b *Baa) SetShutdownSignals(timeout time.Duration, sig ...os.Signal) {
+	b.shutdownTimeout = timeout
+	b.shutdownSignals = sig
+	b.shutdownSigSet = true
+}
+
+// SetReusePort enables SO_REUSEPORT of listeners created by b.Listen, so a new process
+// can bind the same address before the old one shuts down for zero-downtime restart.
+// It's supported on Linux and BSDs.
+func (b *Baa) SetReusePort(v bool) {
+	b.reusePort = v
+}
+
+// handleSignals shuts down b on shutdown signals, stop must be called after serving,
+// it waits the shutdown triggered by signal to complete.
+func (b *Baa) handleSignals() (stop func()) {
+	sigs, timeout := b.shutdownSignals, b.shutdownTimeout
+	if !b.shutdownSigSet {
+		sigs, timeout = []os.Signal{os.Interrupt, syscall.SIGTERM}, defaultShutdownTimeout
+	}
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case sig := <-ch:
+			b.Logger().Printf("Shutdown on signal %v, waiting %v for in-flight requests", sig, timeout)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := b.Shutdown(ctx); err != nil {
+				b.Logger().Printf("Shutdown error: %v", err)
+			}
+		case <-quit:
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		select {
+		case <-done:
+		default:
+			close(quit)
+			<-done
+		}
+	}
+}
+
+// inherited holds listeners passed by socket activation
+var inherited struct {
+	once      sync.Once
+	listeners []net.Listener
+	mu        sync.Mutex
+}
+
+// inheritedListener returns the listener bound to addr of network passed by systemd
+// socket activation protocol (LISTEN_PID, LISTEN_FDS), nil when there is none.
+func inheritedListener(network, addr string) (net.Listener, error) {
+	var err error
+	inherited.once.Do(func() {
+		pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		n, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if pid != os.Getpid() || n <= 0 {
+			return
+		}
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+			f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+			ln, e := net.FileListener(f)
+			f.Close()
+			if e != nil {
+				err = fmt.Errorf("baa: inherited listener fd %d: %v", fd, e)
+				return
+			}
+			inherited.listeners = append(inherited.listeners, ln)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+	for i, ln := range inherited.listeners {
+		if listenerMatches(ln.Addr(), network, addr) {
+			inherited.listeners = append(inherited.listeners[:i], inherited.listeners[i+1:]...)
+			return ln, nil
+		}
+	}
+	return nil, nil
+}
+
+// listenerMatches checks a listener bound to la can serve addr of network, an
+// empty or unspecified host matches any host of the same port.
+func listenerMatches(la net.Addr, network, addr string) bool {
+	switch la := la.(type) {
+	case *net.TCPAddr:
+		want, err := net.ResolveTCPAddr(network, addr)
+		if err != nil || want.Port != la.Port {
+			return false
+		}
+		return want.IP == nil || want.IP.IsUnspecified() || want.IP.Equal(la.IP)
+	case *net.UnixAddr:
+		return (network == "unix" || network == "unixpacket") && la.Name == addr
+	}
+	return false
+}
+
+// listen creates listener on network, the listener of addr passed by socket activation is used first
+func (b *Baa) listen(network, addr string) (net.Listener, error) {
+	ln, err := inheritedListener(network, addr)
+	if ln != nil || err != nil {
+		return ln, err
+	}
+	if !b.reusePort {
+		return net.Listen(network, addr)
+	}
+	lc := net.ListenConfig{Control: reusePortControl}
+	return lc.Listen(context.Background(), network, addr)
+}
@@ -0,0 +1,6 @@
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package baa
+
+// soReusePort is SO_REUSEPORT which is missing in package syscall
+const soReusePort = 0x200
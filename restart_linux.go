@@ -0,0 +1,7 @@
+// +build linux
+// +build !mips,!mipsle,!mips64,!mips64le,!sparc64
+
+package baa
+
+// soReusePort is SO_REUSEPORT which is missing in package syscall
+const soReusePort = 0xf
@@ -0,0 +1,8 @@
+// +build linux
+// +build mips mipsle mips64 mips64le sparc64
+
+package baa
+
+// soReusePort is SO_REUSEPORT which is missing in package syscall, it differs
+// on mips and sparc.
+const soReusePort = 0x200
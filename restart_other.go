@@ -0,0 +1,13 @@
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package baa
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reusePortControl fails as SO_REUSEPORT is not supported
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return errors.New("baa: SO_REUSEPORT is not supported on this platform")
+}
@@ -0,0 +1,21 @@
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package baa
+
+import (
+	"syscall"
+)
+
+// reusePortControl sets SO_REUSEADDR and SO_REUSEPORT on the socket
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var err error
+	if e := c.Control(func(fd uintptr) {
+		if err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); err != nil {
+			return
+		}
+		err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	}); e != nil {
+		return e
+	}
+	return err
+}
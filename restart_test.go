@@ -0,0 +1,117 @@
+package baa
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRestart1(t *testing.T) {
+	Convey("shutdown on signal waits in-flight requests", t, func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		b2 := New()
+		logs := new(bytes.Buffer)
+		b2.SetDI("logger", log.New(logs, "", 0))
+		b2.SetShutdownSignals(time.Second, syscall.SIGUSR2)
+		started := make(chan bool)
+		b2.Get("/slow", func(c *Context) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			c.String(http.StatusOK, "done")
+		})
+		exited := make(chan bool)
+		go func() {
+			b2.Run(addr)
+			close(exited)
+		}()
+
+		result := make(chan string, 1)
+		go func() {
+			for i := 0; i < 100; i++ {
+				resp, err := http.Get("http://" + addr + "/slow")
+				if err != nil {
+					time.Sleep(10 * time.Millisecond)
+					continue
+				}
+				body, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				result <- string(body)
+				return
+			}
+			close(result)
+		}()
+		<-started
+		syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+		So(<-result, ShouldEqual, "done")
+		select {
+		case <-exited:
+		case <-time.After(time.Second):
+			t.Error("run does not return after shutdown")
+		}
+		So(logs.String(), ShouldContainSubstring, "Shutdown on signal user defined signal 2")
+	})
+	Convey("reuse port", t, func() {
+		if runtime.GOOS != "linux" {
+			return
+		}
+		b2 := New()
+		b2.SetDI("logger", log.New(new(bytes.Buffer), "", 0))
+		b2.SetReusePort(true)
+		ln, err := b2.Listen("tcp4", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer ln.Close()
+		ln2, err := b2.Listen("tcp4", "127.0.0.1:"+strconv.Itoa(ListenerPort(ln)))
+		So(err, ShouldBeNil)
+		ln2.Close()
+	})
+	Convey("inherited listener", t, func() {
+		ln, err := net.Listen("tcp4", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer ln.Close()
+		f, err := ln.(*net.TCPListener).File()
+		So(err, ShouldBeNil)
+		defer f.Close()
+
+		defer func(start int) {
+			listenFdsStart = start
+			inherited.once = sync.Once{}
+		}(listenFdsStart)
+		listenFdsStart = int(f.Fd())
+		inherited.once = sync.Once{}
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		os.Setenv("LISTEN_FDS", "1")
+
+		b2 := New()
+		b2.SetDI("logger", log.New(new(bytes.Buffer), "", 0))
+		ln1, err := b2.Listen("tcp4", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		So(ln1.Addr().String(), ShouldNotEqual, ln.Addr().String())
+		ln1.Close()
+		So(os.Getenv("LISTEN_FDS"), ShouldBeEmpty)
+
+		ln2, err := b2.Listen("tcp", ":"+strconv.Itoa(ListenerPort(ln)))
+		So(err, ShouldBeNil)
+		So(ln2.Addr().String(), ShouldEqual, ln.Addr().String())
+		ln2.Close()
+
+		So(listenerMatches(ln.Addr(), "tcp4", ln.Addr().String()), ShouldBeTrue)
+		So(listenerMatches(ln.Addr(), "tcp4", "127.0.0.2:"+strconv.Itoa(ListenerPort(ln))), ShouldBeFalse)
+		So(listenerMatches(ln.Addr(), "unix", ln.Addr().String()), ShouldBeFalse)
+		So(listenerMatches(&net.UnixAddr{Name: "/run/app.sock", Net: "unix"}, "unix", "/run/app.sock"), ShouldBeTrue)
+	})
+}
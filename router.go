@@ -1,5 +1,7 @@
 package baa
 
+import "fmt"
+
 const (
 	GET int = iota
 	POST
@@ -55,11 +57,116 @@ type Router interface {
 	NamedRoutes() map[string]string
 }
 
+// RouteMetaNoTransform is the route meta key marks the response should not be
+// transformed by compression or minification middlewares, eg: SSE, websocket.
+const RouteMetaNoTransform = "baa.noTransform"
+
 // RouteNode is an router node
 type RouteNode interface {
 	Name(name string)
 }
 
+// MetaRouteNode is a route node supports metadata and route middlewares, routes
+// registered by b.Get, b.Route and others are MetaRouteNode. SetMeta and Use panic
+// on nodes returned by a Router.Add not implement it, so middlewares are never
+// dropped silently.
+type MetaRouteNode interface {
+	RouteNode
+	// SetMeta set route metadata, it can be read by c.RouteMeta in handlers and middlewares
+	SetMeta(key string, v interface{}) MetaRouteNode
+	// Use registers middlewares only executed by the route
+	Use(m ...Middleware) MetaRouteNode
+	// Meta returns route metadata by key
+	Meta(key string) interface{}
+	// Pattern returns route pattern
+	Pattern() string
+	// ParamIndex returns index of param name in the route, it can be used with c.ParamAt
+	ParamIndex(name string) int
+}
+
+// routeNodes is a list of route node registered by one call, eg: b.Route, b.Any
+type routeNodes []RouteNode
+
+// metaNode returns n as MetaRouteNode
+func metaNode(n RouteNode) MetaRouteNode {
+	if m, ok := n.(MetaRouteNode); ok {
+		return m
+	}
+	return routeNodes{n}
+}
+
+// first returns the first route supports metadata
+func (ns routeNodes) first() MetaRouteNode {
+	for i := range ns {
+		if m, ok := ns[i].(MetaRouteNode); ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// Name set name of all routes
+func (ns routeNodes) Name(name string) {
+	for i := range ns {
+		ns[i].Name(name)
+	}
+}
+
+// SetMeta set metadata of all routes, it panics when a route does not support metadata
+func (ns routeNodes) SetMeta(key string, v interface{}) MetaRouteNode {
+	for _, n := range ns.metaNodes("SetMeta") {
+		n.SetMeta(key, v)
+	}
+	return ns
+}
+
+// Use registers middlewares of all routes, it panics when a route does not support
+// middlewares
+func (ns routeNodes) Use(m ...Middleware) MetaRouteNode {
+	for _, n := range ns.metaNodes("Use") {
+		n.Use(m...)
+	}
+	return ns
+}
+
+// metaNodes returns routes as MetaRouteNode, it panics when any route is not,
+// before changing any route
+func (ns routeNodes) metaNodes(method string) []MetaRouteNode {
+	nodes := make([]MetaRouteNode, len(ns))
+	for i := range ns {
+		n, ok := ns[i].(MetaRouteNode)
+		if !ok {
+			panic(fmt.Sprintf("baa.RouteNode.%s route node %T of the router does not implement baa.MetaRouteNode", method, ns[i]))
+		}
+		nodes[i] = n
+	}
+	return nodes
+}
+
+// Meta returns metadata of the first route
+func (ns routeNodes) Meta(key string) interface{} {
+	if n := ns.first(); n != nil {
+		return n.Meta(key)
+	}
+	return nil
+}
+
+// Pattern returns pattern of the first route
+func (ns routeNodes) Pattern() string {
+	if n := ns.first(); n != nil {
+		return n.Pattern()
+	}
+	return ""
+}
+
+// ParamIndex returns param index of the first route
+func (ns routeNodes) ParamIndex(name string) int {
+	if n := ns.first(); n != nil {
+		return n.ParamIndex(name)
+	}
+	return -1
+}
+
 // IsParamChar check the char can used for route params
 // a-z->65:90, A-Z->97:122, 0-9->48->57, _->95
 func IsParamChar(c byte) bool {
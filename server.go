@@ -0,0 +1,134 @@
+package baa
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultReadHeaderTimeout is the amount of time allowed to read request headers,
+	// protect the server from slow header attacks (slowloris).
+	defaultReadHeaderTimeout = 10 * time.Second
+	// defaultIdleTimeout is the maximum amount of time to wait for the next request
+	// when keep-alives are enabled.
+	defaultIdleTimeout = 120 * time.Second
+)
+
+// ServerOption configures the *http.Server built by b.Server
+type ServerOption func(*http.Server)
+
+// ServerReadHeaderTimeout sets the amount of time allowed to read request headers.
+func ServerReadHeaderTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) {
+		s.ReadHeaderTimeout = d
+	}
+}
+
+// ServerReadTimeout sets the maximum duration for reading the entire request, including the body.
+func ServerReadTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) {
+		s.ReadTimeout = d
+	}
+}
+
+// ServerWriteTimeout sets the maximum duration before timing out writes of the response.
+func ServerWriteTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) {
+		s.WriteTimeout = d
+	}
+}
+
+// ServerIdleTimeout sets the maximum amount of time to wait for the next request
+// when keep-alives are enabled.
+func ServerIdleTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) {
+		s.IdleTimeout = d
+	}
+}
+
+// ServerMaxHeaderBytes sets the maximum number of bytes the server will read
+// parsing the request header's keys and values, including the request line.
+func ServerMaxHeaderBytes(n int) ServerOption {
+	return func(s *http.Server) {
+		s.MaxHeaderBytes = n
+	}
+}
+
+// ServerConnState adds a hook called when a client connection changes state,
+// hooks are chained, so it can be used more than once.
+func ServerConnState(h func(net.Conn, http.ConnState)) ServerOption {
+	return func(s *http.Server) {
+		if h == nil {
+			return
+		}
+		prev := s.ConnState
+		if prev == nil {
+			s.ConnState = h
+			return
+		}
+		s.ConnState = func(conn net.Conn, state http.ConnState) {
+			prev(conn, state)
+			h(conn, state)
+		}
+	}
+}
+
+// SetMaxConnsPerIP limits concurrent connections per remote IP of servers started by
+// Run, RunTLS, RunServer and RunTLSServer, 0 means no limit. See LimitListenerPerIP.
+func (b *Baa) SetMaxConnsPerIP(n int) {
+	b.maxConnsPerIP = n
+}
+
+// SetKeepAlivesEnabled controls whether HTTP keep-alives are enabled of running servers
+// and servers started later, eg: disable them while draining before shutdown so clients
+// reconnect to other instances. Keep-alives are enabled by default.
+func (b *Baa) SetKeepAlivesEnabled(v bool) {
+	b.streams.mu.Lock()
+	b.keepAlivesOff = !v
+	servers := append([]*http.Server(nil), b.streams.servers...)
+	b.streams.mu.Unlock()
+	for _, s := range servers {
+		s.SetKeepAlivesEnabled(v)
+	}
+}
+
+// trackServer tracks a running server for shutdown and keep-alives control
+func (b *Baa) trackServer(s *http.Server) {
+	b.streams.mu.Lock()
+	b.streams.servers = append(b.streams.servers, s)
+	off := b.keepAlivesOff
+	b.streams.mu.Unlock()
+	if off {
+		s.SetKeepAlivesEnabled(false)
+	}
+}
+
+// SetServerOption registers options applied to every server built by b.Server,
+// include the servers used by Run and RunTLS.
+func (b *Baa) SetServerOption(opts ...ServerOption) {
+	for i := range opts {
+		if opts[i] != nil {
+			b.serverOptions = append(b.serverOptions, opts[i])
+		}
+	}
+}
+
+// newServer create a http server with default timeouts then apply the options
+func (b *Baa) newServer(addr string, opts []ServerOption) *http.Server {
+	s := &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		IdleTimeout:       defaultIdleTimeout,
+		ConnState:         b.connStats.track,
+	}
+	for _, opt := range b.serverOptions {
+		opt(s)
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	return s
+}
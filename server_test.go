@@ -0,0 +1,44 @@
+package baa
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServerOption1(t *testing.T) {
+	Convey("server options", t, func() {
+		Convey("default timeouts", func() {
+			b2 := New()
+			s := b2.Server(":8016")
+			So(s.Addr, ShouldEqual, ":8016")
+			So(s.ReadHeaderTimeout, ShouldEqual, defaultReadHeaderTimeout)
+			So(s.IdleTimeout, ShouldEqual, defaultIdleTimeout)
+		})
+		Convey("custom options", func() {
+			b2 := New()
+			n := 0
+			hook := func(net.Conn, http.ConnState) { n++ }
+			b2.SetServerOption(ServerReadTimeout(time.Second), nil)
+			s := b2.Server(":8016",
+				ServerReadHeaderTimeout(2*time.Second),
+				ServerWriteTimeout(3*time.Second),
+				ServerIdleTimeout(4*time.Second),
+				ServerMaxHeaderBytes(1024),
+				ServerConnState(hook),
+				ServerConnState(hook),
+				ServerConnState(nil),
+			)
+			So(s.ReadTimeout, ShouldEqual, time.Second)
+			So(s.ReadHeaderTimeout, ShouldEqual, 2*time.Second)
+			So(s.WriteTimeout, ShouldEqual, 3*time.Second)
+			So(s.IdleTimeout, ShouldEqual, 4*time.Second)
+			So(s.MaxHeaderBytes, ShouldEqual, 1024)
+			s.ConnState(nil, http.StateNew)
+			So(n, ShouldEqual, 2)
+		})
+	})
+}
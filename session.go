@@ -0,0 +1,422 @@
+package baa
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sessionKey is the context store key of current session
+	sessionKey = "baa.session"
+	// sessionIDLength length of encoded session ids
+	sessionIDLength = 43
+	// sessionTouchInterval minimum interval the access time of session is refreshed
+	sessionTouchInterval = time.Minute
+)
+
+// ErrSessionWritten is returned by Session.Save when the session cookie must be
+// set but the response header has been written.
+var ErrSessionWritten = errors.New("baa: session cookie can not be set after the response is written")
+
+// SessionStore persists encoded sessions by id
+type SessionStore interface {
+	// Load returns data of session id, nil without error when it does not exist
+	Load(id string) ([]byte, error)
+	// Save stores data of session id, it expires after ttl
+	Save(id string, data []byte, ttl time.Duration) error
+	// Delete removes session id
+	Delete(id string) error
+}
+
+// SessionOptions config of sessions
+type SessionOptions struct {
+	// Store session storage, default NewMemorySessionStore(0)
+	Store SessionStore
+	// Codec encodes session values, default GobCodec, types stored in interfaces
+	// must be registered by gob.Register
+	Codec Codec
+	// CookieName name of session cookie, default baa_session
+	CookieName string
+	// Path of session cookie, default /
+	Path string
+	// Domain of session cookie
+	Domain string
+	// Secure sets the Secure attribute of session cookie
+	Secure bool
+	// SameSite attribute of session cookie, default http.SameSiteLaxMode
+	SameSite http.SameSite
+	// IdleTimeout sessions expire when not accessed in, default 30 minutes
+	IdleTimeout time.Duration
+	// MaxLifetime sessions expire after created or renewed, default 24 hours
+	MaxLifetime time.Duration
+}
+
+// SetSessions enables c.Session, sessions are identified by a HttpOnly cookie and
+// kept in the store, they expire when idle longer than IdleTimeout or older than
+// MaxLifetime. Modified sessions are saved before the response header is written.
+//
+// Example:
+// 		b.SetSessions(baa.SessionOptions{
+// 			Store:  baa.NewRedisSessionStore(client, "session:"),
+// 			Secure: true,
+// 		})
+// 		b.Get("/", func(c *baa.Context) {
+// 			s := c.Session()
+// 			n, _ := s.Get("visits").(int)
+// 			s.Set("visits", n+1)
+// 			c.String(200, strconv.Itoa(n+1))
+// 		})
+func (b *Baa) SetSessions(opt SessionOptions) {
+	if opt.Store == nil {
+		opt.Store = NewMemorySessionStore(0)
+	}
+	if s, ok := opt.Store.(interface{ SetClock(Clock) }); ok {
+		s.SetClock(clockOf(b))
+	}
+	if opt.Codec == nil {
+		opt.Codec = GobCodec
+	}
+	if opt.CookieName == "" {
+		opt.CookieName = "baa_session"
+	}
+	if opt.Path == "" {
+		opt.Path = "/"
+	}
+	if opt.SameSite == 0 {
+		opt.SameSite = http.SameSiteLaxMode
+	}
+	if opt.IdleTimeout <= 0 {
+		opt.IdleTimeout = 30 * time.Minute
+	}
+	if opt.MaxLifetime <= 0 {
+		opt.MaxLifetime = 24 * time.Hour
+	}
+	b.sessions = &opt
+}
+
+// Session is the session of a client, it's loaded once per request by c.Session.
+// Session implements KVStore, structured values can be kept by a TypedStore.
+type Session struct {
+	c          *Context
+	opt        *SessionOptions
+	mu         sync.Mutex
+	id         string
+	values     map[string]interface{}
+	created    time.Time
+	accessed   time.Time
+	stored     bool // the session exists in the store
+	changed    bool // values or id changed since saved
+	touched    bool // access time changed since saved
+	sendCookie bool // the cookie must be sent on save
+}
+
+// sessionData is the encoded form of session
+type sessionData struct {
+	Values   map[string]interface{}
+	Created  int64
+	Accessed int64
+}
+
+// Session returns the session of request, a new session is started when the client
+// has no valid session. It panics when SetSessions is not called.
+func (c *Context) Session() *Session {
+	if s, ok := c.Get(sessionKey).(*Session); ok {
+		return s
+	}
+	opt := c.baa.sessions
+	if opt == nil {
+		panic("baa.Context.Session sessions are not configured, call SetSessions first")
+	}
+	s := &Session{c: c, opt: opt}
+	if !s.load() {
+		s.start(c.baa.Clock().Now())
+	}
+	c.Set(sessionKey, s)
+	c.Resp.OnWriteHeader(func(int) { s.autoSave() })
+	c.OnClose(s.autoSave)
+	return s
+}
+
+// load loads the session of cookie, it returns false when there is no valid session
+func (s *Session) load() bool {
+	cookie, err := s.c.Req.Cookie(s.opt.CookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	_, client := s.opt.Store.(*CookieSessionStore)
+	if !client && !validSessionID(cookie.Value) {
+		return false
+	}
+	data, err := s.opt.Store.Load(cookie.Value)
+	if err != nil {
+		s.c.baa.Logger().Printf("baa: load session: %v", err)
+		return false
+	}
+	if data == nil {
+		return false
+	}
+	var v sessionData
+	if err = s.opt.Codec.Unmarshal(data, &v); err != nil {
+		s.c.baa.Logger().Printf("baa: decode session: %v", err)
+		return false
+	}
+	now := s.c.baa.Clock().Now()
+	s.created, s.accessed = time.Unix(0, v.Created), time.Unix(0, v.Accessed)
+	if now.Sub(s.accessed) >= s.opt.IdleTimeout || now.Sub(s.created) >= s.opt.MaxLifetime {
+		if !client {
+			s.opt.Store.Delete(cookie.Value)
+		}
+		return false
+	}
+	if !client {
+		s.id = cookie.Value
+	}
+	s.values = v.Values
+	if s.values == nil {
+		s.values = make(map[string]interface{})
+	}
+	s.stored = true
+	if now.Sub(s.accessed) >= sessionTouchInterval {
+		s.accessed = now
+		s.touched = true
+	}
+	return true
+}
+
+// start resets s to a new empty session
+func (s *Session) start(now time.Time) {
+	if _, client := s.opt.Store.(*CookieSessionStore); !client {
+		s.id = newSessionID()
+	} else {
+		s.id = ""
+	}
+	s.values = make(map[string]interface{})
+	s.created, s.accessed = now, now
+	s.stored = false
+	s.changed, s.touched = false, false
+	s.sendCookie = true
+}
+
+// ID returns the session id, it's empty for sessions kept in cookies
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// IsNew returns if the session has not been saved
+func (s *Session) IsNew() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.stored
+}
+
+// Get returns value of key, nil when not found
+func (s *Session) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set sets value of key
+func (s *Session) Set(key string, v interface{}) {
+	s.mu.Lock()
+	s.values[key] = v
+	s.changed = true
+	s.mu.Unlock()
+}
+
+// Delete removes key
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.changed = true
+	}
+	s.mu.Unlock()
+}
+
+// Renew changes the session id and restarts its lifetime keeping values, it should
+// be called on privilege change, eg: login, to prevent session fixation.
+func (s *Session) Renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, client := s.opt.Store.(*CookieSessionStore); !client {
+		if s.stored {
+			s.deleteStored()
+		}
+		s.id = newSessionID()
+		s.stored = false
+	}
+	now := s.c.baa.Clock().Now()
+	s.created, s.accessed = now, now
+	s.changed = true
+	s.sendCookie = true
+}
+
+// Destroy removes the session and its cookie, following calls use a new session
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, client := s.opt.Store.(*CookieSessionStore); !client && s.stored {
+		s.deleteStored()
+	}
+	if !s.c.Resp.Wrote() {
+		s.setCookie("", -1)
+	}
+	s.start(s.c.baa.Clock().Now())
+}
+
+// Save saves the session when it's modified, it's called automatically before
+// the response header is written and when the request finished.
+func (s *Session) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.changed && !s.touched {
+		return nil
+	}
+	changed := s.changed
+	s.changed, s.touched = false, false
+	now := s.c.baa.Clock().Now()
+	remaining := s.created.Add(s.opt.MaxLifetime).Sub(now)
+	ttl := s.opt.IdleTimeout
+	if remaining < ttl {
+		ttl = remaining
+	}
+	data, err := s.opt.Codec.Marshal(&sessionData{
+		Values:   s.values,
+		Created:  s.created.UnixNano(),
+		Accessed: s.accessed.UnixNano(),
+	})
+	if err != nil {
+		return err
+	}
+	value := s.id
+	if cs, ok := s.opt.Store.(*CookieSessionStore); ok {
+		if value, err = cs.seal(data, now.Add(ttl)); err != nil {
+			return err
+		}
+		s.sendCookie = true
+	} else if err = s.opt.Store.Save(s.id, data, ttl); err != nil {
+		return err
+	}
+	s.stored = true
+	if !s.sendCookie {
+		return nil
+	}
+	if s.c.Resp.Wrote() {
+		if changed {
+			return ErrSessionWritten
+		}
+		// the cookie of a touched session still holds the last access time
+		return nil
+	}
+	s.setCookie(value, int(remaining/time.Second))
+	s.sendCookie = false
+	return nil
+}
+
+// autoSave saves the session and logs errors
+func (s *Session) autoSave() {
+	if err := s.Save(); err != nil {
+		s.c.baa.Logger().Printf("baa: save session: %v", err)
+	}
+}
+
+// deleteStored removes the session from store
+func (s *Session) deleteStored() {
+	if err := s.opt.Store.Delete(s.id); err != nil {
+		s.c.baa.Logger().Printf("baa: delete session: %v", err)
+	}
+}
+
+// setCookie sets the session cookie, the cookie set before in the response is replaced
+func (s *Session) setCookie(value string, maxAge int) {
+	h := s.c.Resp.Header()
+	prefix := s.opt.CookieName + "="
+	var cookies []string
+	for _, v := range h["Set-Cookie"] {
+		if !strings.HasPrefix(v, prefix) {
+			cookies = append(cookies, v)
+		}
+	}
+	if len(cookies) > 0 {
+		h["Set-Cookie"] = cookies
+	} else {
+		h.Del("Set-Cookie")
+	}
+	http.SetCookie(s.c.Resp, &http.Cookie{
+		Name:     s.opt.CookieName,
+		Value:    value,
+		Path:     s.opt.Path,
+		Domain:   s.opt.Domain,
+		MaxAge:   maxAge,
+		Secure:   s.opt.Secure,
+		HttpOnly: true,
+		SameSite: s.opt.SameSite,
+	})
+}
+
+// newSessionID returns a random session id
+func newSessionID() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic("baa: generate session id: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// validSessionID checks id is generated by newSessionID
+func validSessionID(id string) bool {
+	if len(id) != sessionIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// MemorySessionStore keeps sessions in a memory LRU cache, sessions are lost on
+// restart and not shared by processes.
+type MemorySessionStore struct {
+	cache *MemoryCache
+}
+
+// NewMemorySessionStore create a memory store holds at most size sessions, 0 means
+// no limit
+func NewMemorySessionStore(size int) *MemorySessionStore {
+	return &MemorySessionStore{cache: NewMemoryCache(size)}
+}
+
+// SetClock set the clock used by ttl
+func (m *MemorySessionStore) SetClock(c Clock) {
+	m.cache.SetClock(c)
+}
+
+// Load returns data of session id
+func (m *MemorySessionStore) Load(id string) ([]byte, error) {
+	v, ok := m.cache.Get(id)
+	if !ok {
+		return nil, nil
+	}
+	return v.([]byte), nil
+}
+
+// Save stores data of session id
+func (m *MemorySessionStore) Save(id string, data []byte, ttl time.Duration) error {
+	return m.cache.Set(id, data, ttl)
+}
+
+// Delete removes session id
+func (m *MemorySessionStore) Delete(id string) error {
+	return m.cache.Delete(id)
+}
@@ -0,0 +1,246 @@
+package baa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxSessionCookieSize maximum size of session cookie browsers accept
+const maxSessionCookieSize = 4000
+
+// ErrSessionTooLarge is returned when a session does not fit in a cookie
+var ErrSessionTooLarge = errors.New("baa: session is too large for a cookie")
+
+// CookieSessionStore keeps sessions in the session cookie encrypted by AES-GCM,
+// nothing is stored on server so sessions must be small and can not be revoked
+// before expiry.
+type CookieSessionStore struct {
+	aeads []cipher.AEAD
+	clock Clock
+}
+
+// NewCookieSessionStore create a cookie store, cookies are encrypted by the first
+// key and decrypted by any key so keys can be rotated, keys of any length are
+// hashed to AES-256 keys.
+func NewCookieSessionStore(keys ...[]byte) *CookieSessionStore {
+	if len(keys) == 0 {
+		panic("baa.NewCookieSessionStore keys can not be empty")
+	}
+	s := &CookieSessionStore{clock: SystemClock}
+	for _, key := range keys {
+		if len(key) == 0 {
+			panic("baa.NewCookieSessionStore key can not be empty")
+		}
+		sum := sha256.Sum256(key)
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			panic("baa.NewCookieSessionStore " + err.Error())
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic("baa.NewCookieSessionStore " + err.Error())
+		}
+		s.aeads = append(s.aeads, aead)
+	}
+	return s
+}
+
+// SetClock set the clock used by expiry
+func (s *CookieSessionStore) SetClock(c Clock) {
+	s.clock = c
+}
+
+// Load decrypts data of the cookie value id, nil is returned when the value is
+// invalid or expired.
+func (s *CookieSessionStore) Load(id string) ([]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return nil, nil
+	}
+	for _, aead := range s.aeads {
+		n := aead.NonceSize()
+		if len(raw) < n {
+			return nil, nil
+		}
+		plain, err := aead.Open(nil, raw[:n], raw[n:], nil)
+		if err != nil || len(plain) < 8 {
+			continue
+		}
+		if s.clock.Now().UnixNano() >= int64(binary.BigEndian.Uint64(plain)) {
+			return nil, nil
+		}
+		return plain[8:], nil
+	}
+	return nil, nil
+}
+
+// Save does nothing, data is sent in the cookie
+func (s *CookieSessionStore) Save(id string, data []byte, ttl time.Duration) error {
+	return nil
+}
+
+// Delete does nothing, the cookie is removed from the client
+func (s *CookieSessionStore) Delete(id string) error {
+	return nil
+}
+
+// seal encrypts data expires at expires into a cookie value
+func (s *CookieSessionStore) seal(data []byte, expires time.Time) (string, error) {
+	aead := s.aeads[0]
+	plain := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(plain, uint64(expires.UnixNano()))
+	copy(plain[8:], data)
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, nil))
+	if len(value) > maxSessionCookieSize {
+		return "", ErrSessionTooLarge
+	}
+	return value, nil
+}
+
+// FileSessionStore keeps sessions in files of a directory, expired files are
+// removed by GC.
+type FileSessionStore struct {
+	dir   string
+	clock Clock
+}
+
+// NewFileSessionStore create a file store in dir, the directory is created when
+// not exists.
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileSessionStore{dir: dir, clock: SystemClock}, nil
+}
+
+// SetClock set the clock used by ttl
+func (s *FileSessionStore) SetClock(c Clock) {
+	s.clock = c
+}
+
+// Load returns data of session id
+func (s *FileSessionStore) Load(id string) ([]byte, error) {
+	if !validSessionID(id) {
+		return nil, nil
+	}
+	raw, err := ioutil.ReadFile(filepath.Join(s.dir, id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 8 || s.clock.Now().UnixNano() >= int64(binary.BigEndian.Uint64(raw)) {
+		return nil, nil
+	}
+	return raw[8:], nil
+}
+
+// Save writes data of session id, the file is replaced atomically
+func (s *FileSessionStore) Save(id string, data []byte, ttl time.Duration) error {
+	if !validSessionID(id) {
+		return errors.New("baa: invalid session id")
+	}
+	f, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	var expires [8]byte
+	binary.BigEndian.PutUint64(expires[:], uint64(s.clock.Now().Add(ttl).UnixNano()))
+	_, err = f.Write(expires[:])
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(s.dir, id))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Delete removes the file of session id
+func (s *FileSessionStore) Delete(id string) error {
+	if !validSessionID(id) {
+		return nil
+	}
+	err := os.Remove(filepath.Join(s.dir, id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// GC removes files of expired sessions, it should be called periodically.
+func (s *FileSessionStore) GC() error {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now().UnixNano()
+	for _, fi := range files {
+		if fi.IsDir() || !validSessionID(fi.Name()) {
+			continue
+		}
+		name := filepath.Join(s.dir, fi.Name())
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		var expires [8]byte
+		_, err = io.ReadFull(f, expires[:])
+		f.Close()
+		if err != nil || now >= int64(binary.BigEndian.Uint64(expires[:])) {
+			os.Remove(name)
+		}
+	}
+	return nil
+}
+
+// RedisSessionStore keeps sessions in redis, expiry is done by redis ttl
+type RedisSessionStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisSessionStore create a redis store, prefix is prepended to session ids
+func NewRedisSessionStore(client RedisClient, prefix string) *RedisSessionStore {
+	return &RedisSessionStore{client: client, prefix: prefix}
+}
+
+// Load returns data of session id
+func (r *RedisSessionStore) Load(id string) ([]byte, error) {
+	data, err := r.client.Get(r.prefix + id)
+	if err == ErrCacheMiss {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save stores data of session id
+func (r *RedisSessionStore) Save(id string, data []byte, ttl time.Duration) error {
+	return r.client.Set(r.prefix+id, data, ttl)
+}
+
+// Delete removes session id
+func (r *RedisSessionStore) Delete(id string) error {
+	return r.client.Del(r.prefix + id)
+}
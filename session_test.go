@@ -0,0 +1,258 @@
+package baa
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// sessionClient is a client keeps the session cookie between requests
+type sessionClient struct {
+	app    *Baa
+	cookie *http.Cookie
+}
+
+func (s *sessionClient) get(uri string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", uri, nil)
+	if s.cookie != nil {
+		req.AddCookie(s.cookie)
+	}
+	w := httptest.NewRecorder()
+	s.app.ServeHTTP(w, req)
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "baa_session" {
+			if cookie.MaxAge < 0 {
+				s.cookie = nil
+			} else {
+				s.cookie = cookie
+			}
+		}
+	}
+	return w
+}
+
+// newSessionApp returns an app counts visits in session
+func newSessionApp(opt SessionOptions) (*Baa, *FakeClock) {
+	b2 := New()
+	clock := NewFakeClock(time.Now())
+	b2.SetDI("clock", clock)
+	b2.SetSessions(opt)
+	b2.Get("/", func(c *Context) {
+		s := c.Session()
+		n, _ := s.Get("visits").(int)
+		s.Set("visits", n+1)
+		c.String(200, s.ID()+" "+string(rune('0'+n+1)))
+	})
+	b2.Get("/peek", func(c *Context) {
+		n, _ := c.Session().Get("visits").(int)
+		c.String(200, string(rune('0'+n)))
+	})
+	b2.Get("/renew", func(c *Context) {
+		c.Session().Renew()
+		c.String(200, c.Session().ID())
+	})
+	b2.Get("/destroy", func(c *Context) {
+		c.Session().Destroy()
+		c.String(200, "bye")
+	})
+	b2.Get("/late", func(c *Context) {
+		c.String(200, "late")
+		c.Session().Set("late", true)
+		So(c.Session().Save() == ErrSessionWritten, ShouldBeTrue)
+	})
+	return b2, clock
+}
+
+func TestSession1(t *testing.T) {
+	Convey("session in memory", t, func() {
+		b2, clock := newSessionApp(SessionOptions{IdleTimeout: time.Hour, MaxLifetime: 3 * time.Hour})
+		client := &sessionClient{app: b2}
+
+		w := client.get("/peek")
+		So(w.Body.String(), ShouldEqual, "0")
+		So(w.Header().Get("Set-Cookie"), ShouldBeEmpty)
+
+		w = client.get("/")
+		So(client.cookie, ShouldNotBeNil)
+		So(client.cookie.HttpOnly, ShouldBeTrue)
+		So(client.cookie.MaxAge, ShouldEqual, 3*3600)
+		So(w.Body.String(), ShouldEqual, client.cookie.Value+" 1")
+		w = client.get("/")
+		So(w.Body.String(), ShouldEqual, client.cookie.Value+" 2")
+		So(w.Header().Get("Set-Cookie"), ShouldBeEmpty)
+
+		Convey("renew", func() {
+			old := client.cookie.Value
+			w := client.get("/renew")
+			So(w.Body.String(), ShouldEqual, client.cookie.Value)
+			So(client.cookie.Value, ShouldNotEqual, old)
+			So(client.get("/peek").Body.String(), ShouldEqual, "2")
+			client.cookie = &http.Cookie{Name: "baa_session", Value: old}
+			So(client.get("/peek").Body.String(), ShouldEqual, "0")
+		})
+
+		Convey("destroy", func() {
+			old := client.cookie
+			So(client.get("/destroy").Body.String(), ShouldEqual, "bye")
+			So(client.cookie, ShouldBeNil)
+			client.cookie = old
+			So(client.get("/peek").Body.String(), ShouldEqual, "0")
+		})
+
+		Convey("idle and absolute expiry", func() {
+			clock.Advance(50 * time.Minute)
+			So(client.get("/peek").Body.String(), ShouldEqual, "2")
+			clock.Advance(50 * time.Minute)
+			So(client.get("/peek").Body.String(), ShouldEqual, "2")
+			clock.Advance(50 * time.Minute)
+			So(client.get("/peek").Body.String(), ShouldEqual, "2")
+			clock.Advance(50 * time.Minute)
+			So(client.get("/peek").Body.String(), ShouldEqual, "0")
+
+			client.get("/")
+			clock.Advance(61 * time.Minute)
+			So(client.get("/peek").Body.String(), ShouldEqual, "0")
+		})
+
+		Convey("invalid cookie and late save", func() {
+			client.cookie = &http.Cookie{Name: "baa_session", Value: "../../etc/passwd"}
+			So(client.get("/peek").Body.String(), ShouldEqual, "0")
+			client.cookie = nil
+			w := client.get("/late")
+			So(w.Body.String(), ShouldEqual, "late")
+			So(w.Header().Get("Set-Cookie"), ShouldBeEmpty)
+		})
+	})
+
+	Convey("session not configured", t, func() {
+		c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), New())
+		So(func() { c.Session() }, ShouldPanic)
+	})
+}
+
+func TestSessionStore1(t *testing.T) {
+	Convey("cookie store", t, func() {
+		store := NewCookieSessionStore([]byte("new key"), []byte("old key"))
+		b2, clock := newSessionApp(SessionOptions{Store: store})
+		client := &sessionClient{app: b2}
+		So(client.get("/").Body.String(), ShouldEqual, " 1")
+		first := client.cookie.Value
+		So(client.get("/").Body.String(), ShouldEqual, " 2")
+		So(client.cookie.Value, ShouldNotEqual, first)
+		So(client.get("/peek").Body.String(), ShouldEqual, "2")
+
+		tampered := []byte(client.cookie.Value)
+		tampered[len(tampered)-2] ^= 1
+		client.cookie = &http.Cookie{Name: "baa_session", Value: string(tampered)}
+		So(client.get("/peek").Body.String(), ShouldEqual, "0")
+
+		client.get("/")
+		rotated := NewCookieSessionStore([]byte("newer key"), []byte("new key"))
+		b3, _ := newSessionApp(SessionOptions{Store: rotated})
+		b3.SetDI("clock", clock)
+		client.app = b3
+		So(client.get("/peek").Body.String(), ShouldEqual, "1")
+
+		clock.Advance(31 * time.Minute)
+		So(client.get("/peek").Body.String(), ShouldEqual, "0")
+
+		data, err := store.Load(client.cookie.Value)
+		So(err, ShouldBeNil)
+		So(data, ShouldBeNil)
+		_, err = store.seal(make([]byte, 5000), clock.Now().Add(time.Hour))
+		So(err == ErrSessionTooLarge, ShouldBeTrue)
+		So(func() { NewCookieSessionStore() }, ShouldPanic)
+	})
+
+	Convey("file store", t, func() {
+		dir, err := ioutil.TempDir("", "baa-session")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		store, err := NewFileSessionStore(dir)
+		So(err, ShouldBeNil)
+		b2, clock := newSessionApp(SessionOptions{Store: store})
+		client := &sessionClient{app: b2}
+		client.get("/")
+		So(client.get("/").Body.String(), ShouldEqual, client.cookie.Value+" 2")
+		_, err = os.Stat(dir + "/" + client.cookie.Value)
+		So(err, ShouldBeNil)
+
+		So(store.Save("../escape", []byte("x"), time.Hour), ShouldNotBeNil)
+		data, err := store.Load("../escape")
+		So(err, ShouldBeNil)
+		So(data, ShouldBeNil)
+
+		clock.Advance(31 * time.Minute)
+		So(store.GC(), ShouldBeNil)
+		_, err = os.Stat(dir + "/" + client.cookie.Value)
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+
+	Convey("redis store", t, func() {
+		redis := &memRedis{data: make(map[string][]byte)}
+		b2, _ := newSessionApp(SessionOptions{Store: NewRedisSessionStore(redis, "session:")})
+		client := &sessionClient{app: b2}
+		client.get("/")
+		So(redis.data["session:"+client.cookie.Value], ShouldNotBeEmpty)
+		So(client.get("/peek").Body.String(), ShouldEqual, "1")
+		client.get("/destroy")
+		So(redis.data, ShouldBeEmpty)
+	})
+}
+
+func TestSessionAuth1(t *testing.T) {
+	Convey("login in session", t, func() {
+		b2 := New()
+		b2.SetSessions(SessionOptions{Codec: JSONCodec})
+		b2.SetAuth(AuthOptions{Provider: UserProviderFunc(func(id string) (AuthUser, error) {
+			return testUser(id), nil
+		})})
+		b2.Get("/cart", func(c *Context) {
+			carts := NewTypedStore(c.Session(), JSONCodec)
+			items := map[string]int{}
+			_, err := carts.Get("cart", &items)
+			So(err, ShouldBeNil)
+			items["apple"]++
+			So(carts.Set("cart", items, time.Hour), ShouldBeNil)
+			c.String(200, strconv.Itoa(items["apple"]))
+		})
+		b2.Get("/login", func(c *Context) {
+			So(Login(c, testUser("tom")), ShouldBeNil)
+			c.Redirect(http.StatusFound, "/")
+		})
+		b2.Get("/logout", func(c *Context) {
+			Logout(c)
+			c.String(200, "bye")
+		})
+		b2.Get("/me", RequireLogin(), func(c *Context) {
+			if u := c.User(); u != nil {
+				c.String(200, u.AuthID())
+				return
+			}
+			c.String(200, "guest")
+		})
+
+		client := &sessionClient{app: b2}
+		So(client.get("/cart").Body.String(), ShouldEqual, "1")
+		guest := client.cookie.Value
+		So(client.get("/me").Code, ShouldEqual, http.StatusUnauthorized)
+
+		w := client.get("/login")
+		So(w.Code, ShouldEqual, http.StatusFound)
+		So(client.cookie.Value, ShouldNotEqual, guest)
+		So(strings.Contains(w.Header().Get("Set-Cookie"), "baa_auth"), ShouldBeFalse)
+		So(client.get("/me").Body.String(), ShouldEqual, "tom")
+		So(client.get("/cart").Body.String(), ShouldEqual, "2")
+
+		So(client.get("/logout").Body.String(), ShouldEqual, "bye")
+		So(client.cookie, ShouldBeNil)
+		So(client.get("/me").Code, ShouldEqual, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,120 @@
+package baa
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// streams tracks long-lived connections, eg: websocket, for graceful shutdown
+type streams struct {
+	conns    map[*websocket.Conn]struct{}
+	servers  []*http.Server
+	hooks    []func(context.Context) error
+	draining chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+// newStreams create a stream tracker
+func newStreams() *streams {
+	return &streams{
+		conns:    make(map[*websocket.Conn]struct{}),
+		draining: make(chan struct{}),
+	}
+}
+
+// add tracks a websocket connection until done is called
+func (s *streams) add(conn *websocket.Conn) (done func()) {
+	s.mu.Lock()
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}
+}
+
+// count returns number of tracked connections
+func (s *streams) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Draining returns a channel closed when shutdown begins, long-lived handlers such as
+// SSE or NDJSON streams can select on it to send final events and return.
+func (b *Baa) Draining() <-chan struct{} {
+	return b.streams.draining
+}
+
+// OnShutdown registers functions called by Shutdown after requests and websocket
+// connections are drained, eg: flushing queues of background workers. They are
+// called in order with the context of Shutdown.
+func (b *Baa) OnShutdown(fn ...func(ctx context.Context) error) {
+	s := b.streams
+	s.mu.Lock()
+	s.hooks = append(s.hooks, fn...)
+	s.mu.Unlock()
+}
+
+// Shutdown gracefully shuts down servers started by Run, RunTLS, RunServer or RunTLSServer.
+// It stops accepting requests, waits active requests, then sends close frames to websocket
+// connections and waits their handlers return, and calls functions registered by OnShutdown.
+// Remained websocket connections are closed when ctx is done, ctx error is returned then.
+func (b *Baa) Shutdown(ctx context.Context) error {
+	s := b.streams
+	s.once.Do(func() {
+		close(s.draining)
+	})
+
+	s.mu.Lock()
+	servers := append([]*http.Server(nil), s.servers...)
+	s.mu.Unlock()
+	var err error
+	for _, srv := range servers {
+		if e := srv.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	// websocket connections are hijacked, http.Server.Shutdown does not wait them
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+	deadline := time.Now().Add(time.Second)
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.WriteControl(websocket.CloseMessage, msg, deadline)
+	}
+	hooks := append([]func(context.Context) error(nil), s.hooks...)
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		err = ctx.Err()
+	}
+
+	for _, fn := range hooks {
+		if e := fn(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
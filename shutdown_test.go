@@ -0,0 +1,90 @@
+package baa
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestShutdown1(t *testing.T) {
+	Convey("shutdown drains websocket connections", t, func() {
+		b2 := New()
+		b2.Websocket("/ws", func(conn *websocket.Conn) {
+			// returns when close frame received
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		})
+		ts := httptest.NewServer(b2)
+		defer ts.Close()
+		url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		for i := 0; i < 100 && b2.Stats().Websockets == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		So(b2.Stats().Websockets, ShouldEqual, 1)
+
+		closeCode := make(chan int, 1)
+		go func() {
+			_, _, err := conn.ReadMessage()
+			if e, ok := err.(*websocket.CloseError); ok {
+				closeCode <- e.Code
+			}
+			close(closeCode)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		So(b2.Shutdown(ctx), ShouldBeNil)
+		So(<-closeCode, ShouldEqual, websocket.CloseGoingAway)
+		So(b2.Stats().Websockets, ShouldEqual, 0)
+		_, ok := <-b2.Draining()
+		So(ok, ShouldBeFalse)
+	})
+	Convey("shutdown force closes after deadline", t, func() {
+		b2 := New()
+		block := make(chan struct{})
+		b2.Websocket("/ws", func(conn *websocket.Conn) {
+			<-block
+		})
+		ts := httptest.NewServer(b2)
+		defer ts.Close()
+		defer close(block)
+		url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		for i := 0; i < 100 && b2.Stats().Websockets == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		So(b2.Shutdown(ctx) == context.DeadlineExceeded, ShouldBeTrue)
+	})
+}
+
+func TestShutdown2(t *testing.T) {
+	Convey("shutdown calls hooks in order", t, func() {
+		b2 := New()
+		var calls []int
+		b2.OnShutdown(func(ctx context.Context) error {
+			calls = append(calls, 1)
+			return nil
+		}, func(ctx context.Context) error {
+			calls = append(calls, 2)
+			return context.Canceled
+		})
+		So(b2.Shutdown(context.Background()), ShouldEqual, context.Canceled)
+		So(calls, ShouldResemble, []int{1, 2})
+	})
+}
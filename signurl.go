@@ -0,0 +1,96 @@
+package baa
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignURLExpires query key of signed URL expiration unix time
+	SignURLExpires = "expires"
+	// SignURLSignature query key of signed URL signature
+	SignURLSignature = "signature"
+)
+
+// ErrSignedURL is returned when signed URL is invalid or expired
+var ErrSignedURL = errors.New("invalid or expired signed URL")
+
+// SetSignKey set the secret key used to sign and verify URLs
+func (b *Baa) SetSignKey(key []byte) {
+	b.signKey = key
+}
+
+// SignURL returns path signed with expiration after expiry, the query of path is signed too.
+// The result can be verified by VerifySignedURL middleware on the protected route.
+//
+// Example:
+// 		b.SetSignKey([]byte("secret"))
+// 		link := b.SignURL("/download/report.pdf", time.Hour)
+func (b *Baa) SignURL(path string, expiry time.Duration) string {
+	if len(b.signKey) == 0 {
+		panic("baa.SignURL sign key is not set, call SetSignKey first")
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		panic("baa.SignURL invalid path: " + err.Error())
+	}
+	q := u.Query()
+	q.Del(SignURLSignature)
+	q.Set(SignURLExpires, strconv.FormatInt(b.Clock().Now().Add(expiry).Unix(), 10))
+	q.Set(SignURLSignature, b.urlSignature(u.Path, q))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// VerifyURL checks signature and expiration of signed request URL
+func (b *Baa) VerifyURL(u *url.URL) error {
+	if len(b.signKey) == 0 {
+		return ErrSignedURL
+	}
+	q := u.Query()
+	sig := q.Get(SignURLSignature)
+	expires, err := strconv.ParseInt(q.Get(SignURLExpires), 10, 64)
+	if sig == "" || err != nil || b.Clock().Now().Unix() > expires {
+		return ErrSignedURL
+	}
+	if !hmac.Equal([]byte(sig), []byte(b.urlSignature(u.Path, q))) {
+		return ErrSignedURL
+	}
+	return nil
+}
+
+// urlSignature returns signature of path and query except the signature
+func (b *Baa) urlSignature(path string, q url.Values) string {
+	q2 := make(url.Values, len(q))
+	for k, v := range q {
+		if k != SignURLSignature {
+			q2[k] = v
+		}
+	}
+	mac := hmac.New(sha256.New, b.signKey)
+	mac.Write([]byte(path))
+	mac.Write([]byte{'?'})
+	mac.Write([]byte(q2.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignedURL returns a middleware rejects requests without valid signed URL with 403,
+// URLs are signed by b.SignURL.
+//
+// Example:
+// 		b.Get("/download/:file", baa.VerifySignedURL(), download)
+func VerifySignedURL() HandlerFunc {
+	return func(c *Context) {
+		if err := c.baa.VerifyURL(c.Req.URL); err != nil {
+			c.Error(NewHTTPError(http.StatusForbidden).WithError(err))
+			return
+		}
+		c.Next()
+	}
+}
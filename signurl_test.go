@@ -0,0 +1,47 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSignURL1(t *testing.T) {
+	Convey("sign and verify url", t, func() {
+		b2 := New()
+		So(func() { b2.SignURL("/files/a.pdf", time.Hour) }, ShouldPanic)
+		b2.SetSignKey([]byte("secret"))
+		b2.Get("/files/:name", VerifySignedURL(), func(c *Context) {
+			c.String(http.StatusOK, c.Param("name"))
+		})
+		get := func(path string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		link := b2.SignURL("/files/a.pdf?download=1", time.Hour)
+		So(link, ShouldStartWith, "/files/a.pdf?")
+		So(link, ShouldContainSubstring, "download=1")
+		w := get(link)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "a.pdf")
+
+		So(get("/files/a.pdf").Code, ShouldEqual, http.StatusForbidden)
+		So(get(strings.Replace(link, "download=1", "download=2", 1)).Code, ShouldEqual, http.StatusForbidden)
+		So(get(strings.Replace(link, "a.pdf", "b.pdf", 1)).Code, ShouldEqual, http.StatusForbidden)
+
+		u, _ := url.Parse(b2.SignURL("/files/a.pdf", -time.Minute))
+		So(b2.VerifyURL(u), ShouldEqual, ErrSignedURL)
+		u, _ = url.Parse(link)
+		So(b2.VerifyURL(u), ShouldBeNil)
+		b2.SetSignKey([]byte("rotated"))
+		So(b2.VerifyURL(u), ShouldEqual, ErrSignedURL)
+	})
+}
@@ -0,0 +1,392 @@
+package baa
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSSEHeartbeat interval of SSE heartbeat comments
+const defaultSSEHeartbeat = 15 * time.Second
+
+// ErrSlowClient is returned by stream writes when the client did not receive the
+// pending data in time, see SetStreamSlowTimeout.
+var ErrSlowClient = errors.New("baa: stream client is too slow")
+
+// ErrSSEventName is returned by SSEvent when the event name contains CR or LF
+var ErrSSEventName = errors.New("baa: SSE event name can not contain CR or LF")
+
+// SetSSEHeartbeat sets the interval of comments sent to idle SSE streams to keep
+// them through proxies, default is 15s, 0 disables heartbeats.
+func (b *Baa) SetSSEHeartbeat(d time.Duration) {
+	b.sseHeartbeat = d
+}
+
+// SetSSEBuffer sets the maximum bytes of events queued per SSE stream, events are
+// written by a background writer so handlers are not blocked by slow clients, and
+// events exceed the buffer are dropped. Default is 0, SSEvent blocks until the event
+// is written.
+func (b *Baa) SetSSEBuffer(n int) {
+	b.sseBuffer = n
+}
+
+// SetStreamSlowTimeout sets the maximum time of writing a chunk to a Stream or SSE
+// client, the stream is ended with ErrSlowClient when a write takes longer, 0
+// disables it, default is 0. Pending writes are interrupted by the write deadline
+// of the connection, it requires Go 1.20 and a server supports deadlines, otherwise
+// slow writes are only detected after they returned.
+//
+// Streams report the metrics:
+// 		baa_stream_buffered_bytes            bytes queued or being written to clients
+// 		baa_stream_dropped_events_total      SSE events dropped by full buffers
+// 		baa_stream_slow_disconnects_total    streams ended by slow clients
+func (b *Baa) SetStreamSlowTimeout(d time.Duration) {
+	b.streamSlow = d
+}
+
+// Stream sends the content read from r, each chunk is flushed immediately.
+// It returns nil when r reaches EOF, or the error when the client disconnected
+// or reading and writing failed, see SetStreamSlowTimeout for slow clients.
+//
+// Example:
+// 		cmd := exec.Command("tail", "-f", "app.log")
+// 		out, _ := cmd.StdoutPipe()
+// 		cmd.Start()
+// 		c.Stream(200, baa.TextPlainCharsetUTF8, out)
+func (c *Context) Stream(code int, contentType string, r io.Reader) error {
+	c.Resp.Header().Set("Content-Type", contentType)
+	c.Resp.WriteHeader(code)
+	c.Resp.Flush()
+
+	w := c.newStreamWriter()
+	buf := make([]byte, 32*1024)
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return c.Req.Context().Err()
+		default:
+		}
+		n, err := r.Read(buf)
+		if n > 0 {
+			if werr := w.write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// SSEvent sends a server-sent event named name, an empty name sends an unnamed
+// message event. String and []byte data are sent as is, other data is encoded
+// in JSON. The first event writes the event-stream headers, heartbeat comments
+// are sent to the stream until the handler returns, see SetSSEHeartbeat.
+// It returns the error when the client disconnected or writing failed, events
+// dropped by a full buffer are not errors, see SetSSEBuffer. Names contain CR or
+// LF are rejected with ErrSSEventName before anything is sent.
+//
+// Example:
+// 		for {
+// 			select {
+// 			case v := <-updates:
+// 				if err := c.SSEvent("update", v); err != nil {
+// 					return
+// 				}
+// 			case <-c.Req.Context().Done():
+// 				return
+// 			case <-c.Baa().Draining():
+// 				return
+// 			}
+// 		}
+func (c *Context) SSEvent(name string, data interface{}) error {
+	if strings.ContainsAny(name, "\r\n") {
+		return ErrSSEventName
+	}
+	if err := c.Req.Context().Err(); err != nil {
+		return err
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if name != "" {
+		buf.WriteString("event: " + name + "\n")
+	}
+	var payload []byte
+	switch v := data.(type) {
+	case string:
+		payload = []byte(v)
+	case []byte:
+		payload = v
+	default:
+		out := getBuffer()
+		defer putBuffer(out)
+		if err := encodeJSON(out, v, false); err != nil {
+			return err
+		}
+		payload = out.Bytes()
+	}
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimSuffix(line, []byte("\r")))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return c.sseStream().send(buf.Bytes())
+}
+
+// sseStream returns the SSE stream of request, the headers are sent and the
+// heartbeat is started on first call.
+func (c *Context) sseStream() *sseStream {
+	if c.sse != nil {
+		return c.sse
+	}
+	h := c.Resp.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	c.Resp.WriteHeader(http.StatusOK)
+	c.Resp.Flush()
+
+	s := &sseStream{w: c.newStreamWriter(), done: make(chan struct{})}
+	c.sse = s
+	disconnected := c.Req.Context().Done()
+	if n := c.baa.sseBuffer; n > 0 {
+		s.limit = n
+		s.wake = make(chan struct{}, 1)
+		s.dropped = c.Metrics().Counter("baa_stream_dropped_events_total")
+		s.wg.Add(1)
+		go s.run(disconnected)
+	}
+	if d := c.baa.sseHeartbeat; d > 0 {
+		s.wg.Add(1)
+		go s.heartbeat(d, disconnected)
+	}
+	c.OnClose(s.close)
+	return s
+}
+
+// sseStream is a server-sent events stream, events are written directly or queued
+// for the background writer when buffered.
+type sseStream struct {
+	w       *streamWriter
+	limit   int
+	queue   [][]byte
+	queued  int
+	wake    chan struct{}
+	dropped *Counter
+	done    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// send writes the event, or queues it when buffered
+func (s *sseStream) send(data []byte) error {
+	if s.wake == nil {
+		return s.w.write(data)
+	}
+	if err := s.w.check(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.queued+len(data) > s.limit {
+		s.mu.Unlock()
+		s.dropped.Inc()
+		return nil
+	}
+	s.queue = append(s.queue, append([]byte(nil), data...))
+	s.queued += len(data)
+	s.mu.Unlock()
+	s.w.buffered.Add(float64(len(data)))
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// run writes queued events until the stream is closed, the remaining events are
+// written before it returns unless the stream failed.
+func (s *sseStream) run(disconnected <-chan struct{}) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.wake:
+			if s.flushQueue() != nil {
+				return
+			}
+		case <-disconnected:
+			s.dropQueue()
+			return
+		case <-s.done:
+			if s.w.check() != nil {
+				s.dropQueue()
+			} else {
+				s.flushQueue()
+			}
+			return
+		}
+	}
+}
+
+// flushQueue writes queued events, the queue is dropped when writing failed
+func (s *sseStream) flushQueue() error {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		data := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.queued -= len(data)
+		s.mu.Unlock()
+		s.w.buffered.Add(-float64(len(data)))
+		if err := s.w.write(data); err != nil {
+			s.dropQueue()
+			return err
+		}
+	}
+}
+
+// dropQueue discards queued events
+func (s *sseStream) dropQueue() {
+	s.mu.Lock()
+	n := s.queued
+	s.queue = nil
+	s.queued = 0
+	s.mu.Unlock()
+	s.w.buffered.Add(-float64(n))
+}
+
+// heartbeat sends comments every interval until the stream is closed
+func (s *sseStream) heartbeat(interval time.Duration, disconnected <-chan struct{}) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.w.write([]byte(": heartbeat\n\n")) != nil {
+				return
+			}
+		case <-disconnected:
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// close stops the heartbeat and writes the queued events, it's called when the
+// request finished. A pending write to a slow client is interrupted by the write
+// deadline so close does not wait longer than the slow timeout.
+func (s *sseStream) close() {
+	close(s.done)
+	s.wg.Wait()
+}
+
+// newStreamWriter returns the writer of streaming response
+func (c *Context) newStreamWriter() *streamWriter {
+	m := c.Metrics()
+	var deadline func(time.Time) error
+	if c.baa.streamSlow > 0 {
+		deadline = writeDeadlineOf(c.Resp)
+	}
+	return &streamWriter{
+		resp:     c.Resp,
+		deadline: deadline,
+		slow:     c.baa.streamSlow,
+		clock:    c.baa.Clock(),
+		logger:   c.baa.Logger(),
+		buffered: m.Gauge("baa_stream_buffered_bytes"),
+		slowOut:  m.Counter("baa_stream_slow_disconnects_total"),
+	}
+}
+
+// streamWriter writes and flushes chunks of streaming response, it measures bytes
+// being written and ends the stream when a write takes longer than slow.
+type streamWriter struct {
+	resp     *Response
+	deadline func(time.Time) error // sets the write deadline, nil when not supported
+	slow     time.Duration
+	clock    Clock
+	logger   Logger
+	buffered *Gauge
+	slowOut  *Counter
+	since    time.Time // start of the pending write
+	err      error
+	mu       sync.Mutex // serializes writes
+	state    sync.Mutex // guards since and err
+}
+
+// write writes data and flushes it
+func (w *streamWriter) write(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.check(); err != nil {
+		return err
+	}
+	w.state.Lock()
+	w.since = w.clock.Now()
+	w.state.Unlock()
+	if w.deadline != nil {
+		w.deadline(time.Now().Add(w.slow))
+	}
+	w.buffered.Add(float64(len(data)))
+	_, err := w.resp.Write(data)
+	if err == nil {
+		w.resp.Flush()
+	}
+	w.buffered.Add(-float64(len(data)))
+	w.state.Lock()
+	defer w.state.Unlock()
+	if w.deadline != nil && w.err == nil {
+		w.deadline(time.Time{})
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() && w.err == nil {
+		w.failSlow()
+	}
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	err = w.checkLocked()
+	w.since = time.Time{}
+	return err
+}
+
+// check returns the error ended the stream, the stream is ended with ErrSlowClient
+// when the pending write takes longer than slow.
+func (w *streamWriter) check() error {
+	w.state.Lock()
+	defer w.state.Unlock()
+	return w.checkLocked()
+}
+
+// checkLocked is check with state lock held
+func (w *streamWriter) checkLocked() error {
+	if w.err == nil && w.slow > 0 && !w.since.IsZero() && w.clock.Now().Sub(w.since) > w.slow {
+		w.failSlow()
+		if w.deadline != nil {
+			// interrupt the pending write
+			w.deadline(time.Now())
+		}
+	}
+	return w.err
+}
+
+// failSlow ends the stream with ErrSlowClient, it's called with state lock held
+func (w *streamWriter) failSlow() {
+	w.err = ErrSlowClient
+	w.slowOut.Inc()
+	w.logger.Printf("baa: stream client is too slow, pending write over %v", w.slow)
+}
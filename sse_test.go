@@ -0,0 +1,268 @@
+package baa
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// plainWriter is a http.ResponseWriter without Flusher
+type plainWriter struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *plainWriter) Header() http.Header         { return w.header }
+func (w *plainWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *plainWriter) WriteHeader(int)             {}
+
+// hookWriter is a http.ResponseRecorder calls onWrite before writes
+type hookWriter struct {
+	*httptest.ResponseRecorder
+	onWrite func()
+}
+
+func (w *hookWriter) Write(b []byte) (int, error) {
+	w.onWrite()
+	return w.ResponseRecorder.Write(b)
+}
+
+// timeoutError is the error of writes exceeded the deadline
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// deadlineWriter is a http.ResponseRecorder blocks body writes until the write
+// deadline passed, like a connection of a client stopped reading.
+type deadlineWriter struct {
+	*httptest.ResponseRecorder
+	mu       sync.Mutex
+	deadline time.Time
+	changed  chan struct{}
+}
+
+func (w *deadlineWriter) SetWriteDeadline(t time.Time) error {
+	w.mu.Lock()
+	w.deadline = t
+	w.mu.Unlock()
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	for {
+		w.mu.Lock()
+		d := w.deadline
+		w.mu.Unlock()
+		if !d.IsZero() && !time.Now().Before(d) {
+			return 0, timeoutError{}
+		}
+		select {
+		case <-w.changed:
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestSSEvent1(t *testing.T) {
+	Convey("send events", t, func() {
+		b2 := New()
+		b2.SetSSEHeartbeat(0)
+		b2.Get("/events", func(c *Context) {
+			c.SSEvent("", "hello\nworld")
+			c.SSEvent("update", map[string]int{"id": 1})
+			c.SSEvent("raw", []byte("bytes"))
+			So(c.SSEvent("x\ndata: forged", "1"), ShouldEqual, ErrSSEventName)
+			So(c.SSEvent("x\rid: 1", "1"), ShouldEqual, ErrSSEventName)
+		})
+		w := serveTo(b2, "/events")
+		So(w.Header().Get("Content-Type"), ShouldEqual, "text/event-stream")
+		So(w.Header().Get("Cache-Control"), ShouldEqual, "no-cache")
+		So(w.Flushed, ShouldBeTrue)
+		So(w.Body.String(), ShouldEqual, "data: hello\ndata: world\n\n"+
+			"event: update\ndata: {\"id\":1}\n\n"+
+			"event: raw\ndata: bytes\n\n")
+	})
+
+	Convey("heartbeat", t, func() {
+		b2 := New()
+		b2.SetSSEHeartbeat(10 * time.Millisecond)
+		b2.Get("/events", func(c *Context) {
+			c.SSEvent("start", "1")
+			time.Sleep(50 * time.Millisecond)
+			c.SSEvent("end", "2")
+		})
+		body := serveTo(b2, "/events").Body.String()
+		So(body, ShouldStartWith, "event: start\ndata: 1\n\n")
+		So(body, ShouldContainSubstring, ": heartbeat\n\n")
+		So(body, ShouldContainSubstring, "event: end\ndata: 2\n\n")
+	})
+
+	Convey("not buffered by body transformers on no-transform routes", t, func() {
+		b2 := New()
+		b2.Use(TransformBody(func(c *Context, body []byte) ([]byte, error) {
+			return []byte("transformed"), nil
+		}))
+		var flushed bool
+		b2.Get("/events", func(c *Context) {
+			c.SSEvent("", "1")
+			flushed = c.Resp.resp.(*httptest.ResponseRecorder).Flushed
+		}).SetMeta(RouteMetaNoTransform, true)
+		So(serveTo(b2, "/events").Body.String(), ShouldEqual, "data: 1\n\n")
+		So(flushed, ShouldBeTrue)
+	})
+
+	Convey("disconnected client", t, func() {
+		b2 := New()
+		var err error
+		b2.Get("/events", func(c *Context) {
+			err = c.SSEvent("", "1")
+		})
+		req, _ := http.NewRequest("GET", "/events", nil)
+		ctx, cancel := context.WithCancel(req.Context())
+		cancel()
+		b2.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestStream1(t *testing.T) {
+	Convey("stream reader", t, func() {
+		b2 := New()
+		var err error
+		b2.Get("/stream", func(c *Context) {
+			err = c.Stream(200, TextPlainCharsetUTF8, strings.NewReader("line1\nline2\n"))
+		})
+		w := serveTo(b2, "/stream")
+		So(err, ShouldBeNil)
+		So(w.Body.String(), ShouldEqual, "line1\nline2\n")
+		So(w.Header().Get("Content-Type"), ShouldEqual, TextPlainCharsetUTF8)
+		So(w.Flushed, ShouldBeTrue)
+	})
+
+	Convey("stream without flusher", t, func() {
+		b2 := New()
+		b2.Get("/stream", func(c *Context) {
+			c.Stream(200, TextPlainCharsetUTF8, io.MultiReader(strings.NewReader("a"), strings.NewReader("b")))
+		})
+		w := &plainWriter{header: make(http.Header)}
+		req, _ := http.NewRequest("GET", "/stream", nil)
+		b2.ServeHTTP(w, req)
+		So(w.body.String(), ShouldEqual, "ab")
+	})
+}
+
+func TestStreamBackpressure1(t *testing.T) {
+	Convey("buffered events are dropped when the client is blocked", t, func() {
+		b2 := New()
+		b2.SetSSEHeartbeat(0)
+		b2.SetSSEBuffer(30)
+		writing := make(chan bool, 10)
+		release := make(chan bool)
+		var buffered float64
+		var errs []error
+		b2.Get("/events", func(c *Context) {
+			errs = append(errs, c.SSEvent("", "1"))
+			<-writing
+			for i := 2; i <= 5; i++ {
+				errs = append(errs, c.SSEvent("", strconv.Itoa(i)))
+			}
+			buffered = c.Metrics().Gauge("baa_stream_buffered_bytes").Value()
+			close(release)
+		})
+		w := &hookWriter{ResponseRecorder: httptest.NewRecorder(), onWrite: func() {
+			writing <- true
+			<-release
+		}}
+		req, _ := http.NewRequest("GET", "/events", nil)
+		b2.ServeHTTP(w, req)
+		So(errs, ShouldResemble, []error{nil, nil, nil, nil, nil})
+		So(buffered, ShouldEqual, 36)
+		So(w.Body.String(), ShouldEqual, "data: 1\n\ndata: 2\n\ndata: 3\n\ndata: 4\n\n")
+		m := b2.Metrics()
+		So(m.Counter("baa_stream_dropped_events_total", "method", "GET", "route", "/events").Value(), ShouldEqual, 1)
+		So(m.Gauge("baa_stream_buffered_bytes", "method", "GET", "route", "/events").Value(), ShouldEqual, 0)
+	})
+
+	Convey("slow clients are disconnected", t, func() {
+		b2 := New()
+		clock := NewFakeClock(time.Now())
+		b2.SetDI("clock", clock)
+		b2.SetSSEHeartbeat(0)
+		b2.SetSSEBuffer(1024)
+		b2.SetStreamSlowTimeout(time.Second)
+		writing := make(chan bool, 10)
+		release := make(chan bool)
+		var err error
+		b2.Get("/events", func(c *Context) {
+			c.SSEvent("", "1")
+			<-writing
+			clock.Advance(2 * time.Second)
+			err = c.SSEvent("", "2")
+			close(release)
+		})
+		w := &hookWriter{ResponseRecorder: httptest.NewRecorder(), onWrite: func() {
+			writing <- true
+			<-release
+		}}
+		req, _ := http.NewRequest("GET", "/events", nil)
+		b2.ServeHTTP(w, req)
+		So(err == ErrSlowClient, ShouldBeTrue)
+		So(w.Body.String(), ShouldEqual, "data: 1\n\n")
+		So(b2.Metrics().Counter("baa_stream_slow_disconnects_total", "method", "GET", "route", "/events").Value(), ShouldEqual, 1)
+
+		var streamErr error
+		b2.Get("/stream", func(c *Context) {
+			streamErr = c.Stream(200, TextPlainCharsetUTF8, strings.NewReader("data"))
+		})
+		w = &hookWriter{ResponseRecorder: httptest.NewRecorder(), onWrite: func() {
+			clock.Advance(2 * time.Second)
+		}}
+		req, _ = http.NewRequest("GET", "/stream", nil)
+		b2.ServeHTTP(w, req)
+		So(streamErr == ErrSlowClient, ShouldBeTrue)
+		So(b2.Metrics().Counter("baa_stream_slow_disconnects_total", "method", "GET", "route", "/stream").Value(), ShouldEqual, 1)
+	})
+	Convey("pending writes to slow clients are interrupted", t, func() {
+		b2 := New()
+		b2.SetSSEHeartbeat(0)
+		b2.SetSSEBuffer(1024)
+		b2.SetStreamSlowTimeout(50 * time.Millisecond)
+		b2.Get("/events", func(c *Context) {
+			for i := 1; i <= 3; i++ {
+				c.SSEvent("", strconv.Itoa(i))
+			}
+		})
+		w := &deadlineWriter{ResponseRecorder: httptest.NewRecorder(), changed: make(chan struct{}, 1)}
+		req, _ := http.NewRequest("GET", "/events", nil)
+		served := make(chan bool)
+		go func() {
+			b2.ServeHTTP(w, req)
+			close(served)
+		}()
+		returned := false
+		select {
+		case <-served:
+			returned = true
+		case <-time.After(5 * time.Second):
+		}
+		So(returned, ShouldBeTrue)
+		So(w.Body.String(), ShouldBeEmpty)
+		So(b2.Metrics().Counter("baa_stream_slow_disconnects_total", "method", "GET", "route", "/events").Value(), ShouldEqual, 1)
+		So(b2.Metrics().Gauge("baa_stream_buffered_bytes", "method", "GET", "route", "/events").Value(), ShouldEqual, 0)
+	})
+}
@@ -6,6 +6,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
 // compatible with go net standard indexPage
@@ -75,20 +78,76 @@ func newStatic(prefix, dir string, index bool, h HandlerFunc) HandlerFunc {
 	}
 }
 
+// DirListing configures directory listings of static routes with index enabled
+type DirListing struct {
+	// Template is the template rendered by Renderer, the built-in listing is used when empty.
+	// data of template is DirListingData.
+	Template string
+	// HideDotFiles hides files and directories which name begins with dot
+	HideDotFiles bool
+}
+
+// DirListingData is the template data of directory listing
+type DirListingData struct {
+	Path        string     // url path of directory
+	Breadcrumbs []DirCrumb // path segments from root
+	Files       []DirEntry // entries sorted by Sort and Order
+	Sort        string     // sort column: name, size or time
+	Order       string     // sort order: asc or desc
+}
+
+// DirCrumb is a segment of directory path
+type DirCrumb struct {
+	Name string
+	URL  string
+}
+
+// DirEntry is a file or directory in listing
+type DirEntry struct {
+	Name    string
+	URL     string
+	IsDir   bool
+	Size    int64 // 0 for directory
+	ModTime time.Time
+}
+
+// SetDirListing set options of static directory listing
+func (b *Baa) SetDirListing(opts DirListing) {
+	b.dirListing = opts
+}
+
 // listDir list given dir files
 func listDir(dir string, s *static, c *Context) {
 	f, err := os.Open(dir)
 	if err != nil {
 		c.baa.Error(fmt.Errorf("baa.Static listDir Error: %s", err), c)
+		return
 	}
 	defer f.Close()
 	fl, err := f.Readdir(-1)
 	if err != nil {
 		c.baa.Error(fmt.Errorf("baa.Static listDir Error: %s", err), c)
+		return
+	}
+	opts := c.baa.dirListing
+	if opts.HideDotFiles {
+		n := 0
+		for _, v := range fl {
+			if !strings.HasPrefix(v.Name(), ".") {
+				fl[n] = v
+				n++
+			}
+		}
+		fl = fl[:n]
 	}
 
 	dirName := f.Name()
 	dirName = dirName[len(s.dir):]
+	if opts.Template != "" {
+		renderDir(opts.Template, fl, c)
+		return
+	}
+
 	c.Resp.Header().Add("Content-Type", "text/html; charset=utf-8")
 	fmt.Fprintf(c.Resp, "<h3 style=\"padding-bottom:5px;border-bottom:1px solid #ccc;\">%s</h3>\n", dirName)
 	fmt.Fprintf(c.Resp, "<pre>\n")
@@ -109,6 +168,70 @@ func listDir(dir string, s *static, c *Context) {
 	fmt.Fprintf(c.Resp, "</pre>\n")
 }
 
+// renderDir renders directory listing by template, sorted by query sort and order
+func renderDir(tpl string, fl []os.FileInfo, c *Context) {
+	data := &DirListingData{
+		Path:  c.Req.URL.Path,
+		Sort:  c.Query("sort"),
+		Order: c.Query("order"),
+	}
+	if data.Sort != "size" && data.Sort != "time" {
+		data.Sort = "name"
+	}
+	if data.Order != "desc" {
+		data.Order = "asc"
+	}
+
+	data.Breadcrumbs = append(data.Breadcrumbs, DirCrumb{Name: "/", URL: "/"})
+	p := ""
+	for _, v := range strings.Split(strings.Trim(data.Path, "/"), "/") {
+		if v == "" {
+			continue
+		}
+		p += "/" + v
+		data.Breadcrumbs = append(data.Breadcrumbs, DirCrumb{Name: v, URL: p + "/"})
+	}
+
+	for _, v := range fl {
+		name := v.Name()
+		if v.IsDir() {
+			name += "/"
+		}
+		u := url.URL{Path: name}
+		e := DirEntry{
+			Name:    v.Name(),
+			URL:     u.String(),
+			IsDir:   v.IsDir(),
+			ModTime: v.ModTime(),
+		}
+		if !e.IsDir {
+			e.Size = v.Size()
+		}
+		data.Files = append(data.Files, e)
+	}
+	sort.SliceStable(data.Files, func(i, j int) bool {
+		a, b := data.Files[i], data.Files[j]
+		if data.Order == "desc" {
+			a, b = b, a
+		}
+		switch data.Sort {
+		case "size":
+			return a.Size < b.Size
+		case "time":
+			return a.ModTime.Before(b.ModTime)
+		}
+		return a.Name < b.Name
+	})
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := c.Renderer().Render(buf, tpl, data); err != nil {
+		c.Error(newTemplateError(tpl, err))
+		return
+	}
+	c.writeBuffer(http.StatusOK, TextHTMLCharsetUTF8, buf)
+}
+
 func serveFile(file string, c *Context) error {
 	f, err := os.Open(file)
 	if err != nil {
@@ -0,0 +1,202 @@
+package baa
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticOptions configures static file serving of StaticWithOptions
+type StaticOptions struct {
+	// Index is the index file served for directories, default is index.html, "-" disables it
+	Index string
+	// Listing lists directories without index file, they are forbidden otherwise
+	Listing bool
+	// MaxAge sets Cache-Control max-age of files when > 0
+	MaxAge time.Duration
+	// CacheSize caches content of files not larger than it in memory, 0 disables the cache.
+	// Cached files are revalidated by modification time and size on each request.
+	CacheSize int64
+	// CacheTotal limits total bytes of cached files, least recently used files are
+	// evicted, default is 32MB.
+	CacheTotal int64
+	// Handler is called before serving files, eg: setting headers
+	Handler HandlerFunc
+}
+
+// defaultStaticCacheTotal is the default total bytes of cached static files
+const defaultStaticCacheTotal = 32 << 20
+
+// staticCache caches content of small files up to total bytes in LRU order
+type staticCache struct {
+	total int64
+	used  int64
+	ll    *list.List
+	items map[string]*list.Element
+	mu    sync.Mutex
+}
+
+// staticFile is a cached file
+type staticFile struct {
+	name    string
+	data    []byte
+	size    int64
+	modTime time.Time
+}
+
+// StaticWithOptions serves files in dir under prefix. Missing files are sent to
+// the not found handler and other errors to the error handler, responses have
+// ETag and Last-Modified headers, conditional and range requests are supported.
+//
+// Example:
+// 		b.StaticWithOptions("/assets", "./public", baa.StaticOptions{MaxAge: time.Hour, CacheSize: 64 << 10})
+func (b *Baa) StaticWithOptions(prefix, dir string, opt StaticOptions) MetaRouteNode {
+	if prefix == "" {
+		panic("baa.StaticWithOptions prefix can not be empty")
+	}
+	if dir == "" {
+		panic("baa.StaticWithOptions dir can not be empty")
+	}
+	if len(prefix) > 1 && prefix[len(prefix)-1] == '/' {
+		prefix = prefix[:len(prefix)-1]
+	}
+	if opt.Index == "" {
+		opt.Index = "index.html"
+	}
+	if opt.CacheTotal <= 0 {
+		opt.CacheTotal = defaultStaticCacheTotal
+	}
+	var cache *staticCache
+	if opt.CacheSize > 0 {
+		cache = &staticCache{total: opt.CacheTotal, ll: list.New(), items: make(map[string]*list.Element)}
+	}
+	return b.Get(prefix+"*", func(c *Context) {
+		name := c.Param("")
+		if name != "" && name[0] != '/' {
+			b.NotFound(c)
+			return
+		}
+		if opt.Handler != nil {
+			opt.Handler(c)
+			if c.Resp.Wrote() {
+				return
+			}
+		}
+		name = path.Clean("/" + name)
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		fi, err := os.Stat(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				b.NotFound(c)
+			} else {
+				c.Error(err)
+			}
+			return
+		}
+
+		if fi.IsDir() {
+			if p := c.Req.URL.Path; p[len(p)-1] != '/' {
+				u := *c.Req.URL
+				u.Path += "/"
+				c.Redirect(http.StatusMovedPermanently, u.String())
+				return
+			}
+			if opt.Index != "-" {
+				index := filepath.Join(file, opt.Index)
+				if ifi, err := os.Stat(index); err == nil && !ifi.IsDir() {
+					serveStatic(c, index, ifi, opt, cache)
+					return
+				}
+			}
+			if opt.Listing {
+				listDir(file, &static{dir: strings.TrimSuffix(dir, "/")}, c)
+				return
+			}
+			c.Error(NewHTTPError(http.StatusForbidden))
+			return
+		}
+		serveStatic(c, file, fi, opt, cache)
+	})
+}
+
+// serveStatic serves file with info fi, small files are served from cache
+func serveStatic(c *Context, file string, fi os.FileInfo, opt StaticOptions, cache *staticCache) {
+	h := c.Resp.Header()
+	if h.Get("ETag") == "" {
+		h.Set("ETag", `"`+strconv.FormatInt(fi.ModTime().UnixNano(), 36)+"-"+strconv.FormatInt(fi.Size(), 36)+`"`)
+	}
+	if opt.MaxAge > 0 {
+		h.Set("Cache-Control", "public, max-age="+strconv.FormatInt(int64(opt.MaxAge/time.Second), 10))
+	}
+
+	if cache != nil && fi.Size() <= opt.CacheSize {
+		if data, ok := cache.get(file, fi); ok {
+			http.ServeContent(c.Resp, c.Req, fi.Name(), fi.ModTime(), bytes.NewReader(data))
+			return
+		}
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer f.Close()
+	var content io.ReadSeeker = f
+	if cache != nil && fi.Size() <= opt.CacheSize {
+		buf := make([]byte, fi.Size())
+		if _, err := io.ReadFull(f, buf); err != nil {
+			c.Error(err)
+			return
+		}
+		cache.set(&staticFile{name: file, data: buf, size: fi.Size(), modTime: fi.ModTime()})
+		content = bytes.NewReader(buf)
+	}
+	http.ServeContent(c.Resp, c.Req, fi.Name(), fi.ModTime(), content)
+}
+
+// get returns cached content of file when it's not modified
+func (s *staticCache) get(file string, fi os.FileInfo) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[file]
+	if !ok {
+		return nil, false
+	}
+	f := e.Value.(*staticFile)
+	if f.size != fi.Size() || !f.modTime.Equal(fi.ModTime()) {
+		return nil, false
+	}
+	s.ll.MoveToFront(e)
+	return f.data, true
+}
+
+// set caches content of file f, least recently used files are evicted to keep
+// the cache in total bytes
+func (s *staticCache) set(f *staticFile) {
+	if f.size > s.total {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.items[f.name]; ok {
+		s.used -= e.Value.(*staticFile).size
+		s.ll.Remove(e)
+	}
+	s.items[f.name] = s.ll.PushFront(f)
+	s.used += f.size
+	for s.used > s.total {
+		e := s.ll.Back()
+		old := e.Value.(*staticFile)
+		s.ll.Remove(e)
+		delete(s.items, old.name)
+		s.used -= old.size
+	}
+}
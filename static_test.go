@@ -1,9 +1,14 @@
 package baa
 
 import (
+	"container/list"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -68,3 +73,109 @@ func TestStaticServe(t *testing.T) {
 		So(w.Code, ShouldEqual, http.StatusNotFound)
 	})
 }
+
+func TestStaticDirListing1(t *testing.T) {
+	Convey("templated directory listing", t, func() {
+		b2 := New()
+		b2.SetDirListing(DirListing{Template: "_fixture/dir.html", HideDotFiles: true})
+		b2.Static("/files", "./_fixture/listing", true, nil)
+
+		req, _ := http.NewRequest("GET", "/files/", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "[/][files]\na.txt:3,b.txt:1,sub/:0,\n")
+
+		req, _ = http.NewRequest("GET", "/files/?sort=size&order=desc", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldStartWith, "[/][files]\na.txt:3,b.txt:1,")
+
+		req, _ = http.NewRequest("GET", "/files/sub/", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "[/][files][sub]\n\n")
+	})
+}
+
+func TestStaticWithOptions1(t *testing.T) {
+	Convey("static serve with options", t, func() {
+		dir, err := ioutil.TempDir("", "baa-static")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+		os.MkdirAll(filepath.Join(dir, "empty"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "docs", "index.html"), []byte("docs"), 0644)
+
+		b2 := New()
+		b2.SetNotFound(func(c *Context) {
+			c.String(http.StatusNotFound, "custom not found")
+		})
+		b2.StaticWithOptions("/assets/", dir, StaticOptions{MaxAge: time.Hour, CacheSize: 1024})
+		b2.StaticWithOptions("/files", dir, StaticOptions{Listing: true, Index: "-"})
+		get := func(uri string, header ...string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", uri, nil)
+			for i := 0; i+1 < len(header); i += 2 {
+				req.Header.Set(header[i], header[i+1])
+			}
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		w := get("/assets/app.js")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "console.log(1)")
+		So(w.Header().Get("Cache-Control"), ShouldEqual, "public, max-age=3600")
+		So(w.Header().Get("Last-Modified"), ShouldNotBeEmpty)
+		etag := w.Header().Get("ETag")
+		So(etag, ShouldNotBeEmpty)
+
+		So(get("/assets/app.js", "If-None-Match", etag).Code, ShouldEqual, http.StatusNotModified)
+		w = get("/assets/app.js", "Range", "bytes=0-6")
+		So(w.Code, ShouldEqual, http.StatusPartialContent)
+		So(w.Body.String(), ShouldEqual, "console")
+
+		// cached file is revalidated
+		ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(2)"), 0644)
+		os.Chtimes(filepath.Join(dir, "app.js"), time.Now().Add(time.Hour), time.Now().Add(time.Hour))
+		w = get("/assets/app.js", "If-None-Match", etag)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "console.log(2)")
+
+		w = get("/assets/missing.js")
+		So(w.Code, ShouldEqual, http.StatusNotFound)
+		So(w.Body.String(), ShouldEqual, "custom not found")
+		So(get("/assets/../static.go").Code, ShouldEqual, http.StatusNotFound)
+		So(get("/assetsx").Code, ShouldEqual, http.StatusNotFound)
+
+		w = get("/assets/docs?v=1")
+		So(w.Code, ShouldEqual, http.StatusMovedPermanently)
+		So(w.Header().Get("Location"), ShouldEqual, "/assets/docs/?v=1")
+		So(get("/assets/docs/").Body.String(), ShouldEqual, "docs")
+		So(get("/assets/empty/").Code, ShouldEqual, http.StatusForbidden)
+
+		w = get("/files/docs/")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldContainSubstring, "index.html")
+	})
+
+	Convey("static cache is bounded", t, func() {
+		cache := &staticCache{total: 10, ll: list.New(), items: make(map[string]*list.Element)}
+		now := time.Now()
+		for _, name := range []string{"a", "b", "c"} {
+			cache.set(&staticFile{name: name, data: []byte("1234"), size: 4, modTime: now})
+		}
+		So(cache.used, ShouldEqual, 8)
+		So(cache.items, ShouldNotContainKey, "a")
+		So(cache.items, ShouldContainKey, "b")
+		cache.ll.MoveToFront(cache.items["b"])
+		cache.set(&staticFile{name: "d", data: []byte("1234"), size: 4, modTime: now})
+		So(cache.items, ShouldContainKey, "b")
+		So(cache.items, ShouldNotContainKey, "c")
+		cache.set(&staticFile{name: "e", data: make([]byte, 11), size: 11, modTime: now})
+		So(cache.items, ShouldNotContainKey, "e")
+		So(cache.used, ShouldEqual, 8)
+	})
+}
@@ -0,0 +1,111 @@
+package baa
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of server connection statistics
+type Stats struct {
+	Uptime     time.Duration // time since application created
+	Accepted   int64         // total accepted connections
+	Open       int64         // current open connections, include new, active and idle
+	Active     int64         // current connections which are serving a request
+	Idle       int64         // current keep-alive connections wait for next request
+	Hijacked   int64         // total hijacked connections
+	Closed     int64         // total closed connections
+	AcceptRate float64       // average accepted connections per second
+	Websockets int64         // current websocket connections
+}
+
+// connStats tracks connection state changes by http.Server.ConnState
+type connStats struct {
+	started  time.Time
+	accepted int64
+	open     int64
+	active   int64
+	idle     int64
+	hijacked int64
+	closed   int64
+	states   map[net.Conn]http.ConnState
+	mu       sync.Mutex
+}
+
+// newConnStats create a connection stats tracker
+func newConnStats() *connStats {
+	s := new(connStats)
+	s.started = time.Now()
+	s.states = make(map[net.Conn]http.ConnState)
+	return s
+}
+
+// track records connection state change, can be used as http.Server.ConnState
+func (s *connStats) track(conn net.Conn, state http.ConnState) {
+	s.mu.Lock()
+	prev, ok := s.states[conn]
+	switch state {
+	case http.StateHijacked, http.StateClosed:
+		delete(s.states, conn)
+	default:
+		s.states[conn] = state
+	}
+	s.mu.Unlock()
+
+	if ok {
+		s.add(prev, -1)
+	}
+	switch state {
+	case http.StateNew:
+		atomic.AddInt64(&s.accepted, 1)
+		atomic.AddInt64(&s.open, 1)
+	case http.StateActive, http.StateIdle:
+		s.add(state, 1)
+	case http.StateHijacked:
+		atomic.AddInt64(&s.hijacked, 1)
+		if ok {
+			atomic.AddInt64(&s.open, -1)
+		}
+	case http.StateClosed:
+		atomic.AddInt64(&s.closed, 1)
+		if ok {
+			atomic.AddInt64(&s.open, -1)
+		}
+	}
+}
+
+// add changes the gauge of given state
+func (s *connStats) add(state http.ConnState, n int64) {
+	switch state {
+	case http.StateActive:
+		atomic.AddInt64(&s.active, n)
+	case http.StateIdle:
+		atomic.AddInt64(&s.idle, n)
+	}
+}
+
+// snapshot returns current stats
+func (s *connStats) snapshot() Stats {
+	st := Stats{
+		Uptime:   time.Since(s.started),
+		Accepted: atomic.LoadInt64(&s.accepted),
+		Open:     atomic.LoadInt64(&s.open),
+		Active:   atomic.LoadInt64(&s.active),
+		Idle:     atomic.LoadInt64(&s.idle),
+		Hijacked: atomic.LoadInt64(&s.hijacked),
+		Closed:   atomic.LoadInt64(&s.closed),
+	}
+	if sec := st.Uptime.Seconds(); sec > 0 {
+		st.AcceptRate = float64(st.Accepted) / sec
+	}
+	return st
+}
+
+// Stats returns a snapshot of connection statistics of servers built by b.Server
+func (b *Baa) Stats() Stats {
+	st := b.connStats.snapshot()
+	st.Websockets = int64(b.streams.count())
+	return st
+}
@@ -0,0 +1,45 @@
+package baa
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStats1(t *testing.T) {
+	Convey("connection stats", t, func() {
+		b2 := New()
+		s := b2.Server(":8017")
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		s.ConnState(c1, http.StateNew)
+		s.ConnState(c2, http.StateNew)
+		s.ConnState(c1, http.StateActive)
+		s.ConnState(c2, http.StateActive)
+		s.ConnState(c2, http.StateIdle)
+		st := b2.Stats()
+		So(st.Accepted, ShouldEqual, 2)
+		So(st.Open, ShouldEqual, 2)
+		So(st.Active, ShouldEqual, 1)
+		So(st.Idle, ShouldEqual, 1)
+		So(st.AcceptRate, ShouldBeGreaterThan, 0)
+
+		s.ConnState(c1, http.StateHijacked)
+		s.ConnState(c2, http.StateClosed)
+		st = b2.Stats()
+		So(st.Open, ShouldEqual, 0)
+		So(st.Active, ShouldEqual, 0)
+		So(st.Idle, ShouldEqual, 0)
+		So(st.Hijacked, ShouldEqual, 1)
+		So(st.Closed, ShouldEqual, 1)
+
+		// untracked connections do not change open count
+		s.ConnState(c1, http.StateHijacked)
+		s.ConnState(c2, http.StateClosed)
+		So(b2.Stats().Open, ShouldEqual, 0)
+	})
+}
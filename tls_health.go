@@ -0,0 +1,211 @@
+package baa
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tlsExpiryMetric gauge name of remaining seconds of certificates, labeled by subject
+const tlsExpiryMetric = "baa_tls_cert_expiry_seconds"
+
+// TLSHealthOptions certificate monitoring config
+type TLSHealthOptions struct {
+	// Warn alerts when a certificate expires within it, default 30 days
+	Warn time.Duration
+	// Interval between checks, default 12 hours
+	Interval time.Duration
+	// Roots verifies the certificate chain, default is the system pool
+	Roots *x509.CertPool
+	// DNSName is verified against the certificate when it is set
+	DNSName string
+	// OnAlert is called when a certificate expires within Warn or expired
+	OnAlert func(s CertStatus)
+}
+
+// CertStatus is the validity of a served certificate
+type CertStatus struct {
+	File      string
+	Subject   string
+	DNSNames  []string
+	NotAfter  time.Time
+	Remaining time.Duration
+	Expired   bool
+}
+
+// String returns readable status
+func (s CertStatus) String() string {
+	if s.Expired {
+		return fmt.Sprintf("certificate %s of %s expired at %s", s.Subject, s.File, s.NotAfter.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("certificate %s of %s expires in %s at %s", s.Subject, s.File,
+		s.Remaining.Round(time.Minute), s.NotAfter.Format(time.RFC3339))
+}
+
+// MonitorTLS verifies the certificate chain in certFile, then checks expiry of every
+// certificate in the chain at start and every interval. Certificates expire within
+// Warn are logged and sent to OnAlert, remaining seconds are exported by gauge
+// baa_tls_cert_expiry_seconds. The file is read on each check so rotated certificates
+// are picked up. Monitoring stops on Shutdown or by the returned func.
+//
+// Example:
+// 		if _, err := b.MonitorTLS("server.crt", baa.TLSHealthOptions{Warn: 14 * 24 * time.Hour}); err != nil {
+// 			log.Fatal(err)
+// 		}
+// 		b.RunTLS(":443", "server.crt", "server.key")
+func (b *Baa) MonitorTLS(certFile string, opt TLSHealthOptions) (stop func(), err error) {
+	if opt.Warn <= 0 {
+		opt.Warn = 30 * 24 * time.Hour
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = 12 * time.Hour
+	}
+	chain, err := loadCertChain(certFile)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyCertChain(chain, opt, b.Clock().Now()); err != nil {
+		return nil, fmt.Errorf("baa: verify certificate %s: %v", certFile, err)
+	}
+	b.checkCerts(certFile, chain, opt)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(opt.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				chain, err := loadCertChain(certFile)
+				if err != nil {
+					b.Logger().Printf("baa: tls health: %v", err)
+					continue
+				}
+				b.checkCerts(certFile, chain, opt)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(done) })
+	}
+	b.OnShutdown(func(ctx context.Context) error {
+		stop()
+		return nil
+	})
+	return stop, nil
+}
+
+// checkCerts exports and alerts expiry of certificates in chain
+func (b *Baa) checkCerts(file string, chain []*x509.Certificate, opt TLSHealthOptions) {
+	now := b.Clock().Now()
+	for _, cert := range chain {
+		s := CertStatus{
+			File:      file,
+			Subject:   cert.Subject.CommonName,
+			DNSNames:  cert.DNSNames,
+			NotAfter:  cert.NotAfter,
+			Remaining: cert.NotAfter.Sub(now),
+		}
+		if s.Subject == "" {
+			s.Subject = cert.Subject.String()
+		}
+		s.Expired = s.Remaining <= 0
+		b.Metrics().Gauge(tlsExpiryMetric, "subject", s.Subject).Set(s.Remaining.Seconds())
+		if s.Remaining > opt.Warn {
+			continue
+		}
+		b.Logger().Printf("baa: tls health: %s", s)
+		if opt.OnAlert != nil {
+			opt.OnAlert(s)
+		}
+	}
+}
+
+// loadCertChain parses PEM certificates in file, the leaf is the first
+func loadCertChain(file string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("baa: parse certificate %s: %v", file, err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("baa: no certificate found in " + file)
+	}
+	return chain, nil
+}
+
+// verifyCertChain verifies the leaf with the intermediates in chain at now
+func verifyCertChain(chain []*x509.Certificate, opt TLSHealthOptions, now time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		DNSName:       opt.DNSName,
+		Roots:         opt.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
+
+// HSTSOptions Strict-Transport-Security header config
+type HSTSOptions struct {
+	// MaxAge default is 1 year
+	MaxAge time.Duration
+	// IncludeSubDomains applies the policy to sub domains
+	IncludeSubDomains bool
+	// Preload marks the domain for browser preload lists, it requires
+	// IncludeSubDomains and MaxAge at least 1 year, both are enforced.
+	Preload bool
+}
+
+// HSTS returns a middleware sets Strict-Transport-Security header on TLS requests.
+//
+// Example:
+// 		b.Use(baa.HSTS(baa.HSTSOptions{Preload: true}))
+func HSTS(opt HSTSOptions) HandlerFunc {
+	const year = 365 * 24 * time.Hour
+	if opt.MaxAge <= 0 || (opt.Preload && opt.MaxAge < year) {
+		opt.MaxAge = year
+	}
+	directives := []string{"max-age=" + strconv.FormatInt(int64(opt.MaxAge/time.Second), 10)}
+	if opt.IncludeSubDomains || opt.Preload {
+		directives = append(directives, "includeSubDomains")
+	}
+	if opt.Preload {
+		directives = append(directives, "preload")
+	}
+	value := strings.Join(directives, "; ")
+	return func(c *Context) {
+		if c.Req.TLS != nil {
+			c.Resp.Header().Set("Strict-Transport-Security", value)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package baa
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMonitorTLS1(t *testing.T) {
+	certFile := "_fixture/cert/cert.pem"
+	data, _ := ioutil.ReadFile(certFile)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(data)
+
+	Convey("certificate close to expiry", t, func() {
+		b2 := New()
+		buf := new(bytes.Buffer)
+		b2.SetDI("logger", log.New(buf, "", 0))
+		b2.SetDI("clock", NewFakeClock(time.Date(2017, 1, 28, 14, 24, 2, 0, time.UTC)))
+		var alerts []CertStatus
+		stop, err := b2.MonitorTLS(certFile, TLSHealthOptions{
+			Roots:   roots,
+			OnAlert: func(s CertStatus) { alerts = append(alerts, s) },
+			Warn:    60 * 24 * time.Hour,
+		})
+		So(err, ShouldBeNil)
+		defer stop()
+		So(alerts, ShouldHaveLength, 1)
+		So(alerts[0].Subject, ShouldEqual, "O=Acme Co")
+		So(alerts[0].Remaining, ShouldEqual, 30*24*time.Hour)
+		So(alerts[0].Expired, ShouldBeFalse)
+		So(buf.String(), ShouldContainSubstring, "baa: tls health: certificate O=Acme Co of _fixture/cert/cert.pem expires in 720h0m0s")
+
+		out := new(bytes.Buffer)
+		b2.Metrics().WriteText(out)
+		So(out.String(), ShouldContainSubstring, `baa_tls_cert_expiry_seconds{subject="O=Acme Co"} 2.592e+06`)
+	})
+
+	Convey("certificate far from expiry", t, func() {
+		b2 := New()
+		b2.SetDI("clock", NewFakeClock(time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC)))
+		alerted := false
+		stop, err := b2.MonitorTLS(certFile, TLSHealthOptions{
+			Roots:   roots,
+			OnAlert: func(s CertStatus) { alerted = true },
+		})
+		So(err, ShouldBeNil)
+		stop()
+		stop()
+		So(alerted, ShouldBeFalse)
+	})
+
+	Convey("chain verification at start", t, func() {
+		b2 := New()
+		b2.SetDI("clock", NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)))
+		_, err := b2.MonitorTLS(certFile, TLSHealthOptions{Roots: roots})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "baa: verify certificate")
+
+		b2.SetDI("clock", NewFakeClock(time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC)))
+		_, err = b2.MonitorTLS(certFile, TLSHealthOptions{Roots: x509.NewCertPool()})
+		So(err, ShouldNotBeNil)
+
+		_, err = b2.MonitorTLS("_fixture/cert/key.pem", TLSHealthOptions{Roots: roots})
+		So(err, ShouldNotBeNil)
+		_, err = b2.MonitorTLS("_fixture/cert/none.pem", TLSHealthOptions{Roots: roots})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestHSTS1(t *testing.T) {
+	Convey("strict transport security", t, func() {
+		b2 := New()
+		b2.Use(HSTS(HSTSOptions{Preload: true, MaxAge: time.Hour}))
+		b2.Get("/", func(c *Context) {
+			c.String(200, "ok")
+		})
+		So(serveTo(b2, "/").Header().Get("Strict-Transport-Security"), ShouldBeEmpty)
+
+		req := httptest.NewRequest("GET", "https://example.com/", nil)
+		req.TLS = new(tls.ConnectionState)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Header().Get("Strict-Transport-Security"), ShouldEqual, "max-age=31536000; includeSubDomains; preload")
+
+		b3 := New()
+		b3.Use(HSTS(HSTSOptions{MaxAge: time.Hour}))
+		b3.Get("/", func(c *Context) {})
+		w = httptest.NewRecorder()
+		b3.ServeHTTP(w, req)
+		So(w.Header().Get("Strict-Transport-Security"), ShouldEqual, "max-age=3600")
+	})
+}
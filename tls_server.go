@@ -0,0 +1,265 @@
+package baa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ocspRetryInterval interval of retrying failed OCSP refresh
+const ocspRetryInterval = 5 * time.Minute
+
+// TLSOptions config of TLS helpers of servers built by b.Server, see ServerTLS
+type TLSOptions struct {
+	// CertFile certificate chain file in PEM, the leaf is the first and its issuer
+	// is required for OCSP stapling.
+	CertFile string
+	// KeyFile private key file in PEM
+	KeyFile string
+	// OCSPStapling staples the OCSP response of the leaf to handshakes
+	OCSPStapling bool
+	// OCSPRefresh maximum interval of refreshing the staple, it's refreshed at half
+	// of the remaining validity of the response when earlier, default 6 hours.
+	OCSPRefresh time.Duration
+	// OCSPFetch fetches the OCSP response, default is FetchOCSP by the app HTTP client
+	OCSPFetch func(leaf, issuer *x509.Certificate) (staple []byte, nextUpdate time.Time, err error)
+	// TicketKeyRotation rotates session ticket keys at the interval, 0 disables
+	TicketKeyRotation time.Duration
+	// TicketKeys number of keys kept to resume sessions issued before rotations, default 3
+	TicketKeys int
+}
+
+// ServerTLS returns a server option configures the certificate, OCSP stapling refresh
+// and session ticket key rotation of servers built by b.Server. The first staple is
+// fetched before it returns, fetch errors are logged and retried without failing.
+// Refresh and rotation stop on Shutdown.
+//
+// Example:
+// 		opt, err := b.ServerTLS(baa.TLSOptions{
+// 			CertFile:          "server.crt",
+// 			KeyFile:           "server.key",
+// 			OCSPStapling:      true,
+// 			TicketKeyRotation: 12 * time.Hour,
+// 		})
+// 		if err != nil {
+// 			log.Fatal(err)
+// 		}
+// 		b.SetServerOption(opt)
+// 		b.RunTLS(":443", "server.crt", "server.key")
+func (b *Baa) ServerTLS(opt TLSOptions) (ServerOption, error) {
+	m, err := b.newServerTLS(opt)
+	if err != nil {
+		return nil, err
+	}
+	go m.run()
+	var once sync.Once
+	b.OnShutdown(func(ctx context.Context) error {
+		once.Do(func() { close(m.done) })
+		return nil
+	})
+	return m.apply, nil
+}
+
+// newServerTLS loads the certificate, fetches the first staple and creates the
+// first session ticket key.
+func (b *Baa) newServerTLS(opt TLSOptions) (*serverTLS, error) {
+	if opt.OCSPRefresh <= 0 {
+		opt.OCSPRefresh = 6 * time.Hour
+	}
+	if opt.TicketKeys <= 0 {
+		opt.TicketKeys = 3
+	}
+	if opt.OCSPFetch == nil {
+		opt.OCSPFetch = func(leaf, issuer *x509.Certificate) ([]byte, time.Time, error) {
+			return FetchOCSP(b.HTTPClient().Client(), leaf, issuer)
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(opt.CertFile, opt.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	m := &serverTLS{
+		b:       b,
+		opt:     opt,
+		cert:    cert,
+		configs: make(map[*tls.Config]serverTLSConfig),
+		done:    make(chan struct{}),
+	}
+	if opt.OCSPStapling {
+		if len(cert.Certificate) < 2 {
+			return nil, errors.New("baa: OCSP stapling requires the issuer in " + opt.CertFile)
+		}
+		if m.leaf, err = x509.ParseCertificate(cert.Certificate[0]); err == nil {
+			m.issuer, err = x509.ParseCertificate(cert.Certificate[1])
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.refreshOCSP()
+	}
+	if opt.TicketKeyRotation > 0 {
+		if err = m.rotateTicketKeys(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// serverTLS keeps the stapled certificate and session ticket keys of servers
+type serverTLS struct {
+	b          *Baa
+	opt        TLSOptions
+	cert       tls.Certificate
+	leaf       *x509.Certificate
+	issuer     *x509.Certificate
+	nextUpdate time.Time
+	keys       [][32]byte
+	version    int
+	configs    map[*tls.Config]serverTLSConfig
+	done       chan struct{}
+	mu         sync.Mutex
+}
+
+// serverTLSConfig is the handshake config derived from a server config at version
+type serverTLSConfig struct {
+	version int
+	config  *tls.Config
+}
+
+// apply sets the certificate of s, handshakes use the config derived by config
+func (m *serverTLS) apply(s *http.Server) {
+	if s.TLSConfig == nil {
+		s.TLSConfig = new(tls.Config)
+	}
+	base := s.TLSConfig
+	base.Certificates = []tls.Certificate{m.certificate()}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return m.config(base), nil
+	}
+}
+
+// certificate returns current certificate
+func (m *serverTLS) certificate() tls.Certificate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cert
+}
+
+// config returns the handshake config of server config base, it's derived again
+// after the staple or ticket keys changed.
+func (m *serverTLS) config(base *tls.Config) *tls.Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.configs[base]; ok && v.version == m.version {
+		return v.config
+	}
+	cfg := base.Clone()
+	cfg.GetConfigForClient = nil
+	cfg.Certificates = []tls.Certificate{m.cert}
+	if len(m.keys) > 0 {
+		cfg.SetSessionTicketKeys(m.keys)
+	}
+	m.configs[base] = serverTLSConfig{version: m.version, config: cfg}
+	return cfg
+}
+
+// run refreshes the staple and rotates ticket keys until shutdown
+func (m *serverTLS) run() {
+	var ocsp, rotate <-chan time.Time
+	var ocspTimer *time.Timer
+	if m.opt.OCSPStapling {
+		ocspTimer = time.NewTimer(m.ocspDelay())
+		defer ocspTimer.Stop()
+		ocsp = ocspTimer.C
+	}
+	if m.opt.TicketKeyRotation > 0 {
+		ticker := time.NewTicker(m.opt.TicketKeyRotation)
+		defer ticker.Stop()
+		rotate = ticker.C
+	}
+	if ocsp == nil && rotate == nil {
+		return
+	}
+	for {
+		select {
+		case <-ocsp:
+			if m.refreshOCSP() != nil {
+				ocspTimer.Reset(ocspRetryInterval)
+			} else {
+				ocspTimer.Reset(m.ocspDelay())
+			}
+		case <-rotate:
+			if err := m.rotateTicketKeys(); err != nil {
+				m.b.Logger().Printf("baa: rotate session ticket keys: %v", err)
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// refreshOCSP fetches the staple, the stale staple is removed when fetching failed
+func (m *serverTLS) refreshOCSP() error {
+	staple, nextUpdate, err := m.opt.OCSPFetch(m.leaf, m.issuer)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.b.Logger().Printf("baa: refresh OCSP staple: %v", err)
+		if m.cert.OCSPStaple != nil && !m.nextUpdate.IsZero() && !m.b.Clock().Now().Before(m.nextUpdate) {
+			m.setStaple(nil, time.Time{})
+		}
+		return err
+	}
+	m.setStaple(staple, nextUpdate)
+	return nil
+}
+
+// setStaple replaces staple of the certificate, it's called with lock held
+func (m *serverTLS) setStaple(staple []byte, nextUpdate time.Time) {
+	cert := m.cert
+	cert.OCSPStaple = staple
+	m.cert = cert
+	m.nextUpdate = nextUpdate
+	m.version++
+}
+
+// ocspDelay returns delay of next OCSP refresh
+func (m *serverTLS) ocspDelay() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cert.OCSPStaple == nil {
+		return ocspRetryInterval
+	}
+	d := m.opt.OCSPRefresh
+	if !m.nextUpdate.IsZero() {
+		if half := m.nextUpdate.Sub(m.b.Clock().Now()) / 2; half < d {
+			d = half
+		}
+	}
+	if d < time.Minute {
+		d = time.Minute
+	}
+	return d
+}
+
+// rotateTicketKeys adds a new session ticket key, old keys are kept to resume sessions
+func (m *serverTLS) rotateTicketKeys() error {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := append([][32]byte{key}, m.keys...)
+	if len(keys) > m.opt.TicketKeys {
+		keys = keys[:m.opt.TicketKeys]
+	}
+	m.keys = keys
+	m.version++
+	return nil
+}
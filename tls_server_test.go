@@ -0,0 +1,221 @@
+package baa
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newTestChain creates a CA and a leaf certificate for 127.0.0.1 in dir,
+// it returns the chain file, key file, leaf, CA and CA pool.
+func newTestChain(dir, ocspServer string) (certFile, keyFile string, leaf, ca *x509.Certificate, pool *x509.CertPool) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "baa test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, _ := x509.CreateCertificate(rand.Reader, caTpl, caTpl, &caKey.PublicKey, caKey)
+	ca, _ = x509.ParseCertificate(caDER)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		OCSPServer:   []string{ocspServer},
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, tpl, ca, &key.PublicKey, caKey)
+	leaf, _ = x509.ParseCertificate(der)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certFile = filepath.Join(dir, "server.crt")
+	keyFile = filepath.Join(dir, "server.key")
+	ioutil.WriteFile(certFile, certPEM, 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	pool = x509.NewCertPool()
+	pool.AddCert(ca)
+	return
+}
+
+// newTestOCSPResponse creates an unsigned OCSP response of leaf
+func newTestOCSPResponse(leaf, issuer *x509.Certificate, good bool, nextUpdate time.Time) []byte {
+	id, _ := newOCSPCertID(leaf, issuer)
+	single := ocspSingleResponse{CertID: id, ThisUpdate: time.Now().Add(-time.Minute).UTC(), NextUpdate: nextUpdate.UTC()}
+	if good {
+		single.Good = true
+	} else {
+		single.Unknown = true
+	}
+	var basic ocspBasicResponse
+	basic.TBSResponseData.ResponderID = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: []byte{4, 0}}
+	basic.TBSResponseData.ProducedAt = time.Now().UTC()
+	basic.TBSResponseData.Responses = []ocspSingleResponse{single}
+	basic.SignatureAlgorithm.Algorithm = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	basic.Signature = asn1.BitString{Bytes: []byte{0}, BitLength: 8}
+	data, _ := asn1.Marshal(basic)
+	var resp ocspResponse
+	resp.Response.ResponseType = oidOCSPBasicResponse
+	resp.Response.Response = data
+	raw, _ := asn1.Marshal(resp)
+	return raw
+}
+
+func TestFetchOCSP1(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "baa-ocsp")
+	defer os.RemoveAll(dir)
+	var respond func(w http.ResponseWriter, r *http.Request)
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond(w, r)
+	}))
+	defer responder.Close()
+	_, _, leaf, ca, _ := newTestChain(dir, responder.URL)
+
+	Convey("fetch OCSP response", t, func() {
+		var reqBody []byte
+		next := time.Now().Add(time.Hour)
+		respond = func(w http.ResponseWriter, r *http.Request) {
+			reqBody, _ = ioutil.ReadAll(r.Body)
+			w.Write(newTestOCSPResponse(leaf, ca, true, next))
+		}
+		staple, nextUpdate, err := FetchOCSP(http.DefaultClient, leaf, ca)
+		So(err, ShouldBeNil)
+		So(staple, ShouldNotBeEmpty)
+		So(nextUpdate.Unix(), ShouldEqual, next.Unix())
+
+		var req ocspRequest
+		_, err = asn1.Unmarshal(reqBody, &req)
+		So(err, ShouldBeNil)
+		So(req.TBSRequest.RequestList[0].Cert.SerialNumber.Int64(), ShouldEqual, 2)
+	})
+
+	Convey("invalid OCSP responses", t, func() {
+		respond = func(w http.ResponseWriter, r *http.Request) {
+			w.Write(newTestOCSPResponse(leaf, ca, false, time.Now().Add(time.Hour)))
+		}
+		_, _, err := FetchOCSP(http.DefaultClient, leaf, ca)
+		So(err, ShouldNotBeNil)
+
+		respond = func(w http.ResponseWriter, r *http.Request) {
+			w.Write(newTestOCSPResponse(leaf, ca, true, time.Now().Add(-time.Second)))
+		}
+		_, _, err = FetchOCSP(http.DefaultClient, leaf, ca)
+		So(err, ShouldNotBeNil)
+
+		respond = func(w http.ResponseWriter, r *http.Request) {
+			w.Write(newTestOCSPResponse(ca, ca, true, time.Now().Add(time.Hour)))
+		}
+		_, _, err = FetchOCSP(http.DefaultClient, leaf, ca)
+		So(err, ShouldNotBeNil)
+
+		respond = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _, err = FetchOCSP(http.DefaultClient, leaf, ca)
+		So(err, ShouldNotBeNil)
+
+		_, _, err = FetchOCSP(http.DefaultClient, ca, ca)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestServerTLS1(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "baa-tls")
+	defer os.RemoveAll(dir)
+	certFile, keyFile, leaf, ca, pool := newTestChain(dir, "http://127.0.0.1:1/ocsp")
+	staple := newTestOCSPResponse(leaf, ca, true, time.Now().Add(time.Hour))
+
+	Convey("staple OCSP response and resume sessions across rotations", t, func() {
+		b2 := New()
+		b2.Get("/", func(c *Context) {
+			c.String(200, "ok")
+		})
+		fetchErr := errors.New("unavailable")
+		m, err := b2.newServerTLS(TLSOptions{
+			CertFile:     certFile,
+			KeyFile:      keyFile,
+			OCSPStapling: true,
+			OCSPFetch: func(l, issuer *x509.Certificate) ([]byte, time.Time, error) {
+				if fetchErr != nil {
+					return nil, time.Time{}, fetchErr
+				}
+				return staple, time.Now().Add(time.Hour), nil
+			},
+			TicketKeyRotation: time.Hour,
+			TicketKeys:        2,
+		})
+		So(err, ShouldBeNil)
+		s := b2.Server("127.0.0.1:0", m.apply)
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		go s.ServeTLS(ln, certFile, keyFile)
+		defer s.Close()
+
+		cache := tls.NewLRUClientSessionCache(8)
+		get := func(cache tls.ClientSessionCache) *tls.ConnectionState {
+			client := &http.Client{Transport: &http.Transport{
+				DisableKeepAlives: true,
+				TLSClientConfig:   &tls.Config{RootCAs: pool, ClientSessionCache: cache},
+			}}
+			resp, err := client.Get("https://" + ln.Addr().String() + "/")
+			So(err, ShouldBeNil)
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			return resp.TLS
+		}
+
+		So(get(nil).OCSPResponse, ShouldBeEmpty)
+		fetchErr = nil
+		m.refreshOCSP()
+		So(bytes.Equal(get(nil).OCSPResponse, staple), ShouldBeTrue)
+
+		So(get(cache).DidResume, ShouldBeFalse)
+		So(get(cache).DidResume, ShouldBeTrue)
+		m.rotateTicketKeys()
+		So(get(cache).DidResume, ShouldBeTrue)
+		m.rotateTicketKeys()
+		m.rotateTicketKeys()
+		So(get(cache).DidResume, ShouldBeFalse)
+	})
+
+	Convey("invalid options", t, func() {
+		b2 := New()
+		_, err := b2.ServerTLS(TLSOptions{CertFile: certFile, KeyFile: filepath.Join(dir, "none")})
+		So(err, ShouldNotBeNil)
+		opt, err := b2.ServerTLS(TLSOptions{CertFile: certFile, KeyFile: keyFile, TicketKeyRotation: time.Hour})
+		So(err, ShouldBeNil)
+		So(opt, ShouldNotBeNil)
+		So(b2.Shutdown(context.Background()), ShouldBeNil)
+		_, err = b2.ServerTLS(TLSOptions{CertFile: "_fixture/cert/cert.pem", KeyFile: "_fixture/cert/key.pem", OCSPStapling: true})
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,119 @@
+package baa
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BodyTransformer transforms buffered response body before it is sent,
+// eg: JSON field redaction, envelope wrapping, HTML snippet injection.
+type BodyTransformer func(c *Context, body []byte) ([]byte, error)
+
+// TransformBody returns a handler buffers the response of subsequent handlers and
+// transforms the body when the response content type matches one of contentTypes,
+// all content types match when contentTypes is empty. Responses marked no-transform
+// are sent as is. It can be used as middleware or group handler.
+//
+// Example:
+// 		b.Group("/api", func() { ... }, baa.TransformBody(redact, baa.ApplicationJSON))
+func TransformBody(fn BodyTransformer, contentTypes ...string) HandlerFunc {
+	if fn == nil {
+		panic("baa.TransformBody transformer can not be nil")
+	}
+	return func(c *Context) {
+		// streamed responses of no-transform routes are not buffered
+		if c.NoTransform() {
+			c.Next()
+			return
+		}
+		buf := &bufferedWriter{
+			header: c.Resp.Header(),
+			code:   http.StatusOK,
+			body:   getBuffer(),
+		}
+		defer putBuffer(buf.body)
+		resp, writer, wrapped := c.Resp.resp, c.Resp.writer, c.Resp.wrapped
+		c.Resp.resp, c.Resp.writer = buf, buf
+
+		c.Next()
+
+		c.Resp.resp, c.Resp.writer, c.Resp.wrapped = resp, writer, wrapped
+		if c.Resp.hijacked || !buf.wrote {
+			return
+		}
+
+		body := buf.body.Bytes()
+		if !c.NoTransform() && matchContentType(buf.header.Get("Content-Type"), contentTypes) {
+			var err error
+			if body, err = fn(c, body); err != nil {
+				c.Resp.wroteHeader = false
+				c.Resp.status = http.StatusOK
+				c.Resp.written = 0
+				c.Error(err)
+				return
+			}
+			if buf.header.Get("Content-Length") != "" {
+				buf.header.Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+		resp.WriteHeader(buf.code)
+		n, _ := writer.Write(body)
+		c.Resp.written = int64(n)
+	}
+}
+
+// matchContentType checks media type of contentType is in list
+func matchContentType(contentType string, list []string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	}
+	for _, v := range list {
+		if i := strings.IndexByte(v, ';'); i >= 0 {
+			v = v[:i]
+		}
+		if strings.EqualFold(strings.TrimSpace(v), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter is a http.ResponseWriter buffers status code and body
+type bufferedWriter struct {
+	header http.Header
+	code   int
+	wrote  bool
+	body   *bytes.Buffer
+}
+
+// Header returns the header of the real response
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader records the status code
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	w.code = code
+}
+
+// Write writes data into buffer
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(b)
+}
+
+// Flush does nothing, the body is sent after transformation
+func (w *bufferedWriter) Flush() {}
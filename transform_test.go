@@ -0,0 +1,70 @@
+package baa
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTransformBody1(t *testing.T) {
+	Convey("transform response body", t, func() {
+		b2 := New()
+		b2.Group("/api", func() {
+			b2.Get("/json", func(c *Context) {
+				c.JSON(201, map[string]string{"password": "secret"})
+			})
+			b2.Get("/text", func(c *Context) {
+				c.String(200, "secret")
+			})
+			b2.Get("/raw", func(c *Context) {
+				c.SetNoTransform()
+				c.JSON(200, map[string]string{"password": "secret"})
+			})
+			b2.Get("/error", func(c *Context) {
+				c.JSON(200, map[string]string{"error": "true"})
+			})
+			b2.Get("/empty", func(c *Context) {})
+		}, TransformBody(func(c *Context, body []byte) ([]byte, error) {
+			if bytes.Contains(body, []byte("error")) {
+				return nil, errors.New("transform error")
+			}
+			return bytes.Replace(body, []byte("secret"), []byte("******"), -1), nil
+		}, ApplicationJSONCharsetUTF8))
+		b2.Use(TransformBody(func(c *Context, body []byte) ([]byte, error) {
+			return append([]byte("<!-- baa -->"), body...), nil
+		}, TextHTML))
+		b2.Get("/html", func(c *Context) {
+			c.Text(200, []byte("<p>html</p>"))
+		})
+
+		for _, v := range [][]string{
+			{"/api/json", "201", `{"password":"******"}`},
+			{"/api/text", "200", "secret"},
+			{"/api/raw", "200", `{"password":"secret"}`},
+			{"/api/error", "500", "Internal Server Error\n"},
+			{"/api/empty", "200", ""},
+			{"/html", "200", "<!-- baa --><p>html</p>"},
+		} {
+			b2.SetDebug(false)
+			req, _ := http.NewRequest("GET", v[0], nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Body.String(), ShouldEqual, v[2])
+			So(strconv.Itoa(w.Code), ShouldEqual, v[1])
+		}
+	})
+	Convey("transform nil", t, func() {
+		So(func() { TransformBody(nil) }, ShouldPanic)
+	})
+	Convey("match content type", t, func() {
+		So(matchContentType("application/json; charset=utf-8", []string{"application/json"}), ShouldBeTrue)
+		So(matchContentType("text/html", nil), ShouldBeTrue)
+		So(matchContentType("invalid;;", []string{"invalid"}), ShouldBeTrue)
+		So(matchContentType("text/plain", []string{"text/html"}), ShouldBeFalse)
+	})
+}
@@ -2,6 +2,9 @@ package baa
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
 	"sync"
 )
 
@@ -20,15 +23,22 @@ type Tree struct {
 	nodes             [RouteLength]*leaf
 	baa               *Baa
 	nameNodes         map[string]*Node
+	cache             *matchCache
 }
 
 // Node is struct for named route
 type Node struct {
 	paramNum int
+	wide     bool // the last param of format is wide
 	pattern  string
 	format   string
 	name     string
 	root     *Tree
+	params   []string // param names in the order of pattern
+	meta     map[string]interface{}
+	aliases  []*Node // nodes registered automatically, eg: HEAD, trailing slash
+	handlers []HandlerFunc
+	prefix   int // number of group handlers and route middlewares before route handlers
 }
 
 // Leaf is a tree node
@@ -36,6 +46,8 @@ type leaf struct {
 	kind        uint
 	pattern     string
 	param       string
+	expr        string         // regexp constraint of param
+	re          *regexp.Regexp // compiled expr
 	handlers    []HandlerFunc
 	children    []*leaf
 	childrenNum uint
@@ -67,10 +79,73 @@ func NewTree(b *Baa) Router {
 func NewNode(pattern string, root *Tree) *Node {
 	return &Node{
 		pattern: pattern,
+		params:  paramNames(pattern),
 		root:    root,
 	}
 }
 
+// paramNames returns param names of pattern, unnamed wide param name is empty
+func paramNames(pattern string) []string {
+	var names []string
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case ':', '*':
+			j := i + 1
+			for ; j < len(pattern) && pattern[j] != '/'; j++ {
+			}
+			name := pattern[i+1 : j]
+			if pattern[i] == ':' {
+				name, _ = splitParam(name)
+			}
+			names = append(names, name)
+			if pattern[i] == '*' {
+				return names
+			}
+			i = j
+		}
+	}
+	return names
+}
+
+// splitParam splits param segment into name and regexp constraint, eg: id(\d+)
+func splitParam(seg string) (name, expr string) {
+	i := strings.IndexByte(seg, '(')
+	if i < 0 || seg[len(seg)-1] != ')' {
+		return seg, ""
+	}
+	return seg[:i], seg[i+1 : len(seg)-1]
+}
+
+// optionalPatterns expands trailing optional params of pattern, eg: /archive/:year?/:month?
+// returns /archive/:year/:month, /archive/:year and /archive. Optional params must be
+// the last segments.
+func optionalPatterns(pattern string) []string {
+	if !strings.Contains(pattern, "?") {
+		return []string{pattern}
+	}
+	segs := strings.Split(pattern, "/")
+	first := -1
+	for i, seg := range segs {
+		optional := strings.HasPrefix(seg, ":") && strings.HasSuffix(seg, "?")
+		if optional {
+			segs[i] = seg[:len(seg)-1]
+			if first < 0 {
+				first = i
+			}
+		} else if first >= 0 {
+			panic("route pattern optional param must be the last segments: " + pattern)
+		}
+	}
+	if first < 0 {
+		return []string{pattern}
+	}
+	patterns := make([]string, 0, len(segs)-first+1)
+	for i := len(segs); i >= first; i-- {
+		patterns = append(patterns, strings.Join(segs[:i], "/"))
+	}
+	return patterns
+}
+
 // newLeaf create a tree leaf
 func newLeaf(pattern string, handlers []HandlerFunc, root *Tree) *leaf {
 	l := new(leaf)
@@ -102,10 +177,22 @@ func (t *Tree) SetAutoTrailingSlash(v bool) {
 
 // Match find matched route then returns handlers and name
 func (t *Tree) Match(method, pattern string, c *Context) ([]HandlerFunc, string) {
+	t.mu.RLock()
+	cache := t.cache
+	t.mu.RUnlock()
+	if cache != nil {
+		return t.matchCached(cache, method, pattern, c)
+	}
+	return t.match(method, pattern, c)
+}
+
+// match find matched route in the radix tree
+func (t *Tree) match(method, pattern string, c *Context) ([]HandlerFunc, string) {
 	var i, l int
 	var root, nl *leaf
 	root = t.nodes[RouterMethods[method]]
 	current := root
+	start := len(c.pNames)
 
 	for {
 		switch current.kind {
@@ -124,7 +211,7 @@ func (t *Tree) Match(method, pattern string, c *Context) ([]HandlerFunc, string)
 			if i >= 0 {
 				break
 			}
-			if len(pattern) == l || current.children[pattern[l]] != nil ||
+			if len(pattern) == l || (pattern[l] < 0x80 && current.children[pattern[l]] != nil) ||
 				current.paramChild != nil ||
 				current.wideChild != nil {
 				pattern = pattern[l:]
@@ -135,6 +222,14 @@ func (t *Tree) Match(method, pattern string, c *Context) ([]HandlerFunc, string)
 			l = len(pattern)
 			for i = 0; i < l && pattern[i] != '/'; i++ {
 			}
+			if current.re != nil && !current.re.MatchString(pattern[:i]) {
+				// constraint not satisfied, try wide route of the same prefix
+				if root.wideChild != nil {
+					current = root.wideChild
+					continue
+				}
+				return nil, ""
+			}
 			c.SetParam(current.param, pattern[:i])
 			pattern = pattern[i:]
 			root = current
@@ -147,8 +242,10 @@ func (t *Tree) Match(method, pattern string, c *Context) ([]HandlerFunc, string)
 
 		if len(pattern) == 0 {
 			if current.handlers != nil {
+				c.routeNode = current.nameNode
 				if current.nameNode != nil {
-					return current.handlers, current.nameNode.name
+					c.keepParams(start, len(current.nameNode.params))
+					return current.nameNode.handlers, current.nameNode.name
 				}
 				return current.handlers, ""
 			}
@@ -157,7 +254,8 @@ func (t *Tree) Match(method, pattern string, c *Context) ([]HandlerFunc, string)
 			}
 		} else {
 			// children static route
-			if current == root {
+			// children table only has ASCII characters
+			if current == root && pattern[0] < 0x80 {
 				if nl = root.children[pattern[0]]; nl != nil {
 					current = nl
 					continue
@@ -183,7 +281,8 @@ func (t *Tree) Match(method, pattern string, c *Context) ([]HandlerFunc, string)
 	return nil, ""
 }
 
-// URLFor use named route return format url
+// URLFor use named route return format url, param values are path escaped,
+// slashes of the wide param value are kept.
 func (t *Tree) URLFor(name string, args ...interface{}) string {
 	if name == "" {
 		return ""
@@ -197,6 +296,23 @@ func (t *Tree) URLFor(name string, args ...interface{}) string {
 	for i := node.paramNum + 1; i <= len(args); i++ {
 		format = append(format, "%v"...)
 	}
+	if node.paramNum > 0 {
+		escaped := make([]interface{}, len(args))
+		copy(escaped, args)
+		for i := 0; i < node.paramNum && i < len(args); i++ {
+			v := fmt.Sprint(args[i])
+			if node.wide && i == node.paramNum-1 {
+				segs := strings.Split(v, "/")
+				for j := range segs {
+					segs[j] = url.PathEscape(segs[j])
+				}
+				escaped[i] = strings.Join(segs, "/")
+			} else {
+				escaped[i] = url.PathEscape(v)
+			}
+		}
+		args = escaped
+	}
 	return fmt.Sprintf(string(format), args...)
 }
 
@@ -222,11 +338,12 @@ func (t *Tree) routes(l *leaf) []string {
 	if l.handlers != nil {
 		data = append(data, l.String())
 	}
-	l.children = append(l.children, l.paramChild)
-	l.children = append(l.children, l.wideChild)
-	for i := range l.children {
-		if l.children[i] != nil {
-			cdata := t.routes(l.children[i])
+	children := make([]*leaf, 0, len(l.children)+2)
+	children = append(children, l.children...)
+	children = append(children, l.paramChild, l.wideChild)
+	for i := range children {
+		if children[i] != nil {
+			cdata := t.routes(children[i])
 			for i := range cdata {
 				data = append(data, l.String()+cdata[i])
 			}
@@ -246,10 +363,28 @@ func (t *Tree) NamedRoutes() map[string]string {
 }
 
 // Add registers a new handle with the given method, pattern and handlers.
-// add check training slash option.
+// add check training slash option. Trailing optional params are registered as
+// aliases of the shorter patterns.
 func (t *Tree) Add(method, pattern string, handlers []HandlerFunc) RouteNode {
+	patterns := optionalPatterns(pattern)
+	n, aliases := t.addAliases(method, patterns[0], handlers)
+	for _, p := range patterns[1:] {
+		if p == "" && len(t.groups) == 0 {
+			p = "/"
+		}
+		an, aa := t.addAliases(method, p, handlers)
+		aliases = append(aliases, an)
+		aliases = append(aliases, aa...)
+	}
+	n.aliases = aliases
+	return n
+}
+
+// addAliases adds route with HEAD and trailing slash aliases
+func (t *Tree) addAliases(method, pattern string, handlers []HandlerFunc) (*Node, []*Node) {
+	var aliases []*Node
 	if method == "GET" && t.autoHead {
-		t.add("HEAD", pattern, handlers)
+		aliases = append(aliases, t.add("HEAD", pattern, handlers))
 	}
 	if t.autoTrailingSlash && (len(pattern) > 1 || len(t.groups) > 0) {
 		var index byte
@@ -257,14 +392,14 @@ func (t *Tree) Add(method, pattern string, handlers []HandlerFunc) RouteNode {
 			index = pattern[len(pattern)-1]
 		}
 		if index == '/' {
-			t.add(method, pattern[:len(pattern)-1], handlers)
+			aliases = append(aliases, t.add(method, pattern[:len(pattern)-1], handlers))
 		} else if index == '*' {
 			// wideChild not need trail slash
 		} else {
-			t.add(method, pattern+"/", handlers)
+			aliases = append(aliases, t.add(method, pattern+"/", handlers))
 		}
 	}
-	return t.add(method, pattern, handlers)
+	return t.add(method, pattern, handlers), aliases
 }
 
 // GroupAdd add a group route has same prefix and handle chain
@@ -280,7 +415,7 @@ func (t *Tree) GroupAdd(pattern string, f func(), handlers []HandlerFunc) {
 }
 
 // add registers a new request handle with the given method, pattern and handlers.
-func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
+func (t *Tree) add(method, pattern string, handlers []HandlerFunc) *Node {
 	if _, ok := RouterMethods[method]; !ok {
 		panic("unsupport http method [" + method + "]")
 	}
@@ -288,7 +423,13 @@ func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	// route table changed, cached match results may be stale
+	if t.cache != nil {
+		t.cache.purge()
+	}
+
 	// check group set
+	var prefix int
 	if len(t.groups) > 0 {
 		var gpattern string
 		var ghandlers []HandlerFunc
@@ -299,6 +440,7 @@ func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
 			}
 		}
 		pattern = gpattern + pattern
+		prefix = len(ghandlers)
 		ghandlers = append(ghandlers, handlers...)
 		handlers = ghandlers
 	}
@@ -318,6 +460,8 @@ func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
 	root := t.nodes[RouterMethods[method]]
 	origPattern := pattern
 	nameNode := NewNode(origPattern, t)
+	nameNode.handlers = handlers
+	nameNode.prefix = prefix
 
 	// specialy route = /
 	if len(pattern) == 1 {
@@ -343,6 +487,7 @@ func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
 			}
 			tl = newLeaf("*", handlers, t)
 			tl.kind = leafKindWide
+			tl.param = paramNames(pattern[i:])[0]
 			tl.nameNode = nameNode
 			root.insertChild(tl)
 			break
@@ -376,7 +521,13 @@ func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
 			} else {
 				tl = newLeaf(":", nil, t)
 			}
-			tl.param = string(param[:k])
+			tl.param, tl.expr = splitParam(string(param[:k]))
+			if tl.param == "" {
+				panic("route pattern param is empty")
+			}
+			if tl.expr != "" {
+				tl.re = regexp.MustCompile("^(?:" + tl.expr + ")$")
+			}
 			tl.kind = leafKindParam
 			root = root.insertChild(tl)
 			continue
@@ -411,8 +562,8 @@ func (l *leaf) insertChild(node *leaf) *leaf {
 			l.paramChild = node
 			return l.paramChild
 		}
-		if l.paramChild.param != node.param {
-			panic("Router Tree.insert error cannot use two param [:" + l.paramChild.param + ", :" + node.param + "] with same prefix!")
+		if l.paramChild.param != node.param || l.paramChild.expr != node.expr {
+			panic("Router Tree.insert error cannot use two param [" + l.paramChild.String() + ", " + node.String() + "] with same prefix!")
 		}
 		if node.handlers != nil {
 			if l.paramChild.handlers != nil {
@@ -425,6 +576,11 @@ func (l *leaf) insertChild(node *leaf) *leaf {
 	}
 
 	// static route
+	for i := 0; i < len(node.pattern); i++ {
+		if node.pattern[i] >= 0x80 {
+			panic("Router Tree.insert error: route pattern can only contain ASCII characters")
+		}
+	}
 	child := l.children[node.pattern[0]]
 	if child == nil {
 		// new child
@@ -488,6 +644,8 @@ func (l *leaf) reset(pattern string, handlers []HandlerFunc) {
 	l.wideChild = nil
 	l.nameNode = nil
 	l.param = ""
+	l.expr = ""
+	l.re = nil
 	l.handlers = handlers
 }
 
@@ -506,7 +664,13 @@ func (l *leaf) hasPrefixString(s string) int {
 // String returns pattern of leaf
 func (l *leaf) String() string {
 	s := l.pattern
-	if l.kind == leafKindParam {
+	switch l.kind {
+	case leafKindParam:
+		s += l.param
+		if l.expr != "" {
+			s += "(" + l.expr + ")"
+		}
+	case leafKindWide:
 		s += l.param
 	}
 	return s
@@ -520,6 +684,13 @@ func (n *Node) Name(name string) {
 	p := 0
 	f := make([]byte, 0, len(n.pattern))
 	for i := 0; i < len(n.pattern); i++ {
+		if n.pattern[i] == '*' {
+			// wide param is the last
+			f = append(f, '%', 'v')
+			p++
+			n.wide = true
+			break
+		}
 		if n.pattern[i] != ':' {
 			f = append(f, n.pattern[i])
 			continue
@@ -539,3 +710,95 @@ func (n *Node) Name(name string) {
 	n.name = name
 	n.root.nameNodes[name] = n
 }
+
+// SetMeta set metadata of route, ParamRules of RouteMetaParams are parsed here
+// and unknown rules panic, the Renderer of RouteMetaRenderer gets the application
+// template functions.
+func (n *Node) SetMeta(key string, v interface{}) MetaRouteNode {
+	var b *Baa
+	if n.root != nil {
+		b = n.root.baa
+	}
+	switch key {
+	case RouteMetaParams:
+		if rules, ok := v.(ParamRules); ok {
+			parsed, err := parseParamRules(b, rules)
+			if err != nil {
+				panic(err.Error())
+			}
+			n.setMeta(routeMetaParamRules, parsed)
+		}
+	case RouteMetaRenderer:
+		if r, ok := v.(Renderer); ok && b != nil {
+			b.addRenderer(r)
+		}
+	}
+	n.setMeta(key, v)
+	return n
+}
+
+// setMeta set metadata of route and its aliases
+func (n *Node) setMeta(key string, v interface{}) {
+	if n.meta == nil {
+		n.meta = make(map[string]interface{})
+	}
+	n.meta[key] = v
+	for i := range n.aliases {
+		n.aliases[i].setMeta(key, v)
+	}
+}
+
+// Use registers middlewares of the route, they are executed after global and group
+// middlewares and before route handlers.
+func (n *Node) Use(m ...Middleware) MetaRouteNode {
+	t := n.root
+	t.mu.Lock()
+	handlers := make([]HandlerFunc, 0, len(n.handlers)+len(m))
+	handlers = append(handlers, n.handlers[:n.prefix]...)
+	for i := range m {
+		if m[i] != nil {
+			handlers = append(handlers, wrapMiddleware(m[i]))
+		}
+	}
+	handlers = append(handlers, n.handlers[n.prefix:]...)
+	n.prefix += len(handlers) - len(n.handlers)
+	n.handlers = handlers
+	if t.cache != nil {
+		t.cache.purge()
+	}
+	t.mu.Unlock()
+	for i := range n.aliases {
+		n.aliases[i].Use(m...)
+	}
+	return n
+}
+
+// addPrefix counts k more handlers of n as group handlers, Use inserts middlewares after them
+func (n *Node) addPrefix(k int) {
+	n.root.mu.Lock()
+	n.prefix += k
+	n.root.mu.Unlock()
+	for i := range n.aliases {
+		n.aliases[i].addPrefix(k)
+	}
+}
+
+// Meta returns metadata of route by key
+func (n *Node) Meta(key string) interface{} {
+	return n.meta[key]
+}
+
+// Pattern returns pattern of route
+func (n *Node) Pattern() string {
+	return n.pattern
+}
+
+// ParamIndex returns index of param name in the route, returns -1 if not found
+func (n *Node) ParamIndex(name string) int {
+	for i := range n.params {
+		if n.params[i] == name {
+			return i
+		}
+	}
+	return -1
+}
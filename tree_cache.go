@@ -0,0 +1,129 @@
+package baa
+
+import (
+	"container/list"
+	"sync"
+)
+
+// matchCache is a LRU cache of route match results keyed by method and path
+type matchCache struct {
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	mu    sync.Mutex
+}
+
+// matchResult is a cached route match result
+type matchResult struct {
+	key      string
+	handlers []HandlerFunc
+	name     string
+	node     *Node
+	pNames   []string
+	pValues  []string
+}
+
+// newMatchCache create a match cache holds at most size results
+func newMatchCache(size int) *matchCache {
+	return &matchCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns cached result of key and marks it recently used
+func (m *matchCache) get(key string) *matchResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	m.ll.MoveToFront(e)
+	return e.Value.(*matchResult)
+}
+
+// add caches result, the least recently used result is evicted when cache is full
+func (m *matchCache) add(r *matchResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.items[r.key]; ok {
+		e.Value = r
+		m.ll.MoveToFront(e)
+		return
+	}
+	m.items[r.key] = m.ll.PushFront(r)
+	if m.ll.Len() > m.size {
+		e := m.ll.Back()
+		m.ll.Remove(e)
+		delete(m.items, e.Value.(*matchResult).key)
+	}
+}
+
+// len returns the number of cached results
+func (m *matchCache) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+// purge removes all cached results
+func (m *matchCache) purge() {
+	m.mu.Lock()
+	m.ll.Init()
+	m.items = make(map[string]*list.Element, m.size)
+	m.mu.Unlock()
+}
+
+// SetMatchCache enables caching of route match results for at most size
+// method and path pairs, it helps hot paths on gateway-style workloads.
+// The cache is purged when a route is added, size <= 0 disables it.
+func (t *Tree) SetMatchCache(size int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if size <= 0 {
+		t.cache = nil
+		return
+	}
+	t.cache = newMatchCache(size)
+}
+
+// matchCached find matched route from cache then falls back to tree, cache is
+// loaded once by Match so SetMatchCache can replace it meanwhile
+func (t *Tree) matchCached(cache *matchCache, method, pattern string, c *Context) ([]HandlerFunc, string) {
+	key := method + " " + pattern
+	if r := cache.get(key); r != nil {
+		c.pNames = append(c.pNames, r.pNames...)
+		c.pValues = append(c.pValues, r.pValues...)
+		c.routeNode = r.node
+		return r.handlers, r.name
+	}
+
+	start := len(c.pNames)
+	h, name := t.match(method, pattern, c)
+	if h == nil {
+		// not found paths are not cached, they may be arbitrary
+		return h, name
+	}
+	r := &matchResult{key: key, handlers: h, name: name, node: c.routeNode}
+	if n := len(c.pNames) - start; n > 0 {
+		r.pNames = make([]string, n)
+		r.pValues = make([]string, n)
+		copy(r.pNames, c.pNames[start:])
+		copy(r.pValues, c.pValues[start:])
+	}
+	cache.add(r)
+	return h, name
+}
+
+// SetRouteCache enables caching of route match results when the router supports it.
+func (b *Baa) SetRouteCache(size int) {
+	if r, ok := b.Router().(interface {
+		SetMatchCache(size int)
+	}); ok {
+		r.SetMatchCache(size)
+		return
+	}
+	b.Logger().Printf("router %T does not support match cache", b.Router())
+}
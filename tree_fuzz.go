@@ -0,0 +1,127 @@
+package baa
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// FuzzRouter is a fuzzing entry of route matching, it can be used by go-fuzz or
+// go test fuzzing, eg:
+//
+// 		func FuzzRouter(f *testing.F) {
+// 			f.Fuzz(func(t *testing.T, data []byte) { baa.FuzzRouter(data) })
+// 		}
+//
+// data is lines of route patterns then a blank line then lines of request paths.
+// Patterns rejected by the router are skipped, it panics when matching panics
+// or an invariant is broken, returns 1 when any path is matched otherwise 0.
+func FuzzRouter(data []byte) int {
+	patterns, paths := parseFuzzRouterData(data)
+	matched, err := fuzzRouter(patterns, paths)
+	if err != nil {
+		panic(err)
+	}
+	if matched {
+		return 1
+	}
+	return 0
+}
+
+// parseFuzzRouterData splits data into patterns and paths
+func parseFuzzRouterData(data []byte) (patterns, paths []string) {
+	parts := bytes.SplitN(data, []byte("\n\n"), 2)
+	patterns = strings.Split(string(parts[0]), "\n")
+	if len(parts) > 1 {
+		paths = strings.Split(string(parts[1]), "\n")
+	}
+	// registered patterns are requested too
+	paths = append(paths, patterns...)
+	return patterns, paths
+}
+
+// fuzzRouter registers patterns on a new tree then matches paths, it checks:
+// matching never panics, params and values are consistent with the matched
+// route pattern, and a registered static pattern matches itself.
+func fuzzRouter(patterns, paths []string) (matched bool, err error) {
+	b := New()
+	t := NewTree(b).(*Tree)
+	routes := make(map[string]bool)
+	for _, pattern := range patterns {
+		if pattern == "" || routes[pattern] {
+			continue
+		}
+		if fuzzAdd(t, pattern) {
+			routes[pattern] = true
+		}
+	}
+
+	c := NewContext(nil, nil, b)
+	for _, path := range paths {
+		if path == "" || path[0] != '/' {
+			continue
+		}
+		c.pNames = c.pNames[:0]
+		c.pValues = c.pValues[:0]
+		c.routeNode = nil
+		h, err := fuzzMatch(t, path, c)
+		if err != nil {
+			return matched, err
+		}
+		if h == nil {
+			if routes[path] && !strings.ContainsAny(path, ":*") {
+				return matched, fmt.Errorf("static route %q does not match itself", path)
+			}
+			continue
+		}
+		matched = true
+		if c.routeNode == nil {
+			return matched, fmt.Errorf("path %q matched without route node", path)
+		}
+		pattern := c.RoutePattern()
+		if !routes[pattern] {
+			return matched, fmt.Errorf("path %q matched unregistered route %q", path, pattern)
+		}
+		if len(c.pNames) != len(c.pValues) {
+			return matched, fmt.Errorf("path %q matched %q with %d names and %d values",
+				path, pattern, len(c.pNames), len(c.pValues))
+		}
+		names := paramNames(pattern)
+		if len(names) != len(c.pNames) {
+			return matched, fmt.Errorf("path %q matched %q with params %v, want %v",
+				path, pattern, c.pNames, names)
+		}
+		for i := range names {
+			if names[i] != c.pNames[i] {
+				return matched, fmt.Errorf("path %q matched %q with params %v, want %v",
+					path, pattern, c.pNames, names)
+			}
+		}
+		if routes[path] && !strings.ContainsAny(path, ":*") && pattern != path {
+			return matched, fmt.Errorf("static route %q matched %q", path, pattern)
+		}
+	}
+	return matched, nil
+}
+
+// fuzzAdd adds a GET route, returns false when the router rejects the pattern
+func fuzzAdd(t *Tree, pattern string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	t.Add("GET", pattern, []HandlerFunc{func(c *Context) {}})
+	return true
+}
+
+// fuzzMatch matches path and turns panic into error
+func fuzzMatch(t *Tree, path string, c *Context) (h []HandlerFunc, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("match %q panic: %v", path, e)
+		}
+	}()
+	h, _ = t.Match("GET", path, c)
+	return h, nil
+}
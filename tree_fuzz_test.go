@@ -0,0 +1,62 @@
+package baa
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// randomRouterData generates fuzz data of n patterns and paths
+func randomRouterData(r *rand.Rand, n int) []byte {
+	segments := []string{"a", "ab", "b", ":id", ":name", "*", "", "\xe4\xb8\xad", "\xff", "x:y"}
+	gen := func() string {
+		s := ""
+		for i := r.Intn(4); i >= 0; i-- {
+			s += "/" + segments[r.Intn(len(segments))]
+		}
+		return s
+	}
+	var data []byte
+	for i := 0; i < n; i++ {
+		data = append(data, gen()+"\n"...)
+	}
+	data = append(data, '\n')
+	for i := 0; i < n; i++ {
+		p := gen()
+		if p[len(p)-1] == '*' {
+			p = p[:len(p)-1] + "x/y"
+		}
+		data = append(data, p+"\n"...)
+	}
+	return data
+}
+
+func TestFuzzRouter1(t *testing.T) {
+	Convey("seed corpus", t, func() {
+		files, err := filepath.Glob("_fixture/fuzz/router/corpus/*")
+		So(err, ShouldBeNil)
+		So(files, ShouldNotBeEmpty)
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			So(err, ShouldBeNil)
+			_, err = fuzzRouter(parseFuzzRouterData(data))
+			So(err, ShouldBeNil)
+		}
+		data, _ := ioutil.ReadFile("_fixture/fuzz/router/corpus/routes")
+		So(FuzzRouter(data), ShouldEqual, 1)
+		So(FuzzRouter([]byte(":a\n\n/b")), ShouldEqual, 0)
+	})
+	Convey("random routes", t, func() {
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 2000; i++ {
+			data := randomRouterData(r, 1+r.Intn(6))
+			_, err := fuzzRouter(parseFuzzRouterData(data))
+			if err != nil {
+				So(string(data)+"\n"+err.Error(), ShouldBeEmpty)
+			}
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -114,6 +115,17 @@ func TestTreeRouteAdd5(t *testing.T) {
 	})
 }
 
+func TestTreeRouteURLFor1(t *testing.T) {
+	Convey("URLFor escapes param values", t, func() {
+		b2 := New()
+		b2.Get("/users/:name/posts/:id", f).Name("userPost")
+		b2.Get("/static/*path", f).Name("static")
+		So(b2.URLFor("userPost", "a b/c", "1?x"), ShouldEqual, "/users/a%20b%2Fc/posts/1%3Fx")
+		So(b2.URLFor("static", "css/a b.css"), ShouldEqual, "/static/css/a%20b.css")
+		So(b2.URLFor("userPost", "tom", 1, "?page=2"), ShouldEqual, "/users/tom/posts/1?page=2")
+	})
+}
+
 func TestTreeRouteAdd6(t *testing.T) {
 	Convey("add route with not support method", t, func() {
 		defer func() {
@@ -293,3 +305,207 @@ func TestTreeRoutePrint3(t *testing.T) {
 		}
 	})
 }
+
+func TestTreeRouteMeta1(t *testing.T) {
+	Convey("route metadata", t, func() {
+		b2 := New()
+		b2.SetAutoHead(true)
+		b2.Get("/meta", func(c *Context) {
+			c.String(200, fmt.Sprintf("%v %v", c.RouteMeta("k"), c.NoTransform()))
+		}).SetMeta("k", "v").SetMeta(RouteMetaNoTransform, true)
+		b2.Route("/meta2", "GET,POST", func(c *Context) {
+			c.String(200, fmt.Sprintf("%v %v", c.RouteMeta("k"), c.NoTransform()))
+		}).SetMeta("k", "v2")
+		b2.Group("/group", func() {
+			b2.Get("/meta", func(c *Context) {
+				c.String(200, fmt.Sprintf("%v %v", c.RouteMeta("k"), c.NoTransform()))
+			})
+		}, DisableTransform)
+		b2.Get("/header", func(c *Context) {
+			c.Resp.Header().Set("Cache-Control", "no-transform")
+			c.String(200, fmt.Sprint(c.NoTransform()))
+		})
+
+		for _, v := range [][]string{
+			{"GET", "/meta", "v true"},
+			{"HEAD", "/meta", "v true"},
+			{"POST", "/meta2", "v2 false"},
+			{"GET", "/group/meta", "<nil> true"},
+			{"GET", "/header", "true"},
+		} {
+			req, _ := http.NewRequest(v[0], v[1], nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, v[2])
+		}
+
+		n := New().Any("/meta3", f).SetMeta("k", 1)
+		So(n.Meta("k"), ShouldEqual, 1)
+		So(routeNodes{}.Meta("k"), ShouldBeNil)
+
+		// nodes of routers without metadata support are wrapped, changes panic
+		plain := metaNode(plainRouteNode{})
+		So(func() { plain.SetMeta("k", 1) }, ShouldPanic)
+		So(func() { plain.Use(nil) }, ShouldPanic)
+		So(plain.Meta("k"), ShouldBeNil)
+		So(plain.Pattern(), ShouldEqual, "")
+		So(plain.ParamIndex("id"), ShouldEqual, -1)
+	})
+}
+
+// plainRouteNode is a RouteNode supports only Name
+type plainRouteNode struct{}
+
+func (plainRouteNode) Name(name string) {}
+
+func TestTreeMatchCache1(t *testing.T) {
+	Convey("match result cache", t, func() {
+		b2 := New()
+		b2.SetRouteCache(2)
+		r2 := b2.Router().(*Tree)
+		b2.Get("/users/:id", f).Name("user")
+		b2.Get("/ping", f)
+		c2 := NewContext(nil, nil, b2)
+
+		h, name := r2.Match("GET", "/users/1", c2)
+		So(h, ShouldNotBeNil)
+		So(name, ShouldEqual, "user")
+		So(r2.cache.len(), ShouldEqual, 1)
+
+		c2.Reset(nil, nil)
+		h, name = r2.Match("GET", "/users/1", c2)
+		So(h, ShouldNotBeNil)
+		So(name, ShouldEqual, "user")
+		So(c2.Param("id"), ShouldEqual, "1")
+		So(c2.RoutePattern(), ShouldEqual, "/users/:id")
+
+		// not found is not cached
+		h, _ = r2.Match("GET", "/none", c2)
+		So(h, ShouldBeNil)
+		So(r2.cache.len(), ShouldEqual, 1)
+
+		// least recently used is evicted
+		r2.Match("GET", "/ping", c2)
+		r2.Match("GET", "/users/2", c2)
+		So(r2.cache.len(), ShouldEqual, 2)
+		So(r2.cache.get("GET /users/1"), ShouldBeNil)
+
+		// route table changed
+		b2.Get("/users/me", f)
+		So(r2.cache.len(), ShouldEqual, 0)
+		c2.Reset(nil, nil)
+		h, _ = r2.Match("GET", "/users/me", c2)
+		So(h, ShouldNotBeNil)
+		So(c2.Param("id"), ShouldEqual, "")
+
+		b2.SetRouteCache(0)
+		So(r2.cache, ShouldBeNil)
+
+		// cache can be toggled while matching
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c := NewContext(nil, nil, b2)
+			for i := 0; i < 1000; i++ {
+				c.Reset(nil, nil)
+				r2.Match("GET", "/users/1", c)
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			b2.SetRouteCache(i % 2)
+		}
+		<-done
+	})
+}
+
+func TestTreeRouteUse1(t *testing.T) {
+	Convey("route middlewares", t, func() {
+		b2 := New()
+		trace := ""
+		mw := func(name string) HandlerFunc {
+			return func(c *Context) {
+				trace += name
+				c.Next()
+			}
+		}
+		b2.Use(mw("g"))
+		b2.Get("/admin", func(c *Context) {
+			c.String(http.StatusOK, trace+"h")
+		}).Use(mw("a"), mw("b")).Use(mw("c"))
+		b2.Group("/api", func() {
+			b2.Get("/users", func(c *Context) {
+				c.String(http.StatusOK, trace+"h")
+			}).Use(mw("a"))
+		}, mw("p"))
+		b2.Get("/public", func(c *Context) {
+			c.String(http.StatusOK, trace+"h")
+		})
+		admin := b2.NewGroup("/manage", mw("x"))
+		admin.Use(mw("y"))
+		admin.Get("/users/", func(c *Context) {
+			c.String(http.StatusOK, trace+"h")
+		}).Use(mw("a"))
+		admin.Group("/posts", mw("z")).Route("/:id", "GET,POST", func(c *Context) {
+			c.String(http.StatusOK, trace+"h")
+		}).Use(mw("a"))
+
+		w := serveTo(b2, "/admin")
+		So(w.Body.String(), ShouldEqual, "gabch")
+		trace = ""
+		w = serveTo(b2, "/api/users")
+		So(w.Body.String(), ShouldEqual, "gpah")
+		trace = ""
+		w = serveTo(b2, "/public")
+		So(w.Body.String(), ShouldEqual, "gh")
+		trace = ""
+		w = serveTo(b2, "/manage/users/")
+		So(w.Body.String(), ShouldEqual, "gxyah")
+		trace = ""
+		w = serveTo(b2, "/manage/posts/1")
+		So(w.Body.String(), ShouldEqual, "gxyzah")
+	})
+}
+
+func TestTreeRouteParams1(t *testing.T) {
+	Convey("named wide, optional and regexp params", t, func() {
+		b2 := New()
+		echo := func(c *Context) {
+			c.String(http.StatusOK, c.RoutePattern()+" "+fmt.Sprint(c.Params()))
+		}
+		b2.Get("/files/*filepath", echo).Name("file")
+		b2.Get("/user/:id(\\d+)", func(c *Context) {
+			c.String(http.StatusOK, strconv.Itoa(c.ParamInt("id")*2))
+		})
+		b2.Get("/user/:id(\\d+)/posts", echo)
+		b2.Get("/archive/:year?/:month?", echo).Name("archive")
+		b2.Get("/tag/:name([a-z]+)", echo)
+		b2.Get("/tag/*", echo)
+
+		So(serveTo(b2, "/files/a/b.txt").Body.String(), ShouldEqual, "/files/*filepath map[filepath:a/b.txt]")
+		So(b2.URLFor("file", "a/b.txt"), ShouldEqual, "/files/a/b.txt")
+
+		So(serveTo(b2, "/user/21").Body.String(), ShouldEqual, "42")
+		So(serveTo(b2, "/user/baa").Code, ShouldEqual, http.StatusNotFound)
+		So(serveTo(b2, "/user/21/posts").Code, ShouldEqual, http.StatusOK)
+		So(serveTo(b2, "/user/2a/posts").Code, ShouldEqual, http.StatusNotFound)
+
+		So(serveTo(b2, "/archive/2020/05").Body.String(), ShouldEqual, "/archive/:year/:month map[month:05 year:2020]")
+		So(serveTo(b2, "/archive/2020").Body.String(), ShouldEqual, "/archive/:year map[year:2020]")
+		So(serveTo(b2, "/archive").Body.String(), ShouldEqual, "/archive map[]")
+		So(b2.URLFor("archive", 2020, 5), ShouldEqual, "/archive/2020/5")
+
+		So(serveTo(b2, "/tag/go").Body.String(), ShouldEqual, "/tag/:name([a-z]+) map[name:go]")
+		So(serveTo(b2, "/tag/Go1").Body.String(), ShouldEqual, "/tag/* map[:Go1]")
+
+		So(b2.Router().Routes()["GET"], ShouldContain, "/user/:id(\\d+)")
+		So(func() { b2.Get("/user/:id(\\w+)/edit", echo) }, ShouldPanic)
+		So(func() { b2.Get("/opt/:a?/b", echo) }, ShouldPanic)
+		So(func() { b2.Get("/bad/:id([)", echo) }, ShouldPanic)
+
+		// static patterns are ASCII, other bytes in paths never match
+		So(func() { b2.Get("/\u7528\u6237", echo) }, ShouldPanic)
+		So(serveTo(b2, "/\xffuser").Code, ShouldEqual, http.StatusNotFound)
+		So(serveTo(b2, "/user\xff").Code, ShouldEqual, http.StatusNotFound)
+	})
+}
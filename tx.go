@@ -0,0 +1,85 @@
+package baa
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+)
+
+// txKey context store key of request transaction
+const txKey = "baa.tx"
+
+// TxBeginner begins a database transaction, *sql.DB implements it.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Transaction returns a middleware begins a transaction by the DI registered with name,
+// the transaction can be fetched by c.Tx() in handlers. The response is buffered and
+// sent after the transaction is finished: it commits when the response status is
+// less than 400, otherwise rolls back, a failed commit is passed to the error handler
+// instead of the buffered response. It rolls back and panics again when handlers panic.
+//
+// Example:
+// 		b.SetDI("db", db)
+// 		b.Post("/orders", baa.Transaction("db", nil), createOrder)
+func Transaction(name string, opts *sql.TxOptions) HandlerFunc {
+	return func(c *Context) {
+		db, ok := c.DI(name).(TxBeginner)
+		if !ok {
+			panic("baa.Transaction DI " + name + " must be implement interface baa.TxBeginner")
+		}
+		tx, err := db.BeginTx(c.Req.Context(), opts)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.Set(txKey, tx)
+
+		buf := &bufferedWriter{
+			header: c.Resp.Header(),
+			code:   http.StatusOK,
+			body:   getBuffer(),
+		}
+		defer putBuffer(buf.body)
+		resp, writer, wrapped := c.Resp.resp, c.Resp.writer, c.Resp.wrapped
+		c.Resp.resp, c.Resp.writer = buf, buf
+		done := false
+		defer func() {
+			if !done {
+				// the buffered response is discarded, the panic is answered by recovery
+				c.Resp.resp, c.Resp.writer, c.Resp.wrapped = resp, writer, wrapped
+				c.Resp.wroteHeader = false
+				c.Resp.status = http.StatusOK
+				c.Resp.written = 0
+				tx.Rollback()
+			}
+		}()
+
+		c.Next()
+
+		done = true
+		c.Resp.resp, c.Resp.writer, c.Resp.wrapped = resp, writer, wrapped
+		if c.Resp.Status() >= 400 || c.Resp.hijacked {
+			tx.Rollback()
+		} else if err := tx.Commit(); err != nil {
+			c.Resp.wroteHeader = false
+			c.Resp.status = http.StatusOK
+			c.Resp.written = 0
+			c.Error(err)
+			return
+		}
+		if c.Resp.hijacked || !buf.wrote {
+			return
+		}
+		resp.WriteHeader(buf.code)
+		n, _ := writer.Write(buf.body.Bytes())
+		c.Resp.written = int64(n)
+	}
+}
+
+// Tx returns the transaction of current request began by Transaction middleware
+func (c *Context) Tx() *sql.Tx {
+	tx, _ := c.Get(txKey).(*sql.Tx)
+	return tx
+}
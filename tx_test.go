@@ -0,0 +1,82 @@
+package baa
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// txDriver is a fake sql driver counts transactions
+type txDriver struct {
+	commits, rollbacks int
+	commitErr          error
+}
+
+func (d *txDriver) Open(name string) (driver.Conn, error)            { return &txConn{d}, nil }
+func (d *txDriver) Connect(ctx context.Context) (driver.Conn, error) { return &txConn{d}, nil }
+func (d *txDriver) Driver() driver.Driver                            { return d }
+
+type txConn struct{ d *txDriver }
+
+func (c *txConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *txConn) Close() error                              { return nil }
+func (c *txConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *txConn) Commit() error                             { c.d.commits++; return c.d.commitErr }
+func (c *txConn) Rollback() error                           { c.d.rollbacks++; return nil }
+
+func TestTransaction1(t *testing.T) {
+	Convey("transaction middleware", t, func() {
+		d := new(txDriver)
+		db := sql.OpenDB(d)
+		defer db.Close()
+
+		b2 := New()
+		b2.SetDI("db", db)
+		var tx *sql.Tx
+		b2.Get("/ok", Transaction("db", nil), func(c *Context) {
+			tx = c.Tx()
+			c.String(200, "ok")
+		})
+		b2.Get("/fail", Transaction("db", nil), func(c *Context) {
+			c.String(400, "fail")
+		})
+		b2.Get("/panic", Transaction("db", nil), func(c *Context) {
+			panic("boom")
+		})
+		b2.Get("/nodb", Transaction("none", nil), f)
+
+		serve := func(uri string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", uri, nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+
+		So(serve("/ok").Code, ShouldEqual, http.StatusOK)
+		So(tx != nil, ShouldBeTrue)
+		So(d.commits, ShouldEqual, 1)
+		So(d.rollbacks, ShouldEqual, 0)
+
+		So(serve("/fail").Code, ShouldEqual, http.StatusBadRequest)
+		So(d.commits, ShouldEqual, 1)
+		So(d.rollbacks, ShouldEqual, 1)
+
+		So(serve("/panic").Code, ShouldEqual, http.StatusInternalServerError)
+		So(d.rollbacks, ShouldEqual, 2)
+
+		So(serve("/nodb").Code, ShouldEqual, http.StatusInternalServerError)
+		So(c.Tx(), ShouldBeNil)
+
+		d.commitErr = errors.New("serialization failure")
+		w := serve("/ok")
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		So(w.Body.String(), ShouldNotContainSubstring, "ok")
+		So(d.commits, ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,146 @@
+package baa
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTypedValueInvalid is returned when a stored typed value can not be decoded
+var ErrTypedValueInvalid = errors.New("baa: invalid typed value")
+
+// Codec encodes and decodes structured values
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec encodes values in JSON by the JSON implementation of baa
+var JSONCodec Codec = jsonCodec{}
+
+// GobCodec encodes values by encoding/gob, types stored in interfaces must be
+// registered by gob.Register.
+var GobCodec Codec = gobCodec{}
+
+// jsonCodec is the codec of JSON
+type jsonCodec struct{}
+
+// Marshal encodes v in JSON
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return Marshal(v)
+}
+
+// Unmarshal decodes JSON data into v
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return Unmarshal(data, v)
+}
+
+// gobCodec is the codec of gob
+type gobCodec struct{}
+
+// Marshal encodes v by gob
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes gob data into v
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// KVStore is a key value storage typed values are kept in, eg: a session
+type KVStore interface {
+	// Get returns value of key, nil when not found
+	Get(key string) interface{}
+	Set(key string, v interface{})
+	Delete(key string)
+}
+
+// TypedStore keeps structured values in a KVStore encoded by a codec with per-key
+// ttl, values are stored as strings so stores can serialize them in any format.
+//
+// Example:
+// 		type Cart struct {
+// 			Items map[string]int
+// 		}
+// 		carts := baa.NewTypedStore(store, baa.JSONCodec)
+// 		carts.Set("cart", &Cart{Items: items}, 24*time.Hour)
+// 		var cart Cart
+// 		ok, err := carts.Get("cart", &cart)
+type TypedStore struct {
+	kv    KVStore
+	codec Codec
+	clock Clock
+}
+
+// NewTypedStore create a typed store of kv, codec is JSONCodec when it's nil
+func NewTypedStore(kv KVStore, codec Codec) *TypedStore {
+	if kv == nil {
+		panic("baa.NewTypedStore kv can not be nil")
+	}
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return &TypedStore{kv: kv, codec: codec, clock: SystemClock}
+}
+
+// SetClock set the clock used by ttl
+func (s *TypedStore) SetClock(c Clock) {
+	s.clock = c
+}
+
+// Set encodes v and stores it as key, it expires after ttl, 0 means never
+func (s *TypedStore) Set(key string, v interface{}, ttl time.Duration) error {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var expires int64
+	if ttl > 0 {
+		expires = s.clock.Now().Add(ttl).UnixNano()
+	}
+	s.kv.Set(key, strconv.FormatInt(expires, 10)+":"+base64.RawURLEncoding.EncodeToString(data))
+	return nil
+}
+
+// Get decodes the value of key into v, it returns false when the key does not exist
+// or expired, expired keys are deleted.
+func (s *TypedStore) Get(key string, v interface{}) (bool, error) {
+	raw, ok := s.kv.Get(key).(string)
+	if !ok {
+		return false, nil
+	}
+	i := strings.IndexByte(raw, ':')
+	if i < 0 {
+		return false, ErrTypedValueInvalid
+	}
+	expires, err := strconv.ParseInt(raw[:i], 10, 64)
+	if err != nil {
+		return false, ErrTypedValueInvalid
+	}
+	if expires > 0 && s.clock.Now().UnixNano() >= expires {
+		s.kv.Delete(key)
+		return false, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw[i+1:])
+	if err != nil {
+		return false, ErrTypedValueInvalid
+	}
+	if err = s.codec.Unmarshal(data, v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes key
+func (s *TypedStore) Delete(key string) {
+	s.kv.Delete(key)
+}
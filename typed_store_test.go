@@ -0,0 +1,70 @@
+package baa
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// mapKV is a KVStore of map
+type mapKV map[string]interface{}
+
+func (m mapKV) Get(key string) interface{}    { return m[key] }
+func (m mapKV) Set(key string, v interface{}) { m[key] = v }
+func (m mapKV) Delete(key string)             { delete(m, key) }
+
+// testCart is a structured value of typed store tests
+type testCart struct {
+	Items map[string]int
+	Note  string
+}
+
+func TestTypedStore1(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec, GobCodec} {
+		Convey("typed values", t, func() {
+			kv := make(mapKV)
+			s := NewTypedStore(kv, codec)
+			clock := NewFakeClock(time.Now())
+			s.SetClock(clock)
+
+			So(s.Set("cart", &testCart{Items: map[string]int{"apple": 2}, Note: "gift"}, time.Hour), ShouldBeNil)
+			So(s.Set("visits", 3, 0), ShouldBeNil)
+			So(kv["cart"], ShouldHaveSameTypeAs, "")
+
+			var cart testCart
+			ok, err := s.Get("cart", &cart)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			So(cart.Items["apple"], ShouldEqual, 2)
+			So(cart.Note, ShouldEqual, "gift")
+
+			clock.Advance(time.Hour)
+			ok, err = s.Get("cart", &cart)
+			So(ok, ShouldBeFalse)
+			So(err, ShouldBeNil)
+			So(kv, ShouldNotContainKey, "cart")
+
+			var visits int
+			ok, _ = s.Get("visits", &visits)
+			So(ok, ShouldBeTrue)
+			So(visits, ShouldEqual, 3)
+			s.Delete("visits")
+			ok, _ = s.Get("visits", &visits)
+			So(ok, ShouldBeFalse)
+
+			kv["bad"] = "x"
+			_, err = s.Get("bad", &visits)
+			So(err == ErrTypedValueInvalid, ShouldBeTrue)
+			kv["bad"] = "0:!"
+			_, err = s.Get("bad", &visits)
+			So(err == ErrTypedValueInvalid, ShouldBeTrue)
+			So(s.Set("bad", make(chan int), 0), ShouldNotBeNil)
+		})
+	}
+
+	Convey("invalid store", t, func() {
+		So(func() { NewTypedStore(nil, nil) }, ShouldPanic)
+		So(NewTypedStore(make(mapKV), nil).codec == JSONCodec, ShouldBeTrue)
+	})
+}
@@ -0,0 +1,387 @@
+package baa
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError is a validation error of a field
+type FieldError struct {
+	Field   string `json:"field"`   // field path, from tag json, form or field name, eg: items[0].name
+	Rule    string `json:"rule"`    // failed rule, eg: required, min
+	Message string `json:"message"` // error message
+}
+
+// ValidationError is returned when bound data is invalid, it's rendered as 422
+// by the default error handler with field-level messages.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error implements error interface
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// FieldErrors returns error messages keyed by field
+func (e *ValidationError) FieldErrors() map[string]string {
+	m := make(map[string]string, len(e.Fields))
+	for _, f := range e.Fields {
+		if _, ok := m[f.Field]; !ok {
+			m[f.Field] = f.Message
+		}
+	}
+	return m
+}
+
+// ValidationRule checks field value v with rule param
+type ValidationRule func(v reflect.Value, param string) bool
+
+// validationRule is a registered rule with its message
+type validationRule struct {
+	check   ValidationRule
+	message string
+}
+
+// format returns the message of rule with param
+func (r validationRule) format(param string) string {
+	if strings.Contains(r.message, "%s") {
+		return fmt.Sprintf(r.message, param)
+	}
+	return r.message
+}
+
+// ruleMessage returns the message of rule name with param
+func (t *TagValidator) ruleMessage(name, param string) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.rules[name].format(param)
+}
+
+// TagValidator validates struct fields by tag `valid`, rules are separated by comma,
+// eg: `valid:"required,email"`, `valid:"omitempty,min=3,max=20"`. Validation is off
+// by default, enable it by b.SetValidator(baa.NewTagValidator()).
+//
+// Built-in rules: required, omitempty, email, url, alphanum, numeric, oneof=a b,
+// and min, max, len which compare numbers by value and others by length.
+// Nested structs, pointers and slices of structs are validated recursively.
+// Tags of a struct type are checked when the type is first seen, unknown rules
+// are returned as an error by Validate.
+type TagValidator struct {
+	rules   map[string]validationRule
+	mu      sync.RWMutex
+	checked map[reflect.Type]error
+}
+
+var (
+	validEmailRegexp    = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	validAlphanumRegexp = regexp.MustCompile(`^[a-zA-Z0-9]*$`)
+	validNumericRegexp  = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?$`)
+)
+
+// NewTagValidator create a tag validator with built-in rules
+func NewTagValidator() *TagValidator {
+	t := &TagValidator{rules: make(map[string]validationRule), checked: make(map[reflect.Type]error)}
+	t.RegisterRule("required", func(v reflect.Value, _ string) bool {
+		return !isZeroValue(v)
+	}, "is required")
+	t.RegisterRule("email", func(v reflect.Value, _ string) bool {
+		return validEmailRegexp.MatchString(fmt.Sprint(v.Interface()))
+	}, "must be a valid email address")
+	t.RegisterRule("url", func(v reflect.Value, _ string) bool {
+		u, err := url.Parse(fmt.Sprint(v.Interface()))
+		return err == nil && u.Scheme != "" && u.Host != ""
+	}, "must be a valid URL")
+	t.RegisterRule("alphanum", func(v reflect.Value, _ string) bool {
+		return validAlphanumRegexp.MatchString(fmt.Sprint(v.Interface()))
+	}, "must contain only letters and numbers")
+	t.RegisterRule("numeric", func(v reflect.Value, _ string) bool {
+		return validNumericRegexp.MatchString(fmt.Sprint(v.Interface()))
+	}, "must be numeric")
+	t.RegisterRule("oneof", func(v reflect.Value, param string) bool {
+		s := fmt.Sprint(v.Interface())
+		for _, o := range strings.Fields(param) {
+			if s == o {
+				return true
+			}
+		}
+		return false
+	}, "must be one of [%s]")
+	t.RegisterRule("min", func(v reflect.Value, param string) bool {
+		n, ok := validSize(v)
+		min, err := strconv.ParseFloat(param, 64)
+		return !ok || err != nil || n >= min
+	}, "must be at least %s")
+	t.RegisterRule("max", func(v reflect.Value, param string) bool {
+		n, ok := validSize(v)
+		max, err := strconv.ParseFloat(param, 64)
+		return !ok || err != nil || n <= max
+	}, "must be at most %s")
+	t.RegisterRule("len", func(v reflect.Value, param string) bool {
+		n, ok := validSize(v)
+		l, err := strconv.ParseFloat(param, 64)
+		return !ok || err != nil || n == l
+	}, "must have length %s")
+	return t
+}
+
+// RegisterRule registers or replaces a rule, message can contain %s for the rule param.
+// Rules should be registered before the validator is used.
+//
+// Example:
+// 		v := baa.NewTagValidator()
+// 		v.RegisterRule("even", func(v reflect.Value, _ string) bool {
+// 			return v.Int()%2 == 0
+// 		}, "must be even")
+// 		b.SetValidator(v)
+func (t *TagValidator) RegisterRule(name string, check ValidationRule, message string) {
+	t.mu.Lock()
+	t.rules[name] = validationRule{check: check, message: message}
+	t.checked = make(map[reflect.Type]error)
+	t.mu.Unlock()
+}
+
+// Validate validates struct v, it returns *ValidationError when fields are invalid
+func (t *TagValidator) Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	if err := t.checkType(rv.Type()); err != nil {
+		return err
+	}
+	var fields []FieldError
+	t.validateStruct(rv, "", &fields)
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// checkType checks tags of struct type rt and the structs it contains, the result
+// is cached so tags are parsed once per type.
+func (t *TagValidator) checkType(rt reflect.Type) error {
+	t.mu.RLock()
+	err, ok := t.checked[rt]
+	t.mu.RUnlock()
+	if ok {
+		return err
+	}
+	err = t.checkTags(rt, make(map[reflect.Type]bool))
+	t.mu.Lock()
+	t.checked[rt] = err
+	t.mu.Unlock()
+	return err
+}
+
+// checkTags returns an error for the first unknown rule in tags of rt
+func (t *TagValidator) checkTags(rt reflect.Type, seen map[reflect.Type]bool) error {
+	for rt.Kind() == reflect.Ptr || rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct || rt == timeType || seen[rt] {
+		return nil
+	}
+	seen[rt] = true
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("valid")
+		if tag == "-" || (sf.PkgPath != "" && !sf.Anonymous) {
+			continue
+		}
+		if tag != "" {
+			if err := t.checkRules(tag); err != nil {
+				return fmt.Errorf("%v of field %s.%s", err, rt, sf.Name)
+			}
+		}
+		if err := t.checkTags(sf.Type, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRules returns an error for the first unknown rule in tag
+func (t *TagValidator) checkRules(tag string) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, r := range strings.Split(tag, ",") {
+		r = strings.TrimSpace(r)
+		if i := strings.IndexByte(r, '='); i >= 0 {
+			r = r[:i]
+		}
+		if r == "" || r == "omitempty" {
+			continue
+		}
+		if _, ok := t.rules[r]; !ok {
+			return fmt.Errorf("baa.TagValidator unknown rule %q", r)
+		}
+	}
+	return nil
+}
+
+// validateStruct validates fields of struct rv, errors are appended with field prefix
+func (t *TagValidator) validateStruct(rv reflect.Value, prefix string, fields *[]FieldError) {
+	if rv.Kind() != reflect.Struct || rv.Type() == timeType {
+		return
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		tag := sf.Tag.Get("valid")
+		if tag == "-" || (sf.PkgPath != "" && !sf.Anonymous) {
+			continue
+		}
+		if sf.Anonymous && fv.Kind() == reflect.Struct {
+			t.validateStruct(fv, prefix, fields)
+			continue
+		}
+		name := prefix + validFieldName(sf)
+		if tag != "" {
+			t.validateField(fv, name, tag, fields)
+		}
+		t.validateNested(fv, name, fields)
+	}
+}
+
+// validateNested validates structs in field fv
+func (t *TagValidator) validateNested(fv reflect.Value, name string, fields *[]FieldError) {
+	for fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return
+		}
+		fv = fv.Elem()
+	}
+	switch fv.Kind() {
+	case reflect.Struct:
+		t.validateStruct(fv, name+".", fields)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			t.validateNested(fv.Index(i), name+"["+strconv.Itoa(i)+"]", fields)
+		}
+	}
+}
+
+// validateField checks rules in tag of field fv, it stops at the first failed rule
+func (t *TagValidator) validateField(fv reflect.Value, name, tag string, fields *[]FieldError) {
+	rules := strings.Split(tag, ",")
+	zero := isZeroValue(fv)
+	for _, r := range rules {
+		if r == "omitempty" && zero {
+			return
+		}
+	}
+	for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+	for _, r := range rules {
+		r = strings.TrimSpace(r)
+		if r == "" || r == "omitempty" {
+			continue
+		}
+		param := ""
+		if i := strings.IndexByte(r, '='); i >= 0 {
+			r, param = r[:i], r[i+1:]
+		}
+		t.mu.RLock()
+		rule, ok := t.rules[r]
+		t.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		if !rule.check(fv, param) {
+			*fields = append(*fields, FieldError{Field: name, Rule: r, Message: rule.format(param)})
+			return
+		}
+	}
+}
+
+// validFieldName returns field name in errors, from tag json, form or field name
+func validFieldName(sf reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		if name := strings.Split(sf.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+// validSize returns number value or length of v used by min, max and len
+func validSize(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+// isZeroValue checks v is the zero value of its type
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil() || (v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface && v.Len() == 0)
+	case reflect.String:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// BindValidate binds request data into struct v like Bind, conversion errors of
+// fields are returned as *ValidationError along with failed rules, so the error
+// handler responds 422 with field-level messages. Rules are checked by the app
+// validator set by SetValidator.
+//
+// Example:
+// 		b.SetValidator(baa.NewTagValidator())
+// 		type Signup struct {
+// 			Email string `json:"email" valid:"required,email"`
+// 			Name  string `json:"name" valid:"required,min=3"`
+// 		}
+// 		if err := c.BindValidate(&signup); err != nil {
+// 			c.Error(err)
+// 			return
+// 		}
+func (c *Context) BindValidate(v interface{}) error {
+	err := c.Bind(v)
+	if e, ok := err.(*BindError); ok {
+		name := e.Key
+		if rv := reflect.Indirect(reflect.ValueOf(v)); rv.Kind() == reflect.Struct {
+			if sf, ok := rv.Type().FieldByName(e.Field); ok {
+				name = validFieldName(sf)
+			}
+		}
+		return &ValidationError{Fields: []FieldError{{Field: name, Rule: "type", Message: "is invalid: " + e.Err.Error()}}}
+	}
+	return err
+}
+
+// validationProblem converts validation error to problem details with field errors
+func validationProblem(e *ValidationError) *Problem {
+	return NewProblem(http.StatusUnprocessableEntity, "validation failed").With("errors", e.Fields)
+}
@@ -0,0 +1,126 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type validAddress struct {
+	City string `json:"city" valid:"required"`
+}
+
+type validSignup struct {
+	Email     string          `json:"email" valid:"required,email"`
+	Name      string          `json:"name" valid:"required,min=3,max=8"`
+	Age       int             `json:"age" valid:"min=18"`
+	Role      string          `json:"role" valid:"omitempty,oneof=admin user"`
+	Website   string          `json:"website" valid:"omitempty,url"`
+	Tags      []string        `json:"tags" valid:"max=2"`
+	Code      *string         `json:"code" valid:"omitempty,len=4,numeric"`
+	Address   validAddress    `json:"address"`
+	Addresses []*validAddress `json:"addresses"`
+	Even      int             `form:"even" valid:"even"`
+}
+
+func TestTagValidator1(t *testing.T) {
+	Convey("validate struct tags", t, func() {
+		v := NewTagValidator()
+		v.RegisterRule("even", func(v reflect.Value, _ string) bool {
+			return v.Int()%2 == 0
+		}, "must be even")
+		code := "12a4"
+		s := &validSignup{
+			Email:     "baa@example",
+			Name:      "go",
+			Age:       17,
+			Role:      "root",
+			Website:   "example.com",
+			Tags:      []string{"a", "b", "c"},
+			Code:      &code,
+			Addresses: []*validAddress{{City: "x"}, {}},
+			Even:      1,
+		}
+		err := v.Validate(s)
+		So(err, ShouldNotBeNil)
+		e := err.(*ValidationError)
+		So(e.Fields, ShouldResemble, []FieldError{
+			{"email", "email", "must be a valid email address"},
+			{"name", "min", "must be at least 3"},
+			{"age", "min", "must be at least 18"},
+			{"role", "oneof", "must be one of [admin user]"},
+			{"website", "url", "must be a valid URL"},
+			{"tags", "max", "must be at most 2"},
+			{"code", "numeric", "must be numeric"},
+			{"address.city", "required", "is required"},
+			{"addresses[1].city", "required", "is required"},
+			{"even", "even", "must be even"},
+		})
+		So(e.FieldErrors()["name"], ShouldEqual, "must be at least 3")
+		So(e.Error(), ShouldStartWith, "validation failed: email must be a valid email address; name")
+
+		code = "1234"
+		s = &validSignup{Email: "baa@example.com", Name: "baa", Age: 18, Code: &code, Address: validAddress{City: "x"}}
+		So(v.Validate(s), ShouldBeNil)
+		So(v.Validate(nil), ShouldBeNil)
+		type badRule struct {
+			A int `valid:"min=1,bad"`
+		}
+		err = v.Validate(&[]badRule{{}})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, `unknown rule "bad" of field baa.badRule.A`)
+		_, ok := err.(*ValidationError)
+		So(ok, ShouldBeFalse)
+		v.RegisterRule("bad", func(v reflect.Value, _ string) bool { return false }, "is bad")
+		So(v.Validate(&badRule{A: 1}).Error(), ShouldEqual, "validation failed: A is bad")
+	})
+}
+
+func TestBindValidate1(t *testing.T) {
+	Convey("bind and validate", t, func() {
+		b2 := New()
+		b2.SetValidator(NewTagValidator())
+		b2.Post("/signup/:age", func(c *Context) {
+			var s struct {
+				Age   int    `param:"age" json:"age" valid:"min=18"`
+				Email string `json:"email" valid:"required,email"`
+			}
+			if err := c.BindValidate(&s); err != nil {
+				c.Error(err)
+				return
+			}
+			c.String(http.StatusOK, "ok")
+		})
+		post := func(uri, body string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("POST", uri, strings.NewReader(body))
+			req.Header.Set("Content-Type", ApplicationJSON)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			return w
+		}
+		So(post("/signup/20", `{"email": "baa@example.com"}`).Body.String(), ShouldEqual, "ok")
+
+		w := post("/signup/10", `{"email": "baa"}`)
+		So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+		So(w.Body.String(), ShouldContainSubstring, "age must be at least 18; email must be a valid email address")
+
+		w = post("/signup/x", `{}`)
+		So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+		So(w.Body.String(), ShouldContainSubstring, "age is invalid")
+
+		b2.SetProblemDetails(true)
+		w = post("/signup/10", `{"email": "baa@example.com"}`)
+		So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+		var p struct {
+			Status int
+			Errors []FieldError
+		}
+		So(Unmarshal(w.Body.Bytes(), &p), ShouldBeNil)
+		So(p.Status, ShouldEqual, http.StatusUnprocessableEntity)
+		So(p.Errors, ShouldResemble, []FieldError{{"age", "min", "must be at least 18"}})
+	})
+}
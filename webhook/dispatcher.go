@@ -0,0 +1,371 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-baa/baa"
+)
+
+var (
+	// ErrClosed is returned by Publish after the dispatcher is closed
+	ErrClosed = errors.New("webhook: dispatcher is closed")
+	// ErrQueueFull is returned by Publish when the delivery queue has no room for
+	// deliveries to all subscribers, none is queued then.
+	ErrQueueFull = errors.New("webhook: delivery queue is full")
+)
+
+// Subscriber is a receiver of outbound webhooks
+type Subscriber struct {
+	ID  string
+	URL string
+	// Secret signs payloads, deliveries are not signed when empty
+	Secret string
+	// Events are event names subscribed, all events are subscribed when empty
+	Events []string
+}
+
+// Delivery is an event delivery to a subscriber
+type Delivery struct {
+	ID         string
+	Event      string
+	Payload    []byte
+	Subscriber Subscriber
+	Attempts   int
+}
+
+// DispatcherOptions outbound webhook dispatcher config
+type DispatcherOptions struct {
+	// Client sends deliveries, default is the outbound http client of application
+	Client *http.Client
+	// Workers number of concurrent deliveries, default is 4
+	Workers int
+	// QueueSize capacity of delivery queue, default is 1024
+	QueueSize int
+	// MaxAttempts maximum attempts of a delivery, default is 5
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled by each retry up to
+	// MaxBackoff, default is 1 second and 1 minute.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// DeadLetter is called with deliveries failed all attempts or not finished
+	// when closed, they are logged when nil.
+	DeadLetter func(d *Delivery, err error)
+}
+
+// Dispatcher delivers events to subscribers in background, payloads are signed
+// and failed deliveries are retried with exponential backoff.
+//
+// Requests have headers X-Webhook-ID, X-Webhook-Event, X-Webhook-Timestamp and
+// X-Webhook-Signature, the signature is sha256= followed by hex encoded HMAC-SHA256
+// of timestamp, "." and body, it's verified by provider Signed.
+type Dispatcher struct {
+	opt     DispatcherOptions
+	app     *baa.Baa
+	subs    []Subscriber
+	queue   chan *Delivery
+	retries map[*Delivery]*time.Timer
+	stop    chan struct{}
+	closed  bool
+	stopped bool
+	pending sync.WaitGroup
+	workers sync.WaitGroup
+	mu      sync.RWMutex
+	// publish serializes Publish calls reserving room in queue
+	publish sync.Mutex
+	once    sync.Once
+}
+
+// NewDispatcher create an outbound webhook dispatcher of application b, it's closed
+// by b.Shutdown.
+//
+// Example:
+// 		d := webhook.NewDispatcher(b, webhook.DispatcherOptions{})
+// 		d.Subscribe(webhook.Subscriber{ID: "1", URL: "https://example.com/hook", Secret: secret})
+// 		d.Publish("order.created", order)
+func NewDispatcher(b *baa.Baa, opt DispatcherOptions) *Dispatcher {
+	if opt.Client == nil {
+		opt.Client = b.HTTPClient().Client()
+	}
+	if opt.Workers <= 0 {
+		opt.Workers = 4
+	}
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = 1024
+	}
+	if opt.MaxAttempts <= 0 {
+		opt.MaxAttempts = 5
+	}
+	if opt.Backoff <= 0 {
+		opt.Backoff = time.Second
+	}
+	if opt.MaxBackoff <= 0 {
+		opt.MaxBackoff = time.Minute
+	}
+	if opt.DeadLetter == nil {
+		opt.DeadLetter = func(d *Delivery, err error) {
+			b.Logger().Printf("webhook: delivery %s of %s to %s failed after %d attempts: %v",
+				d.ID, d.Event, d.Subscriber.URL, d.Attempts, err)
+		}
+	}
+	d := &Dispatcher{
+		opt:     opt,
+		app:     b,
+		queue:   make(chan *Delivery, opt.QueueSize),
+		retries: make(map[*Delivery]*time.Timer),
+		stop:    make(chan struct{}),
+	}
+	for i := 0; i < opt.Workers; i++ {
+		d.workers.Add(1)
+		go d.work()
+	}
+	b.OnShutdown(d.Close)
+	return d
+}
+
+// Subscribe adds or replaces subscriber with the same ID
+func (d *Dispatcher) Subscribe(s Subscriber) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.subs {
+		if d.subs[i].ID == s.ID {
+			d.subs[i] = s
+			return
+		}
+	}
+	d.subs = append(d.subs, s)
+}
+
+// Unsubscribe removes subscriber by ID, queued deliveries are still sent
+func (d *Dispatcher) Unsubscribe(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.subs {
+		if d.subs[i].ID == id {
+			d.subs = append(d.subs[:i], d.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish queues deliveries of event to subscribers, payload is sent as is when
+// it's []byte, or encoded as JSON. Deliveries are queued for all subscribers or
+// none, so a Publish failed with ErrQueueFull can be retried.
+func (d *Dispatcher) Publish(event string, payload interface{}) error {
+	data, ok := payload.([]byte)
+	if !ok {
+		var err error
+		if data, err = baa.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrClosed
+	}
+	var dls []*Delivery
+	for _, s := range d.subs {
+		if subscribed(s, event) {
+			dls = append(dls, &Delivery{ID: d.app.IDGenerator().NewID(), Event: event, Payload: data, Subscriber: s})
+		}
+	}
+	// retries queue with the write lock and workers only take from queue,
+	// so the room checked here is kept until deliveries are queued.
+	d.publish.Lock()
+	defer d.publish.Unlock()
+	if cap(d.queue)-len(d.queue) < len(dls) {
+		return ErrQueueFull
+	}
+	d.pending.Add(len(dls))
+	for _, dl := range dls {
+		d.queue <- dl
+	}
+	return nil
+}
+
+// Close stops accepting events and waits queued deliveries and retries finish,
+// deliveries not finished when ctx is done are passed to DeadLetter. It's safe to
+// call Close more than once, later calls wait for the first one and return nil.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	var err error
+	d.once.Do(func() {
+		err = d.close(ctx)
+	})
+	return err
+}
+
+// close stops the dispatcher, it's called once by Close
+func (d *Dispatcher) close(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.pending.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	d.mu.Lock()
+	d.stopped = true
+	close(d.stop)
+	var failed []*Delivery
+	for dl, t := range d.retries {
+		if t.Stop() {
+			failed = append(failed, dl)
+		}
+	}
+	d.retries = make(map[*Delivery]*time.Timer)
+	d.mu.Unlock()
+	for _, dl := range failed {
+		d.fail(dl, ErrClosed)
+	}
+	d.workers.Wait()
+	for {
+		select {
+		case dl := <-d.queue:
+			d.fail(dl, ErrClosed)
+		default:
+			return err
+		}
+	}
+}
+
+// work sends deliveries in queue
+func (d *Dispatcher) work() {
+	defer d.workers.Done()
+	for {
+		select {
+		case dl := <-d.queue:
+			d.deliver(dl)
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// deliver sends delivery dl, schedules a retry when failed
+func (d *Dispatcher) deliver(dl *Delivery) {
+	dl.Attempts++
+	err := d.send(dl)
+	if err == nil {
+		d.pending.Done()
+		return
+	}
+	if dl.Attempts >= d.opt.MaxAttempts {
+		d.fail(dl, err)
+		return
+	}
+	backoff := d.opt.Backoff << uint(dl.Attempts-1)
+	if backoff > d.opt.MaxBackoff || backoff <= 0 {
+		backoff = d.opt.MaxBackoff
+	}
+	d.schedule(dl, backoff, err)
+}
+
+// schedule retries dl after delay, dl fails with err when the dispatcher is stopped
+func (d *Dispatcher) schedule(dl *Delivery, delay time.Duration, err error) {
+	d.mu.Lock()
+	stopped := d.stopped
+	if !stopped {
+		d.retries[dl] = time.AfterFunc(delay, func() {
+			d.retry(dl)
+		})
+	}
+	d.mu.Unlock()
+	if stopped {
+		d.fail(dl, err)
+	}
+}
+
+// retry queues dl again, it's rescheduled when the queue is full
+func (d *Dispatcher) retry(dl *Delivery) {
+	d.mu.Lock()
+	delete(d.retries, dl)
+	stopped := d.stopped
+	queued := false
+	if !stopped {
+		select {
+		case d.queue <- dl:
+			queued = true
+		default:
+		}
+	}
+	d.mu.Unlock()
+	if stopped {
+		d.fail(dl, ErrClosed)
+	} else if !queued {
+		d.schedule(dl, d.opt.Backoff, ErrClosed)
+	}
+}
+
+// fail passes dl to DeadLetter
+func (d *Dispatcher) fail(dl *Delivery, err error) {
+	d.opt.DeadLetter(dl, err)
+	d.pending.Done()
+}
+
+// send posts delivery dl to the subscriber, non 2xx responses are failures
+func (d *Dispatcher) send(dl *Delivery) error {
+	req, err := http.NewRequest("POST", dl.Subscriber.URL, bytes.NewReader(dl.Payload))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(d.app.Clock().Now().Unix(), 10)
+	req.Header.Set("Content-Type", baa.ApplicationJSONCharsetUTF8)
+	req.Header.Set("X-Webhook-ID", dl.ID)
+	req.Header.Set("X-Webhook-Event", dl.Event)
+	req.Header.Set("X-Webhook-Timestamp", ts)
+	if dl.Subscriber.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+signature(dl.Subscriber.Secret, ts, dl.Payload))
+	}
+	resp, err := d.opt.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// subscribed checks s subscribes event
+func subscribed(s Subscriber, event string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// signature returns hex encoded HMAC-SHA256 of ts and payload
+func signature(secret, ts string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDispatcher1(t *testing.T) {
+	Convey("deliver signed events with retries", t, func() {
+		// receiver application verifies deliveries
+		var mu sync.Mutex
+		var received []string
+		failures := 2
+		receiver := baa.New()
+		r := New(Options{Provider: Signed{Secret: "s"}})
+		r.On("order.created", func(c *baa.Context, order *struct{ ID int }) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if failures > 0 {
+				failures--
+				return errors.New("busy")
+			}
+			received = append(received, c.Req.Header.Get("X-Webhook-Event"))
+			return nil
+		})
+		receiver.Post("/hook", r.Handle)
+		ts := httptest.NewServer(receiver)
+		defer ts.Close()
+
+		app := baa.New()
+		var dead []string
+		d := NewDispatcher(app, DispatcherOptions{
+			Backoff:     time.Millisecond,
+			MaxAttempts: 3,
+			DeadLetter: func(dl *Delivery, err error) {
+				mu.Lock()
+				dead = append(dead, dl.Subscriber.ID+" "+err.Error())
+				mu.Unlock()
+			},
+		})
+		d.Subscribe(Subscriber{ID: "ok", URL: ts.URL + "/hook", Secret: "s", Events: []string{"order.created"}})
+		d.Subscribe(Subscriber{ID: "bad", URL: ts.URL + "/hook", Secret: "x"})
+		d.Subscribe(Subscriber{ID: "gone", URL: ts.URL + "/hook"})
+		d.Unsubscribe("gone")
+
+		So(d.Publish("order.created", map[string]int{"ID": 1}), ShouldBeNil)
+		So(d.Publish("order.deleted", []byte(`{"ID":1}`)), ShouldBeNil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		So(app.Shutdown(ctx), ShouldBeNil)
+		So(received, ShouldResemble, []string{"order.created"})
+		So(len(dead), ShouldEqual, 2)
+		So(dead[0], ShouldStartWith, "bad status 401")
+		So(d.Publish("order.created", nil), ShouldEqual, ErrClosed)
+	})
+	Convey("pending deliveries are dead lettered when closed", t, func() {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		app := baa.New()
+		dead := make(chan error, 1)
+		d := NewDispatcher(app, DispatcherOptions{
+			Backoff:    time.Hour,
+			DeadLetter: func(dl *Delivery, err error) { dead <- err },
+		})
+		d.Subscribe(Subscriber{ID: "1", URL: ts.URL})
+		So(d.Publish("ping", nil), ShouldBeNil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		So(d.Close(ctx) == context.DeadlineExceeded, ShouldBeTrue)
+		So(<-dead, ShouldEqual, ErrClosed)
+		So(d.Close(ctx), ShouldBeNil)
+	})
+	Convey("full queue queues nothing and close is idempotent", t, func() {
+		var mu sync.Mutex
+		var hits int
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits++
+			mu.Unlock()
+		}))
+		defer ts.Close()
+
+		app := baa.New()
+		d := NewDispatcher(app, DispatcherOptions{QueueSize: 1})
+		d.Subscribe(Subscriber{ID: "1", URL: ts.URL})
+		d.Subscribe(Subscriber{ID: "2", URL: ts.URL})
+		So(d.Publish("ping", nil), ShouldEqual, ErrQueueFull)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.Close(context.Background())
+			}()
+		}
+		So(app.Shutdown(context.Background()), ShouldBeNil)
+		wg.Wait()
+		So(hits, ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,222 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-baa/baa"
+)
+
+// defaultTolerance is the default maximum age of signed timestamps
+const defaultTolerance = 5 * time.Minute
+
+// secretProvider is a provider verifies signatures with a shared secret
+type secretProvider interface {
+	secret() string
+}
+
+// GitHub verifies X-Hub-Signature-256 of GitHub webhooks, event name is header
+// X-GitHub-Event and delivery id is header X-GitHub-Delivery.
+type GitHub struct {
+	Secret string
+}
+
+// secret returns the shared secret
+func (p GitHub) secret() string {
+	return p.Secret
+}
+
+// Verify verifies signature of body
+func (p GitHub) Verify(c *baa.Context, body []byte) error {
+	sig := c.Req.Header.Get("X-Hub-Signature-256")
+	if !strings.HasPrefix(sig, "sha256=") {
+		return errors.New("missing X-Hub-Signature-256")
+	}
+	return checkMAC(p.Secret, body, sig[len("sha256="):])
+}
+
+// Event returns event name and delivery id
+func (p GitHub) Event(c *baa.Context, body []byte) (string, string, error) {
+	name := c.Req.Header.Get("X-GitHub-Event")
+	if name == "" {
+		return "", "", errors.New("missing X-GitHub-Event")
+	}
+	return name, c.Req.Header.Get("X-GitHub-Delivery"), nil
+}
+
+// Stripe verifies Stripe-Signature of Stripe webhooks, event name and id are
+// type and id of the event object in body.
+type Stripe struct {
+	Secret string
+	// Tolerance maximum age of signed timestamp, default is 5 minutes
+	Tolerance time.Duration
+}
+
+// secret returns the shared secret
+func (p Stripe) secret() string {
+	return p.Secret
+}
+
+// Verify verifies timestamp and signatures of body
+func (p Stripe) Verify(c *baa.Context, body []byte) error {
+	var ts string
+	var sigs []string
+	for _, kv := range strings.Split(c.Req.Header.Get("Stripe-Signature"), ",") {
+		kv = strings.TrimSpace(kv)
+		if strings.HasPrefix(kv, "t=") {
+			ts = kv[2:]
+		} else if strings.HasPrefix(kv, "v1=") {
+			sigs = append(sigs, kv[3:])
+		}
+	}
+	if ts == "" || len(sigs) == 0 {
+		return errors.New("missing Stripe-Signature")
+	}
+	if err := checkTimestamp(c, ts, p.Tolerance); err != nil {
+		return err
+	}
+	payload := append([]byte(ts+"."), body...)
+	for _, sig := range sigs {
+		if checkMAC(p.Secret, payload, sig) == nil {
+			return nil
+		}
+	}
+	return errors.New("signature mismatch")
+}
+
+// Event returns event type and id
+func (p Stripe) Event(c *baa.Context, body []byte) (string, string, error) {
+	var e struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	if err := baa.Unmarshal(body, &e); err != nil {
+		return "", "", err
+	}
+	if e.Type == "" {
+		return "", "", errors.New("missing event type")
+	}
+	return e.Type, e.ID, nil
+}
+
+// Slack verifies X-Slack-Signature of Slack Events API requests, event name is
+// the inner event type of event callbacks and type of other requests, eg:
+// url_verification, delivery id is event_id.
+type Slack struct {
+	Secret string
+	// Tolerance maximum age of signed timestamp, default is 5 minutes
+	Tolerance time.Duration
+}
+
+// secret returns the shared secret
+func (p Slack) secret() string {
+	return p.Secret
+}
+
+// Verify verifies timestamp and signature of body
+func (p Slack) Verify(c *baa.Context, body []byte) error {
+	ts := c.Req.Header.Get("X-Slack-Request-Timestamp")
+	sig := c.Req.Header.Get("X-Slack-Signature")
+	if ts == "" || !strings.HasPrefix(sig, "v0=") {
+		return errors.New("missing X-Slack-Signature")
+	}
+	if err := checkTimestamp(c, ts, p.Tolerance); err != nil {
+		return err
+	}
+	return checkMAC(p.Secret, append([]byte("v0:"+ts+":"), body...), sig[3:])
+}
+
+// Event returns event type and id
+func (p Slack) Event(c *baa.Context, body []byte) (string, string, error) {
+	var e struct {
+		Type    string `json:"type"`
+		EventID string `json:"event_id"`
+		Event   struct {
+			Type string `json:"type"`
+		} `json:"event"`
+	}
+	if err := baa.Unmarshal(body, &e); err != nil {
+		return "", "", err
+	}
+	if e.Type == "event_callback" && e.Event.Type != "" {
+		return e.Event.Type, e.EventID, nil
+	}
+	if e.Type == "" {
+		return "", "", errors.New("missing event type")
+	}
+	return e.Type, e.EventID, nil
+}
+
+// Signed verifies webhooks sent by Dispatcher, event name is header X-Webhook-Event
+// and delivery id is header X-Webhook-ID.
+type Signed struct {
+	Secret string
+	// Tolerance maximum age of signed timestamp, default is 5 minutes
+	Tolerance time.Duration
+}
+
+// secret returns the shared secret
+func (p Signed) secret() string {
+	return p.Secret
+}
+
+// Verify verifies timestamp and signature of body
+func (p Signed) Verify(c *baa.Context, body []byte) error {
+	ts := c.Req.Header.Get("X-Webhook-Timestamp")
+	sig := c.Req.Header.Get("X-Webhook-Signature")
+	if ts == "" || !strings.HasPrefix(sig, "sha256=") {
+		return errors.New("missing X-Webhook-Signature")
+	}
+	if err := checkTimestamp(c, ts, p.Tolerance); err != nil {
+		return err
+	}
+	return checkMAC(p.Secret, append([]byte(ts+"."), body...), sig[len("sha256="):])
+}
+
+// Event returns event name and delivery id
+func (p Signed) Event(c *baa.Context, body []byte) (string, string, error) {
+	name := c.Req.Header.Get("X-Webhook-Event")
+	if name == "" {
+		return "", "", errors.New("missing X-Webhook-Event")
+	}
+	return name, c.Req.Header.Get("X-Webhook-ID"), nil
+}
+
+// checkMAC checks hex encoded HMAC-SHA256 of payload
+func checkMAC(secret string, payload []byte, sig string) error {
+	if secret == "" {
+		return errors.New("empty secret")
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return errors.New("malformed signature")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// checkTimestamp checks unix timestamp ts is within tolerance, it prevents replay attacks
+func checkTimestamp(c *baa.Context, ts string, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = defaultTolerance
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed timestamp %q", ts)
+	}
+	d := c.Baa().Clock().Now().Sub(time.Unix(sec, 0))
+	if d > tolerance || d < -tolerance {
+		return errors.New("timestamp out of tolerance")
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+// Package webhook provides webhooks for baa. Receiver verifies signatures, limits
+// payload size, skips redelivered events and dispatches events to handlers registered
+// by event name. Dispatcher delivers signed events to subscribers with retries.
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-baa/baa"
+)
+
+var (
+	// ErrProcessed is returned by Store.Reserve when the event was processed
+	ErrProcessed = errors.New("webhook: event is processed")
+	// ErrProcessing is returned by Store.Reserve when the event is being processed
+	ErrProcessing = errors.New("webhook: event is being processed")
+)
+
+// Provider verifies and parses webhook requests of a sender, eg: GitHub, Stripe, Slack
+type Provider interface {
+	// Verify verifies signature of request body
+	Verify(c *baa.Context, body []byte) error
+	// Event returns event name and delivery id of request, an empty id disables deduplication
+	Event(c *baa.Context, body []byte) (name, id string, err error)
+}
+
+// Store remembers event ids for deduplication, it can be implemented by a shared
+// store, eg: redis, when the application runs on multiple instances.
+type Store interface {
+	// Reserve reserves id for processing, it returns ErrProcessing when id is reserved
+	// and ErrProcessed when id was committed.
+	Reserve(id string) error
+	// Commit marks reserved id processed, it's remembered for ttl
+	Commit(id string, ttl time.Duration)
+	// Release releases reserved id when processing failed, so a retry is processed
+	Release(id string)
+}
+
+// Event is a received webhook event
+type Event struct {
+	Name    string
+	ID      string
+	Payload []byte
+}
+
+// Options webhook receiver config
+type Options struct {
+	// Provider verifies and parses requests, required
+	Provider Provider
+	// MaxBodySize maximum payload bytes, default is 1MB
+	MaxBodySize int64
+	// Store remembers processed event ids, default is an in-memory store
+	Store Store
+	// TTL is how long processed event ids are remembered, default is 72 hours
+	TTL time.Duration
+}
+
+// Receiver receives webhook requests and dispatches events to handlers
+type Receiver struct {
+	opt      Options
+	handlers map[string]reflect.Value
+	once     sync.Once
+}
+
+// eventType is the type of *Event
+var eventType = reflect.TypeOf((*Event)(nil))
+
+// errorType is the type of error
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// contextType is the type of *baa.Context
+var contextType = reflect.TypeOf((*baa.Context)(nil))
+
+// New create a webhook receiver, it panics when the secret of a built-in provider is empty.
+//
+// Example:
+// 		r := webhook.New(webhook.Options{Provider: webhook.GitHub{Secret: secret}})
+// 		r.On("push", func(c *baa.Context, e *PushEvent) error { ... })
+// 		b.Post("/hooks/github", r.Handle)
+func New(opt Options) *Receiver {
+	if opt.Provider == nil {
+		panic("webhook.Options.Provider is required")
+	}
+	if p, ok := opt.Provider.(secretProvider); ok && p.secret() == "" {
+		panic("webhook.Options.Provider secret can not be empty")
+	}
+	if opt.MaxBodySize <= 0 {
+		opt.MaxBodySize = 1 << 20
+	}
+	if opt.TTL <= 0 {
+		opt.TTL = 72 * time.Hour
+	}
+	return &Receiver{opt: opt, handlers: make(map[string]reflect.Value)}
+}
+
+// On registers handler of event name, "*" handles events without a handler.
+// h is func(*baa.Context, *Event) error, or func(*baa.Context, *T) error
+// receives payload decoded as JSON into T. A handler can write the response,
+// eg: answering a verification challenge, 200 OK is sent otherwise.
+func (r *Receiver) On(name string, h interface{}) {
+	v := reflect.ValueOf(h)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.NumOut() != 1 ||
+		t.In(0) != contextType || t.In(1).Kind() != reflect.Ptr || t.Out(0) != errorType {
+		panic(fmt.Sprintf("webhook: invalid handler %s of event %s", t, name))
+	}
+	r.handlers[name] = v
+}
+
+// Handle handles webhook request, it responds 413 when payload is too large,
+// 401 when signature is invalid, 200 for processed events and 409 for events
+// being processed, and 500 when the handler fails so the sender retries.
+func (r *Receiver) Handle(c *baa.Context) {
+	r.once.Do(func() {
+		if r.opt.Store == nil {
+			app := c.Baa()
+			r.opt.Store = newMemoryStore(baa.ClockFunc(func() time.Time {
+				return app.Clock().Now()
+			}))
+		}
+	})
+
+	body, err := ioutil.ReadAll(io.LimitReader(c.Req.Body, r.opt.MaxBodySize+1))
+	if err != nil {
+		c.Error(baa.NewHTTPError(http.StatusBadRequest, "read webhook payload").WithError(err))
+		return
+	}
+	if int64(len(body)) > r.opt.MaxBodySize {
+		c.Error(baa.NewHTTPError(http.StatusRequestEntityTooLarge, "webhook payload too large"))
+		return
+	}
+	if err = r.opt.Provider.Verify(c, body); err != nil {
+		c.Error(baa.NewHTTPError(http.StatusUnauthorized, "invalid webhook signature").WithError(err))
+		return
+	}
+	e := &Event{Payload: body}
+	if e.Name, e.ID, err = r.opt.Provider.Event(c, body); err != nil {
+		c.Error(baa.NewHTTPError(http.StatusBadRequest, "invalid webhook event").WithError(err))
+		return
+	}
+
+	h, ok := r.handlers[e.Name]
+	if !ok {
+		h, ok = r.handlers["*"]
+	}
+	if !ok {
+		c.String(http.StatusOK, "ignored")
+		return
+	}
+
+	if e.ID != "" {
+		switch err := r.opt.Store.Reserve(e.ID); err {
+		case nil:
+		case ErrProcessed:
+			c.String(http.StatusOK, "duplicate")
+			return
+		case ErrProcessing:
+			c.Error(baa.NewHTTPError(http.StatusConflict, "webhook event is being processed"))
+			return
+		default:
+			c.Error(err)
+			return
+		}
+	}
+	committed := false
+	defer func() {
+		if e.ID != "" && !committed {
+			r.opt.Store.Release(e.ID)
+		}
+	}()
+
+	if err = r.call(h, c, e); err != nil {
+		if _, ok := err.(*baa.HTTPError); !ok {
+			err = baa.NewHTTPError(http.StatusInternalServerError, "webhook handler failed").WithError(err)
+		}
+		c.Error(err)
+		return
+	}
+	if e.ID != "" {
+		r.opt.Store.Commit(e.ID, r.opt.TTL)
+		committed = true
+	}
+	if !c.Resp.Wrote() {
+		c.String(http.StatusOK, "OK")
+	}
+}
+
+// call calls handler h with event e or its decoded payload
+func (r *Receiver) call(h reflect.Value, c *baa.Context, e *Event) error {
+	arg := reflect.ValueOf(e)
+	if t := h.Type().In(1); t != eventType {
+		arg = reflect.New(t.Elem())
+		if err := baa.Unmarshal(e.Payload, arg.Interface()); err != nil {
+			return baa.NewHTTPError(http.StatusBadRequest, "invalid webhook payload").WithError(err)
+		}
+	}
+	out := h.Call([]reflect.Value{reflect.ValueOf(c), arg})
+	if err, _ := out[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	items map[string]time.Time // processed ids and expiry, zero time for reserved ids
+	clock baa.Clock
+	mu    sync.Mutex
+}
+
+// NewMemoryStore create an in-memory store, expired ids are removed lazily
+func NewMemoryStore() Store {
+	return newMemoryStore(baa.SystemClock)
+}
+
+// newMemoryStore create an in-memory store uses clock
+func newMemoryStore(clock baa.Clock) Store {
+	return &memoryStore{items: make(map[string]time.Time), clock: clock}
+}
+
+// Reserve reserves id for processing
+func (s *memoryStore) Reserve(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	if expire, ok := s.items[id]; ok {
+		if expire.IsZero() {
+			return ErrProcessing
+		}
+		if now.Before(expire) {
+			return ErrProcessed
+		}
+	}
+	if len(s.items) > 1024 {
+		for k, v := range s.items {
+			if !v.IsZero() && !now.Before(v) {
+				delete(s.items, k)
+			}
+		}
+	}
+	s.items[id] = time.Time{}
+	return nil
+}
+
+// Commit marks id processed
+func (s *memoryStore) Commit(id string, ttl time.Duration) {
+	s.mu.Lock()
+	s.items[id] = s.clock.Now().Add(ttl)
+	s.mu.Unlock()
+}
+
+// Release releases reserved id
+func (s *memoryStore) Release(id string) {
+	s.mu.Lock()
+	if s.items[id].IsZero() {
+		delete(s.items, id)
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-baa/baa"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type pushEvent struct {
+	Ref string `json:"ref"`
+}
+
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func request(app *baa.Baa, body string, header map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/hook", strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func TestWebhook1(t *testing.T) {
+	Convey("receive GitHub webhooks", t, func() {
+		app := baa.New()
+		r := New(Options{Provider: GitHub{Secret: "s"}, MaxBodySize: 64})
+		var refs []string
+		fail := true
+		r.On("push", func(c *baa.Context, e *pushEvent) error {
+			refs = append(refs, e.Ref)
+			if fail {
+				fail = false
+				return errors.New("busy")
+			}
+			return nil
+		})
+		r.On("ping", func(c *baa.Context, e *Event) error {
+			c.String(http.StatusAccepted, "pong "+e.ID)
+			return nil
+		})
+		app.Post("/hook", r.Handle)
+
+		github := func(event, id, body, sig string) *httptest.ResponseRecorder {
+			return request(app, body, map[string]string{
+				"X-GitHub-Event":      event,
+				"X-GitHub-Delivery":   id,
+				"X-Hub-Signature-256": "sha256=" + sig,
+			})
+		}
+		body := `{"ref":"refs/heads/master"}`
+		w := github("push", "d1", body, sign("s", body))
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		w = github("push", "d1", body, sign("s", body))
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "OK")
+		w = github("push", "d1", body, sign("s", body))
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "duplicate")
+		So(refs, ShouldResemble, []string{"refs/heads/master", "refs/heads/master"})
+
+		w = github("ping", "d2", "{}", sign("s", "{}"))
+		So(w.Code, ShouldEqual, http.StatusAccepted)
+		So(w.Body.String(), ShouldEqual, "pong d2")
+		w = github("issues", "d3", "{}", sign("s", "{}"))
+		So(w.Body.String(), ShouldEqual, "ignored")
+
+		So(github("push", "d4", body, sign("x", body)).Code, ShouldEqual, http.StatusUnauthorized)
+		So(github("push", "d4", body, "zz").Code, ShouldEqual, http.StatusUnauthorized)
+		So(github("push", "d4", "[1]", sign("s", "[1]")).Code, ShouldEqual, http.StatusBadRequest)
+		large := `{"ref":"` + strings.Repeat("x", 64) + `"}`
+		So(github("push", "d5", large, sign("s", large)).Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+
+		So(func() { r.On("push", func(e *Event) error { return nil }) }, ShouldPanic)
+		So(func() { New(Options{}) }, ShouldPanic)
+		So(func() { New(Options{Provider: GitHub{}}) }, ShouldPanic)
+		So(func() { New(Options{Provider: Stripe{}}) }, ShouldPanic)
+		So(func() { New(Options{Provider: Slack{}}) }, ShouldPanic)
+		So(func() { New(Options{Provider: Signed{}}) }, ShouldPanic)
+		So(checkMAC("", nil, ""), ShouldNotBeNil)
+	})
+	Convey("verify Stripe and Slack signatures", t, func() {
+		clock := baa.NewFakeClock(time.Unix(1600000000, 0))
+		newApp := func(p Provider) (*baa.Baa, *[]string) {
+			app := baa.New()
+			app.SetDI("clock", clock)
+			r := New(Options{Provider: p})
+			var events []string
+			r.On("*", func(c *baa.Context, e *Event) error {
+				events = append(events, e.Name+" "+e.ID)
+				return nil
+			})
+			app.Post("/hook", r.Handle)
+			return app, &events
+		}
+		ts := strconv.FormatInt(clock.Now().Unix(), 10)
+
+		app, events := newApp(Stripe{Secret: "s"})
+		body := `{"id":"evt_1","type":"charge.succeeded"}`
+		header := map[string]string{"Stripe-Signature": "t=" + ts + ",v1=00,v1=" + sign("s", ts+"."+body)}
+		So(request(app, body, header).Code, ShouldEqual, http.StatusOK)
+		So(*events, ShouldResemble, []string{"charge.succeeded evt_1"})
+		clock.Advance(10 * time.Minute)
+		So(request(app, `{"id":"evt_2","type":"x"}`, header).Code, ShouldEqual, http.StatusUnauthorized)
+		clock.Advance(-10 * time.Minute)
+
+		app, events = newApp(Slack{Secret: "s"})
+		body = `{"type":"event_callback","event_id":"Ev1","event":{"type":"app_mention"}}`
+		header = map[string]string{
+			"X-Slack-Request-Timestamp": ts,
+			"X-Slack-Signature":         "v0=" + sign("s", "v0:"+ts+":"+body),
+		}
+		So(request(app, body, header).Code, ShouldEqual, http.StatusOK)
+		So(request(app, body, header).Body.String(), ShouldEqual, "duplicate")
+		So(*events, ShouldResemble, []string{"app_mention Ev1"})
+		header["X-Slack-Signature"] = "v0=" + sign("x", "v0:"+ts+":"+body)
+		So(request(app, body, header).Code, ShouldEqual, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,166 @@
+package baa
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebsocketOptions configures websocket upgrade of all websocket routes
+type WebsocketOptions struct {
+	// ReadBufferSize, WriteBufferSize specify I/O buffer sizes, default is 4096
+	ReadBufferSize  int
+	WriteBufferSize int
+	// DisableCompression disables per message compression negotiation
+	DisableCompression bool
+	// CheckOrigin returns true if the request Origin header is acceptable,
+	// all origins are allowed when it is nil.
+	CheckOrigin func(r *http.Request) bool
+	// MaxConnsPerIP limits concurrent connections of one peer ip, proxy headers set
+	// by clients are not trusted, clients behind a reverse proxy share its limit,
+	// 0 means no limit
+	MaxConnsPerIP int
+}
+
+// SetWebsocketOptions set options of websocket upgrade
+func (b *Baa) SetWebsocketOptions(opts WebsocketOptions) {
+	b.wsOptions = opts
+}
+
+// Websocket register a websocket router handler, handlers in m are executed
+// before upgrade, eg: authorization, connection is not upgraded when they write response.
+func (b *Baa) Websocket(pattern string, h func(*websocket.Conn), m ...HandlerFunc) MetaRouteNode {
+	return b.Route(pattern, "GET,POST", append(m[:len(m):len(m)], b.websocketHandler(h))...)
+}
+
+// Websocket register a websocket router handler under group
+func (g *Group) Websocket(pattern string, h func(*websocket.Conn), m ...HandlerFunc) MetaRouteNode {
+	return g.Route(pattern, "GET,POST", append(m[:len(m):len(m)], g.baa.websocketHandler(h))...)
+}
+
+// websocketHandler returns a route handler upgrades connection to websocket
+func (b *Baa) websocketHandler(h func(*websocket.Conn)) HandlerFunc {
+	return func(c *Context) {
+		opts := b.wsOptions
+		upgrader := websocket.Upgrader{
+			ReadBufferSize:    4096,
+			WriteBufferSize:   4096,
+			EnableCompression: !opts.DisableCompression,
+			CheckOrigin:       opts.CheckOrigin,
+		}
+		if opts.ReadBufferSize > 0 {
+			upgrader.ReadBufferSize = opts.ReadBufferSize
+		}
+		if opts.WriteBufferSize > 0 {
+			upgrader.WriteBufferSize = opts.WriteBufferSize
+		}
+		if upgrader.CheckOrigin == nil {
+			upgrader.CheckOrigin = func(r *http.Request) bool {
+				return true
+			}
+		}
+
+		// the proxy headers of RemoteAddr are set by clients, limit the peer address
+		ip, _, err := net.SplitHostPort(c.Req.RemoteAddr)
+		if err != nil {
+			ip = c.Req.RemoteAddr
+		}
+		if !b.wsConns.acquire(ip, opts.MaxConnsPerIP) {
+			http.Error(c.Resp, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		defer b.wsConns.release(ip)
+
+		conn, err := upgrader.Upgrade(c.Resp, c.Req, nil)
+		if err != nil {
+			b.Logger().Printf("websocket upgrade connection error: %v", err)
+			return
+		}
+		defer b.streams.add(conn)()
+		h(conn)
+	}
+}
+
+// WebsocketSameOrigin checks the Origin header has same host with request
+func WebsocketSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// WebsocketAllowOrigins returns an origin checker allows listed origins,
+// origin can be a host or a full url, prefix *. matches all sub domains.
+//
+// Example:
+// 		b.SetWebsocketOptions(baa.WebsocketOptions{
+// 			CheckOrigin: baa.WebsocketAllowOrigins("example.com", "*.example.com"),
+// 		})
+func WebsocketAllowOrigins(origins ...string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		for _, v := range origins {
+			if strings.EqualFold(v, origin) || strings.EqualFold(v, u.Host) {
+				return true
+			}
+			if strings.HasPrefix(v, "*.") && strings.HasSuffix(strings.ToLower(u.Host), strings.ToLower(v[1:])) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// connLimiter counts concurrent connections by key
+type connLimiter struct {
+	conns map[string]int
+	mu    sync.Mutex
+}
+
+// newConnLimiter create a connection limiter
+func newConnLimiter() *connLimiter {
+	return &connLimiter{conns: make(map[string]int)}
+}
+
+// acquire adds a connection of key, returns false when key has max connections
+func (l *connLimiter) acquire(key string, max int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if max > 0 && l.conns[key] >= max {
+		return false
+	}
+	l.conns[key]++
+	return true
+}
+
+// release removes a connection of key
+func (l *connLimiter) release(key string) {
+	l.mu.Lock()
+	if l.conns[key]--; l.conns[key] <= 0 {
+		delete(l.conns, key)
+	}
+	l.mu.Unlock()
+}
+
+// count returns connections of key
+func (l *connLimiter) count(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conns[key]
+}
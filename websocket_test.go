@@ -0,0 +1,83 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWebsocket1(t *testing.T) {
+	Convey("websocket policies", t, func() {
+		b2 := New()
+		b2.SetWebsocketOptions(WebsocketOptions{
+			CheckOrigin:   WebsocketAllowOrigins("http://example.com", "*.baa.io"),
+			MaxConnsPerIP: 1,
+		})
+		closed := make(chan bool)
+		auth := func(c *Context) {
+			if c.Query("token") != "secret" {
+				c.String(http.StatusUnauthorized, "unauthorized")
+			}
+		}
+		b2.Websocket("/ws", func(conn *websocket.Conn) {
+			conn.ReadMessage()
+			conn.Close()
+			closed <- true
+		}, auth)
+		ts := httptest.NewServer(b2)
+		defer ts.Close()
+		url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+
+		forwarded := 0
+		dial := func(query, origin string) (*websocket.Conn, int) {
+			h := http.Header{}
+			h.Set("Origin", origin)
+			forwarded++
+			h.Set("X-Forwarded-For", "10.0.0."+strconv.Itoa(forwarded))
+			conn, resp, err := websocket.DefaultDialer.Dial(url+query, h)
+			if err != nil {
+				if resp == nil {
+					return nil, 0
+				}
+				return nil, resp.StatusCode
+			}
+			return conn, resp.StatusCode
+		}
+
+		_, code := dial("", "http://example.com")
+		So(code, ShouldEqual, http.StatusUnauthorized)
+		_, code = dial("?token=secret", "http://evil.com")
+		So(code, ShouldEqual, http.StatusForbidden)
+
+		conn, code := dial("?token=secret", "http://ws.baa.io")
+		So(code, ShouldEqual, http.StatusSwitchingProtocols)
+		_, code = dial("?token=secret", "http://example.com")
+		So(code, ShouldEqual, http.StatusTooManyRequests)
+
+		conn.WriteMessage(websocket.TextMessage, []byte("bye"))
+		<-closed
+		conn.Close()
+		for i := 0; i < 100 && b2.wsConns.count("127.0.0.1") > 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		conn, code = dial("?token=secret", "http://example.com")
+		So(code, ShouldEqual, http.StatusSwitchingProtocols)
+		conn.WriteMessage(websocket.TextMessage, []byte("bye"))
+		<-closed
+		conn.Close()
+	})
+	Convey("same origin", t, func() {
+		req, _ := http.NewRequest("GET", "http://baa.io/ws", nil)
+		So(WebsocketSameOrigin(req), ShouldBeTrue)
+		req.Header.Set("Origin", "http://baa.io")
+		So(WebsocketSameOrigin(req), ShouldBeTrue)
+		req.Header.Set("Origin", "http://evil.io")
+		So(WebsocketSameOrigin(req), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,42 @@
+package baa
+
+import (
+	"sort"
+	"strings"
+)
+
+// wellKnownPrefix is the path prefix of well-known URIs, RFC 8615
+const wellKnownPrefix = "/.well-known/"
+
+// RouteMetaWellKnown is the route meta key of well-known name
+const RouteMetaWellKnown = "baa.wellKnown"
+
+// WellKnown registers a GET handler of /.well-known/name, eg: security.txt,
+// change-password, assetlinks.json. It panics when name is registered twice.
+// Registered names are listed by b.WellKnowns.
+func (b *Baa) WellKnown(name string, h ...HandlerFunc) MetaRouteNode {
+	name = strings.Trim(name, "/")
+	if name == "" {
+		panic("baa.WellKnown name can not be empty")
+	}
+	if b.wellKnowns == nil {
+		b.wellKnowns = make(map[string]RouteNode)
+	}
+	if _, ok := b.wellKnowns[name]; ok {
+		panic("baa.WellKnown " + name + " has been registered")
+	}
+	ru := b.Get(wellKnownPrefix+name, h...)
+	ru.SetMeta(RouteMetaWellKnown, name)
+	b.wellKnowns[name] = ru
+	return ru
+}
+
+// WellKnowns returns registered well-known names in order
+func (b *Baa) WellKnowns() []string {
+	names := make([]string, 0, len(b.wellKnowns))
+	for k := range b.wellKnowns {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,42 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWellKnown1(t *testing.T) {
+	Convey("well-known endpoints", t, func() {
+		b2 := New()
+		b2.WellKnown("security.txt", func(c *Context) {
+			c.String(200, "Contact: mailto:security@example.com")
+		})
+		b2.WellKnown("/change-password", func(c *Context) {
+			c.Redirect(302, "/settings/password")
+		})
+		var meta interface{}
+		b2.WellKnown("acme-challenge/:token", func(c *Context) {
+			meta = c.RouteMeta(RouteMetaWellKnown)
+			c.String(200, c.Param("token"))
+		})
+		So(b2.WellKnowns(), ShouldResemble, []string{"acme-challenge/:token", "change-password", "security.txt"})
+		So(b2.Router().Routes()["GET"], ShouldContain, "/.well-known/security.txt")
+
+		req, _ := http.NewRequest("GET", "/.well-known/security.txt", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "Contact: mailto:security@example.com")
+
+		req, _ = http.NewRequest("GET", "/.well-known/acme-challenge/abc", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "abc")
+		So(meta, ShouldEqual, "acme-challenge/:token")
+
+		So(func() { b2.WellKnown("security.txt", f) }, ShouldPanic)
+		So(func() { b2.WellKnown("/", f) }, ShouldPanic)
+	})
+}